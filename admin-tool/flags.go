@@ -6,6 +6,8 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
 )
 
 type addNode struct {
@@ -57,6 +59,12 @@ type flagContainer struct {
 	clustersReport     bool
 	getCluster         string
 	getClusters        bool
+	getLifecycle       string
+	setLifecycle       string
+	deleteLifecycle    string
+	setBlockSize       string
+	setWriteQuorum     string
+	setReadPreference  string
 	force              bool
 	help               bool
 	version            bool
@@ -214,6 +222,83 @@ func (f *flagContainer) Define(v string) int {
 		f.active = "getClusters"
 	}
 
+	if len(f.getLifecycle) > 0 {
+		activeCount++
+		f.active = "getLifecycle"
+	}
+
+	if len(f.setLifecycle) > 0 {
+		pipeIdx := strings.Index(f.setLifecycle, "|")
+		if pipeIdx < 1 || pipeIdx == len(f.setLifecycle)-1 {
+			fmt.Println("you should define the folder path and the lifecycle rule")
+			fmt.Println()
+			return 1
+		}
+
+		activeCount++
+		f.active = "setLifecycle"
+	}
+
+	if len(f.deleteLifecycle) > 0 {
+		activeCount++
+		f.active = "deleteLifecycle"
+	}
+
+	if len(f.setBlockSize) > 0 {
+		eqIdx := strings.Index(f.setBlockSize, "=")
+		if eqIdx < 1 {
+			fmt.Println("you should define the target cluster id and the block size")
+			fmt.Println()
+			return 1
+		}
+
+		_, err := strconv.ParseUint(f.setBlockSize[eqIdx+1:], 10, 32)
+		if err != nil {
+			fmt.Println("block size should be 0 or positive numeric value")
+			fmt.Println()
+			return 1
+		}
+
+		activeCount++
+		f.active = "setBlockSize"
+	}
+
+	if len(f.setWriteQuorum) > 0 {
+		eqIdx := strings.Index(f.setWriteQuorum, "=")
+		if eqIdx < 1 {
+			fmt.Println("you should define the target cluster id and the write quorum")
+			fmt.Println()
+			return 1
+		}
+
+		if !common.WriteQuorum(f.setWriteQuorum[eqIdx+1:]).Valid() {
+			fmt.Println("write quorum should be master, majority, all or empty")
+			fmt.Println()
+			return 1
+		}
+
+		activeCount++
+		f.active = "setWriteQuorum"
+	}
+
+	if len(f.setReadPreference) > 0 {
+		eqIdx := strings.Index(f.setReadPreference, "=")
+		if eqIdx < 1 {
+			fmt.Println("you should define the target cluster id and the read preference")
+			fmt.Println()
+			return 1
+		}
+
+		if !common.ReadPreference(f.setReadPreference[eqIdx+1:]).Valid() {
+			fmt.Println("read preference should be nearest, master, slaves, roundRobin, leastLoaded or empty")
+			fmt.Println()
+			return 1
+		}
+
+		activeCount++
+		f.active = "setReadPreference"
+	}
+
 	if activeCount == 0 {
 		fmt.Printf("Kertish-dfs Admin (v%s) usage: \n", v)
 		fmt.Println()
@@ -283,6 +368,28 @@ Ex: clusterId=snapshotIndex`)
 	var syncCluster string
 	set.StringVar(&syncCluster, `sync-cluster`, "", `Synchronise selected cluster and their nodes for data consistency. Use --force flag to force synchronization for frozen cluster`)
 
+	var getLifecycle string
+	set.StringVar(&getLifecycle, `get-lifecycle`, "", `Gets and prints the lifecycle rule of the folder.`)
+
+	var setLifecycle string
+	set.StringVar(&setLifecycle, `set-lifecycle`, "", `Sets the lifecycle rule of the folder. Files will be deleted automatically by the manager when they match with the rule. Provide folder path and rule with | separator. Possible rule options (expireAfter: days, keepLast: file count, deleteZombies: true/false)
+Ex: /renders|expireAfter=7,keepLast=10,deleteZombies=true`)
+
+	var deleteLifecycle string
+	set.StringVar(&deleteLifecycle, `delete-lifecycle`, "", `Deletes the lifecycle rule of the folder.`)
+
+	var setBlockSize string
+	set.StringVar(&setBlockSize, `set-block-size`, "", `Sets the block size of the cluster in bytes. New files are split to the chunks in this size on the cluster. Provide cluster id with block size or 0 to reset to the default (33554432).
+Ex: clusterId=67108864`)
+
+	var setWriteQuorum string
+	set.StringVar(&setWriteQuorum, `set-write-quorum`, "", `Sets the write quorum of the cluster. Uploads are accepted when the master (master), more than half of the nodes (majority) or every node (all) of the cluster holds the chunks. Provide cluster id with write quorum or empty to reset to the default (master).
+Ex: clusterId=majority`)
+
+	var setReadPreference string
+	set.StringVar(&setReadPreference, `set-read-preference`, "", `Sets the read preference of the cluster. Downloads are served by the nearest node (nearest), only the master (master), only the slaves (slaves), the nodes in turn (roundRobin) or the node with the least recent reads (leastLoaded). Provide cluster id with read preference or empty to reset to the default (nearest).
+Ex: clusterId=slaves`)
+
 	set.Bool(`sync-clusters`, false, `Synchronise all clusters and their nodes for data consistency. Use --force flag to force synchronization for frozen clusters`)
 	set.Bool(`clusters-report`, false, `Gets clusters health report.`)
 	set.Bool(`force`, false, `Force to apply the given command`)
@@ -373,6 +480,12 @@ Ex: clusterId=snapshotIndex`)
 		clustersReport:     strings.Contains(joinedArgs, "clusters-report"),
 		getCluster:         getCluster,
 		getClusters:        strings.Contains(joinedArgs, "get-clusters"),
+		getLifecycle:       getLifecycle,
+		setLifecycle:       setLifecycle,
+		deleteLifecycle:    deleteLifecycle,
+		setBlockSize:       setBlockSize,
+		setWriteQuorum:     setWriteQuorum,
+		setReadPreference:  setReadPreference,
 		force:              strings.Contains(joinedArgs, "-force"),
 		help:               strings.Contains(joinedArgs, "-help"),
 		version:            strings.Contains(joinedArgs, "-version"),
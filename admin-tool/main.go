@@ -267,5 +267,49 @@ func main() {
 			os.Exit(70)
 		}
 		fmt.Println("ok.")
+	case "getLifecycle":
+		if err := manager.GetLifecycle([]string{fc.managerAddress}, fc.getLifecycle); err != nil {
+			fmt.Printf("%s\n", err.Error())
+			os.Exit(85)
+		}
+		fmt.Println("ok.")
+	case "setLifecycle":
+		pipeIdx := strings.Index(fc.setLifecycle, "|")
+		if err := manager.SetLifecycle([]string{fc.managerAddress}, fc.setLifecycle[:pipeIdx], fc.setLifecycle[pipeIdx+1:]); err != nil {
+			fmt.Printf("%s\n", err.Error())
+			os.Exit(90)
+		}
+		fmt.Println("ok.")
+	case "deleteLifecycle":
+		if err := manager.DeleteLifecycle([]string{fc.managerAddress}, fc.deleteLifecycle); err != nil {
+			fmt.Printf("%s\n", err.Error())
+			os.Exit(95)
+		}
+		fmt.Println("ok.")
+	case "setBlockSize":
+		eqIdx := strings.Index(fc.setBlockSize, "=")
+		blockSize, _ := strconv.ParseUint(fc.setBlockSize[eqIdx+1:], 10, 32)
+
+		if err := manager.SetBlockSize([]string{fc.managerAddress}, fc.setBlockSize[:eqIdx], uint32(blockSize)); err != nil {
+			fmt.Printf("%s\n", err.Error())
+			os.Exit(100)
+		}
+		fmt.Println("ok.")
+	case "setWriteQuorum":
+		eqIdx := strings.Index(fc.setWriteQuorum, "=")
+
+		if err := manager.SetWriteQuorum([]string{fc.managerAddress}, fc.setWriteQuorum[:eqIdx], common.WriteQuorum(fc.setWriteQuorum[eqIdx+1:])); err != nil {
+			fmt.Printf("%s\n", err.Error())
+			os.Exit(105)
+		}
+		fmt.Println("ok.")
+	case "setReadPreference":
+		eqIdx := strings.Index(fc.setReadPreference, "=")
+
+		if err := manager.SetReadPreference([]string{fc.managerAddress}, fc.setReadPreference[:eqIdx], common.ReadPreference(fc.setReadPreference[eqIdx+1:])); err != nil {
+			fmt.Printf("%s\n", err.Error())
+			os.Exit(110)
+		}
+		fmt.Println("ok.")
 	}
 }
@@ -7,12 +7,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/freakmaxi/kertish-dfs/basics/caller"
 	"github.com/freakmaxi/kertish-dfs/basics/common"
 )
 
 const managerEndPoint = "/client/manager"
 
-var client = http.Client{Timeout: time.Hour * 24 * 7} // one week timeout
+var client = http.Client{Transport: caller.NewTransport(), Timeout: time.Hour * 24 * 7} // one week timeout
 
 func CreateCluster(managerAddr []string, addresses []string) error {
 	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s%s", managerAddr[0], managerEndPoint), nil)
@@ -229,6 +230,99 @@ func Unfreeze(managerAddr []string, clusterIds []string) error {
 	return nil
 }
 
+func SetBlockSize(managerAddr []string, clusterId string, blockSize uint32) error {
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s%s", managerAddr[0], managerEndPoint), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Action", "blockSize")
+	req.Header.Set("X-Options", fmt.Sprintf("%s=%d", clusterId, blockSize))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: manager node is not reachable", managerAddr[0])
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != 200 {
+		if res.StatusCode == 422 {
+			return fmt.Errorf("block size should be between %d and %d", common.MinBlockSize, common.MaxBlockSize)
+		}
+
+		var e common.Error
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+			return err
+		}
+		return fmt.Errorf(e.Message)
+	}
+
+	fmt.Println("Cluster block size is set...")
+
+	return nil
+}
+
+func SetWriteQuorum(managerAddr []string, clusterId string, writeQuorum common.WriteQuorum) error {
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s%s", managerAddr[0], managerEndPoint), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Action", "writeQuorum")
+	req.Header.Set("X-Options", fmt.Sprintf("%s=%s", clusterId, writeQuorum))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: manager node is not reachable", managerAddr[0])
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != 200 {
+		if res.StatusCode == 422 {
+			return fmt.Errorf("write quorum should be master, majority, all or empty")
+		}
+
+		var e common.Error
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+			return err
+		}
+		return fmt.Errorf(e.Message)
+	}
+
+	fmt.Println("Cluster write quorum is set...")
+
+	return nil
+}
+
+func SetReadPreference(managerAddr []string, clusterId string, readPreference common.ReadPreference) error {
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s%s", managerAddr[0], managerEndPoint), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Action", "readPreference")
+	req.Header.Set("X-Options", fmt.Sprintf("%s=%s", clusterId, readPreference))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: manager node is not reachable", managerAddr[0])
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != 200 {
+		if res.StatusCode == 422 {
+			return fmt.Errorf("read preference should be nearest, master, slaves, roundRobin, leastLoaded or empty")
+		}
+
+		var e common.Error
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+			return err
+		}
+		return fmt.Errorf(e.Message)
+	}
+
+	fmt.Println("Cluster read preference is set...")
+
+	return nil
+}
+
 func CreateSnapshot(managerAddr []string, clusterId string) error {
 	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s%s", managerAddr[0], managerEndPoint), nil)
 	if err != nil {
@@ -415,6 +509,9 @@ func GetClusters(managerAddr []string, clusterId string) error {
 		fmt.Printf("      Size:      %d (%d Gb)\n", cluster.Size, cluster.Size/(1024*1024*1024))
 		fmt.Printf("      Available: %d (%d Gb)\n", cluster.Available(), cluster.Available()/(1024*1024*1024))
 		fmt.Printf("      Weight:    %.2f\n", cluster.Weight())
+		fmt.Printf("      Block:     %d (%d Kb)\n", cluster.ChunkSize(0), cluster.ChunkSize(0)/1024)
+		fmt.Printf("      Quorum:    %d of %d\n", cluster.Quorum(""), len(cluster.Nodes))
+		fmt.Printf("      Reads:     %s\n", cluster.Preference(""))
 		state := "Online"
 		if cluster.Paralyzed {
 			state = "Paralyzed"
@@ -0,0 +1,113 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+)
+
+func GetLifecycle(managerAddr []string, folderPath string) error {
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s%s", managerAddr[0], managerEndPoint), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Action", "lifecycle")
+	req.Header.Set("X-Path", url.QueryEscape(folderPath))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: manager node is not reachable", managerAddr[0])
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != 200 {
+		if res.StatusCode == 422 {
+			return fmt.Errorf("folder path is not valid")
+		}
+
+		var e common.Error
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+			return err
+		}
+		return fmt.Errorf(e.Message)
+	}
+
+	var l common.Lifecycle
+	if err := json.NewDecoder(res.Body).Decode(&l); err != nil {
+		return err
+	}
+
+	fmt.Printf("Lifecycle Details: %s\n", folderPath)
+	if l.ExpireAfter > 0 {
+		fmt.Printf("      Expire After:   %d day(s)\n", l.ExpireAfter)
+	}
+	if l.KeepLast > 0 {
+		fmt.Printf("      Keep Last:      %d file(s)\n", l.KeepLast)
+	}
+	fmt.Printf("      Delete Zombies: %t\n", l.DeleteZombies)
+	fmt.Println()
+
+	return nil
+}
+
+func SetLifecycle(managerAddr []string, folderPath string, lifecycle string) error {
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s%s", managerAddr[0], managerEndPoint), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Action", "lifecycle")
+	req.Header.Set("X-Path", url.QueryEscape(folderPath))
+	req.Header.Set("X-Options", lifecycle)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: manager node is not reachable", managerAddr[0])
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != 200 {
+		if res.StatusCode == 422 {
+			return fmt.Errorf("folder path or lifecycle rule is not valid")
+		}
+
+		var e common.Error
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+			return err
+		}
+		return fmt.Errorf(e.Message)
+	}
+
+	return nil
+}
+
+func DeleteLifecycle(managerAddr []string, folderPath string) error {
+	req, err := http.NewRequest("DELETE", fmt.Sprintf("http://%s%s", managerAddr[0], managerEndPoint), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Action", "lifecycle")
+	req.Header.Set("X-Path", url.QueryEscape(folderPath))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: manager node is not reachable", managerAddr[0])
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != 200 {
+		if res.StatusCode == 422 {
+			return fmt.Errorf("folder path is not valid")
+		}
+
+		var e common.Error
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+			return err
+		}
+		return fmt.Errorf(e.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"go.uber.org/zap"
+)
+
+const auditQueueLimit = 10000
+const auditBulkLimit = 100
+const defaultAuditLimit = 1000
+
+type Auditor interface {
+	// Record queues the entry to be written in the background. It does not block the request, the entry is dropped
+	// and counted when the queue is full
+	Record(entry *common.AuditEntry)
+	List(pathPrefix string, begins time.Time, ends time.Time, limit int64) (common.AuditEntries, error)
+}
+
+type auditor struct {
+	store  Store
+	node   string
+	logger *zap.Logger
+
+	queue   chan *common.AuditEntry
+	dropped uint64
+}
+
+func NewAuditor(store Store, node string, logger *zap.Logger) Auditor {
+	a := &auditor{
+		store:  store,
+		node:   node,
+		logger: logger,
+		queue:  make(chan *common.AuditEntry, auditQueueLimit),
+	}
+	go a.process()
+
+	return a
+}
+
+func (a *auditor) Record(entry *common.AuditEntry) {
+	entry.Node = a.node
+
+	select {
+	case a.queue <- entry:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+		droppedTotal.Inc()
+	}
+}
+
+func (a *auditor) List(pathPrefix string, begins time.Time, ends time.Time, limit int64) (common.AuditEntries, error) {
+	if limit <= 0 || limit > defaultAuditLimit {
+		limit = defaultAuditLimit
+	}
+	return a.store.List(pathPrefix, begins, ends, limit)
+}
+
+func (a *auditor) process() {
+	for entry := range a.queue {
+		entries := common.AuditEntries{entry}
+		for len(entries) < auditBulkLimit && len(a.queue) > 0 {
+			entries = append(entries, <-a.queue)
+		}
+
+		if err := a.store.Append(entries); err != nil {
+			for _, entry := range entries {
+				a.logger.Error(
+					"Unable to write audit entry",
+					zap.String("operation", entry.Operation),
+					zap.Strings("paths", entry.Paths),
+					zap.String("options", entry.Options),
+					zap.String("caller", entry.Caller),
+					zap.String("clientAddress", entry.ClientAddress),
+					zap.String("forwardedFor", entry.ForwardedFor),
+					zap.Int("result", entry.Result),
+					zap.Time("date", entry.Date),
+					zap.Error(err),
+				)
+			}
+			continue
+		}
+
+		// drops are reported once the store accepts the entries again
+		if dropped := atomic.SwapUint64(&a.dropped, 0); dropped > 0 {
+			a.logger.Warn(fmt.Sprintf("%d audit entries are dropped, audit queue was full", dropped))
+		}
+	}
+}
+
+var _ Auditor = &auditor{}
@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type countingStore struct {
+	appended int64
+}
+
+func (c *countingStore) Append(entries common.AuditEntries) error {
+	atomic.AddInt64(&c.appended, int64(len(entries)))
+	return nil
+}
+
+func (c *countingStore) List(_ string, _ time.Time, _ time.Time, _ int64) (common.AuditEntries, error) {
+	return common.AuditEntries{}, nil
+}
+
+func TestAuditor_RecordDropsWhenFull(t *testing.T) {
+	store := &countingStore{}
+
+	// queue is not processed until it is full, it is the same as the store that does not respond
+	a := &auditor{
+		store:  store,
+		node:   "test",
+		logger: zap.NewNop(),
+		queue:  make(chan *common.AuditEntry, auditQueueLimit),
+	}
+
+	for i := 0; i < auditQueueLimit+100; i++ {
+		a.Record(common.NewAuditEntry("create", []string{"/a"}, "", "", ""))
+	}
+	assert.Equal(t, uint64(100), atomic.LoadUint64(&a.dropped))
+	assert.Len(t, a.queue, auditQueueLimit)
+
+	go a.process()
+	defer close(a.queue)
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&store.appended) == int64(auditQueueLimit) && atomic.LoadUint64(&a.dropped) == 0
+	}, time.Second*5, time.Millisecond*10)
+}
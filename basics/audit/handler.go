@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"go.uber.org/zap"
+)
+
+// ResponseWriter keeps the status code of the response to complete the audit entry
+type ResponseWriter struct {
+	http.ResponseWriter
+
+	statusCode int
+}
+
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{
+		ResponseWriter: w,
+		statusCode:     200,
+	}
+}
+
+func (a *ResponseWriter) WriteHeader(statusCode int) {
+	a.statusCode = statusCode
+	a.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (a *ResponseWriter) StatusCode() int {
+	return a.statusCode
+}
+
+// NewEntry creates the audit entry of the request with the peer address of the connection. Caller and forwarded for
+// headers are kept in their own fields as the client sends them
+func NewEntry(r *http.Request, operation string, paths []string, options string) *common.AuditEntry {
+	clientAddress := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(clientAddress); err == nil {
+		clientAddress = host
+	}
+
+	entry := common.NewAuditEntry(operation, paths, options, r.Header.Get("X-Caller"), clientAddress)
+	entry.ForwardedFor = r.Header.Get("X-Forwarded-For")
+
+	return entry
+}
+
+type handler struct {
+	auditor Auditor
+	logger  *zap.Logger
+}
+
+// NewHandler creates the http handler of the audit query requests
+func NewHandler(auditor Auditor, logger *zap.Logger) http.HandlerFunc {
+	h := &handler{
+		auditor: auditor,
+		logger:  logger,
+	}
+	return h.manipulate
+}
+
+func (a *handler) manipulate(w http.ResponseWriter, r *http.Request) {
+	defer func() { _ = r.Body.Close() }()
+
+	switch r.Method {
+	case "GET":
+		a.handleGet(w, r)
+	default:
+		w.WriteHeader(406)
+	}
+}
+
+func (a *handler) handleGet(w http.ResponseWriter, r *http.Request) {
+	pathPrefix, err := url.QueryUnescape(r.Header.Get("X-Path"))
+	if err != nil || len(pathPrefix) > 0 && !common.ValidatePath(pathPrefix) {
+		w.WriteHeader(422)
+		return
+	}
+	if len(pathPrefix) > 0 {
+		pathPrefix = common.CorrectPath(pathPrefix)
+	}
+
+	begins, ends, err := a.describeTimeRange(r.Header.Get("X-Begins"), r.Header.Get("X-Ends"))
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	limit := int64(0)
+	if limitHeader := r.Header.Get("X-Limit"); len(limitHeader) > 0 {
+		limit, err = strconv.ParseInt(limitHeader, 10, 64)
+		if err != nil {
+			w.WriteHeader(422)
+			return
+		}
+	}
+
+	entries, err := a.auditor.List(pathPrefix, begins, ends, limit)
+	if err != nil {
+		w.WriteHeader(500)
+		a.logger.Error("Audit request is failed", zap.String("path", pathPrefix), zap.Error(err))
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		a.logger.Error("Response of audit request is failed", zap.Error(err))
+	}
+}
+
+func (a *handler) describeTimeRange(beginsHeader string, endsHeader string) (time.Time, time.Time, error) {
+	begins := time.Time{}
+	ends := time.Now().UTC()
+
+	if len(beginsHeader) > 0 {
+		t, err := time.Parse(time.RFC3339, beginsHeader)
+		if err != nil {
+			return begins, ends, err
+		}
+		begins = t.UTC()
+	}
+
+	if len(endsHeader) > 0 {
+		t, err := time.Parse(time.RFC3339, endsHeader)
+		if err != nil {
+			return begins, ends, err
+		}
+		ends = t.UTC()
+	}
+
+	if ends.Before(begins) {
+		return begins, ends, os.ErrInvalid
+	}
+
+	return begins, ends, nil
+}
@@ -0,0 +1,20 @@
+package audit
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEntry_ClientAddress(t *testing.T) {
+	r := httptest.NewRequest("DELETE", "/client/dfs", nil)
+	r.RemoteAddr = "10.0.0.5:51234"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.1")
+	r.Header.Set("X-Caller", "admin@workstation")
+
+	entry := NewEntry(r, "delete", []string{"/a"}, "")
+	assert.Equal(t, "10.0.0.5", entry.ClientAddress)
+	assert.Equal(t, "1.2.3.4, 10.0.0.1", entry.ForwardedFor)
+	assert.Equal(t, "admin@workstation", entry.Caller)
+}
@@ -0,0 +1,13 @@
+package audit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var droppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "kertish",
+	Subsystem: "audit",
+	Name:      "dropped_entries_total",
+	Help:      "Number of the audit entries that are dropped because the audit queue is full",
+})
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Store keeps the audit entries of the head and manager nodes
+type Store interface {
+	Append(entries common.AuditEntries) error
+	List(pathPrefix string, begins time.Time, ends time.Time, limit int64) (common.AuditEntries, error)
+}
+
+const auditCollection = "audit"
+
+type store struct {
+	col *mongo.Collection
+}
+
+func NewStore(database *mongo.Database) (Store, error) {
+	a := &store{
+		col: database.Collection(auditCollection),
+	}
+	if err := a.setupIndices(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *store) context(parentContext context.Context) (context.Context, context.CancelFunc) {
+	timeoutDuration := time.Second * 30
+	return context.WithTimeout(parentContext, timeoutDuration)
+}
+
+func (a *store) setupIndices() error {
+	models := []mongo.IndexModel{
+		{Keys: bson.M{"date": 1}},
+		{Keys: bson.M{"paths": 1}},
+	}
+
+	ctx, cancelFunc := a.context(context.Background())
+	defer cancelFunc()
+
+	_, err := a.col.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+func (a *store) next(cursor *mongo.Cursor) (*common.AuditEntry, error) {
+	ctx, cancelFunc := a.context(context.Background())
+	defer cancelFunc()
+
+	if !cursor.Next(ctx) {
+		return nil, io.EOF
+	}
+
+	var entry *common.AuditEntry
+	if err := cursor.Decode(&entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (a *store) Append(entries common.AuditEntries) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0)
+	for _, entry := range entries {
+		documents = append(documents, entry)
+	}
+
+	ctx, cancelFunc := a.context(context.Background())
+	defer cancelFunc()
+
+	_, err := a.col.InsertMany(ctx, documents)
+	return err
+}
+
+func (a *store) List(pathPrefix string, begins time.Time, ends time.Time, limit int64) (common.AuditEntries, error) {
+	filter := bson.M{"date": bson.M{"$gte": begins, "$lte": ends}}
+	if len(pathPrefix) > 0 {
+		// prefix matches on the path boundary, /a does not match /ab
+		pattern := regexp.QuoteMeta(strings.TrimSuffix(pathPrefix, "/"))
+		filter["paths"] = bson.M{"$regex": primitive.Regex{Pattern: fmt.Sprintf("^%s(/|$)", pattern)}}
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.M{"date": 1})
+	opts.SetLimit(limit)
+
+	ctx, cancelFunc := a.context(context.Background())
+	defer cancelFunc()
+
+	cursor, err := a.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		ctx, cancelFunc := a.context(context.Background())
+		defer cancelFunc()
+
+		_ = cursor.Close(ctx)
+	}()
+
+	entries := make(common.AuditEntries, 0)
+	for {
+		entry, err := a.next(cursor)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+var _ Store = &store{}
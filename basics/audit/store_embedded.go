@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"sort"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	bolt "go.etcd.io/bbolt"
+)
+
+type storeEmbedded struct {
+	store embedded.Store
+}
+
+func NewStoreEmbedded(store embedded.Store) (Store, error) {
+	if err := store.Setup(auditCollection); err != nil {
+		return nil, err
+	}
+
+	return &storeEmbedded{
+		store: store,
+	}, nil
+}
+
+func (a *storeEmbedded) Append(entries common.AuditEntries) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return a.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(auditCollection))
+
+		for _, entry := range entries {
+			sequence, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := embedded.Put(bucket, string(embedded.SequenceKey(sequence)), entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (a *storeEmbedded) List(pathPrefix string, begins time.Time, ends time.Time, limit int64) (common.AuditEntries, error) {
+	entries := make(common.AuditEntries, 0)
+
+	if err := a.store.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(auditCollection)).ForEach(func(_, v []byte) error {
+			var entry *common.AuditEntry
+			if err := embedded.Decode(v, &entry); err != nil {
+				return err
+			}
+
+			if entry.Date.Before(begins) || entry.Date.After(ends) {
+				return nil
+			}
+			if len(pathPrefix) > 0 && !a.matches(entry.Paths, pathPrefix) {
+				return nil
+			}
+
+			entries = append(entries, entry)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	if limit > 0 && int64(len(entries)) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries, nil
+}
+
+func (a *storeEmbedded) matches(paths []string, pathPrefix string) bool {
+	for _, path := range paths {
+		if common.InFolder(path, pathPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var _ Store = &storeEmbedded{}
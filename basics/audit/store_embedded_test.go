@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreEmbedded_ListPathPrefix(t *testing.T) {
+	folder, err := ioutil.TempDir("", "kertish-audit")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(folder) })
+
+	s, err := embedded.NewStore(path.Join(folder, "kertish.db"))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	store, err := NewStoreEmbedded(s)
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.Append(common.AuditEntries{
+		common.NewAuditEntry("createFolder", []string{"/a"}, "", "", ""),
+		common.NewAuditEntry("createFile", []string{"/a/file"}, "", "", ""),
+		common.NewAuditEntry("createFile", []string{"/ab/file"}, "", "", ""),
+	}))
+
+	entries, err := store.List("/a", time.Time{}, time.Now().UTC(), 10)
+	assert.Nil(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, []string{"/a"}, entries[0].Paths)
+		assert.Equal(t, []string{"/a/file"}, entries[1].Paths)
+	}
+}
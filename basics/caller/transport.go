@@ -0,0 +1,34 @@
+package caller
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+)
+
+type transport struct {
+	caller string
+}
+
+// NewTransport creates the http transport that defines the user and host of the tool with X-Caller header
+func NewTransport() http.RoundTripper {
+	caller := ""
+	if u, err := user.Current(); err == nil {
+		caller = u.Username
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		caller = fmt.Sprintf("%s@%s", caller, hostname)
+	}
+
+	return &transport{
+		caller: caller,
+	}
+}
+
+func (c *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Caller", c.caller)
+
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,40 @@
+package common
+
+import "time"
+
+// AuditEntry keeps the operation with the peer address of the connection in ClientAddress. Caller and ForwardedFor are
+// the values that the client sends in X-Caller and X-Forwarded-For headers, they are not verified
+type AuditEntry struct {
+	Node          string    `json:"node"`
+	Operation     string    `json:"operation"`
+	Paths         []string  `json:"paths"`
+	Options       string    `json:"options"`
+	Caller        string    `json:"caller"`
+	ClientAddress string    `json:"clientAddress"`
+	ForwardedFor  string    `json:"forwardedFor"`
+	Result        int       `json:"result"`
+	Duration      int64     `json:"duration"` // Milliseconds
+	Date          time.Time `json:"date"`
+}
+
+type AuditEntries []*AuditEntry
+
+func NewAuditEntry(operation string, paths []string, options string, caller string, clientAddress string) *AuditEntry {
+	if paths == nil {
+		paths = make([]string, 0)
+	}
+
+	return &AuditEntry{
+		Operation:     operation,
+		Paths:         paths,
+		Options:       options,
+		Caller:        caller,
+		ClientAddress: clientAddress,
+		Date:          time.Now().UTC(),
+	}
+}
+
+func (a *AuditEntry) Complete(result int) {
+	a.Result = result
+	a.Duration = time.Now().UTC().Sub(a.Date).Milliseconds()
+}
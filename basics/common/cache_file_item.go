@@ -42,7 +42,7 @@ func NewCacheFileItemFromMap(cache map[string]string) *CacheFileItem {
 	return &CacheFileItem{
 		FileItem: SyncFileItem{
 			Sha512Hex: cache["sha512Hex"],
-			Usage:     uint16(usage),
+			Usage:     uint32(usage),
 			Size:      uint32(size),
 		},
 		ClusterId: cache["clusterId"],
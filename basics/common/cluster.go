@@ -8,15 +8,62 @@ import (
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
 )
 
+const (
+	DefaultBlockSize uint32 = 1024 * 1024 * 32   // 32Mb
+	MinBlockSize     uint32 = 1024 * 64          // 64Kb
+	MaxBlockSize     uint32 = 1024 * 1024 * 1024 // 1Gb
+)
+
+// WriteQuorum defines the nodes of the cluster that should hold the block before the upload is acknowledged
+type WriteQuorum string
+
+const (
+	WQ_Master   WriteQuorum = "master"
+	WQ_Majority WriteQuorum = "majority"
+	WQ_All      WriteQuorum = "all"
+)
+
+// Valid returns true if the write quorum is known, empty write quorum is valid and means the default
+func (w WriteQuorum) Valid() bool {
+	switch w {
+	case "", WQ_Master, WQ_Majority, WQ_All:
+		return true
+	}
+	return false
+}
+
+// ReadPreference defines the nodes of the cluster that serve the reads and their order
+type ReadPreference string
+
+const (
+	RP_Nearest     ReadPreference = "nearest"
+	RP_Master      ReadPreference = "master"
+	RP_Slaves      ReadPreference = "slaves"
+	RP_RoundRobin  ReadPreference = "roundRobin"
+	RP_LeastLoaded ReadPreference = "leastLoaded"
+)
+
+// Valid returns true if the read preference is known, empty read preference is valid and means the default
+func (r ReadPreference) Valid() bool {
+	switch r {
+	case "", RP_Nearest, RP_Master, RP_Slaves, RP_RoundRobin, RP_LeastLoaded:
+		return true
+	}
+	return false
+}
+
 type Cluster struct {
-	Id           string            `json:"clusterId"`
-	Size         uint64            `json:"size"`
-	Used         uint64            `json:"used"`
-	Nodes        NodeList          `json:"nodes"`
-	Reservations map[string]uint64 `json:"reservations"`
-	Paralyzed    bool              `json:"paralyzed"`
-	Frozen       bool              `json:"frozen"`
-	Snapshots    Snapshots         `json:"snapshots"`
+	Id             string            `json:"clusterId"`
+	Size           uint64            `json:"size"`
+	Used           uint64            `json:"used"`
+	Nodes          NodeList          `json:"nodes"`
+	Reservations   map[string]uint64 `json:"reservations"`
+	Paralyzed      bool              `json:"paralyzed"`
+	Frozen         bool              `json:"frozen"`
+	BlockSize      uint32            `json:"blockSize"`
+	WriteQuorum    WriteQuorum       `json:"writeQuorum"`
+	ReadPreference ReadPreference    `json:"readPreference"`
+	Snapshots      Snapshots         `json:"snapshots"`
 }
 
 type Clusters []*Cluster
@@ -69,6 +116,37 @@ func (c *Cluster) Available() uint64 {
 	return c.Size - c.Used
 }
 
+// ChunkSize returns the block size of the cluster. Requested size is used when it is smaller than the cluster block size
+func (c *Cluster) ChunkSize(requested uint32) uint32 {
+	blockSize := c.BlockSize
+	if blockSize == 0 {
+		blockSize = DefaultBlockSize
+	}
+
+	if requested > 0 && requested < blockSize {
+		return requested
+	}
+	return blockSize
+}
+
+// Quorum returns the number of the nodes that should hold the block, master included. Requested write quorum is used
+// instead of the cluster write quorum when it is defined
+func (c *Cluster) Quorum(requested WriteQuorum) int {
+	writeQuorum := c.WriteQuorum
+	if len(requested) > 0 {
+		writeQuorum = requested
+	}
+
+	switch writeQuorum {
+	case WQ_Majority:
+		return len(c.Nodes)/2 + 1
+	case WQ_All:
+		return len(c.Nodes)
+	default:
+		return 1
+	}
+}
+
 func (c *Cluster) Weight() float64 {
 	weight := float64(c.Used) / float64(c.Size) * 1000
 	return math.Round(weight) / 1000
@@ -128,24 +206,53 @@ func (c *Cluster) Slaves() NodeList {
 	return slaves
 }
 
-func (c *Cluster) HighQualityNode(nodeIdsMap CacheFileItemLocationMap) *Node {
-	quality := int64(^uint(0) >> 1) // MaxIntNumber
-	nodeIndex := -1
-	for i, n := range c.Nodes {
-		if exists, has := nodeIdsMap[n.Id]; !has || !exists {
-			continue
-		}
+// Preference returns the read preference of the cluster. Requested read preference is used instead of the cluster read
+// preference when it is defined
+func (c *Cluster) Preference(requested ReadPreference) ReadPreference {
+	if len(requested) > 0 {
+		return requested
+	}
+	if len(c.ReadPreference) > 0 {
+		return c.ReadPreference
+	}
+	return RP_Nearest
+}
+
+// Readers returns the nodes having the file that are allowed by the read preference ordered by quality. Master and
+// slaves preferences drop the other nodes even when they are the only ones having the file
+func (c *Cluster) Readers(nodeIdsMap CacheFileItemLocationMap, preference ReadPreference) NodeList {
+	replicas := c.Replicas(nodeIdsMap)
 
-		if n.Quality < quality {
-			quality = n.Quality
-			nodeIndex = i
+	readers := make(NodeList, 0, len(replicas))
+	for _, n := range replicas {
+		switch preference {
+		case RP_Master:
+			if !n.Master {
+				continue
+			}
+		case RP_Slaves:
+			if n.Master {
+				continue
+			}
 		}
+		readers = append(readers, n)
 	}
+	return readers
+}
 
-	if nodeIndex > -1 {
-		return c.Nodes[nodeIndex]
+// Replicas returns the nodes having the file ordered by quality, the best is the first
+func (c *Cluster) Replicas(nodeIdsMap CacheFileItemLocationMap) NodeList {
+	replicas := make(NodeList, 0)
+	for _, n := range c.Nodes {
+		if exists, has := nodeIdsMap[n.Id]; !has || !exists {
+			continue
+		}
+		replicas = append(replicas, n)
 	}
-	return nil
+	sort.SliceStable(replicas, func(i, j int) bool {
+		return replicas[i].Quality < replicas[j].Quality
+	})
+	return replicas
 }
 
 func (c *Cluster) Others(nodeId string) NodeList {
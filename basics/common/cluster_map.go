@@ -4,4 +4,7 @@ type ClusterMap struct {
 	Id      string `json:"clusterId"`
 	Address string `json:"address"`
 	Chunk   Chunk  `json:"chunk"`
+	// Quorum is the number of the nodes that should hold the chunk, 0 means only the master
+	Quorum   int      `json:"quorum,omitempty"`
+	Replicas []string `json:"replicas,omitempty"`
 }
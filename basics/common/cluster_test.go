@@ -0,0 +1,41 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCluster_Readers(t *testing.T) {
+	c := &Cluster{
+		Nodes: NodeList{
+			{Id: "master", Master: true, Quality: 5},
+			{Id: "slave1", Quality: 3},
+			{Id: "slave2", Quality: 1},
+		},
+	}
+	existsIn := CacheFileItemLocationMap{"master": true, "slave1": true, "slave2": false}
+
+	assert.Equal(t, RP_Nearest, c.Preference(""))
+	c.ReadPreference = RP_Slaves
+	assert.Equal(t, RP_Slaves, c.Preference(""))
+	assert.Equal(t, RP_Master, c.Preference(RP_Master))
+
+	nodeIds := func(nodes NodeList) []string {
+		ids := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			ids = append(ids, n.Id)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"slave1", "master"}, nodeIds(c.Readers(existsIn, RP_Nearest)))
+	assert.Equal(t, []string{"master"}, nodeIds(c.Readers(existsIn, RP_Master)))
+	assert.Equal(t, []string{"slave1"}, nodeIds(c.Readers(existsIn, RP_Slaves)))
+
+	existsIn["slave1"] = false
+	assert.Empty(t, c.Readers(existsIn, RP_Slaves))
+
+	assert.True(t, ReadPreference("").Valid())
+	assert.False(t, ReadPreference("random").Valid())
+}
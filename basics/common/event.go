@@ -0,0 +1,64 @@
+package common
+
+import (
+	"time"
+)
+
+type EventType string
+
+const (
+	ET_Create    EventType = "create"
+	ET_Overwrite EventType = "overwrite"
+	ET_Copy      EventType = "copy"
+	ET_Move      EventType = "move"
+	ET_Delete    EventType = "delete"
+)
+
+type Event struct {
+	Sequence uint64    `json:"sequence"`
+	Type     EventType `json:"type"`
+	Folder   bool      `json:"folder"`
+	Sources  []string  `json:"sources"`
+	Target   string    `json:"target"`
+	Date     time.Time `json:"date"`
+}
+
+type Events []*Event
+
+func NewEvent(eventType EventType, folder bool, sources []string, target string) *Event {
+	if sources == nil {
+		sources = make([]string, 0)
+	}
+
+	return &Event{
+		Type:    eventType,
+		Folder:  folder,
+		Sources: sources,
+		Target:  target,
+		Date:    time.Now().UTC(),
+	}
+}
+
+func (e *Event) Paths() []string {
+	paths := make([]string, 0)
+	paths = append(paths, e.Sources...)
+	if len(e.Target) > 0 {
+		paths = append(paths, e.Target)
+	}
+	return paths
+}
+
+func (e *Event) Match(pathPrefixes []string) bool {
+	if len(pathPrefixes) == 0 {
+		return true
+	}
+
+	for _, p := range e.Paths() {
+		for _, pathPrefix := range pathPrefixes {
+			if InFolder(p, pathPrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
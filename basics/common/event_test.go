@@ -0,0 +1,27 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFolder(t *testing.T) {
+	assert.True(t, InFolder("/a", "/a"))
+	assert.True(t, InFolder("/a/b", "/a"))
+	assert.True(t, InFolder("/a/b", "/a/"))
+	assert.True(t, InFolder("/a", "/"))
+	assert.False(t, InFolder("/ab", "/a"))
+	assert.False(t, InFolder("/ab/c", "/a"))
+	assert.False(t, InFolder("/", "/a"))
+}
+
+func TestEvent_Match(t *testing.T) {
+	e := NewEvent(ET_Move, false, []string{"/renders/frame.png"}, "/archive/frame.png")
+
+	assert.True(t, e.Match(nil))
+	assert.True(t, e.Match([]string{"/renders"}))
+	assert.True(t, e.Match([]string{"/uploads", "/archive"}))
+	assert.False(t, e.Match([]string{"/render"}))
+	assert.False(t, e.Match([]string{"/archive/frame"}))
+}
@@ -12,13 +12,14 @@ import (
 )
 
 type Folder struct {
-	Full     string        `json:"full"`
-	Name     string        `json:"name"`
-	Created  time.Time     `json:"created"`
-	Modified time.Time     `json:"modified"`
-	Folders  FolderShadows `json:"folders"`
-	Files    Files         `json:"files"`
-	Size     uint64        `json:"size" bson:"-"`
+	Full      string        `json:"full"`
+	Name      string        `json:"name"`
+	Created   time.Time     `json:"created"`
+	Modified  time.Time     `json:"modified"`
+	Folders   FolderShadows `json:"folders"`
+	Files     Files         `json:"files"`
+	Lifecycle *Lifecycle    `json:"lifecycle"`
+	Size      uint64        `json:"size" bson:"-"`
 }
 
 func NewFolder(folderPath string) *Folder {
@@ -30,6 +30,17 @@ func CorrectPath(folderPath string) string {
 	return CorrectPaths([]string{folderPath})[0]
 }
 
+// InFolder returns true if the path is the folder path itself or placed under it
+func InFolder(path string, folderPath string) bool {
+	if strings.Compare(path, folderPath) == 0 {
+		return true
+	}
+	if !strings.HasSuffix(folderPath, pathSeparator) {
+		folderPath = fmt.Sprintf("%s%s", folderPath, pathSeparator)
+	}
+	return strings.HasPrefix(path, folderPath)
+}
+
 func PathTree(folderPath string) []string {
 	folderPath = CorrectPath(folderPath)
 	if strings.Compare(folderPath, pathSeparator) == 0 {
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+)
+
+type Lifecycle struct {
+	ExpireAfter   uint16 `json:"expireAfter"` // Days
+	KeepLast      uint32 `json:"keepLast"`
+	DeleteZombies bool   `json:"deleteZombies"`
+}
+
+func NewLifecycle(expireAfter uint16, keepLast uint32, deleteZombies bool) *Lifecycle {
+	return &Lifecycle{
+		ExpireAfter:   expireAfter,
+		KeepLast:      keepLast,
+		DeleteZombies: deleteZombies,
+	}
+}
+
+// ParseLifecycle parses the rule definition in "expireAfter=7,keepLast=10,deleteZombies=true" format
+func ParseLifecycle(definition string) (*Lifecycle, error) {
+	lifecycle := &Lifecycle{}
+
+	for _, option := range strings.Split(definition, ",") {
+		option = strings.TrimSpace(option)
+		if len(option) == 0 {
+			continue
+		}
+
+		eqIdx := strings.Index(option, "=")
+		if eqIdx == -1 {
+			return nil, errors.ErrLifecycle
+		}
+		key, value := option[:eqIdx], option[eqIdx+1:]
+
+		switch key {
+		case "expireAfter":
+			v, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return nil, errors.ErrLifecycle
+			}
+			lifecycle.ExpireAfter = uint16(v)
+		case "keepLast":
+			v, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, errors.ErrLifecycle
+			}
+			lifecycle.KeepLast = uint32(v)
+		case "deleteZombies":
+			lifecycle.DeleteZombies = strings.Compare(value, "1") == 0 || strings.Compare(value, "true") == 0
+		default:
+			return nil, errors.ErrLifecycle
+		}
+	}
+
+	if lifecycle.Empty() {
+		return nil, errors.ErrLifecycle
+	}
+
+	return lifecycle, nil
+}
+
+func (l *Lifecycle) Empty() bool {
+	return l.ExpireAfter == 0 && l.KeepLast == 0 && !l.DeleteZombies
+}
+
+func (l *Lifecycle) String() string {
+	return fmt.Sprintf("expireAfter=%d,keepLast=%d,deleteZombies=%t", l.ExpireAfter, l.KeepLast, l.DeleteZombies)
+}
+
+// Expired returns the files that are matching with the lifecycle rule. Locked files are always skipped
+func (l *Lifecycle) Expired(files Files, now time.Time) Files {
+	expiredMap := make(map[string]*File)
+
+	candidates := make(Files, 0)
+	for _, file := range files {
+		if file.Locked() {
+			continue
+		}
+
+		if file.Zombie {
+			if l.DeleteZombies {
+				expiredMap[file.Name] = file
+			}
+			continue
+		}
+
+		if l.ExpireAfter > 0 && now.Sub(file.Modified) > time.Hour*24*time.Duration(l.ExpireAfter) {
+			expiredMap[file.Name] = file
+			continue
+		}
+
+		candidates = append(candidates, file)
+	}
+
+	if l.KeepLast > 0 && uint32(len(candidates)) > l.KeepLast {
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].Created.After(candidates[j].Created)
+		})
+		for _, file := range candidates[l.KeepLast:] {
+			expiredMap[file.Name] = file
+		}
+	}
+
+	expired := make(Files, 0)
+	for _, file := range expiredMap {
+		expired = append(expired, file)
+	}
+	sort.Sort(expired)
+
+	return expired
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLifecycle(t *testing.T) {
+	l, err := ParseLifecycle("expireAfter=7,keepLast=10,deleteZombies=true")
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(7), l.ExpireAfter)
+	assert.Equal(t, uint32(10), l.KeepLast)
+	assert.True(t, l.DeleteZombies)
+
+	_, err = ParseLifecycle("")
+	assert.NotNil(t, err)
+
+	_, err = ParseLifecycle("expireAfter=seven")
+	assert.NotNil(t, err)
+
+	_, err = ParseLifecycle("unknown=1")
+	assert.NotNil(t, err)
+}
+
+func TestLifecycle_Expired(t *testing.T) {
+	now := time.Now().UTC()
+
+	files := make(Files, 0)
+	for i := 0; i < 5; i++ {
+		f := newFile(fmt.Sprintf("file%d", i))
+		f.Created = now.Add(-time.Hour * time.Duration(i))
+		f.Modified = f.Created
+		f.Lock.Cancel()
+		f.Zombie = false
+		f.Chunks = append(f.Chunks, NewDataChunk(0, 1, NullSha512Hex))
+		files = append(files, f)
+	}
+	files[4].Modified = now.Add(-time.Hour * 24 * 10)
+
+	zombie := newFile("zombie")
+	zombie.Lock.Cancel()
+	files = append(files, zombie)
+
+	locked := newFile("locked")
+	locked.Modified = now.Add(-time.Hour * 24 * 10)
+	files = append(files, locked)
+
+	expired := NewLifecycle(7, 0, false).Expired(files, now)
+	assert.Len(t, expired, 1)
+	assert.Equal(t, "file4", expired[0].Name)
+
+	expired = NewLifecycle(0, 2, true).Expired(files, now)
+	assert.Len(t, expired, 4)
+	assert.Equal(t, "file2", expired[0].Name)
+	assert.Equal(t, "file3", expired[1].Name)
+	assert.Equal(t, "file4", expired[2].Name)
+	assert.Equal(t, "zombie", expired[3].Name)
+}
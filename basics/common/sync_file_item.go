@@ -4,7 +4,7 @@ import "strings"
 
 type SyncFileItem struct {
 	Sha512Hex string `json:"sha512Hex"`
-	Usage     uint16 `json:"usage"`
+	Usage     uint32 `json:"usage"`
 	Size      uint32 `json:"size"`
 	Shadow    bool   `json:"shadow"`
 }
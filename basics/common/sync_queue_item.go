@@ -0,0 +1,31 @@
+package common
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SyncQueueItem struct {
+	Id         string    `json:"id"`
+	Create     bool      `json:"create"`
+	Date       time.Time `json:"date"`
+	ClusterId  string    `json:"clusterId"`
+	SourceAddr string    `json:"sourceAddr"`
+	Sha512Hex  string    `json:"sha512Hex"`
+	Targets    []string  `json:"targets"`
+}
+
+type SyncQueueItems []*SyncQueueItem
+
+func NewSyncQueueItem(create bool, clusterId string, sourceAddr string, sha512Hex string, targets []string) *SyncQueueItem {
+	return &SyncQueueItem{
+		Id:         uuid.New().String(),
+		Create:     create,
+		Date:       time.Now().UTC(),
+		ClusterId:  clusterId,
+		SourceAddr: sourceAddr,
+		Sha512Hex:  sha512Hex,
+		Targets:    targets,
+	}
+}
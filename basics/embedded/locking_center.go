@@ -0,0 +1,85 @@
+package embedded
+
+import (
+	"sync"
+
+	"github.com/freakmaxi/locking-center-client-go/mutex"
+)
+
+type keyLock struct {
+	lockChan   chan bool
+	references int
+}
+
+type lockingCenter struct {
+	mutex sync.Mutex
+	keys  map[string]*keyLock
+}
+
+// NewLockingCenter creates the in-process replacement of the locking-center client. It only synchronizes
+// the accesses of the same process.
+func NewLockingCenter() mutex.LockingCenter {
+	return &lockingCenter{
+		mutex: sync.Mutex{},
+		keys:  make(map[string]*keyLock),
+	}
+}
+
+func (l *lockingCenter) Lock(key string) {
+	l.mutex.Lock()
+	kl, has := l.keys[key]
+	if !has {
+		kl = &keyLock{
+			lockChan: make(chan bool, 1),
+		}
+		l.keys[key] = kl
+	}
+	kl.references++
+	l.mutex.Unlock()
+
+	kl.lockChan <- true
+}
+
+func (l *lockingCenter) Unlock(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	kl, has := l.keys[key]
+	if !has {
+		return
+	}
+
+	select {
+	case <-kl.lockChan:
+	default:
+	}
+
+	kl.references--
+	if kl.references == 0 {
+		delete(l.keys, key)
+	}
+}
+
+func (l *lockingCenter) Wait(key string) {
+	l.Lock(key)
+	l.Unlock(key)
+}
+
+func (l *lockingCenter) ResetByKey(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	kl, has := l.keys[key]
+	if !has {
+		return
+	}
+
+	select {
+	case <-kl.lockChan:
+	default:
+	}
+}
+
+func (l *lockingCenter) ResetBySource(_ *string) {}
+
+var _ mutex.LockingCenter = &lockingCenter{}
@@ -0,0 +1,98 @@
+package embedded
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const openTimeout = time.Second
+
+// Store keeps the data of single machine farms in a bolt database file. The file is opened once and locked by the
+// process, so head and manager nodes of the farm run in the same process and share the store and the locking center.
+type Store interface {
+	View(handler func(tx *bolt.Tx) error) error
+	Update(handler func(tx *bolt.Tx) error) error
+	Setup(buckets ...string) error
+	Close() error
+}
+
+type store struct {
+	db *bolt.DB
+}
+
+func NewStore(path string) (Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0666, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		if err == bolt.ErrTimeout {
+			return nil, fmt.Errorf("embedded store is in use by another process: %s", path)
+		}
+		return nil, err
+	}
+
+	return &store{
+		db: db,
+	}, nil
+}
+
+func (s *store) View(handler func(tx *bolt.Tx) error) error {
+	return s.db.View(handler)
+}
+
+func (s *store) Update(handler func(tx *bolt.Tx) error) error {
+	return s.db.Update(handler)
+}
+
+func (s *store) Setup(buckets ...string) error {
+	return s.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range buckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *store) Close() error {
+	return s.db.Close()
+}
+
+// Get decodes the value of the key into v and returns false if the key does not exist
+func Get(bucket *bolt.Bucket, key string, v interface{}) (bool, error) {
+	value := bucket.Get([]byte(key))
+	if value == nil {
+		return false, nil
+	}
+	return true, Decode(value, v)
+}
+
+func Decode(value []byte, v interface{}) error {
+	return json.Unmarshal(value, v)
+}
+
+func Put(bucket *bolt.Bucket, key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(key), value)
+}
+
+// SequenceKey creates the key that keeps the insertion order of the sequence in the bucket
+func SequenceKey(sequence uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, sequence)
+	return key
+}
+
+var _ Store = &store{}
@@ -0,0 +1,85 @@
+package embedded
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestStore_PutGet(t *testing.T) {
+	folder, err := ioutil.TempDir("", "embedded")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(folder) }()
+
+	s, err := NewStore(path.Join(folder, "store", "kertish.db"))
+	assert.Nil(t, err)
+	assert.Nil(t, s.Setup("test"))
+
+	err = s.Update(func(tx *bolt.Tx) error {
+		return Put(tx.Bucket([]byte("test")), "key", map[string]string{"value": "1"})
+	})
+	assert.Nil(t, err)
+
+	err = s.View(func(tx *bolt.Tx) error {
+		value := make(map[string]string)
+		found, err := Get(tx.Bucket([]byte("test")), "key", &value)
+		assert.True(t, found)
+		assert.Equal(t, "1", value["value"])
+
+		found, err = Get(tx.Bucket([]byte("test")), "missing", &value)
+		assert.False(t, found)
+		return err
+	})
+	assert.Nil(t, err)
+}
+
+func TestSequenceKey(t *testing.T) {
+	assert.True(t, string(SequenceKey(9)) < string(SequenceKey(10)))
+	assert.True(t, string(SequenceKey(255)) < string(SequenceKey(256)))
+}
+
+func TestLockingCenter_Lock(t *testing.T) {
+	l := NewLockingCenter()
+
+	l.Lock("key")
+
+	locked := make(chan bool)
+	go func() {
+		l.Lock("key")
+		locked <- true
+		l.Unlock("key")
+	}()
+
+	select {
+	case <-locked:
+		assert.Fail(t, "key should not be locked twice")
+	default:
+	}
+
+	l.Unlock("key")
+	assert.True(t, <-locked)
+
+	l.Wait("key")
+}
+
+func TestStore_InUse(t *testing.T) {
+	folder, err := ioutil.TempDir("", "embedded")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(folder) }()
+
+	s, err := NewStore(path.Join(folder, "kertish.db"))
+	assert.Nil(t, err)
+
+	_, err = NewStore(path.Join(folder, "kertish.db"))
+	assert.NotNil(t, err)
+
+	assert.Nil(t, s.Close())
+
+	s, err = NewStore(path.Join(folder, "kertish.db"))
+	assert.Nil(t, err)
+	assert.Nil(t, s.Close())
+}
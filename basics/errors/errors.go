@@ -16,6 +16,10 @@ var (
 	ErrJoinConflict          = errors.New("joining source folders will have conflict in target")
 	ErrSync                  = errors.New("syncing is failed")
 	ErrSnapshot              = errors.New("snapshot operation is failed")
+	ErrLifecycle             = errors.New("lifecycle rule definition is not valid")
+	ErrVerify                = errors.New("block is not verified")
+	ErrOutOfRange            = errors.New("out of range")
+	ErrQuorum                = errors.New("write quorum is not reached")
 
 	ErrExists                       = errors.New("cluster is already exists")
 	ErrPing                         = errors.New("node is not reachable")
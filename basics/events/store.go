@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Store keeps the change feed events of the head and manager nodes
+type Store interface {
+	List(cursor uint64, limit int64, pathPrefixes []string) (common.Events, error)
+}
+
+// MongoStore appends the events with the context of the metadata transaction, so they are saved with the change
+type MongoStore interface {
+	Store
+	Append(ctx context.Context, events common.Events) error
+}
+
+const eventsCollection = "events"
+const eventsSequenceCollection = "events_sequence"
+
+type store struct {
+	col         *mongo.Collection
+	sequenceCol *mongo.Collection
+	retention   time.Duration
+}
+
+func NewStore(database *mongo.Database, retention time.Duration) (MongoStore, error) {
+	e := &store{
+		col:         database.Collection(eventsCollection),
+		sequenceCol: database.Collection(eventsSequenceCollection),
+		retention:   retention,
+	}
+	if err := e.setupIndices(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *store) context(parentContext context.Context) (context.Context, context.CancelFunc) {
+	timeoutDuration := time.Second * 30
+	return context.WithTimeout(parentContext, timeoutDuration)
+}
+
+func (e *store) setupIndices() error {
+	models := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"sequence": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if e.retention > 0 {
+		models = append(models, mongo.IndexModel{
+			Keys:    bson.M{"date": 1},
+			Options: options.Index().SetExpireAfterSeconds(int32(e.retention.Seconds())),
+		})
+	}
+
+	ctx, cancelFunc := e.context(context.Background())
+	defer cancelFunc()
+
+	_, err := e.col.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+// nextSequence reserves the sequences out of the metadata transaction, the counter document would conflict in
+// concurrent transactions. Aborted transactions leave gaps in the sequence
+func (e *store) nextSequence(count int) (uint64, error) {
+	ctx, cancelFunc := e.context(context.Background())
+	defer cancelFunc()
+
+	opts := options.FindOneAndUpdate()
+	opts.SetUpsert(true)
+	opts.SetReturnDocument(options.After)
+
+	var counter struct {
+		Sequence uint64 `bson:"sequence"`
+	}
+	if err := e.sequenceCol.FindOneAndUpdate(ctx, bson.M{"_id": eventsCollection}, bson.M{"$inc": bson.M{"sequence": uint64(count)}}, opts).Decode(&counter); err != nil {
+		return 0, err
+	}
+	return counter.Sequence - uint64(count) + 1, nil
+}
+
+func (e *store) next(cursor *mongo.Cursor) (*common.Event, error) {
+	ctx, cancelFunc := e.context(context.Background())
+	defer cancelFunc()
+
+	if !cursor.Next(ctx) {
+		return nil, io.EOF
+	}
+
+	var event *common.Event
+	if err := cursor.Decode(&event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (e *store) Append(parentContext context.Context, events common.Events) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	sequence, err := e.nextSequence(len(events))
+	if err != nil {
+		return err
+	}
+
+	documents := make([]interface{}, 0)
+	for i, event := range events {
+		event.Sequence = sequence + uint64(i)
+		documents = append(documents, event)
+	}
+
+	ctx, cancelFunc := e.context(parentContext)
+	defer cancelFunc()
+
+	_, err = e.col.InsertMany(ctx, documents)
+	return err
+}
+
+func (e *store) List(cursor uint64, limit int64, pathPrefixes []string) (common.Events, error) {
+	filter := bson.M{"sequence": bson.M{"$gt": cursor}}
+
+	if len(pathPrefixes) > 0 {
+		conditions := make(bson.A, 0)
+		for _, pathPrefix := range pathPrefixes {
+			// prefix matches on the path boundary, /a does not match /ab
+			pattern := regexp.QuoteMeta(strings.TrimSuffix(pathPrefix, "/"))
+			regex := primitive.Regex{Pattern: fmt.Sprintf("^%s(/|$)", pattern)}
+
+			conditions = append(conditions, bson.M{"sources": bson.M{"$regex": regex}})
+			conditions = append(conditions, bson.M{"target": bson.M{"$regex": regex}})
+		}
+		filter["$or"] = conditions
+	}
+
+	opts := options.Find()
+	opts.SetSort(bson.M{"sequence": 1})
+	opts.SetLimit(limit)
+
+	ctx, cancelFunc := e.context(context.Background())
+	defer cancelFunc()
+
+	c, err := e.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		ctx, cancelFunc := e.context(context.Background())
+		defer cancelFunc()
+
+		_ = c.Close(ctx)
+	}()
+
+	list := make(common.Events, 0)
+	for {
+		event, err := e.next(c)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		list = append(list, event)
+	}
+
+	return list, nil
+}
+
+var _ MongoStore = &store{}
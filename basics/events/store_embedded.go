@@ -0,0 +1,109 @@
+package events
+
+import (
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	bolt "go.etcd.io/bbolt"
+)
+
+// EmbeddedStore appends the events in the transaction of the metadata change, so they are saved with the change
+type EmbeddedStore interface {
+	Store
+	Append(tx *bolt.Tx, events common.Events) error
+}
+
+type storeEmbedded struct {
+	store     embedded.Store
+	retention time.Duration
+}
+
+func NewStoreEmbedded(store embedded.Store, retention time.Duration) (EmbeddedStore, error) {
+	if err := store.Setup(eventsCollection); err != nil {
+		return nil, err
+	}
+
+	return &storeEmbedded{
+		store:     store,
+		retention: retention,
+	}, nil
+}
+
+// expire removes the events older than retention, it is the replacement of the mongo ttl index
+func (e *storeEmbedded) expire(bucket *bolt.Bucket) error {
+	if e.retention == 0 {
+		return nil
+	}
+	expiresAt := time.Now().UTC().Add(-e.retention)
+
+	cursor := bucket.Cursor()
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		var event *common.Event
+		if err := embedded.Decode(v, &event); err != nil {
+			return err
+		}
+		if event.Date.After(expiresAt) {
+			return nil
+		}
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *storeEmbedded) Append(tx *bolt.Tx, events common.Events) error {
+	if len(events) == 0 {
+		return nil
+	}
+	bucket := tx.Bucket([]byte(eventsCollection))
+
+	if err := e.expire(bucket); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		sequence, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		event.Sequence = sequence
+
+		if err := embedded.Put(bucket, string(embedded.SequenceKey(sequence)), event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *storeEmbedded) List(cursor uint64, limit int64, pathPrefixes []string) (common.Events, error) {
+	list := make(common.Events, 0)
+
+	if err := e.store.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(eventsCollection)).Cursor()
+
+		for k, v := c.Seek(embedded.SequenceKey(cursor + 1)); k != nil; k, v = c.Next() {
+			if limit > 0 && int64(len(list)) >= limit {
+				break
+			}
+
+			var event *common.Event
+			if err := embedded.Decode(v, &event); err != nil {
+				return err
+			}
+
+			if !event.Match(pathPrefixes) {
+				continue
+			}
+			list = append(list, event)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+var _ EmbeddedStore = &storeEmbedded{}
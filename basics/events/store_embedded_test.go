@@ -0,0 +1,64 @@
+package events
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newStore(t *testing.T) embedded.Store {
+	folder, err := ioutil.TempDir("", "kertish-events")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(folder) })
+
+	store, err := embedded.NewStore(path.Join(folder, "kertish.db"))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	return store
+}
+
+func TestStoreEmbedded_List(t *testing.T) {
+	store := newStore(t)
+
+	events, err := NewStoreEmbedded(store, 0)
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.Update(func(tx *bolt.Tx) error {
+		return events.Append(tx, common.Events{
+			common.NewEvent(common.ET_Create, true, nil, "/a"),
+			common.NewEvent(common.ET_Create, false, nil, "/a/file"),
+			common.NewEvent(common.ET_Create, false, nil, "/ab/file"),
+			common.NewEvent(common.ET_Move, false, []string{"/ab/file"}, "/a/moved"),
+		})
+	}))
+
+	list, err := events.List(0, 10, nil)
+	assert.Nil(t, err)
+	if assert.Len(t, list, 4) {
+		assert.Equal(t, uint64(1), list[0].Sequence)
+		assert.Equal(t, uint64(4), list[3].Sequence)
+	}
+
+	list, err = events.List(0, 10, []string{"/a"})
+	assert.Nil(t, err)
+	if assert.Len(t, list, 3) {
+		assert.Equal(t, "/a", list[0].Target)
+		assert.Equal(t, "/a/file", list[1].Target)
+		assert.Equal(t, "/a/moved", list[2].Target)
+	}
+
+	list, err = events.List(list[1].Sequence, 10, []string{"/a"})
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+}
@@ -0,0 +1,178 @@
+package protocol
+
+import (
+	"encoding/binary"
+	errors2 "errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const handshakeTimeout = time.Second * 10
+const legacyRetryInterval = time.Minute
+
+var errLegacy = errors2.New("peer does not support handshake")
+
+// legacyPeers keeps the addresses refused the handshake, they are tried again after legacyRetryInterval
+// to catch the upgraded data nodes
+var legacyPeers = sync.Map{}
+
+// Conn is the client side data node connection with the negotiated protocol
+type Conn struct {
+	*net.TCPConn
+
+	peer Peer
+	code ErrorCode
+
+	reused   bool
+	received int
+	broken   bool
+	command  string
+}
+
+// Dial connects to the data node and negotiates the protocol version and capabilities
+func Dial(address *net.TCPAddr) (*Conn, error) {
+	key := address.String()
+
+	if refusedAt, has := legacyPeers.Load(key); has && time.Since(refusedAt.(time.Time)) < legacyRetryInterval {
+		return dial(address, false)
+	}
+
+	conn, err := dial(address, true)
+	if err == errLegacy {
+		legacyPeers.Store(key, time.Now())
+		return dial(address, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	legacyPeers.Delete(key)
+
+	return conn, nil
+}
+
+func dial(address *net.TCPAddr, handshake bool) (*Conn, error) {
+	tcpConn, err := net.DialTCP("tcp", nil, address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &Conn{
+		TCPConn: tcpConn,
+		peer:    Legacy,
+	}
+
+	if !handshake {
+		return conn, nil
+	}
+
+	if err := conn.handshake(); err != nil {
+		_ = tcpConn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (c *Conn) handshake() error {
+	if err := c.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return err
+	}
+
+	if err := WriteHandshake(c.TCPConn); err != nil {
+		return err
+	}
+
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(c.TCPConn, b); err != nil {
+		// legacy data node closes the connection after the unknown command
+		if err == io.EOF || errors2.Is(err, syscall.ECONNRESET) {
+			return errLegacy
+		}
+		return err
+	}
+	if b[0] != '+' {
+		return errLegacy
+	}
+
+	remote, err := ReadPeer(c.TCPConn)
+	if err != nil {
+		return err
+	}
+	c.peer = Local.Negotiate(remote)
+
+	return c.SetDeadline(time.Time{})
+}
+
+// Peer returns the negotiated protocol definition of the connection
+func (c *Conn) Peer() Peer {
+	return c.peer
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	n, err := c.TCPConn.Read(b)
+	c.received += n
+	if err != nil {
+		c.broken = true
+	}
+	return n, err
+}
+
+// SetCommand defines the command of the request for the retry decision of the pool. It should be set before any
+// prefix, like the trace context, is written. Otherwise, the first four bytes written are taken as the command
+func (c *Conn) SetCommand(command string) {
+	c.command = command
+}
+
+func (c *Conn) Write(b []byte) (int, error) {
+	// commands are written as the first four bytes of the request
+	if len(c.command) == 0 && len(b) >= 4 {
+		c.command = string(b[:4])
+	}
+
+	n, err := c.TCPConn.Write(b)
+	if err != nil {
+		c.broken = true
+	}
+	return n, err
+}
+
+// Result reads the command result. The error code of the failure is kept for Error
+func (c *Conn) Result() bool {
+	c.code = EC_Unknown
+
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(c, b); err != nil {
+		return false
+	}
+	if b[0] == '+' {
+		return true
+	}
+
+	if c.peer.Supports(CapErrorCodes) {
+		var code uint16
+		if err := binary.Read(c, binary.LittleEndian, &code); err == nil {
+			c.code = ErrorCode(code)
+		}
+	}
+	// data node keeps the stream consistent only when the command quits
+	if c.code != EC_Quit {
+		c.broken = true
+	}
+	return false
+}
+
+// Code returns the error code of the last failed result
+func (c *Conn) Code() ErrorCode {
+	return c.code
+}
+
+// Error returns the error of the last failed result with the error code sent by the data node
+func (c *Conn) Error(message string) error {
+	return &Error{
+		Code:    c.code,
+		Message: message,
+	}
+}
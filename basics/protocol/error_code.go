@@ -0,0 +1,100 @@
+package protocol
+
+import (
+	"encoding/binary"
+	errors2 "errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+)
+
+type ErrorCode uint16
+
+const (
+	EC_Unknown        ErrorCode = 1
+	EC_UnknownCommand ErrorCode = 2
+	EC_Quit           ErrorCode = 3
+	EC_NotFound       ErrorCode = 4
+	EC_NotVerified    ErrorCode = 5
+	EC_Timeout        ErrorCode = 6
+	EC_OutOfRange     ErrorCode = 7
+)
+
+var ErrUnknownCommand = errors2.New("not a meaningful command")
+
+func (e ErrorCode) String() string {
+	switch e {
+	case EC_UnknownCommand:
+		return "unknown command"
+	case EC_Quit:
+		return "operation does not need to continue"
+	case EC_NotFound:
+		return "not found"
+	case EC_NotVerified:
+		return "not verified"
+	case EC_Timeout:
+		return "timeout"
+	case EC_OutOfRange:
+		return "out of range"
+	default:
+		return "unknown error"
+	}
+}
+
+// CodeOf returns the error code that will be sent to the peer for err
+func CodeOf(err error) ErrorCode {
+	switch {
+	case errors2.Is(err, ErrUnknownCommand):
+		return EC_UnknownCommand
+	case errors2.Is(err, errors.ErrQuit):
+		return EC_Quit
+	case errors2.Is(err, errors.ErrVerify):
+		return EC_NotVerified
+	case errors2.Is(err, errors.ErrOutOfRange):
+		return EC_OutOfRange
+	case os.IsNotExist(err), errors2.Is(err, os.ErrNotExist):
+		return EC_NotFound
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return EC_Timeout
+	}
+	return EC_Unknown
+}
+
+// Error is the failure result of the data node command
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+}
+
+// CodeOfError returns the error code of the data node failure result, EC_Unknown if it does not have one
+func CodeOfError(err error) ErrorCode {
+	var protocolErr *Error
+	if errors2.As(err, &protocolErr) {
+		return protocolErr.Code
+	}
+	return EC_Unknown
+}
+
+// WriteFailure writes the failure result, error code is added only if the peer supports it
+func WriteFailure(w io.Writer, peer Peer, err error) error {
+	if !peer.Supports(CapErrorCodes) {
+		_, err := w.Write([]byte{'-'})
+		return err
+	}
+
+	b := make([]byte, 3)
+	b[0] = '-'
+	binary.LittleEndian.PutUint16(b[1:], uint16(CodeOf(err)))
+
+	_, err = w.Write(b)
+	return err
+}
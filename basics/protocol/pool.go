@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const maxIdlePerAddress = 16
+
+// idleTimeout is kept shorter than the data node keep alive timeout not to pick a connection closed by the data node
+const idleTimeout = time.Second * 30
+
+// retryCommands are the commands that do not change the data node state, a command that is sent on a broken
+// connection may be already executed on the data node
+var retryCommands = map[string]bool{
+	"READ": true,
+	"RDRN": true,
+	"EXST": true,
+	"HWID": true,
+	"USED": true,
+	"SIZE": true,
+	"PING": true,
+}
+
+type idleConn struct {
+	conn     *Conn
+	idleFrom time.Time
+}
+
+var poolMutex sync.Mutex
+var pool = make(map[string][]idleConn)
+
+// Do runs the handler on an idle connection of the address or dials a new one. The connection is put back
+// to the pool only if the data node keeps it alive and the handler completes the command without error.
+// Only the commands in retryCommands are sent again on a new connection when the idle connection is broken
+func Do(address *net.TCPAddr, handler func(conn *Conn) error) error {
+	conn, err := acquire(address)
+	if err != nil {
+		return err
+	}
+
+	err = handler(conn)
+	if err != nil && conn.reused && conn.received == 0 && conn.broken && retryCommands[conn.command] {
+		// data node may have closed the idle connection before the command reached, it is safe to try once more
+		_ = conn.Close()
+
+		conn, err = Dial(address)
+		if err != nil {
+			return err
+		}
+		err = handler(conn)
+	}
+
+	if err != nil || conn.broken || !conn.peer.Supports(CapKeepAlive) {
+		_ = conn.Close()
+		return err
+	}
+	release(address, conn)
+
+	return nil
+}
+
+func acquire(address *net.TCPAddr) (*Conn, error) {
+	key := address.String()
+
+	poolMutex.Lock()
+	for len(pool[key]) > 0 {
+		idle := pool[key][len(pool[key])-1]
+		pool[key] = pool[key][:len(pool[key])-1]
+
+		if time.Since(idle.idleFrom) > idleTimeout {
+			_ = idle.conn.Close()
+			continue
+		}
+		poolMutex.Unlock()
+
+		if err := idle.conn.SetDeadline(time.Time{}); err != nil {
+			_ = idle.conn.Close()
+			poolMutex.Lock()
+			continue
+		}
+		idle.conn.reused = true
+		idle.conn.received = 0
+		idle.conn.code = 0
+		idle.conn.command = ""
+
+		return idle.conn, nil
+	}
+	poolMutex.Unlock()
+
+	return Dial(address)
+}
+
+func release(address *net.TCPAddr, conn *Conn) {
+	key := address.String()
+
+	poolMutex.Lock()
+	defer poolMutex.Unlock()
+
+	if len(pool[key]) >= maxIdlePerAddress {
+		_ = conn.Close()
+		return
+	}
+	pool[key] = append(pool[key], idleConn{conn: conn, idleFrom: time.Now()})
+}
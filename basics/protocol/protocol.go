@@ -0,0 +1,100 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Version is the data node protocol version of this build
+const Version uint16 = 1
+
+// HandshakeCommand opens the data node connection with the protocol version and capabilities of the client.
+// Data nodes older than version 1 do not know the command and refuse the connection
+const HandshakeCommand = "HELO"
+
+type Capability uint64
+
+const (
+	// CapErrorCodes marks that the failure result (-) is followed by the error code
+	CapErrorCodes Capability = 1 << iota
+	// CapKeepAlive marks that the connection accepts the next command after the result of the previous one
+	CapKeepAlive
+	// CapRangedRead marks that the data node reads the byte range of the block with RDRN command
+	CapRangedRead
+	// CapWideUsage marks that the block usage counts are 4 bytes instead of 2 bytes
+	CapWideUsage
+	// CapExists marks that the data node reports the block placement with EXST command
+	CapExists
+)
+
+// Capabilities are the features supported by this build
+const Capabilities = CapErrorCodes | CapKeepAlive | CapRangedRead | CapWideUsage | CapExists
+
+// Peer describes the protocol support of the other side of the connection
+type Peer struct {
+	Version      uint16
+	Capabilities Capability
+}
+
+// Legacy is the peer which does not know the handshake
+var Legacy = Peer{}
+
+// Local is the peer definition of this build
+var Local = Peer{Version: Version, Capabilities: Capabilities}
+
+func (p Peer) Supports(capability Capability) bool {
+	return p.Capabilities&capability == capability
+}
+
+// Negotiate returns the peer definition that both sides can talk
+func (p Peer) Negotiate(remote Peer) Peer {
+	version := p.Version
+	if remote.Version < version {
+		version = remote.Version
+	}
+
+	return Peer{
+		Version:      version,
+		Capabilities: p.Capabilities & remote.Capabilities,
+	}
+}
+
+// WriteHandshake writes the handshake command with the local peer definition
+func WriteHandshake(w io.Writer) error {
+	b := make([]byte, 0, len(HandshakeCommand)+10)
+	b = append(b, HandshakeCommand...)
+	b = append(b, encodePeer(Local)...)
+
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadPeer reads the peer definition following the handshake command or the handshake result
+func ReadPeer(r io.Reader) (Peer, error) {
+	b := make([]byte, 10)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return Legacy, err
+	}
+
+	return Peer{
+		Version:      binary.LittleEndian.Uint16(b[:2]),
+		Capabilities: Capability(binary.LittleEndian.Uint64(b[2:])),
+	}, nil
+}
+
+// WritePeer writes the local peer definition as handshake result
+func WritePeer(w io.Writer) error {
+	b := make([]byte, 0, 11)
+	b = append(b, '+')
+	b = append(b, encodePeer(Local)...)
+
+	_, err := w.Write(b)
+	return err
+}
+
+func encodePeer(peer Peer) []byte {
+	b := make([]byte, 10)
+	binary.LittleEndian.PutUint16(b[:2], peer.Version)
+	binary.LittleEndian.PutUint64(b[2:], uint64(peer.Capabilities))
+	return b
+}
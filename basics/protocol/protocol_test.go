@@ -0,0 +1,239 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/basics/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func listen(t *testing.T, handler func(conn net.Conn)) *net.TCPAddr {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				handler(conn)
+			}()
+		}
+	}()
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return listener.Addr().(*net.TCPAddr)
+}
+
+func TestPeer_Negotiate(t *testing.T) {
+	negotiated := Local.Negotiate(Legacy)
+	assert.Equal(t, uint16(0), negotiated.Version)
+	assert.False(t, negotiated.Supports(CapErrorCodes))
+
+	negotiated = Local.Negotiate(Peer{Version: Version + 1, Capabilities: Capabilities | 1<<63})
+	assert.Equal(t, Version, negotiated.Version)
+	assert.Equal(t, Capabilities, negotiated.Capabilities)
+}
+
+func TestDial_Handshake(t *testing.T) {
+	address := listen(t, func(conn net.Conn) {
+		command := make([]byte, 4)
+		if _, err := io.ReadFull(conn, command); err != nil || string(command) != HandshakeCommand {
+			return
+		}
+		peer, err := ReadPeer(conn)
+		if err != nil {
+			return
+		}
+		_ = WritePeer(conn)
+
+		if _, err := io.ReadFull(conn, command); err != nil {
+			return
+		}
+		_ = WriteFailure(conn, Local.Negotiate(peer), os.ErrNotExist)
+	})
+
+	conn, err := Dial(address)
+	assert.Nil(t, err)
+	defer func() { _ = conn.Close() }()
+
+	assert.Equal(t, Version, conn.Peer().Version)
+	assert.True(t, conn.Peer().Supports(CapErrorCodes))
+
+	_, err = conn.Write([]byte("READ"))
+	assert.Nil(t, err)
+	assert.False(t, conn.Result())
+	assert.Equal(t, EC_NotFound, conn.Code())
+	assert.Equal(t, EC_NotFound, CodeOfError(conn.Error("read is failed")))
+}
+
+func TestDial_Legacy(t *testing.T) {
+	address := listen(t, func(conn net.Conn) {
+		command := make([]byte, 4)
+		if _, err := io.ReadFull(conn, command); err != nil {
+			return
+		}
+		if string(command) == HandshakeCommand {
+			_, _ = conn.Write([]byte{'-'})
+			return
+		}
+		_, _ = conn.Write([]byte{'-'})
+	})
+
+	conn, err := Dial(address)
+	assert.Nil(t, err)
+	defer func() { _ = conn.Close() }()
+
+	assert.Equal(t, Legacy, conn.Peer())
+
+	_, has := legacyPeers.Load(address.String())
+	assert.True(t, has)
+
+	_, err = conn.Write([]byte("READ"))
+	assert.Nil(t, err)
+	assert.False(t, conn.Result())
+	assert.Equal(t, EC_Unknown, conn.Code())
+}
+
+func TestWriteFailure(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+
+	assert.Nil(t, WriteFailure(buffer, Legacy, errors.ErrQuit))
+	assert.Equal(t, []byte{'-'}, buffer.Bytes())
+
+	buffer.Reset()
+	assert.Nil(t, WriteFailure(buffer, Local, errors.ErrQuit))
+	assert.Equal(t, []byte{'-', byte(EC_Quit), 0}, buffer.Bytes())
+}
+
+// serve answers the handshake and the commands following it with success till the connection is closed
+func serve(t *testing.T, connections *int32, commandsPerConnection int) *net.TCPAddr {
+	return listen(t, func(conn net.Conn) {
+		atomic.AddInt32(connections, 1)
+
+		command := make([]byte, 4)
+		if _, err := io.ReadFull(conn, command); err != nil || string(command) != HandshakeCommand {
+			return
+		}
+		if _, err := ReadPeer(conn); err != nil {
+			return
+		}
+		_ = WritePeer(conn)
+
+		for i := 0; commandsPerConnection == 0 || i < commandsPerConnection; i++ {
+			if _, err := io.ReadFull(conn, command); err != nil {
+				return
+			}
+			if string(command) == tracing.BinaryCommand {
+				if _, err := tracing.ReadBinary(conn); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(conn, command); err != nil {
+					return
+				}
+			}
+			if _, err := conn.Write([]byte{'+'}); err != nil {
+				return
+			}
+		}
+	})
+}
+
+func ping(conn *Conn) error {
+	if _, err := conn.Write([]byte("PING")); err != nil {
+		return err
+	}
+	if !conn.Result() {
+		return conn.Error("ping is failed")
+	}
+	return nil
+}
+
+func TestDo_KeepAlive(t *testing.T) {
+	var connections int32
+	address := serve(t, &connections, 0)
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, Do(address, ping))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+
+	assert.NotNil(t, Do(address, func(conn *Conn) error {
+		return os.ErrInvalid
+	}))
+	assert.Nil(t, Do(address, ping))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&connections))
+}
+
+func TestDo_Stale(t *testing.T) {
+	var connections int32
+	address := serve(t, &connections, 1)
+
+	assert.Nil(t, Do(address, ping))
+	assert.Nil(t, Do(address, ping))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&connections))
+}
+
+func TestDo_StaleNotRetried(t *testing.T) {
+	var connections int32
+	address := serve(t, &connections, 1)
+
+	assert.Nil(t, Do(address, ping))
+	assert.NotNil(t, Do(address, func(conn *Conn) error {
+		if _, err := conn.Write([]byte("DELE")); err != nil {
+			return err
+		}
+		if !conn.Result() {
+			return conn.Error("delete is failed")
+		}
+		return nil
+	}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+}
+
+func TestDo_StaleTraced(t *testing.T) {
+	target, err := ioutil.TempDir("", "kertish-protocol")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(target) }()
+
+	assert.Nil(t, tracing.Setup("protocol-test", "file", target, zap.NewNop()))
+	ctx, span := tracing.Start(context.Background(), "ping")
+	defer span.End()
+
+	var connections int32
+	address := serve(t, &connections, 1)
+
+	tracedPing := func(conn *Conn) error {
+		conn.SetCommand("PING")
+		if err := tracing.WriteBinary(ctx, conn); err != nil {
+			return err
+		}
+		return ping(conn)
+	}
+
+	assert.Nil(t, Do(address, tracedPing))
+	assert.Nil(t, Do(address, tracedPing))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&connections))
+}
+
+func TestEncodeUsage(t *testing.T) {
+	b := EncodeUsage(Local, 100000)
+	assert.Len(t, b, 4)
+	assert.Equal(t, uint32(100000), DecodeUsage(Local, b))
+
+	b = EncodeUsage(Legacy, 100000)
+	assert.Len(t, b, 2)
+	assert.Equal(t, uint32(65535), DecodeUsage(Legacy, b))
+}
@@ -0,0 +1,38 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// UsageSize returns the byte size of the block usage count that the peer talks
+func UsageSize(peer Peer) int {
+	if peer.Supports(CapWideUsage) {
+		return 4
+	}
+	return 2
+}
+
+// EncodeUsage encodes the block usage count for the peer. Peers without the wide usage support get the count limited
+// to the 2 bytes
+func EncodeUsage(peer Peer, usage uint32) []byte {
+	b := make([]byte, UsageSize(peer))
+	if len(b) == 4 {
+		binary.LittleEndian.PutUint32(b, usage)
+		return b
+	}
+
+	if usage > math.MaxUint16 {
+		usage = math.MaxUint16
+	}
+	binary.LittleEndian.PutUint16(b, uint16(usage))
+	return b
+}
+
+// DecodeUsage decodes the block usage count that is encoded for the peer
+func DecodeUsage(peer Peer, b []byte) uint32 {
+	if UsageSize(peer) == 4 {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return uint32(binary.LittleEndian.Uint16(b))
+}
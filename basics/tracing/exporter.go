@@ -0,0 +1,225 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const otlpTracesPath = "/v1/traces"
+
+type Exporter interface {
+	Export(service string, hostname string, spans []*Span) error
+}
+
+// NewExporter creates the exporter for the output type. Supported outputs are "file" and "otlp"
+func NewExporter(output string, target string, service string) (Exporter, error) {
+	switch strings.ToLower(strings.TrimSpace(output)) {
+	case "file":
+		if len(target) == 0 {
+			target = "/var/log"
+		}
+		return newFileExporter(path.Join(target, fmt.Sprintf("kertish-dfs-%s", service)))
+	case "otlp":
+		if len(target) == 0 {
+			target = "http://127.0.0.1:4318"
+		}
+		return newOtlpExporter(target), nil
+	default:
+		return nil, fmt.Errorf("tracing output is not supported: %s", output)
+	}
+}
+
+type fileExporter struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// newFileExporter writes the spans to the file in OTLP json format, a request per line
+func newFileExporter(targetPath string) (Exporter, error) {
+	if err := os.MkdirAll(targetPath, 0777); err != nil {
+		return nil, err
+	}
+
+	filePath := path.Join(targetPath, fmt.Sprintf("%s.trace", time.Now().Format("since-20060102")))
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileExporter{
+		mutex: sync.Mutex{},
+		file:  file,
+	}, nil
+}
+
+func (f *fileExporter) Export(service string, hostname string, spans []*Span) error {
+	b, err := json.Marshal(newOtlpRequest(service, hostname, spans))
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_, err = f.file.Write(b)
+	return err
+}
+
+type otlpExporter struct {
+	endpoint string
+	client   http.Client
+}
+
+// newOtlpExporter posts the spans to the OpenTelemetry collector using OTLP/HTTP with json encoding
+func newOtlpExporter(endpoint string) Exporter {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(endpoint, otlpTracesPath) {
+		endpoint = fmt.Sprintf("%s%s", endpoint, otlpTracesPath)
+	}
+
+	return &otlpExporter{
+		endpoint: endpoint,
+		client:   http.Client{Timeout: time.Second * 10},
+	}
+}
+
+func (o *otlpExporter) Export(service string, hostname string, spans []*Span) error {
+	b, err := json.Marshal(newOtlpRequest(service, hostname, spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", o.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("otlp collector responded with status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string             `json:"key"`
+	Value otlpAttributeValue `json:"value"`
+}
+
+type otlpAttributeValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func newOtlpAttribute(key string, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpAttributeValue{StringValue: value}}
+}
+
+func newOtlpRequest(service string, hostname string, spans []*Span) otlpRequest {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		otlpSpans = append(otlpSpans, newOtlpSpan(span))
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: []otlpAttribute{
+						newOtlpAttribute("service.name", service),
+						newOtlpAttribute("host.name", hostname),
+					},
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: "kertish-dfs"},
+						Spans: otlpSpans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func newOtlpSpan(span *Span) otlpSpan {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	s := otlpSpan{
+		TraceId:           span.SpanContext.TraceId.String(),
+		SpanId:            span.SpanContext.SpanId.String(),
+		Name:              span.Name,
+		Kind:              1, // Internal
+		StartTimeUnixNano: strconv.FormatInt(span.Begins.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.Ends.UnixNano(), 10),
+		Attributes:        make([]otlpAttribute, 0, len(span.Attributes)),
+		Status:            otlpStatus{Code: 0}, // Unset
+	}
+	if span.ParentSpanId != (SpanId{}) {
+		s.ParentSpanId = span.ParentSpanId.String()
+	}
+
+	for k, v := range span.Attributes {
+		s.Attributes = append(s.Attributes, newOtlpAttribute(k, v))
+	}
+
+	if len(span.Error) > 0 {
+		s.Status = otlpStatus{Code: 2, Message: span.Error} // Error
+	}
+
+	return s
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+const TraceparentHeader = "traceparent"
+
+// BinaryCommand prefixes the data node command to carry the span context in the binary protocol
+const BinaryCommand = "TRCX"
+
+// Inject places the span context of ctx into the http headers
+func Inject(ctx context.Context, header http.Header) {
+	sc := FromContext(ctx)
+	if !sc.Valid() {
+		return
+	}
+	header.Set(TraceparentHeader, sc.Traceparent())
+}
+
+// Extract returns the ctx that carries the span context defined in the http headers
+func Extract(ctx context.Context, header http.Header) context.Context {
+	traceparent := header.Get(TraceparentHeader)
+	if len(traceparent) == 0 {
+		return ctx
+	}
+
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// WriteBinary writes the span context of ctx as trace context command to the data node connection.
+// It does not write anything if tracing is disabled or ctx does not carry a span context
+func WriteBinary(ctx context.Context, w io.Writer) error {
+	if current() == nil {
+		return nil
+	}
+
+	sc := FromContext(ctx)
+	if !sc.Valid() {
+		return nil
+	}
+
+	traceparent := sc.Traceparent()
+
+	b := make([]byte, 0, len(BinaryCommand)+1+len(traceparent))
+	b = append(b, BinaryCommand...)
+	b = append(b, byte(len(traceparent)))
+	b = append(b, traceparent...)
+
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadBinary reads the span context from the data node connection after the trace context command
+func ReadBinary(r io.Reader) (SpanContext, error) {
+	length := make([]byte, 1)
+	if _, err := io.ReadFull(r, length); err != nil {
+		return SpanContext{}, err
+	}
+
+	traceparent := make([]byte, length[0])
+	if _, err := io.ReadFull(r, traceparent); err != nil {
+		return SpanContext{}, err
+	}
+
+	return ParseTraceparent(string(traceparent))
+}
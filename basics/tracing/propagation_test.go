@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent(testTraceparent)
+	assert.Nil(t, err)
+	assert.True(t, sc.Valid())
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceId.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanId.String())
+	assert.Equal(t, testTraceparent, sc.Traceparent())
+
+	_, err = ParseTraceparent("00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	assert.NotNil(t, err)
+
+	_, err = ParseTraceparent("00-4bf92f3577b34da6-00f067aa0ba902b7-01")
+	assert.NotNil(t, err)
+
+	_, err = ParseTraceparent("ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NotNil(t, err)
+}
+
+func TestInjectExtract(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, testTraceparent)
+
+	ctx := Extract(context.Background(), header)
+	assert.True(t, FromContext(ctx).Valid())
+
+	injected := http.Header{}
+	Inject(ctx, injected)
+	assert.Equal(t, testTraceparent, injected.Get(TraceparentHeader))
+
+	empty := http.Header{}
+	Inject(context.Background(), empty)
+	assert.Empty(t, empty.Get(TraceparentHeader))
+}
+
+func TestBinary(t *testing.T) {
+	sc, _ := ParseTraceparent(testTraceparent)
+	ctx := ContextWithSpanContext(context.Background(), sc)
+
+	buffer := &bytes.Buffer{}
+	assert.Nil(t, WriteBinary(ctx, buffer))
+	assert.Equal(t, 0, buffer.Len())
+
+	defaultTracer = &tracer{spanChan: make(chan *Span, 1)}
+	defer func() { defaultTracer = nil }()
+
+	assert.Nil(t, WriteBinary(ctx, buffer))
+
+	command := make([]byte, len(BinaryCommand))
+	_, _ = buffer.Read(command)
+	assert.Equal(t, BinaryCommand, string(command))
+
+	read, err := ReadBinary(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, sc, read)
+
+	buffer.Reset()
+	assert.Nil(t, WriteBinary(context.Background(), buffer))
+	assert.Equal(t, 0, buffer.Len())
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"sync"
+	"time"
+)
+
+type Span struct {
+	Name         string
+	SpanContext  SpanContext
+	ParentSpanId SpanId
+	Begins       time.Time
+	Ends         time.Time
+	Attributes   map[string]string
+	Error        string
+
+	mutex  sync.Mutex
+	tracer *tracer
+}
+
+// SetAttribute is safe to call on nil span that is created when tracing is disabled
+func (s *Span) SetAttribute(key string, value string) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Attributes[key] = value
+}
+
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Error = err.Error()
+}
+
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	if !s.Ends.IsZero() {
+		s.mutex.Unlock()
+		return
+	}
+	s.Ends = time.Now().UTC()
+	s.mutex.Unlock()
+
+	s.tracer.queue(s)
+}
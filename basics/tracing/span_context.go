@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const traceparentVersion = "00"
+
+type TraceId [16]byte
+
+func (t TraceId) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+type SpanId [8]byte
+
+func (s SpanId) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext is the part of the span that is propagated between the nodes
+type SpanContext struct {
+	TraceId TraceId
+	SpanId  SpanId
+	Sampled bool
+}
+
+func (s SpanContext) Valid() bool {
+	return s.TraceId != TraceId{} && s.SpanId != SpanId{}
+}
+
+// Traceparent formats the span context as W3C Trace Context traceparent value
+func (s SpanContext) Traceparent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("%s-%s-%s-%s", traceparentVersion, s.TraceId, s.SpanId, flags)
+}
+
+// ParseTraceparent parses the W3C Trace Context traceparent value
+func ParseTraceparent(traceparent string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || strings.Compare(parts[0], "ff") == 0 {
+		return SpanContext{}, fmt.Errorf("traceparent is not valid: %s", traceparent)
+	}
+
+	sc := SpanContext{}
+	if err := decodeHex(parts[1], sc.TraceId[:]); err != nil {
+		return SpanContext{}, err
+	}
+	if err := decodeHex(parts[2], sc.SpanId[:]); err != nil {
+		return SpanContext{}, err
+	}
+
+	flags := make([]byte, 1)
+	if err := decodeHex(parts[3], flags); err != nil {
+		return SpanContext{}, err
+	}
+	sc.Sampled = flags[0]&1 == 1
+
+	if !sc.Valid() {
+		return SpanContext{}, fmt.Errorf("traceparent is not valid: %s", traceparent)
+	}
+
+	return sc, nil
+}
+
+func decodeHex(value string, target []byte) error {
+	if len(value) != len(target)*2 {
+		return fmt.Errorf("hex value length is not valid: %s", value)
+	}
+	_, err := hex.Decode(target, []byte(value))
+	return err
+}
+
+func newTraceId() TraceId {
+	var t TraceId
+	_, _ = rand.Read(t[:])
+	return t
+}
+
+func newSpanId() SpanId {
+	var s SpanId
+	_, _ = rand.Read(s[:])
+	return s
+}
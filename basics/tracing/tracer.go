@@ -0,0 +1,159 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const queueLimit = 10000
+const batchLimit = 512
+const flushInterval = time.Second * 5
+
+type contextKey int
+
+const spanContextKey contextKey = 0
+
+type tracer struct {
+	service  string
+	hostname string
+	exporter Exporter
+	logger   *zap.Logger
+
+	spanChan chan *Span
+}
+
+var defaultTracerMutex sync.Mutex
+var defaultTracer *tracer
+
+// Setup creates the default tracer of the service that exports the spans to the output.
+// Tracing stays disabled when output is empty
+func Setup(service string, output string, target string, logger *zap.Logger) error {
+	if len(output) == 0 {
+		return nil
+	}
+
+	exporter, err := NewExporter(output, target, service)
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+
+	t := &tracer{
+		service:  service,
+		hostname: hostname,
+		exporter: exporter,
+		logger:   logger,
+		spanChan: make(chan *Span, queueLimit),
+	}
+	go t.start()
+
+	defaultTracerMutex.Lock()
+	defer defaultTracerMutex.Unlock()
+
+	defaultTracer = t
+
+	return nil
+}
+
+func (t *tracer) start() {
+	spans := make([]*Span, 0)
+
+	flush := func() {
+		if len(spans) == 0 {
+			return
+		}
+		if err := t.exporter.Export(t.service, t.hostname, spans); err != nil {
+			t.logger.Warn("Unable to export trace spans", zap.Int("count", len(spans)), zap.Error(err))
+		}
+		spans = make([]*Span, 0)
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case span := <-t.spanChan:
+			spans = append(spans, span)
+			if len(spans) >= batchLimit {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (t *tracer) queue(span *Span) {
+	if !span.SpanContext.Sampled {
+		return
+	}
+
+	select {
+	case t.spanChan <- span:
+	default:
+		t.logger.Warn("Trace span queue is full, span is dropped", zap.String("name", span.Name))
+	}
+}
+
+func current() *tracer {
+	defaultTracerMutex.Lock()
+	defer defaultTracerMutex.Unlock()
+
+	return defaultTracer
+}
+
+// Start creates a child span of the span context in ctx or a root span if ctx does not carry any.
+// When tracing is disabled, it returns ctx as it is and a nil span
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	t := current()
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		Name: name,
+		SpanContext: SpanContext{
+			SpanId:  newSpanId(),
+			Sampled: true,
+		},
+		Begins:     time.Now().UTC(),
+		Attributes: make(map[string]string),
+		tracer:     t,
+	}
+
+	if parent := FromContext(ctx); parent.Valid() {
+		span.SpanContext.TraceId = parent.TraceId
+		span.SpanContext.Sampled = parent.Sampled
+		span.ParentSpanId = parent.SpanId
+	} else {
+		span.SpanContext.TraceId = newTraceId()
+	}
+
+	return ContextWithSpanContext(ctx, span.SpanContext), span
+}
+
+// ContextWithSpanContext places the span context into the ctx to be used as parent of the next spans
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, spanContextKey, sc)
+}
+
+func FromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+
+	sc, ok := ctx.Value(spanContextKey).(SpanContext)
+	if !ok {
+		return SpanContext{}
+	}
+	return sc
+}
@@ -0,0 +1,52 @@
+package cache
+
+type blockLimitedContainer struct {
+	inner      Container
+	blockLimit uint64
+}
+
+// NewBlockLimitedContainer skips caching the blocks larger than the block limit. Large blocks of the clusters that are
+// configured for the big block size would flush the whole cache with a few reads otherwise
+func NewBlockLimitedContainer(inner Container, blockLimit uint64) Container {
+	return &blockLimitedContainer{
+		inner:      inner,
+		blockLimit: blockLimit,
+	}
+}
+
+func (b *blockLimitedContainer) Query(sha512Hex string) []byte {
+	return b.inner.Query(sha512Hex)
+}
+
+func (b *blockLimitedContainer) Upsert(sha512Hex string, data []byte) {
+	if uint64(len(data)) > b.blockLimit {
+		return
+	}
+	b.inner.Upsert(sha512Hex, data)
+}
+
+func (b *blockLimitedContainer) Accepts(size uint64) bool {
+	return size <= b.blockLimit && b.inner.Accepts(size)
+}
+
+func (b *blockLimitedContainer) Remove(sha512Hex string) {
+	b.inner.Remove(sha512Hex)
+}
+
+func (b *blockLimitedContainer) Invalidate() {
+	b.inner.Invalidate()
+}
+
+func (b *blockLimitedContainer) Pin(sha512Hex string) {
+	b.inner.Pin(sha512Hex)
+}
+
+func (b *blockLimitedContainer) Unpin(sha512Hex string) {
+	b.inner.Unpin(sha512Hex)
+}
+
+func (b *blockLimitedContainer) Purge() {
+	b.inner.Purge()
+}
+
+var _ Container = &blockLimitedContainer{}
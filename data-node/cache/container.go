@@ -14,9 +14,15 @@ type Container interface {
 	Query(sha512Hex string) []byte
 
 	Upsert(sha512Hex string, data []byte)
+	// Accepts reports if the block in the size can be cached, blocks should not be compiled for the cache otherwise
+	Accepts(size uint64) bool
 	Remove(sha512Hex string)
 	Invalidate()
 
+	// Pin keeps the block in the cache till it is unpinned, it can be pinned before it is cached
+	Pin(sha512Hex string)
+	Unpin(sha512Hex string)
+
 	Purge()
 }
 
@@ -43,9 +49,13 @@ type container struct {
 	mutex       *sync.Mutex
 	index       map[string]indexItem
 	sortedIndex indexItemList
+
+	policy Policy
+	pins   map[string]bool
+	stats  Stats
 }
 
-func NewContainer(limit uint64, lifetime time.Duration, logger *zap.Logger) Container {
+func NewContainer(limit uint64, lifetime time.Duration, policy Policy, logger *zap.Logger) Container {
 	container := &container{
 		limit:       limit,
 		lifetime:    lifetime,
@@ -53,11 +63,14 @@ func NewContainer(limit uint64, lifetime time.Duration, logger *zap.Logger) Cont
 		mutex:       &sync.Mutex{},
 		index:       make(map[string]indexItem),
 		sortedIndex: make(indexItemList, 0),
+		policy:      policy,
+		pins:        make(map[string]bool),
 	}
 
 	if limit == 0 {
 		return container
 	}
+	cacheLimit.Set(float64(limit))
 
 	container.start()
 	container.autoReport()
@@ -92,6 +105,7 @@ func (c *container) autoReport() {
 
 			if usageBackup != usage || freeBackup != free {
 				c.logger.Info(fmt.Sprintf("Data Node Memory: %dM Used, %dM Free, %dM Total", usage, free, limit))
+				c.logger.Info(fmt.Sprintf("Data Node Cache (%s): %s", c.policyName(), c.Stats()))
 
 				usageBackup = usage
 				freeBackup = free
@@ -110,10 +124,26 @@ func (c *container) Query(sha512Hex string) []byte {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	if c.policy != nil {
+		c.policy.Access(sha512Hex)
+	}
+
 	index, has := c.index[sha512Hex]
 	if !has {
+		c.stats.Misses++
+		cacheMisses.Inc()
+		policyEvents.WithLabelValues(c.policyName(), "miss").Inc()
 		return nil
 	}
+	c.stats.Hits++
+	c.stats.HitBytes += uint64(len(index.data))
+	cacheHits.Inc()
+	policyEvents.WithLabelValues(c.policyName(), "hit").Inc()
+	policyBytes.WithLabelValues(c.policyName(), "hit").Add(float64(len(index.data)))
+
+	if c.policy != nil {
+		c.policy.Hit(sha512Hex)
+	}
 
 	c.sortedIndex[index.sortIndex] = nil
 
@@ -126,6 +156,10 @@ func (c *container) Query(sha512Hex string) []byte {
 	return index.data
 }
 
+func (c *container) Accepts(size uint64) bool {
+	return c.limit > 0 && size <= c.limit
+}
+
 func (c *container) Upsert(sha512Hex string, data []byte) {
 	if c.limit == 0 {
 		return
@@ -138,12 +172,25 @@ func (c *container) Upsert(sha512Hex string, data []byte) {
 		c.sortedIndex[currentItem.sortIndex] = nil
 		c.usage -= uint64(len(currentItem.data))
 		delete(c.index, currentItem.sha512Hex)
+
+		if c.policy != nil {
+			c.policy.Removed(sha512Hex, false)
+		}
 	}
 
 	dataSize := uint64(len(data))
 
 	if c.limit < c.usage+dataSize {
 		size := int(c.usage + dataSize - c.limit)
+		if dataSize > c.limit || !c.admitUnsafe(sha512Hex, size) {
+			c.stats.Rejections++
+			c.stats.RejectedBytes += dataSize
+			policyEvents.WithLabelValues(c.policyName(), "rejection").Inc()
+			policyBytes.WithLabelValues(c.policyName(), "rejection").Add(float64(dataSize))
+
+			c.reportUnsafe()
+			return
+		}
 		c.trimUnsafe(size)
 	}
 
@@ -158,6 +205,12 @@ func (c *container) Upsert(sha512Hex string, data []byte) {
 	c.sortedIndex = append(c.sortedIndex, &item)
 	c.usage += dataSize
 	c.index[item.sha512Hex] = item
+
+	if c.policy != nil {
+		c.policy.Added(sha512Hex, dataSize)
+	}
+
+	c.reportUnsafe()
 }
 
 func (c *container) Remove(sha512Hex string) {
@@ -176,6 +229,12 @@ func (c *container) Remove(sha512Hex string) {
 	c.sortedIndex[currentItem.sortIndex] = nil
 	c.usage -= uint64(len(currentItem.data))
 	delete(c.index, currentItem.sha512Hex)
+
+	if c.policy != nil {
+		c.policy.Removed(sha512Hex, false)
+	}
+
+	c.reportUnsafe()
 }
 
 func (c *container) Invalidate() {
@@ -186,9 +245,42 @@ func (c *container) Invalidate() {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	if c.policy != nil {
+		for sha512Hex := range c.index {
+			c.policy.Removed(sha512Hex, false)
+		}
+	}
+
 	c.sortedIndex = make(indexItemList, 0)
 	c.index = make(map[string]indexItem)
 	c.usage = 0
+
+	c.reportUnsafe()
+}
+
+func (c *container) Pin(sha512Hex string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.pins == nil {
+		c.pins = make(map[string]bool)
+	}
+	c.pins[sha512Hex] = true
+}
+
+func (c *container) Unpin(sha512Hex string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.pins, sha512Hex)
+}
+
+// Stats returns the counters of the container since it is created
+func (c *container) Stats() Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.stats
 }
 
 func (c *container) Purge() {
@@ -209,12 +301,15 @@ func (c *container) Purge() {
 			continue
 		}
 
-		if indexItem.expiresAt.Before(time.Now().UTC()) {
+		if indexItem.expiresAt.Before(time.Now().UTC()) && !c.pins[indexItem.sha512Hex] {
 			c.sortedIndex = append(c.sortedIndex[:i], c.sortedIndex[i+1:]...)
 			c.usage -= uint64(len(indexItem.data))
 			delete(c.index, indexItem.sha512Hex)
 			i--
 
+			if c.policy != nil {
+				c.policy.Removed(indexItem.sha512Hex, false)
+			}
 			continue
 		}
 
@@ -223,6 +318,32 @@ func (c *container) Purge() {
 
 		currentIndex++
 	}
+
+	c.reportUnsafe()
+}
+
+// admitUnsafe asks the policy if the new block is worth to evict the blocks to free the size
+func (c *container) admitUnsafe(sha512Hex string, size int) bool {
+	if c.policy == nil {
+		return true
+	}
+
+	rejected := false
+	c.policy.Victims(func(victim string) bool {
+		if size <= 0 {
+			return false
+		}
+		if c.pins[victim] {
+			return true
+		}
+		if !c.policy.Admit(sha512Hex, victim) {
+			rejected = true
+			return false
+		}
+		size -= len(c.index[victim].data)
+		return true
+	})
+	return !rejected && size <= 0
 }
 
 func (c *container) trimUnsafe(size int) {
@@ -230,8 +351,30 @@ func (c *container) trimUnsafe(size int) {
 		return
 	}
 
+	if c.policy != nil {
+		// victims are evicted after the walk, the policy can not be changed while its victims are visited
+		victims := make([]string, 0)
+		c.policy.Victims(func(victim string) bool {
+			if size <= 0 {
+				return false
+			}
+			if c.pins[victim] {
+				return true
+			}
+			victims = append(victims, victim)
+			if victimItem, has := c.index[victim]; has {
+				size -= len(victimItem.data)
+			}
+			return true
+		})
+		for _, victim := range victims {
+			c.evictUnsafe(victim)
+		}
+		return
+	}
+
 	for i, indexItem := range c.sortedIndex {
-		if indexItem == nil {
+		if indexItem == nil || c.pins[indexItem.sha512Hex] {
 			continue
 		}
 
@@ -246,5 +389,43 @@ func (c *container) trimUnsafe(size int) {
 		delete(c.index, indexItem.sha512Hex)
 
 		size -= dataSize
+		c.countEvictionUnsafe(dataSize)
 	}
 }
+
+func (c *container) evictUnsafe(sha512Hex string) int {
+	currentItem, has := c.index[sha512Hex]
+	if !has {
+		c.policy.Removed(sha512Hex, false)
+		return 0
+	}
+	dataSize := len(currentItem.data)
+
+	c.sortedIndex[currentItem.sortIndex] = nil
+	c.usage -= uint64(dataSize)
+	delete(c.index, sha512Hex)
+
+	c.policy.Removed(sha512Hex, true)
+	c.countEvictionUnsafe(dataSize)
+
+	return dataSize
+}
+
+func (c *container) countEvictionUnsafe(dataSize int) {
+	c.stats.Evictions++
+	c.stats.EvictedBytes += uint64(dataSize)
+	policyEvents.WithLabelValues(c.policyName(), "eviction").Inc()
+	policyBytes.WithLabelValues(c.policyName(), "eviction").Add(float64(dataSize))
+}
+
+func (c *container) policyName() string {
+	if c.policy == nil {
+		return "expiry"
+	}
+	return c.policy.Name()
+}
+
+func (c *container) reportUnsafe() {
+	cacheUsage.Set(float64(c.usage))
+	cacheItems.Set(float64(len(c.index)))
+}
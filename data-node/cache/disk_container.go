@@ -0,0 +1,350 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const diskIndexFile = "index.json"
+const diskTempPrefix = ".tmp-"
+const diskIndexSaveInterval = time.Minute
+
+var diskChecksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+var errDiskItemCorrupted = errors.New("disk cache file does not match the index")
+
+type diskItem struct {
+	Sha512Hex  string    `json:"sha512Hex"`
+	Size       uint64    `json:"size"`
+	Checksum   uint32    `json:"checksum"`
+	AccessedAt time.Time `json:"accessedAt"`
+}
+
+type diskContainer struct {
+	root   string
+	limit  uint64
+	logger *zap.Logger
+	usage  uint64
+
+	mutex   *sync.Mutex
+	index   map[string]*diskItem
+	pins    map[string]bool
+	changed bool
+}
+
+// NewDiskContainer creates the cache on the disk folder, it is planned to be placed on a faster disk than the
+// data node root. Blocks are evicted by the last access time when the limit is reached and the index is saved
+// in the folder to serve the blocks after the restart
+func NewDiskContainer(root string, limit uint64, logger *zap.Logger) (Container, error) {
+	if limit == 0 {
+		return nil, fmt.Errorf("disk cache limit should be defined")
+	}
+
+	if err := os.MkdirAll(root, 0777); err != nil {
+		return nil, err
+	}
+
+	container := &diskContainer{
+		root:   root,
+		limit:  limit,
+		logger: logger,
+		mutex:  &sync.Mutex{},
+		index:  make(map[string]*diskItem),
+		pins:   make(map[string]bool),
+	}
+
+	if err := container.load(); err != nil {
+		return nil, err
+	}
+	cacheDiskLimit.Set(float64(limit))
+
+	container.start()
+
+	return container, nil
+}
+
+func (d *diskContainer) start() {
+	// Index Save Timer
+	go func() {
+		for {
+			time.Sleep(diskIndexSaveInterval)
+			d.Purge()
+		}
+	}()
+}
+
+// load reads the saved index and drops the block files that are not in the index or are not completely written
+func (d *diskContainer) load() error {
+	savedIndex := make([]*diskItem, 0)
+
+	content, err := ioutil.ReadFile(path.Join(d.root, diskIndexFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(content, &savedIndex); err != nil {
+			d.logger.Warn("Disk cache index is corrupted, cache is started empty", zap.Error(err))
+			savedIndex = make([]*diskItem, 0)
+		}
+	}
+
+	savedIndexMap := make(map[string]*diskItem)
+	for _, item := range savedIndex {
+		savedIndexMap[item.Sha512Hex] = item
+	}
+
+	infos, err := ioutil.ReadDir(d.root)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() || strings.Compare(info.Name(), diskIndexFile) == 0 {
+			continue
+		}
+
+		// items of the index saved without the checksum are dropped
+		item, has := savedIndexMap[info.Name()]
+		if !has || item.Size != uint64(info.Size()) || item.Checksum == 0 {
+			_ = os.Remove(path.Join(d.root, info.Name()))
+			continue
+		}
+
+		d.index[item.Sha512Hex] = item
+		d.usage += item.Size
+	}
+
+	if d.usage > d.limit {
+		d.trimUnsafe(d.usage - d.limit)
+	}
+	d.changed = true
+	d.reportUnsafe()
+
+	return d.saveUnsafe()
+}
+
+func (d *diskContainer) Query(sha512Hex string) []byte {
+	d.mutex.Lock()
+	item, has := d.index[sha512Hex]
+	if !has {
+		d.mutex.Unlock()
+		cacheDiskMisses.Inc()
+		return nil
+	}
+	item.AccessedAt = time.Now().UTC()
+	d.changed = true
+	size, checksum := item.Size, item.Checksum
+	d.mutex.Unlock()
+
+	data, err := ioutil.ReadFile(path.Join(d.root, sha512Hex))
+	if err == nil && (uint64(len(data)) != size || crc32.Checksum(data, diskChecksumTable) != checksum) {
+		err = errDiskItemCorrupted
+	}
+	if err != nil {
+		d.logger.Warn("Reading disk cache is failed", zap.String("sha512Hex", sha512Hex), zap.Error(err))
+		d.removeItem(item)
+
+		cacheDiskMisses.Inc()
+		return nil
+	}
+	cacheDiskHits.Inc()
+
+	return data
+}
+
+func (d *diskContainer) Accepts(size uint64) bool {
+	return size <= d.limit
+}
+
+func (d *diskContainer) Upsert(sha512Hex string, data []byte) {
+	dataSize := uint64(len(data))
+	if dataSize > d.limit {
+		return
+	}
+	checksum := crc32.Checksum(data, diskChecksumTable)
+
+	tempFile, err := ioutil.TempFile(d.root, diskTempPrefix)
+	if err != nil {
+		d.logger.Warn("Creating disk cache file is failed", zap.String("sha512Hex", sha512Hex), zap.Error(err))
+		return
+	}
+	_, err = tempFile.Write(data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+		d.logger.Warn("Writing disk cache file is failed", zap.String("sha512Hex", sha512Hex), zap.Error(err))
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if currentItem, has := d.index[sha512Hex]; has {
+		d.usage -= currentItem.Size
+		delete(d.index, sha512Hex)
+	}
+
+	if d.limit < d.usage+dataSize {
+		d.trimUnsafe(d.usage + dataSize - d.limit)
+	}
+
+	if err := os.Rename(tempFile.Name(), path.Join(d.root, sha512Hex)); err != nil {
+		_ = os.Remove(tempFile.Name())
+		d.logger.Warn("Placing disk cache file is failed", zap.String("sha512Hex", sha512Hex), zap.Error(err))
+		return
+	}
+
+	d.index[sha512Hex] = &diskItem{
+		Sha512Hex:  sha512Hex,
+		Size:       dataSize,
+		Checksum:   checksum,
+		AccessedAt: time.Now().UTC(),
+	}
+	d.usage += dataSize
+	d.changed = true
+
+	d.reportUnsafe()
+}
+
+func (d *diskContainer) Remove(sha512Hex string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.removeUnsafe(sha512Hex)
+	d.reportUnsafe()
+}
+
+// removeItem removes the item only if it is not replaced after it is looked up
+func (d *diskContainer) removeItem(item *diskItem) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.index[item.Sha512Hex] != item {
+		return
+	}
+	d.removeUnsafe(item.Sha512Hex)
+	d.reportUnsafe()
+}
+
+func (d *diskContainer) Invalidate() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for sha512Hex := range d.index {
+		d.removeUnsafe(sha512Hex)
+	}
+	d.reportUnsafe()
+
+	if err := d.saveUnsafe(); err != nil {
+		d.logger.Warn("Saving disk cache index is failed", zap.Error(err))
+	}
+}
+
+func (d *diskContainer) Pin(sha512Hex string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.pins[sha512Hex] = true
+}
+
+func (d *diskContainer) Unpin(sha512Hex string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.pins, sha512Hex)
+}
+
+// Purge saves the index if it is changed, disk cache items do not expire
+func (d *diskContainer) Purge() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if err := d.saveUnsafe(); err != nil {
+		d.logger.Warn("Saving disk cache index is failed", zap.Error(err))
+	}
+}
+
+func (d *diskContainer) removeUnsafe(sha512Hex string) {
+	item, has := d.index[sha512Hex]
+	if !has {
+		return
+	}
+
+	if err := os.Remove(path.Join(d.root, sha512Hex)); err != nil && !os.IsNotExist(err) {
+		d.logger.Warn("Removing disk cache file is failed", zap.String("sha512Hex", sha512Hex), zap.Error(err))
+	}
+
+	d.usage -= item.Size
+	delete(d.index, sha512Hex)
+	d.changed = true
+}
+
+// trimUnsafe removes the least recently accessed items till the size is freed
+func (d *diskContainer) trimUnsafe(size uint64) {
+	items := make([]*diskItem, 0, len(d.index))
+	for _, item := range d.index {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].AccessedAt.Before(items[j].AccessedAt)
+	})
+
+	freed := uint64(0)
+	for _, item := range items {
+		if freed >= size {
+			return
+		}
+		if d.pins[item.Sha512Hex] {
+			continue
+		}
+		freed += item.Size
+		d.removeUnsafe(item.Sha512Hex)
+	}
+}
+
+func (d *diskContainer) saveUnsafe() error {
+	if !d.changed {
+		return nil
+	}
+
+	items := make([]*diskItem, 0, len(d.index))
+	for _, item := range d.index {
+		items = append(items, item)
+	}
+
+	content, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+
+	indexPath := path.Join(d.root, diskIndexFile)
+	if err := ioutil.WriteFile(indexPath+diskTempPrefix, content, 0666); err != nil {
+		return err
+	}
+	if err := os.Rename(indexPath+diskTempPrefix, indexPath); err != nil {
+		return err
+	}
+	d.changed = false
+
+	return nil
+}
+
+func (d *diskContainer) reportUnsafe() {
+	cacheDiskUsage.Set(float64(d.usage))
+	cacheDiskItems.Set(float64(len(d.index)))
+}
+
+var _ Container = &diskContainer{}
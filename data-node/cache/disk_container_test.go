@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestDiskContainer_Persisted(t *testing.T) {
+	root, err := ioutil.TempDir("", "kertish-cache")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(root) }()
+
+	container, err := NewDiskContainer(root, 1024, zap.NewNop())
+	assert.Nil(t, err)
+
+	container.Upsert("a1", []byte("block a1"))
+	container.Upsert("a2", []byte("block a2"))
+	container.Remove("a2")
+	assert.Equal(t, []byte("block a1"), container.Query("a1"))
+	assert.Nil(t, container.Query("a2"))
+	container.Purge()
+
+	// not completed write of the previous run
+	assert.Nil(t, ioutil.WriteFile(path.Join(root, diskTempPrefix+"a3"), []byte("block"), 0666))
+
+	container, err = NewDiskContainer(root, 1024, zap.NewNop())
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("block a1"), container.Query("a1"))
+
+	infos, err := ioutil.ReadDir(root)
+	assert.Nil(t, err)
+	assert.Len(t, infos, 2)
+
+	container.Invalidate()
+	assert.Nil(t, container.Query("a1"))
+}
+
+func TestDiskContainer_Trim(t *testing.T) {
+	root, err := ioutil.TempDir("", "kertish-cache")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(root) }()
+
+	container, err := NewDiskContainer(root, 20, zap.NewNop())
+	assert.Nil(t, err)
+
+	container.Upsert("a1", make([]byte, 8))
+	time.Sleep(time.Millisecond)
+	container.Upsert("a2", make([]byte, 8))
+	time.Sleep(time.Millisecond)
+	assert.NotNil(t, container.Query("a1"))
+
+	container.Upsert("a3", make([]byte, 8))
+	assert.NotNil(t, container.Query("a1"))
+	assert.Nil(t, container.Query("a2"))
+	assert.NotNil(t, container.Query("a3"))
+
+	container.Upsert("a4", make([]byte, 32))
+	assert.Nil(t, container.Query("a4"))
+}
+
+func TestDiskContainer_Corrupted(t *testing.T) {
+	root, err := ioutil.TempDir("", "kertish-cache")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(root) }()
+
+	container, err := NewDiskContainer(root, 1024, zap.NewNop())
+	assert.Nil(t, err)
+
+	container.Upsert("a1", []byte("block a1"))
+	container.Upsert("a2", []byte("block a2"))
+
+	// same size with different content and truncated content
+	assert.Nil(t, ioutil.WriteFile(path.Join(root, "a1"), []byte("block b1"), 0666))
+	assert.Nil(t, ioutil.WriteFile(path.Join(root, "a2"), []byte("block"), 0666))
+
+	assert.Nil(t, container.Query("a1"))
+	assert.Nil(t, container.Query("a2"))
+
+	d := container.(*diskContainer)
+	assert.Len(t, d.index, 0)
+	assert.Equal(t, uint64(0), d.usage)
+}
+
+func TestDiskContainer_RemoveReplaced(t *testing.T) {
+	root, err := ioutil.TempDir("", "kertish-cache")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(root) }()
+
+	container, err := NewDiskContainer(root, 1024, zap.NewNop())
+	assert.Nil(t, err)
+	d := container.(*diskContainer)
+
+	container.Upsert("a1", []byte("block a1"))
+	lookedUp := d.index["a1"]
+
+	// failed read of the looked up item does not remove the item upserted meanwhile
+	container.Upsert("a1", []byte("block a1"))
+	d.removeItem(lookedUp)
+	assert.Equal(t, []byte("block a1"), container.Query("a1"))
+
+	d.removeItem(d.index["a1"])
+	assert.Nil(t, container.Query("a1"))
+}
+
+func TestTieredContainer_Query(t *testing.T) {
+	root, err := ioutil.TempDir("", "kertish-cache")
+	assert.Nil(t, err)
+	defer func() { _ = os.RemoveAll(root) }()
+
+	memory := &container{
+		limit:       1024,
+		lifetime:    time.Minute,
+		mutex:       &sync.Mutex{},
+		index:       make(map[string]indexItem),
+		sortedIndex: make(indexItemList, 0),
+		logger:      zap.NewNop(),
+	}
+	disk, err := NewDiskContainer(root, 1024, zap.NewNop())
+	assert.Nil(t, err)
+
+	tiered := NewTieredContainer(memory, disk)
+	tiered.Upsert("a1", []byte("block a1"))
+
+	memory.Invalidate()
+	assert.Nil(t, memory.Query("a1"))
+
+	assert.Equal(t, []byte("block a1"), tiered.Query("a1"))
+	assert.Equal(t, []byte("block a1"), memory.Query("a1"))
+
+	tiered.Remove("a1")
+	assert.Nil(t, tiered.Query("a1"))
+}
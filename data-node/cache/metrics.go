@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	cacheQueriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "cache_queries_total",
+		Help:      "Number of cache queries by result, hit or miss",
+	}, []string{"result"})
+	cacheHits   = cacheQueriesTotal.WithLabelValues("hit")
+	cacheMisses = cacheQueriesTotal.WithLabelValues("miss")
+
+	cacheUsage = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "cache_usage_bytes",
+		Help:      "Size of the data in the cache",
+	})
+	cacheItems = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "cache_items",
+		Help:      "Number of blocks in the cache",
+	})
+	cacheLimit = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "cache_limit_bytes",
+		Help:      "Size limit of the cache",
+	})
+
+	policyEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "cache_policy_events_total",
+		Help:      "Number of cache events by policy and event, hit, miss, eviction or rejection",
+	}, []string{"policy", "event"})
+	policyBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "cache_policy_bytes_total",
+		Help:      "Size of the data by policy and event, hit, eviction or rejection",
+	}, []string{"policy", "event"})
+
+	cacheDiskQueriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "cache_disk_queries_total",
+		Help:      "Number of disk cache queries by result, hit or miss",
+	}, []string{"result"})
+	cacheDiskHits   = cacheDiskQueriesTotal.WithLabelValues("hit")
+	cacheDiskMisses = cacheDiskQueriesTotal.WithLabelValues("miss")
+
+	cacheDiskUsage = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "cache_disk_usage_bytes",
+		Help:      "Size of the data in the disk cache",
+	})
+	cacheDiskItems = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "cache_disk_items",
+		Help:      "Number of blocks in the disk cache",
+	})
+	cacheDiskLimit = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "cache_disk_limit_bytes",
+		Help:      "Size limit of the disk cache",
+	})
+)
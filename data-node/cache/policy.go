@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/heap"
+	"fmt"
+	"strings"
+)
+
+// Policy decides the eviction order of the cached blocks and the admission of the new blocks when the cache is full
+type Policy interface {
+	Name() string
+
+	// Access records the query of the block even it is not in the cache
+	Access(sha512Hex string)
+	// Admit returns true if the candidate block is worth to evict the victim block
+	Admit(candidate string, victim string) bool
+
+	Added(sha512Hex string, size uint64)
+	Hit(sha512Hex string)
+	Removed(sha512Hex string, evicted bool)
+
+	// Victims visits the cached blocks in the eviction order till the handler returns false. Blocks are visited lazily,
+	// so the handler should not change the policy
+	Victims(handler func(sha512Hex string) bool)
+}
+
+// Stats are the counters of the cache container
+type Stats struct {
+	Hits       uint64
+	Misses     uint64
+	Evictions  uint64
+	Rejections uint64
+
+	HitBytes      uint64
+	EvictedBytes  uint64
+	RejectedBytes uint64
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf(
+		"%d Hits (%dM), %d Misses, %d Evictions (%dM), %d Rejections (%dM)",
+		s.Hits, s.HitBytes/(1024*1024), s.Misses, s.Evictions, s.EvictedBytes/(1024*1024), s.Rejections, s.RejectedBytes/(1024*1024),
+	)
+}
+
+// NewPolicy creates the policy by its name. Values: lru, lfu, tinylfu or size
+func NewPolicy(name string) (Policy, error) {
+	switch strings.ToLower(name) {
+	case "", "lru":
+		return NewLRUPolicy(), nil
+	case "lfu":
+		return NewLFUPolicy(), nil
+	case "tinylfu":
+		return NewTinyLFUPolicy(tinyLFUSketchWidth), nil
+	case "size":
+		return NewSizePolicy(), nil
+	}
+	return nil, fmt.Errorf("%s is not a cache policy", name)
+}
+
+// victimHeap keeps the cached blocks of the policy in the eviction order
+type victimHeap interface {
+	heap.Interface
+	key(i int) string
+}
+
+// walkHeap visits the heap items in the order without changing the heap. Only the children of the visited items are
+// compared, so taking the first k items costs O(k log k)
+func walkHeap(h victimHeap, handler func(sha512Hex string) bool) {
+	if h.Len() == 0 {
+		return
+	}
+
+	walk := &heapWalk{source: h, indexes: []int{0}}
+	for walk.Len() > 0 {
+		i := heap.Pop(walk).(int)
+		if !handler(h.key(i)) {
+			return
+		}
+
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < h.Len() {
+				heap.Push(walk, child)
+			}
+		}
+	}
+}
+
+type heapWalk struct {
+	source  victimHeap
+	indexes []int
+}
+
+func (w *heapWalk) Len() int {
+	return len(w.indexes)
+}
+
+func (w *heapWalk) Less(i, j int) bool {
+	return w.source.Less(w.indexes[i], w.indexes[j])
+}
+
+func (w *heapWalk) Swap(i, j int) {
+	w.indexes[i], w.indexes[j] = w.indexes[j], w.indexes[i]
+}
+
+func (w *heapWalk) Push(x interface{}) {
+	w.indexes = append(w.indexes, x.(int))
+}
+
+func (w *heapWalk) Pop() interface{} {
+	last := len(w.indexes) - 1
+	i := w.indexes[last]
+	w.indexes = w.indexes[:last]
+	return i
+}
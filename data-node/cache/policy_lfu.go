@@ -0,0 +1,112 @@
+package cache
+
+import "container/heap"
+
+type lfuItem struct {
+	sha512Hex string
+	count     uint64
+	tick      uint64
+	index     int
+}
+
+type lfuHeap []*lfuItem
+
+func (h lfuHeap) Len() int {
+	return len(h)
+}
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].count == h[j].count {
+		return h[i].tick < h[j].tick
+	}
+	return h[i].count < h[j].count
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	item := x.(*lfuItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+func (h lfuHeap) key(i int) string {
+	return h[i].sha512Hex
+}
+
+type lfuPolicy struct {
+	tick  uint64
+	items map[string]*lfuItem
+	heap  lfuHeap
+}
+
+// NewLFUPolicy evicts the least frequently used blocks first, the least recently used one is picked on a tie
+func NewLFUPolicy() Policy {
+	return &lfuPolicy{
+		items: make(map[string]*lfuItem),
+		heap:  make(lfuHeap, 0),
+	}
+}
+
+func (l *lfuPolicy) Name() string {
+	return "lfu"
+}
+
+func (l *lfuPolicy) Access(_ string) {}
+
+func (l *lfuPolicy) Admit(_ string, _ string) bool {
+	return true
+}
+
+func (l *lfuPolicy) Added(sha512Hex string, _ uint64) {
+	l.tick++
+
+	if item, has := l.items[sha512Hex]; has {
+		item.count = 1
+		item.tick = l.tick
+		heap.Fix(&l.heap, item.index)
+		return
+	}
+
+	item := &lfuItem{sha512Hex: sha512Hex, count: 1, tick: l.tick}
+	l.items[sha512Hex] = item
+	heap.Push(&l.heap, item)
+}
+
+func (l *lfuPolicy) Hit(sha512Hex string) {
+	item, has := l.items[sha512Hex]
+	if !has {
+		return
+	}
+	l.tick++
+	item.count++
+	item.tick = l.tick
+	heap.Fix(&l.heap, item.index)
+}
+
+func (l *lfuPolicy) Removed(sha512Hex string, _ bool) {
+	item, has := l.items[sha512Hex]
+	if !has {
+		return
+	}
+	heap.Remove(&l.heap, item.index)
+	delete(l.items, sha512Hex)
+}
+
+func (l *lfuPolicy) Victims(handler func(sha512Hex string) bool) {
+	walkHeap(&l.heap, handler)
+}
+
+var _ Policy = &lfuPolicy{}
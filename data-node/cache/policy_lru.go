@@ -0,0 +1,62 @@
+package cache
+
+import "container/list"
+
+type lruPolicy struct {
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+// NewLRUPolicy evicts the least recently used blocks first
+func NewLRUPolicy() Policy {
+	return &lruPolicy{
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+func (l *lruPolicy) Name() string {
+	return "lru"
+}
+
+func (l *lruPolicy) Access(_ string) {}
+
+func (l *lruPolicy) Admit(_ string, _ string) bool {
+	return true
+}
+
+func (l *lruPolicy) Added(sha512Hex string, _ uint64) {
+	l.touch(sha512Hex)
+}
+
+func (l *lruPolicy) Hit(sha512Hex string) {
+	l.touch(sha512Hex)
+}
+
+func (l *lruPolicy) Removed(sha512Hex string, _ bool) {
+	element, has := l.elements[sha512Hex]
+	if !has {
+		return
+	}
+	l.order.Remove(element)
+	delete(l.elements, sha512Hex)
+}
+
+func (l *lruPolicy) Victims(handler func(sha512Hex string) bool) {
+	for element := l.order.Front(); element != nil; element = element.Next() {
+		if !handler(element.Value.(string)) {
+			return
+		}
+	}
+}
+
+// touch moves the block to the end of the eviction order
+func (l *lruPolicy) touch(sha512Hex string) {
+	if element, has := l.elements[sha512Hex]; has {
+		l.order.MoveToBack(element)
+		return
+	}
+	l.elements[sha512Hex] = l.order.PushBack(sha512Hex)
+}
+
+var _ Policy = &lruPolicy{}
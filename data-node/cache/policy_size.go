@@ -0,0 +1,122 @@
+package cache
+
+import "container/heap"
+
+type sizeItem struct {
+	sha512Hex string
+	count     uint64
+	size      uint64
+	priority  float64
+	index     int
+}
+
+type sizeHeap []*sizeItem
+
+func (h sizeHeap) Len() int {
+	return len(h)
+}
+
+func (h sizeHeap) Less(i, j int) bool {
+	return h[i].priority < h[j].priority
+}
+
+func (h sizeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *sizeHeap) Push(x interface{}) {
+	item := x.(*sizeItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *sizeHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+func (h sizeHeap) key(i int) string {
+	return h[i].sha512Hex
+}
+
+type sizePolicy struct {
+	clock float64
+	items map[string]*sizeItem
+	heap  sizeHeap
+}
+
+// NewSizePolicy is the size aware policy (greedy dual size frequency). Priority of the block grows with its hits and
+// shrinks with its size, so many small hot blocks are kept instead of a single large one. Evicted priority ages the
+// remaining blocks
+func NewSizePolicy() Policy {
+	return &sizePolicy{
+		items: make(map[string]*sizeItem),
+		heap:  make(sizeHeap, 0),
+	}
+}
+
+func (s *sizePolicy) Name() string {
+	return "size"
+}
+
+func (s *sizePolicy) Access(_ string) {}
+
+func (s *sizePolicy) Admit(_ string, _ string) bool {
+	return true
+}
+
+func (s *sizePolicy) Added(sha512Hex string, size uint64) {
+	if item, has := s.items[sha512Hex]; has {
+		item.count = 0
+		item.size = size
+		s.touch(item)
+		heap.Fix(&s.heap, item.index)
+		return
+	}
+
+	item := &sizeItem{sha512Hex: sha512Hex, size: size}
+	s.items[sha512Hex] = item
+	s.touch(item)
+	heap.Push(&s.heap, item)
+}
+
+func (s *sizePolicy) Hit(sha512Hex string) {
+	item, has := s.items[sha512Hex]
+	if !has {
+		return
+	}
+	s.touch(item)
+	heap.Fix(&s.heap, item.index)
+}
+
+func (s *sizePolicy) Removed(sha512Hex string, evicted bool) {
+	item, has := s.items[sha512Hex]
+	if !has {
+		return
+	}
+	if evicted && item.priority > s.clock {
+		s.clock = item.priority
+	}
+	heap.Remove(&s.heap, item.index)
+	delete(s.items, sha512Hex)
+}
+
+func (s *sizePolicy) Victims(handler func(sha512Hex string) bool) {
+	walkHeap(&s.heap, handler)
+}
+
+func (s *sizePolicy) touch(item *sizeItem) {
+	size := item.size
+	if size == 0 {
+		size = 1
+	}
+	item.count++
+	item.priority = s.clock + float64(item.count)*1024*1024/float64(size)
+}
+
+var _ Policy = &sizePolicy{}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newPolicyContainer(limit uint64, policy Policy) *container {
+	return NewContainer(limit, time.Hour, policy, zap.NewNop()).(*container)
+}
+
+func TestNewPolicy(t *testing.T) {
+	for _, name := range []string{"lru", "lfu", "tinylfu", "size"} {
+		policy, err := NewPolicy(name)
+		assert.Nil(t, err)
+		assert.Equal(t, name, policy.Name())
+	}
+
+	_, err := NewPolicy("random")
+	assert.NotNil(t, err)
+}
+
+func victims(policy Policy, limit int) []string {
+	result := make([]string, 0)
+	policy.Victims(func(sha512Hex string) bool {
+		result = append(result, sha512Hex)
+		return len(result) < limit
+	})
+	return result
+}
+
+func TestPolicy_Victims(t *testing.T) {
+	lru := NewLRUPolicy()
+	lfu := NewLFUPolicy()
+	size := NewSizePolicy()
+
+	for i := 0; i < 100; i++ {
+		sha512Hex := fmt.Sprintf("a%d", i)
+		lru.Added(sha512Hex, 10)
+		lfu.Added(sha512Hex, 10)
+		size.Added(sha512Hex, uint64(100-i))
+	}
+	// hits push the first blocks to the end of the eviction order
+	for i := 0; i < 10; i++ {
+		for j := 0; j <= i; j++ {
+			sha512Hex := fmt.Sprintf("a%d", i)
+			lru.Hit(sha512Hex)
+			lfu.Hit(sha512Hex)
+		}
+	}
+	lru.Removed("a10", false)
+	lfu.Removed("a10", false)
+	size.Removed("a99", true)
+
+	assert.Equal(t, []string{"a11", "a12", "a13"}, victims(lru, 3))
+	assert.Equal(t, []string{"a99", "a0"}, victims(lru, 100)[88:90])
+	assert.Equal(t, []string{"a11", "a12", "a13"}, victims(lfu, 3))
+	assert.Equal(t, []string{"a0", "a1", "a2"}, victims(lfu, 100)[89:92])
+	assert.Equal(t, []string{"a0", "a1", "a2"}, victims(size, 3))
+	assert.Len(t, victims(size, 1000), 99)
+}
+
+func TestContainer_LRU(t *testing.T) {
+	c := newPolicyContainer(30, NewLRUPolicy())
+
+	c.Upsert("a1", make([]byte, 10))
+	c.Upsert("a2", make([]byte, 10))
+	c.Upsert("a3", make([]byte, 10))
+	assert.NotNil(t, c.Query("a1"))
+
+	c.Upsert("a4", make([]byte, 10))
+	assert.Nil(t, c.Query("a2"))
+	assert.NotNil(t, c.Query("a1"))
+	assert.NotNil(t, c.Query("a4"))
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, uint64(10), stats.EvictedBytes)
+	assert.Equal(t, uint64(1), stats.Misses)
+}
+
+func TestContainer_LFU(t *testing.T) {
+	c := newPolicyContainer(30, NewLFUPolicy())
+
+	c.Upsert("a1", make([]byte, 10))
+	c.Upsert("a2", make([]byte, 10))
+	c.Upsert("a3", make([]byte, 10))
+	for i := 0; i < 3; i++ {
+		assert.NotNil(t, c.Query("a1"))
+		assert.NotNil(t, c.Query("a3"))
+	}
+	assert.NotNil(t, c.Query("a2"))
+
+	c.Upsert("a4", make([]byte, 10))
+	assert.Nil(t, c.Query("a2"))
+	assert.NotNil(t, c.Query("a1"))
+	assert.NotNil(t, c.Query("a3"))
+}
+
+func TestContainer_TinyLFU(t *testing.T) {
+	c := newPolicyContainer(1024*10, NewTinyLFUPolicy(tinyLFUSketchWidth))
+
+	for i := 0; i < 10; i++ {
+		sha512Hex := fmt.Sprintf("hot%d", i)
+		for j := 0; j < 5; j++ {
+			if c.Query(sha512Hex) == nil {
+				c.Upsert(sha512Hex, make([]byte, 1024))
+			}
+		}
+	}
+
+	// large scan queries every block once
+	for i := 0; i < 100; i++ {
+		sha512Hex := fmt.Sprintf("scan%d", i)
+		if c.Query(sha512Hex) == nil {
+			c.Upsert(sha512Hex, make([]byte, 1024))
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		assert.NotNil(t, c.Query(fmt.Sprintf("hot%d", i)))
+	}
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(100), stats.Rejections)
+	assert.Equal(t, uint64(0), stats.Evictions)
+}
+
+func TestContainer_Size(t *testing.T) {
+	c := newPolicyContainer(100, NewSizePolicy())
+
+	c.Upsert("large", make([]byte, 60))
+	c.Upsert("small1", make([]byte, 10))
+	c.Upsert("small2", make([]byte, 10))
+	assert.NotNil(t, c.Query("large"))
+
+	c.Upsert("small3", make([]byte, 30))
+	assert.Nil(t, c.Query("large"))
+	assert.NotNil(t, c.Query("small1"))
+	assert.NotNil(t, c.Query("small2"))
+	assert.NotNil(t, c.Query("small3"))
+}
+
+func TestContainer_Pin(t *testing.T) {
+	c := newPolicyContainer(20, NewLRUPolicy())
+
+	c.Pin("a1")
+	c.Upsert("a1", make([]byte, 10))
+	c.Upsert("a2", make([]byte, 10))
+	c.Upsert("a3", make([]byte, 10))
+	assert.NotNil(t, c.Query("a1"))
+	assert.Nil(t, c.Query("a2"))
+
+	c.Upsert("a4", make([]byte, 20))
+	assert.NotNil(t, c.Query("a1"))
+	assert.Nil(t, c.Query("a4"))
+
+	c.Unpin("a1")
+	c.Upsert("a4", make([]byte, 20))
+	assert.Nil(t, c.Query("a1"))
+	assert.NotNil(t, c.Query("a4"))
+}
+
+func TestBlockLimitedContainer(t *testing.T) {
+	c := NewBlockLimitedContainer(newPolicyContainer(100, NewLRUPolicy()), 20)
+
+	assert.True(t, c.Accepts(20))
+	assert.False(t, c.Accepts(30))
+
+	c.Upsert("small", make([]byte, 20))
+	c.Upsert("large", make([]byte, 30))
+	assert.NotNil(t, c.Query("small"))
+	assert.Nil(t, c.Query("large"))
+
+	assert.False(t, NewBlockLimitedContainer(newPolicyContainer(0, NewLRUPolicy()), 20).Accepts(10))
+}
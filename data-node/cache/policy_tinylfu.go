@@ -0,0 +1,99 @@
+package cache
+
+import "hash/fnv"
+
+const tinyLFUSketchWidth = 4096
+const tinyLFUSketchDepth = 4
+const tinyLFUCounterLimit = 15
+
+type tinyLFUPolicy struct {
+	lru Policy
+
+	width     uint64
+	sketch    [tinyLFUSketchDepth][]uint8
+	additions uint64
+}
+
+// NewTinyLFUPolicy evicts as LRU but admits the new block only if it is queried more often than the victim. Query
+// frequencies are kept in the count-min sketch and halved periodically, so a large scan does not flush the hot blocks
+func NewTinyLFUPolicy(width uint64) Policy {
+	t := &tinyLFUPolicy{
+		lru:   NewLRUPolicy(),
+		width: width,
+	}
+	for i := range t.sketch {
+		t.sketch[i] = make([]uint8, width)
+	}
+	return t
+}
+
+func (t *tinyLFUPolicy) Name() string {
+	return "tinylfu"
+}
+
+func (t *tinyLFUPolicy) Access(sha512Hex string) {
+	for i, idx := range t.indexes(sha512Hex) {
+		if t.sketch[i][idx] < tinyLFUCounterLimit {
+			t.sketch[i][idx]++
+		}
+	}
+
+	t.additions++
+	if t.additions >= t.width*10 {
+		t.age()
+	}
+}
+
+func (t *tinyLFUPolicy) Admit(candidate string, victim string) bool {
+	return t.estimate(candidate) > t.estimate(victim)
+}
+
+func (t *tinyLFUPolicy) Added(sha512Hex string, size uint64) {
+	t.lru.Added(sha512Hex, size)
+}
+
+func (t *tinyLFUPolicy) Hit(sha512Hex string) {
+	t.lru.Hit(sha512Hex)
+}
+
+func (t *tinyLFUPolicy) Removed(sha512Hex string, evicted bool) {
+	t.lru.Removed(sha512Hex, evicted)
+}
+
+func (t *tinyLFUPolicy) Victims(handler func(sha512Hex string) bool) {
+	t.lru.Victims(handler)
+}
+
+func (t *tinyLFUPolicy) estimate(sha512Hex string) uint8 {
+	estimate := uint8(tinyLFUCounterLimit)
+	for i, idx := range t.indexes(sha512Hex) {
+		if t.sketch[i][idx] < estimate {
+			estimate = t.sketch[i][idx]
+		}
+	}
+	return estimate
+}
+
+func (t *tinyLFUPolicy) indexes(sha512Hex string) [tinyLFUSketchDepth]uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(sha512Hex))
+	sum := h.Sum64()
+
+	h1, h2 := sum&0xffffffff, sum>>32
+	var indexes [tinyLFUSketchDepth]uint64
+	for i := range indexes {
+		indexes[i] = (h1 + uint64(i)*h2) % t.width
+	}
+	return indexes
+}
+
+func (t *tinyLFUPolicy) age() {
+	for i := range t.sketch {
+		for j := range t.sketch[i] {
+			t.sketch[i][j] >>= 1
+		}
+	}
+	t.additions = 0
+}
+
+var _ Policy = &tinyLFUPolicy{}
@@ -0,0 +1,63 @@
+package cache
+
+type tieredContainer struct {
+	memory Container
+	disk   Container
+}
+
+// NewTieredContainer places the disk cache below the memory cache. Blocks found on the disk are moved up to the
+// memory and the new blocks are written to both
+func NewTieredContainer(memory Container, disk Container) Container {
+	return &tieredContainer{
+		memory: memory,
+		disk:   disk,
+	}
+}
+
+func (t *tieredContainer) Query(sha512Hex string) []byte {
+	if data := t.memory.Query(sha512Hex); data != nil {
+		return data
+	}
+
+	data := t.disk.Query(sha512Hex)
+	if data != nil {
+		t.memory.Upsert(sha512Hex, data)
+	}
+	return data
+}
+
+func (t *tieredContainer) Upsert(sha512Hex string, data []byte) {
+	t.memory.Upsert(sha512Hex, data)
+	t.disk.Upsert(sha512Hex, data)
+}
+
+func (t *tieredContainer) Accepts(size uint64) bool {
+	return t.memory.Accepts(size) || t.disk.Accepts(size)
+}
+
+func (t *tieredContainer) Remove(sha512Hex string) {
+	t.memory.Remove(sha512Hex)
+	t.disk.Remove(sha512Hex)
+}
+
+func (t *tieredContainer) Invalidate() {
+	t.memory.Invalidate()
+	t.disk.Invalidate()
+}
+
+func (t *tieredContainer) Pin(sha512Hex string) {
+	t.memory.Pin(sha512Hex)
+	t.disk.Pin(sha512Hex)
+}
+
+func (t *tieredContainer) Unpin(sha512Hex string) {
+	t.memory.Unpin(sha512Hex)
+	t.disk.Unpin(sha512Hex)
+}
+
+func (t *tieredContainer) Purge() {
+	t.memory.Purge()
+	t.disk.Purge()
+}
+
+var _ Container = &tieredContainer{}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/protocol"
 )
 
 const commandSyncRead = "SYRD"
@@ -21,7 +22,7 @@ type DataNode interface {
 	SyncList(snapshotTime *time.Time) (*common.SyncContainer, error)
 	SyncRead(snapshotTime *time.Time, sha512Hex string, drop bool,
 		dataHandler func(data []byte) error,
-		verifyHandler func(usage uint16) bool,
+		verifyHandler func(usage uint32) bool,
 	) error
 }
 
@@ -40,26 +41,11 @@ func NewDataNode(address string) (DataNode, error) {
 	}, nil
 }
 
-func (d *dataNode) connect(connectionHandler func(conn *net.TCPConn) error) error {
-	conn, err := net.DialTCP("tcp", nil, d.address)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = conn.Close() }()
-
-	return connectionHandler(conn)
-}
-
-func (d *dataNode) result(conn *net.TCPConn) bool {
-	b := make([]byte, 1)
-	_, err := conn.Read(b)
-	if err != nil {
-		return false
-	}
-	return strings.Compare("+", string(b)) == 0
+func (d *dataNode) connect(connectionHandler func(conn *protocol.Conn) error) error {
+	return protocol.Do(d.address, connectionHandler)
 }
 
-func (d *dataNode) hashAsHex(conn *net.TCPConn) (string, error) {
+func (d *dataNode) hashAsHex(conn *protocol.Conn) (string, error) {
 	h := make([]byte, 32)
 	total, err := io.ReadAtLeast(conn, h, len(h))
 	if err != nil {
@@ -74,7 +60,7 @@ func (d *dataNode) hashAsHex(conn *net.TCPConn) (string, error) {
 func (d *dataNode) SyncList(snapshotTime *time.Time) (*common.SyncContainer, error) {
 	container := common.NewSyncContainer()
 
-	if err := d.connect(func(conn *net.TCPConn) error {
+	if err := d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSyncList)); err != nil {
 			return err
 		}
@@ -88,8 +74,8 @@ func (d *dataNode) SyncList(snapshotTime *time.Time) (*common.SyncContainer, err
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("data node refused the sync list request")
+		if !conn.Result() {
+			return conn.Error("data node refused the sync list request")
 		}
 
 		var snapshotsLength uint64
@@ -121,10 +107,11 @@ func (d *dataNode) SyncList(snapshotTime *time.Time) (*common.SyncContainer, err
 				break
 			}
 
-			var usage uint16
-			if err := binary.Read(conn, binary.LittleEndian, &usage); err != nil {
+			usageBuffer := make([]byte, protocol.UsageSize(conn.Peer()))
+			if _, err := io.ReadFull(conn, usageBuffer); err != nil {
 				return err
 			}
+			usage := protocol.DecodeUsage(conn.Peer(), usageBuffer)
 
 			var size int32
 			if err := binary.Read(conn, binary.LittleEndian, &size); err != nil {
@@ -139,8 +126,8 @@ func (d *dataNode) SyncList(snapshotTime *time.Time) (*common.SyncContainer, err
 				}
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("sync list command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("sync list command is failed on data node")
 		}
 
 		return nil
@@ -151,8 +138,8 @@ func (d *dataNode) SyncList(snapshotTime *time.Time) (*common.SyncContainer, err
 	return container, nil
 }
 
-func (d *dataNode) SyncRead(snapshotTime *time.Time, sha512Hex string, drop bool, dataHandler func([]byte) error, verifyHandler func(usage uint16) bool) error {
-	return d.connect(func(conn *net.TCPConn) error {
+func (d *dataNode) SyncRead(snapshotTime *time.Time, sha512Hex string, drop bool, dataHandler func([]byte) error, verifyHandler func(usage uint32) bool) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSyncRead)); err != nil {
 			return err
 		}
@@ -178,8 +165,8 @@ func (d *dataNode) SyncRead(snapshotTime *time.Time, sha512Hex string, drop bool
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("data node refused the sync read request")
+		if !conn.Result() {
+			return conn.Error("data node refused the sync read request")
 		}
 
 		var blockSize uint32
@@ -187,10 +174,11 @@ func (d *dataNode) SyncRead(snapshotTime *time.Time, sha512Hex string, drop bool
 			return err
 		}
 
-		var usage uint16
-		if err := binary.Read(conn, binary.LittleEndian, &usage); err != nil {
+		usageBuffer := make([]byte, protocol.UsageSize(conn.Peer()))
+		if _, err := io.ReadFull(conn, usageBuffer); err != nil {
 			return err
 		}
+		usage := protocol.DecodeUsage(conn.Peer(), usageBuffer)
 
 		if _, err := conn.Write([]byte{'+'}); err != nil {
 			return err
@@ -219,8 +207,8 @@ func (d *dataNode) SyncRead(snapshotTime *time.Time, sha512Hex string, drop bool
 			}
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("sync read command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("sync read command is failed on data node")
 		}
 
 		if !verifyHandler(usage) {
@@ -1,24 +1,99 @@
 package common
 
 import (
-	"io/ioutil"
+	"io"
 	"os"
+	"path"
 )
 
-func Traverse(p string, fileHandler func(info os.FileInfo) error) error {
-	infos, err := ioutil.ReadDir(p)
-	if err != nil {
+const (
+	shardLevels       = 2
+	shardWidth        = 2
+	traverseBatchSize = 1024
+)
+
+// BlockPath returns the path of the block in the sharded layout. Blocks are placed in the sub folders named by the
+// first characters of the hash (root/ab/cd/abcd...) to keep the folders small
+func BlockPath(root string, sha512Hex string) string {
+	p := root
+	for i := 0; i < shardLevels; i++ {
+		p = path.Join(p, sha512Hex[i*shardWidth:(i+1)*shardWidth])
+	}
+	return path.Join(p, sha512Hex)
+}
+
+// FlatBlockPath returns the path of the block in the flat layout that is used before the sharded layout
+func FlatBlockPath(root string, sha512Hex string) string {
+	return path.Join(root, sha512Hex)
+}
+
+// Traverse streams the block files of the root in both flat and sharded layouts without loading the whole listing.
+// Flat files are traversed before the shards, so a block moved to its shard during the traversal is not missed but it
+// can be visited twice
+func Traverse(root string, fileHandler func(filePath string, info os.FileInfo) error) error {
+	if err := TraverseFlat(root, fileHandler); err != nil {
 		return err
 	}
+	return traverseShards(root, 0, fileHandler)
+}
 
-	for _, info := range infos {
+// TraverseFlat streams the block files of the root that are still in the flat layout
+func TraverseFlat(root string, fileHandler func(filePath string, info os.FileInfo) error) error {
+	return readDir(root, func(info os.FileInfo) error {
 		if info.IsDir() || len(info.Name()) != 64 {
-			continue
+			return nil
+		}
+		return fileHandler(path.Join(root, info.Name()), info)
+	})
+}
+
+func traverseShards(p string, level int, fileHandler func(filePath string, info os.FileInfo) error) error {
+	return readDir(p, func(info os.FileInfo) error {
+		if !info.IsDir() {
+			if level < shardLevels || len(info.Name()) != 64 {
+				return nil
+			}
+			return fileHandler(path.Join(p, info.Name()), info)
 		}
 
-		if err := fileHandler(info); err != nil {
+		if level >= shardLevels || !isShard(info.Name()) {
+			return nil
+		}
+		return traverseShards(path.Join(p, info.Name()), level+1, fileHandler)
+	})
+}
+
+func isShard(name string) bool {
+	if len(name) != shardWidth {
+		return false
+	}
+	for _, c := range name {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func readDir(p string, infoHandler func(info os.FileInfo) error) error {
+	dir, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dir.Close() }()
+
+	for {
+		infos, err := dir.Readdir(traverseBatchSize)
+		for _, info := range infos {
+			if err := infoHandler(info); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
 			return err
 		}
 	}
-	return nil
 }
@@ -0,0 +1,253 @@
+package block
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"path"
+	"sync"
+
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	PlacementHash  = "hash"
+	PlacementSpace = "space"
+)
+
+// Disk is the data path of the data node with its own capacity
+type Disk struct {
+	Path string
+	Size uint64
+}
+
+type DiskSet interface {
+	Manager
+
+	// Disks returns the disks that are in use
+	Disks() []Disk
+	// Fail drops the disk from the set and returns the blocks that can still be listed on it. The error is returned when
+	// the blocks can not be listed completely, a dead or unmounted disk can not be listed at all
+	Fail(dataPath string) ([]string, error)
+}
+
+type diskSetMember struct {
+	disk    Disk
+	manager *manager
+}
+
+type diskSet struct {
+	placement string
+	logger    *zap.Logger
+
+	locks *lockTable
+
+	mutex   sync.RWMutex
+	members []*diskSetMember
+}
+
+// NewDiskSet creates the block manager that places the blocks across the disks. hash placement selects the disk by the
+// block hash weighted with the disk sizes and space placement selects the disk that has the most free space. Blocks are
+// looked up on the disk of the hash placement first and the other disks are probed when it is not there
+func NewDiskSet(disks []Disk, placement string, packLimit uint32, logger *zap.Logger) (DiskSet, error) {
+	switch placement {
+	case PlacementHash, PlacementSpace:
+	default:
+		return nil, fmt.Errorf("placement should be %s or %s", PlacementHash, PlacementSpace)
+	}
+
+	if len(disks) == 0 {
+		return nil, fmt.Errorf("at least one disk should be defined")
+	}
+
+	members := make([]*diskSetMember, 0, len(disks))
+	for _, disk := range disks {
+		m, err := NewManager(disk.Path, packLimit, logger)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, &diskSetMember{
+			disk:    Disk{Path: path.Clean(disk.Path), Size: disk.Size},
+			manager: m.(*manager),
+		})
+	}
+
+	return &diskSet{
+		placement: placement,
+		logger:    logger,
+		locks:     newLockTable(),
+		mutex:     sync.RWMutex{},
+		members:   members,
+	}, nil
+}
+
+func (d *diskSet) Wait() {
+	d.locks.wait()
+
+	d.mutex.RLock()
+	members := d.members
+	d.mutex.RUnlock()
+
+	for _, member := range members {
+		member.manager.Wait()
+	}
+}
+
+func (d *diskSet) File(sha512Hex string, fileHandler func(file File) error) error {
+	member := d.member(sha512Hex)
+	if member == nil {
+		return errors.ErrNoDiskSpace
+	}
+	return member.manager.File(sha512Hex, fileHandler)
+}
+
+func (d *diskSet) LockFile(sha512Hex string, fileHandler func(file File) error) error {
+	d.locks.lock(sha512Hex)
+	defer d.locks.unlock(sha512Hex)
+
+	member := d.member(sha512Hex)
+	if member == nil {
+		return errors.ErrNoDiskSpace
+	}
+
+	return member.manager.LockFile(sha512Hex, fileHandler)
+}
+
+// member returns the disk that the block is placed or the disk that the block should be placed. The disk of the hash
+// placement is probed first, blocks are found there unless they are placed by the space placement or the disk set is
+// changed after the placement
+func (d *diskSet) member(sha512Hex string) *diskSetMember {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	hashed := d.hashUnsafe(sha512Hex)
+	if hashed == nil {
+		return nil
+	}
+	if hashed.manager.exists(sha512Hex) {
+		return hashed
+	}
+
+	for _, member := range d.members {
+		if member != hashed && member.manager.exists(sha512Hex) {
+			return member
+		}
+	}
+
+	if d.placement == PlacementSpace {
+		return d.mostFreeUnsafe()
+	}
+	return hashed
+}
+
+// hashUnsafe selects the disk with the weighted rendezvous hashing, so only the blocks of a failed disk are placed
+// somewhere else and the other blocks keep their disks
+func (d *diskSet) hashUnsafe(sha512Hex string) *diskSetMember {
+	var selected *diskSetMember
+	selectedScore := 0.0
+
+	for _, member := range d.members {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(member.disk.Path))
+		_, _ = h.Write([]byte(sha512Hex))
+
+		u := (float64(h.Sum64()>>11) + 0.5) / (1 << 53)
+		score := float64(member.disk.Size) / -math.Log(u)
+
+		if selected == nil || score > selectedScore {
+			selected = member
+			selectedScore = score
+		}
+	}
+
+	return selected
+}
+
+func (d *diskSet) mostFreeUnsafe() *diskSetMember {
+	var selected *diskSetMember
+	selectedFree := uint64(0)
+
+	for _, member := range d.members {
+		free := uint64(0)
+		if used := Used(member.disk.Path); member.disk.Size > used {
+			free = member.disk.Size - used
+		}
+
+		if selected == nil || free > selectedFree {
+			selected = member
+			selectedFree = free
+		}
+	}
+
+	return selected
+}
+
+func (d *diskSet) Traverse(hexHandler func(sha512Hex string) error) error {
+	d.mutex.RLock()
+	members := d.members
+	d.mutex.RUnlock()
+
+	for _, member := range members {
+		if err := member.manager.Traverse(hexHandler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *diskSet) Wipe() error {
+	d.mutex.RLock()
+	members := d.members
+	d.mutex.RUnlock()
+
+	for _, member := range members {
+		if err := member.manager.Wipe(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *diskSet) Disks() []Disk {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	disks := make([]Disk, 0, len(d.members))
+	for _, member := range d.members {
+		disks = append(disks, member.disk)
+	}
+	return disks
+}
+
+func (d *diskSet) Fail(dataPath string) ([]string, error) {
+	d.mutex.Lock()
+
+	dataPath = path.Clean(dataPath)
+
+	var failed *diskSetMember
+	for i, member := range d.members {
+		if member.disk.Path != dataPath {
+			continue
+		}
+
+		d.members = append(append(make([]*diskSetMember, 0, len(d.members)-1), d.members[:i]...), d.members[i+1:]...)
+
+		failed = member
+		break
+	}
+	d.mutex.Unlock()
+
+	if failed == nil {
+		return nil, nil
+	}
+
+	sha512HexList := make([]string, 0)
+	err := failed.manager.Traverse(func(sha512Hex string) error {
+		sha512HexList = append(sha512HexList, sha512Hex)
+		return nil
+	})
+	return sha512HexList, err
+}
+
+var _ DiskSet = &diskSet{}
@@ -0,0 +1,129 @@
+package block
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newDiskSet(t *testing.T, count int, placement string) (DiskSet, []Disk) {
+	disks := make([]Disk, 0, count)
+	for i := 0; i < count; i++ {
+		dataPath, err := ioutil.TempDir("", "kertish-disk")
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		t.Cleanup(func() {
+			ReleasePack(dataPath)
+			_ = os.RemoveAll(dataPath)
+		})
+		disks = append(disks, Disk{Path: dataPath, Size: 1024 * 1024})
+	}
+
+	d, err := NewDiskSet(disks, placement, 0, zap.NewNop())
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return d, disks
+}
+
+func diskBlocks(t *testing.T, disk Disk) []string {
+	m, err := NewManager(disk.Path, 0, zap.NewNop())
+	assert.Nil(t, err)
+
+	blocks := make([]string, 0)
+	assert.Nil(t, m.Traverse(func(sha512Hex string) error {
+		blocks = append(blocks, sha512Hex)
+		return nil
+	}))
+	return blocks
+}
+
+func TestDiskSet_Hash(t *testing.T) {
+	d, disks := newDiskSet(t, 2, PlacementHash)
+
+	contents := make(map[string][]byte)
+	for i := 0; i < 20; i++ {
+		content := randomContent(128)
+		contents[writeBlock(t, d, content)] = content
+	}
+
+	first := diskBlocks(t, disks[0])
+	second := diskBlocks(t, disks[1])
+	assert.NotEmpty(t, first)
+	assert.NotEmpty(t, second)
+	assert.Len(t, append(first, second...), len(contents))
+
+	for sha512Hex, content := range contents {
+		assert.Equal(t, content, readBlock(t, d, sha512Hex))
+	}
+
+	// locations are found again from the disks
+	d, err := NewDiskSet(disks, PlacementSpace, 0, zap.NewNop())
+	assert.Nil(t, err)
+	for sha512Hex, content := range contents {
+		assert.Equal(t, content, readBlock(t, d, sha512Hex))
+	}
+
+	failed, err := d.Fail(disks[1].Path)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, second, failed)
+	assert.Len(t, d.Disks(), 1)
+
+	for _, sha512Hex := range second {
+		assert.Nil(t, d.File(sha512Hex, func(file File) error {
+			assert.True(t, file.Temporary())
+			return nil
+		}))
+	}
+	for _, sha512Hex := range first {
+		assert.Equal(t, contents[sha512Hex], readBlock(t, d, sha512Hex))
+	}
+}
+
+func TestDiskSet_Space(t *testing.T) {
+	d, disks := newDiskSet(t, 2, PlacementSpace)
+	SetUsed(disks[0].Path, 1024)
+	SetUsed(disks[1].Path, 0)
+
+	sha512Hex := writeBlock(t, d, randomContent(256))
+	assert.Equal(t, []string{sha512Hex}, diskBlocks(t, disks[1]))
+	assert.Empty(t, diskBlocks(t, disks[0]))
+	assert.Equal(t, uint64(256+headerSize), Used(disks[1].Path))
+
+	// blocks out of the hash placement are found by probing the disks
+	for _, placement := range []string{PlacementHash, PlacementSpace} {
+		probed, err := NewDiskSet(disks, placement, 0, zap.NewNop())
+		assert.Nil(t, err)
+		assert.Nil(t, probed.File(sha512Hex, func(file File) error {
+			assert.False(t, file.Temporary())
+			return nil
+		}))
+	}
+
+	assert.Nil(t, d.LockFile(sha512Hex, func(file File) error {
+		return file.Wipe()
+	}))
+	assert.Equal(t, uint64(0), Used(disks[1].Path))
+	assert.Empty(t, diskBlocks(t, disks[1]))
+}
+
+func TestDiskSet_FailRemoved(t *testing.T) {
+	d, disks := newDiskSet(t, 2, PlacementHash)
+
+	for i := 0; i < 20; i++ {
+		writeBlock(t, d, randomContent(128))
+	}
+	assert.NotEmpty(t, diskBlocks(t, disks[1]))
+
+	// removed disk can not be listed, the failure is reported instead of an empty block list
+	assert.Nil(t, os.RemoveAll(disks[1].Path))
+
+	failed, err := d.Fail(disks[1].Path)
+	assert.NotNil(t, err)
+	assert.Empty(t, failed)
+	assert.Len(t, d.Disks(), 1)
+}
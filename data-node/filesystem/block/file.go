@@ -4,17 +4,22 @@ import (
 	"crypto/sha512"
 	"encoding/hex"
 	"hash"
+	"hash/crc32"
 	"io"
 	"os"
 	"path"
 	"strings"
 
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/data-node/common"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 const chunkSize uint32 = 1024 * 1024 // 1mb
 
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
 type File interface {
 	Temporary() bool
 
@@ -22,13 +27,13 @@ type File interface {
 	Verify() bool
 	VerifyForce() bool
 
-	Seek(offset int64) error
+	SeekData(offset int64) error
 	Read(readHandler func(data []byte) error, completedHandler func() error) error
 
 	Id() string
-	Usage() uint16
+	Usage() uint32
 	IncreaseUsage() error
-	ResetUsage(uint16) error
+	ResetUsage(uint32) error
 	Size() (uint32, error)
 
 	Delete() error
@@ -44,6 +49,8 @@ type file struct {
 	header *FileHeader
 
 	sha512   hash.Hash
+	checksum hash.Hash32
+	written  bool
 	tempPath string
 	verified bool
 	canceled bool
@@ -51,19 +58,29 @@ type file struct {
 	sha512Hex  string
 	targetPath string
 	logger     *zap.Logger
+
+	pack      *pack
+	packLimit uint32
 }
 
 func NewFile(root string, sha512Hex string, logger *zap.Logger) (File, error) {
+	return newFile(root, sha512Hex, nil, 0, logger)
+}
+
+func newFile(root string, sha512Hex string, pack *pack, packLimit uint32, logger *zap.Logger) (File, error) {
 	file := &file{
 		sha512:     sha512.New512_256(),
+		checksum:   crc32.New(checksumTable),
 		sha512Hex:  sha512Hex,
-		targetPath: path.Join(root, sha512Hex),
+		targetPath: common.BlockPath(root, sha512Hex),
 		verified:   true,
 		canceled:   false,
 		logger:     logger,
+		pack:       pack,
+		packLimit:  packLimit,
 	}
 
-	f, err := os.OpenFile(file.targetPath, os.O_RDWR, 0666)
+	f, err := file.open(root)
 	if err != nil {
 		if !os.IsNotExist(err) {
 			return nil, err
@@ -86,6 +103,23 @@ func NewFile(root string, sha512Hex string, logger *zap.Logger) (File, error) {
 	return file, nil
 }
 
+// open opens the block in the sharded layout or in the flat layout if it is not migrated yet
+func (f *file) open(root string) (*os.File, error) {
+	inner, err := os.OpenFile(f.targetPath, os.O_RDWR, 0666)
+	if err == nil || !os.IsNotExist(err) {
+		return inner, err
+	}
+
+	flatPath := common.FlatBlockPath(root, f.sha512Hex)
+	inner, err = os.OpenFile(flatPath, os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+	f.targetPath = flatPath
+
+	return inner, nil
+}
+
 func (f *file) Temporary() bool {
 	return len(f.tempPath) > 0
 }
@@ -94,6 +128,9 @@ func (f *file) Write(data []byte) error {
 	if _, err := f.sha512.Write(data); err != nil {
 		return err
 	}
+	_, _ = f.checksum.Write(data)
+	f.written = true
+
 	_, err := f.inner.Write(data)
 	return err
 }
@@ -126,22 +163,44 @@ func (f *file) VerifyForce() bool {
 	return f.verified
 }
 
-func (f *file) Seek(offset int64) error {
+func (f *file) SeekData(offset int64) error {
 	_, err := f.inner.Seek(f.header.Size()+offset, io.SeekStart)
 	return err
 }
 
 func (f *file) Read(readHandler func(data []byte) error, completedHandler func() error) error {
+	if !f.Temporary() {
+		if err := f.header.Access(); err != nil {
+			f.logger.Warn("Updating block access time is failed", zap.String("sha512Hex", f.sha512Hex), zap.Error(err))
+		}
+	}
+
+	// checksum is verified when the whole block is read, legacy header does not keep the checksum
+	var checksum hash.Hash32
+	if !f.Temporary() && f.header.Checksum() != 0 {
+		if position, err := f.inner.Seek(0, io.SeekCurrent); err == nil && position == f.header.Size() {
+			checksum = crc32.New(checksumTable)
+		}
+	}
+
 	buffer := make([]byte, chunkSize)
 	for {
 		s, err := f.inner.Read(buffer)
 		if err != nil {
 			if err == io.EOF {
+				if checksum != nil && checksum.Sum32() != f.header.Checksum() {
+					checksumFailuresTotal.Inc()
+					f.logger.Error("Block checksum does not match", zap.String("sha512Hex", f.sha512Hex))
+					return errors.ErrVerify
+				}
 				return completedHandler()
 			}
 			return err
 		}
 
+		if checksum != nil {
+			_, _ = checksum.Write(buffer[0:s])
+		}
 		if err := readHandler(buffer[0:s]); err != nil {
 			return err
 		}
@@ -152,18 +211,56 @@ func (f *file) Id() string {
 	return f.sha512Hex
 }
 
-func (f *file) Usage() uint16 {
+func (f *file) Usage() uint32 {
 	return f.header.Usage()
 }
 
 func (f *file) IncreaseUsage() error {
+	if err := f.fit(f.header.Usage() + 1); err != nil {
+		return err
+	}
 	return f.header.IncreaseUsage()
 }
 
-func (f *file) ResetUsage(usage uint16) error {
+func (f *file) ResetUsage(usage uint32) error {
+	if err := f.fit(usage); err != nil {
+		return err
+	}
 	return f.header.ResetUsage(usage)
 }
 
+// fit upgrades the legacy header of the block file when the usage does not fit to it
+func (f *file) fit(usage uint32) error {
+	if f.Temporary() || f.header.Fits(usage) {
+		return nil
+	}
+
+	position, err := f.inner.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	position -= f.header.Size()
+
+	if err := upgradeFile(f.targetPath); err != nil {
+		return err
+	}
+
+	inner, err := os.OpenFile(f.targetPath, os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	_ = f.inner.Close()
+
+	f.inner = inner
+	f.header = NewFileHeader(inner)
+	if err := f.header.Load(); err != nil {
+		return err
+	}
+
+	_, err = f.inner.Seek(f.header.Size()+position, io.SeekStart)
+	return err
+}
+
 func (f *file) Size() (uint32, error) {
 	info, err := f.inner.Stat()
 	if err != nil {
@@ -186,14 +283,25 @@ func (f *file) Delete() error {
 }
 
 func (f *file) Wipe() error {
+	release(f.targetPath)
 	return os.Remove(f.targetPath)
 }
 
+// Truncate prepares the block file to be written again from the beginning
 func (f *file) Truncate(blockSize uint32) error {
 	if err := os.Truncate(f.targetPath, int64(blockSize)+f.header.Size()); err != nil {
 		return err
 	}
-	return f.ResetUsage(1)
+	if err := f.ResetUsage(1); err != nil {
+		return err
+	}
+
+	f.sha512.Reset()
+	f.checksum.Reset()
+	f.verified = false
+
+	_, err := f.inner.Seek(f.header.Size(), io.SeekStart)
+	return err
 }
 
 func (f *file) Cancel() {
@@ -201,6 +309,13 @@ func (f *file) Cancel() {
 }
 
 func (f *file) Close() {
+	if f.written && f.verified && !f.canceled {
+		if err := f.header.SetChecksum(f.checksum.Sum32()); err != nil {
+			f.logger.Warn("Block checksum can not be saved", zap.String("sha512Hex", f.sha512Hex), zap.Error(err))
+		}
+	}
+
+	packable := f.packable()
 	_ = f.inner.Close()
 
 	if !f.verified || f.canceled {
@@ -212,9 +327,36 @@ func (f *file) Close() {
 		return
 	}
 
+	if packable {
+		defer func() { _ = os.Remove(f.tempPath) }()
+
+		if err := f.pack.append(f.sha512Hex, f.tempPath, f.header.Size(), f.Usage()); err != nil {
+			f.logger.Error("File packing is failed silently", zap.Error(err))
+		}
+		return
+	}
+
 	if err := f.move(f.tempPath, f.targetPath); err != nil {
 		f.logger.Error("File creation is failed silently", zap.Error(err))
+		return
+	}
+
+	if info, err := os.Stat(f.targetPath); err == nil {
+		account(path.Dir(f.targetPath), info.Size())
+	}
+}
+
+// packable checks if the temporary file should be appended to the pack instead of placing as a block file
+func (f *file) packable() bool {
+	if f.pack == nil || !f.Temporary() {
+		return false
 	}
+
+	size, err := f.Size()
+	if err != nil {
+		return false
+	}
+	return size <= f.packLimit
 }
 
 func (f *file) move(source string, target string) error {
@@ -226,6 +368,10 @@ func (f *file) move(source string, target string) error {
 	}
 	defer func() { _ = sourceFile.Close() }()
 
+	if err := os.MkdirAll(path.Dir(target), 0777); err != nil {
+		return err
+	}
+
 	targetFile, err := os.OpenFile(target, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		return err
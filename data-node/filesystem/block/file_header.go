@@ -2,47 +2,99 @@ package block
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
+	"math"
 	"os"
+	"time"
 )
 
-const headerSize int64 = 2
+const (
+	legacyHeaderSize int64 = 2
+	headerSize       int64 = 64
+	headerVersion    uint8 = 2
 
+	// accessInterval limits the header writes of the reads, access time is updated once in the interval
+	accessInterval = time.Hour
+)
+
+// FileHeader is the header of the block file. Version 2 header starts with 0 usage that the legacy (2 bytes usage)
+// header never has, so both versions are read from the same place
+//
+// marker (2) + version (1) + reserved (1) + usage (4) + created (8) + accessed (8) + checksum (4) + reserved (36)
 type FileHeader struct {
 	inner *os.File
 
-	usage uint16 // 2 bytes
+	version  uint8
+	usage    uint32
+	created  int64
+	accessed int64
+	checksum uint32
 }
 
 func NewFileHeader(file *os.File) *FileHeader {
+	now := time.Now().UTC().UnixNano()
+
 	return &FileHeader{
-		inner: file,
-		usage: 1,
+		inner:    file,
+		version:  headerVersion,
+		usage:    1,
+		created:  now,
+		accessed: now,
 	}
 }
 
 func (h *FileHeader) Size() int64 {
+	if h.Legacy() {
+		return legacyHeaderSize
+	}
 	return headerSize
 }
 
+// Legacy returns true if the header is in the 2 bytes usage format
+func (h *FileHeader) Legacy() bool {
+	return h.version < headerVersion
+}
+
+// Load reads the header and places the file cursor to the beginning of the block data
 func (h *FileHeader) Load() error {
-	if _, err := h.inner.Seek(0, io.SeekStart); err != nil {
+	b := make([]byte, headerSize)
+
+	n, err := h.inner.ReadAt(b, 0)
+	if err != nil && err != io.EOF {
 		return err
 	}
 
-	var usage uint16
-	if err := binary.Read(h.inner, binary.LittleEndian, &usage); err != nil {
-		if err == io.EOF {
-			return h.save()
+	switch {
+	case n == 0:
+		if err := h.save(); err != nil {
+			return err
 		}
-		return err
+	case n < int(legacyHeaderSize):
+		return io.ErrUnexpectedEOF
+	case binary.LittleEndian.Uint16(b) != 0:
+		h.version = 1
+		h.usage = uint32(binary.LittleEndian.Uint16(b))
+		h.created = 0
+		h.accessed = 0
+	case n < int(headerSize):
+		return io.ErrUnexpectedEOF
+	default:
+		h.version = b[2]
+		if h.version != headerVersion {
+			return fmt.Errorf("block header version %d is not supported", h.version)
+		}
+		h.usage = binary.LittleEndian.Uint32(b[4:])
+		h.created = int64(binary.LittleEndian.Uint64(b[8:]))
+		h.accessed = int64(binary.LittleEndian.Uint64(b[16:]))
+		h.checksum = binary.LittleEndian.Uint32(b[24:])
 	}
-	h.usage = usage
 
-	return nil
+	_, err = h.inner.Seek(h.Size(), io.SeekStart)
+	return err
 }
 
-func (h *FileHeader) Usage() uint16 {
+func (h *FileHeader) Usage() uint32 {
 	return h.usage
 }
 
@@ -60,7 +112,7 @@ func (h *FileHeader) DecreaseUsage() error {
 	return h.save()
 }
 
-func (h *FileHeader) ResetUsage(usage uint16) error {
+func (h *FileHeader) ResetUsage(usage uint32) error {
 	if usage < 1 {
 		usage = 1
 	}
@@ -69,14 +121,74 @@ func (h *FileHeader) ResetUsage(usage uint16) error {
 	return h.save()
 }
 
-func (h *FileHeader) save() error {
-	if _, err := h.inner.Seek(0, io.SeekStart); err != nil {
-		return err
+// Fits returns true if the usage can be kept in the header, legacy header should be upgraded for the larger usages
+func (h *FileHeader) Fits(usage uint32) bool {
+	return !h.Legacy() || usage <= math.MaxUint16
+}
+
+func (h *FileHeader) Created() time.Time {
+	return time.Unix(0, h.created).UTC()
+}
+
+func (h *FileHeader) Accessed() time.Time {
+	return time.Unix(0, h.accessed).UTC()
+}
+
+// Access updates the access time without changing the other fields, so the readers without the lock do not overwrite
+// the usage
+func (h *FileHeader) Access() error {
+	if h.Legacy() {
+		return nil
+	}
+
+	now := time.Now().UTC().UnixNano()
+	if time.Duration(now-h.accessed) < accessInterval {
+		return nil
+	}
+	h.accessed = now
+
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(h.accessed))
+	_, err := h.inner.WriteAt(b, 16)
+	return err
+}
+
+func (h *FileHeader) Checksum() uint32 {
+	return h.checksum
+}
+
+func (h *FileHeader) SetChecksum(checksum uint32) error {
+	if h.Legacy() {
+		return nil
 	}
 
-	if err := binary.Write(h.inner, binary.LittleEndian, h.usage); err != nil {
+	h.checksum = checksum
+	return h.save()
+}
+
+func (h *FileHeader) encode() []byte {
+	b := make([]byte, headerSize)
+	b[2] = h.version
+	binary.LittleEndian.PutUint32(b[4:], h.usage)
+	binary.LittleEndian.PutUint64(b[8:], uint64(h.created))
+	binary.LittleEndian.PutUint64(b[16:], uint64(h.accessed))
+	binary.LittleEndian.PutUint32(b[24:], h.checksum)
+	return b
+}
+
+// save writes the header without moving the file cursor
+func (h *FileHeader) save() error {
+	if h.Legacy() {
+		if h.usage > math.MaxUint16 {
+			return fmt.Errorf("block usage %d does not fit to the legacy header", h.usage)
+		}
+
+		b := make([]byte, legacyHeaderSize)
+		binary.LittleEndian.PutUint16(b, uint16(h.usage))
+		_, err := h.inner.WriteAt(b, 0)
 		return err
 	}
 
-	return nil
+	_, err := h.inner.WriteAt(h.encode(), 0)
+	return err
 }
@@ -0,0 +1,110 @@
+package block
+
+import "sync"
+
+// lockStripes is the number of the lock table parts, the blocks of the different stripes do not contend on the table
+const lockStripes = 64
+
+type blockLock struct {
+	mutex sync.Mutex
+	refs  int
+}
+
+type lockStripe struct {
+	mutex sync.Mutex
+	locks map[string]*blockLock
+}
+
+// lockTable keeps the locks of the blocks only while they are held or waited, so it does not grow with the number of
+// the blocks that are touched
+type lockTable struct {
+	stripes [lockStripes]lockStripe
+	pool    sync.Pool
+}
+
+func newLockTable() *lockTable {
+	l := &lockTable{
+		pool: sync.Pool{
+			New: func() interface{} { return &blockLock{} },
+		},
+	}
+	for i := range l.stripes {
+		l.stripes[i].locks = make(map[string]*blockLock)
+	}
+	return l
+}
+
+// stripe selects the stripe with the last byte of the hash, hashes are already uniform
+func (l *lockTable) stripe(sha512Hex string) *lockStripe {
+	h := uint32(0)
+	for i := len(sha512Hex) - 2; i < len(sha512Hex); i++ {
+		if i >= 0 {
+			h = h<<4 | hexValue(sha512Hex[i])
+		}
+	}
+	return &l.stripes[h%lockStripes]
+}
+
+func hexValue(c byte) uint32 {
+	switch {
+	case c >= '0' && c <= '9':
+		return uint32(c - '0')
+	case c >= 'a' && c <= 'f':
+		return uint32(c-'a') + 10
+	default:
+		return uint32(c)
+	}
+}
+
+func (l *lockTable) lock(sha512Hex string) {
+	s := l.stripe(sha512Hex)
+
+	s.mutex.Lock()
+	b, has := s.locks[sha512Hex]
+	if !has {
+		b = l.pool.Get().(*blockLock)
+		s.locks[sha512Hex] = b
+	}
+	b.refs++
+	s.mutex.Unlock()
+
+	b.mutex.Lock()
+}
+
+func (l *lockTable) unlock(sha512Hex string) {
+	s := l.stripe(sha512Hex)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	b := s.locks[sha512Hex]
+	b.mutex.Unlock()
+
+	b.refs--
+	if b.refs == 0 {
+		delete(s.locks, sha512Hex)
+		l.pool.Put(b)
+	}
+}
+
+// size returns the number of the locks that are held or waited
+func (l *lockTable) size() int {
+	total := 0
+	for i := range l.stripes {
+		s := &l.stripes[i]
+
+		s.mutex.Lock()
+		total += len(s.locks)
+		s.mutex.Unlock()
+	}
+	return total
+}
+
+func (l *lockTable) wait() {
+	for i := range l.stripes {
+		s := &l.stripes[i]
+
+		s.mutex.Lock()
+		s.mutex.Unlock()
+	}
+}
@@ -0,0 +1,140 @@
+package block
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockTable(t *testing.T) {
+	l := newLockTable()
+
+	counters := make(map[string]*int32)
+	for i := 0; i < 16; i++ {
+		counters[fmt.Sprintf("%064x", i)] = new(int32)
+	}
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 1000; j++ {
+				for sha512Hex, counter := range counters {
+					l.lock(sha512Hex)
+					assert.Equal(t, int32(1), atomic.AddInt32(counter, 1))
+					atomic.AddInt32(counter, -1)
+					l.unlock(sha512Hex)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 0, l.size())
+}
+
+func benchmarkHashes(count int) []string {
+	hashes := make([]string, count)
+	for i := range hashes {
+		sum := sha512.Sum512_256([]byte(fmt.Sprintf("block-%d", i)))
+		hashes[i] = hex.EncodeToString(sum[:])
+	}
+	return hashes
+}
+
+func BenchmarkLockTable(b *testing.B) {
+	l := newLockTable()
+	hashes := benchmarkHashes(4096)
+
+	var next uint32
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			sha512Hex := hashes[atomic.AddUint32(&next, 1)%uint32(len(hashes))]
+
+			l.lock(sha512Hex)
+			l.unlock(sha512Hex)
+		}
+	})
+}
+
+// BenchmarkManager_Parallel creates, reads and deletes the same set of blocks concurrently as CREA, READ and DELE do
+func BenchmarkManager_Parallel(b *testing.B) {
+	m, _ := newPackManager(b, 0)
+
+	contents := make(map[string][]byte)
+	for i := 0; i < 256; i++ {
+		content := randomContent(4096)
+		sum := sha512.Sum512_256(content)
+		contents[hex.EncodeToString(sum[:])] = content
+	}
+	hashes := make([]string, 0, len(contents))
+	for sha512Hex := range contents {
+		hashes = append(hashes, sha512Hex)
+	}
+
+	create := func(sha512Hex string) error {
+		return m.LockFile(sha512Hex, func(file File) error {
+			if !file.Temporary() {
+				return file.IncreaseUsage()
+			}
+			if err := file.Write(contents[sha512Hex]); err != nil {
+				return err
+			}
+			if !file.Verify() {
+				return os.ErrInvalid
+			}
+			return nil
+		})
+	}
+	read := func(sha512Hex string) error {
+		return m.File(sha512Hex, func(file File) error {
+			if file.Temporary() {
+				return nil
+			}
+			return file.Read(func(data []byte) error {
+				return nil
+			}, func() error {
+				return nil
+			})
+		})
+	}
+	remove := func(sha512Hex string) error {
+		return m.LockFile(sha512Hex, func(file File) error {
+			if file.Temporary() {
+				return nil
+			}
+			return file.Delete()
+		})
+	}
+
+	var next uint32
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := atomic.AddUint32(&next, 1)
+			sha512Hex := hashes[i%uint32(len(hashes))]
+
+			var err error
+			switch (i / uint32(len(hashes))) % 3 {
+			case 0:
+				err = create(sha512Hex)
+			case 1:
+				err = read(sha512Hex)
+			default:
+				err = remove(sha512Hex)
+			}
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -2,7 +2,6 @@ package block
 
 import (
 	"os"
-	"sync"
 
 	"github.com/freakmaxi/kertish-dfs/data-node/common"
 	"go.uber.org/zap"
@@ -20,25 +19,29 @@ type Manager interface {
 }
 
 type manager struct {
-	dataPath string
-	logger   *zap.Logger
+	dataPath  string
+	packLimit uint32
+	logger    *zap.Logger
 
-	blockLockMutex sync.Mutex
-	blockLock      map[string]*sync.Mutex
+	locks *lockTable
 }
 
-func NewManager(dataPath string, logger *zap.Logger) (Manager, error) {
+// NewManager creates the block manager on the data path. Blocks smaller than or equal to the pack limit are appended
+// to the pack segments instead of creating a file for each, 0 disables packing. Blocks of the flat layout are moved to
+// the sharded layout in the background
+func NewManager(dataPath string, packLimit uint32, logger *zap.Logger) (Manager, error) {
 	m := &manager{
-		dataPath: dataPath,
-		logger:   logger,
+		dataPath:  dataPath,
+		packLimit: packLimit,
+		logger:    logger,
 
-		blockLockMutex: sync.Mutex{},
-		blockLock:      make(map[string]*sync.Mutex),
+		locks: newLockTable(),
 	}
 
 	if err := m.prepare(); err != nil {
 		return nil, err
 	}
+	go m.migrate()
 
 	return m, nil
 }
@@ -54,32 +57,12 @@ func (m *manager) prepare() error {
 	return nil
 }
 
-func (m *manager) lock(sha512Hex string) {
-	m.blockLockMutex.Lock()
-	l, has := m.blockLock[sha512Hex]
-	if !has {
-		l = &sync.Mutex{}
-		m.blockLock[sha512Hex] = l
-	}
-	m.blockLockMutex.Unlock()
-
-	l.Lock()
-}
-
-func (m *manager) unlock(sha512Hex string) {
-	m.blockLockMutex.Lock()
-	defer m.blockLockMutex.Unlock()
-
-	m.blockLock[sha512Hex].Unlock()
-}
-
 func (m *manager) Wait() {
-	m.blockLockMutex.Lock()
-	defer m.blockLockMutex.Unlock()
+	m.locks.wait()
 }
 
 func (m *manager) File(sha512Hex string, fileHandler func(file File) error) error {
-	file, err := NewFile(m.dataPath, sha512Hex, m.logger)
+	file, err := m.open(sha512Hex)
 	if err != nil {
 		return err
 	}
@@ -88,27 +71,63 @@ func (m *manager) File(sha512Hex string, fileHandler func(file File) error) erro
 	return fileHandler(file)
 }
 
+func (m *manager) open(sha512Hex string) (File, error) {
+	p, err := openPack(m.dataPath, m.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := p.file(sha512Hex, m.logger)
+	if err != nil || file != nil {
+		return file, err
+	}
+
+	if m.packLimit == 0 {
+		p = nil
+	}
+	return newFile(m.dataPath, sha512Hex, p, m.packLimit, m.logger)
+}
+
+// exists checks if the block is placed in the data path without opening it
+func (m *manager) exists(sha512Hex string) bool {
+	p, err := openPack(m.dataPath, m.logger)
+	if err == nil && p.has(sha512Hex) {
+		return true
+	}
+
+	if _, err := os.Stat(common.BlockPath(m.dataPath, sha512Hex)); err == nil {
+		return true
+	}
+	_, err = os.Stat(common.FlatBlockPath(m.dataPath, sha512Hex))
+	return err == nil
+}
+
 func (m *manager) LockFile(sha512Hex string, fileHandler func(file File) error) error {
-	m.lock(sha512Hex)
-	defer m.unlock(sha512Hex)
+	m.locks.lock(sha512Hex)
+	defer m.locks.unlock(sha512Hex)
 
 	return m.File(sha512Hex, fileHandler)
 }
 
 func (m *manager) Traverse(hexHandler func(sha512Hex string) error) error {
-	return common.Traverse(m.dataPath, func(info os.FileInfo) error {
+	if err := common.Traverse(m.dataPath, func(_ string, info os.FileInfo) error {
 		return hexHandler(info.Name())
-	})
+	}); err != nil {
+		return err
+	}
+
+	p, err := openPack(m.dataPath, m.logger)
+	if err != nil {
+		return err
+	}
+	return p.traverse(hexHandler)
 }
 
 func (m *manager) Wipe() error {
-	m.blockLockMutex.Lock()
-	defer m.blockLockMutex.Unlock()
-
 	sha512HexList := make([]string, 0)
 
-	if err := common.Traverse(m.dataPath, func(info os.FileInfo) error {
-		sha512HexList = append(sha512HexList, info.Name())
+	if err := m.Traverse(func(sha512Hex string) error {
+		sha512HexList = append(sha512HexList, sha512Hex)
 		return nil
 	}); err != nil {
 		return err
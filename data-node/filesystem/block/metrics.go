@@ -0,0 +1,27 @@
+package block
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	packCompactions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "pack_compactions_total",
+		Help:      "Number of compacted pack segments",
+	})
+	packReclaimedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "pack_reclaimed_bytes_total",
+		Help:      "Size of the deleted blocks reclaimed by the pack compaction",
+	})
+	checksumFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "checksum_failures_total",
+		Help:      "Number of the block reads that do not match the checksum in the block header",
+	})
+)
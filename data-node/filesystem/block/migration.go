@@ -0,0 +1,255 @@
+package block
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/freakmaxi/kertish-dfs/data-node/common"
+	"go.uber.org/zap"
+)
+
+// headerMarkerFile is created when the legacy block headers of the data path are upgraded
+const headerMarkerFile = ".headers"
+
+var migrationsMutex sync.Mutex
+var migrations = make(map[string]bool)
+
+// migrate moves the blocks of the flat layout to the sharded layout and upgrades the legacy block headers in the
+// background. Blocks are changed under their locks one by one, so the data path is served during the migration
+func (m *manager) migrate() {
+	dataPath := path.Clean(m.dataPath)
+
+	migrationsMutex.Lock()
+	if migrations[dataPath] {
+		migrationsMutex.Unlock()
+		return
+	}
+	migrations[dataPath] = true
+	migrationsMutex.Unlock()
+
+	defer func() {
+		migrationsMutex.Lock()
+		delete(migrations, dataPath)
+		migrationsMutex.Unlock()
+	}()
+
+	m.migrateLayout(dataPath)
+	m.upgradeHeaders(dataPath)
+}
+
+func (m *manager) migrateLayout(dataPath string) {
+	total := 0
+	for {
+		migrated := 0
+		if err := common.TraverseFlat(dataPath, func(filePath string, info os.FileInfo) error {
+			if total == 0 && migrated == 0 {
+				m.logger.Info("Block layout migration is started", zap.String("dataPath", dataPath))
+			}
+
+			if err := m.migrateFile(info.Name()); err != nil {
+				return err
+			}
+			migrated++
+
+			return nil
+		}); err != nil {
+			if !os.IsNotExist(err) {
+				m.logger.Error("Block layout migration is failed", zap.String("dataPath", dataPath), zap.Error(err))
+			}
+			return
+		}
+
+		if migrated == 0 {
+			break
+		}
+		total += migrated
+	}
+
+	if total > 0 {
+		m.logger.Info(
+			fmt.Sprintf("Block layout migration is completed, %d blocks are moved", total),
+			zap.String("dataPath", dataPath),
+		)
+	}
+}
+
+func (m *manager) migrateFile(sha512Hex string) error {
+	m.locks.lock(sha512Hex)
+	defer m.locks.unlock(sha512Hex)
+
+	flatPath := common.FlatBlockPath(m.dataPath, sha512Hex)
+	blockPath := common.BlockPath(m.dataPath, sha512Hex)
+
+	if _, err := os.Stat(flatPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if err := os.MkdirAll(path.Dir(blockPath), 0777); err != nil {
+		return err
+	}
+
+	// the sharded block is the latest one when the block is placed in both layouts
+	if _, err := os.Stat(blockPath); err == nil {
+		if err := os.Remove(flatPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.Rename(flatPath, blockPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// upgradeHeaders rewrites the block files that have the legacy header. Block files shared with the snapshots are
+// skipped to keep sharing the disk space, they are upgraded when their usage does not fit to the legacy header
+func (m *manager) upgradeHeaders(dataPath string) {
+	markerPath := path.Join(dataPath, headerMarkerFile)
+	if _, err := os.Stat(markerPath); err == nil {
+		return
+	}
+
+	upgraded := 0
+	if err := common.Traverse(dataPath, func(filePath string, info os.FileInfo) error {
+		if linked(info) {
+			return nil
+		}
+
+		legacy, err := legacyFile(filePath)
+		if err != nil || !legacy {
+			return nil
+		}
+
+		if err := m.upgradeFile(info.Name(), filePath); err != nil {
+			return err
+		}
+		upgraded++
+
+		return nil
+	}); err != nil {
+		if !os.IsNotExist(err) {
+			m.logger.Error("Block header upgrade is failed", zap.String("dataPath", dataPath), zap.Error(err))
+		}
+		return
+	}
+
+	if upgraded > 0 {
+		m.logger.Info(
+			fmt.Sprintf("Block header upgrade is completed, %d blocks are upgraded", upgraded),
+			zap.String("dataPath", dataPath),
+		)
+	}
+
+	if err := ioutil.WriteFile(markerPath, []byte{headerVersion}, 0666); err != nil {
+		m.logger.Warn("Block header upgrade marker can not be created", zap.String("dataPath", dataPath), zap.Error(err))
+	}
+}
+
+func (m *manager) upgradeFile(sha512Hex string, filePath string) error {
+	m.locks.lock(sha512Hex)
+	defer m.locks.unlock(sha512Hex)
+
+	if err := upgradeFile(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func legacyFile(filePath string) (bool, error) {
+	f, err := os.OpenFile(filePath, os.O_RDONLY, 0666)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+
+	b := make([]byte, legacyHeaderSize)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return false, err
+	}
+	return binary.LittleEndian.Uint16(b) != 0, nil
+}
+
+// upgradeFile rewrites the block file with the current header. The creation time of the legacy block is not known, the
+// modification time of the file is used instead
+func upgradeFile(filePath string) error {
+	source, err := os.OpenFile(filePath, os.O_RDONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = source.Close() }()
+
+	legacy := NewFileHeader(source)
+	if err := legacy.Load(); err != nil {
+		return err
+	}
+	if !legacy.Legacy() {
+		return nil
+	}
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	tempPath := path.Join(path.Dir(filePath), packTempPrefix+path.Base(filePath))
+	target, err := os.OpenFile(tempPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+
+	if err := func() error {
+		defer func() { _ = target.Close() }()
+
+		header := NewFileHeader(target)
+		header.usage = legacy.Usage()
+		header.created = info.ModTime().UTC().UnixNano()
+
+		if _, err := target.Seek(headerSize, io.SeekStart); err != nil {
+			return err
+		}
+
+		checksum := crc32.New(checksumTable)
+		if _, err := io.Copy(io.MultiWriter(target, checksum), source); err != nil {
+			return err
+		}
+		header.checksum = checksum.Sum32()
+
+		if err := header.save(); err != nil {
+			return err
+		}
+		return target.Sync()
+	}(); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	upgradedInfo, err := os.Stat(tempPath)
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	if err := os.Rename(tempPath, filePath); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	// the space of the legacy file is still used if it is shared with a snapshot
+	delta := upgradedInfo.Size()
+	if !linked(info) {
+		delta -= info.Size()
+	}
+	account(path.Dir(filePath), delta)
+
+	return nil
+}
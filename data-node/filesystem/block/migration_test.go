@@ -0,0 +1,166 @@
+package block
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/data-node/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestManager_Migrate(t *testing.T) {
+	m, dataPath := newPackManager(t, 0)
+
+	contents := make(map[string][]byte)
+	for i := 0; i < 10; i++ {
+		content := randomContent(128)
+		sha512Hex := writeBlock(t, m, content)
+		contents[sha512Hex] = content
+
+		// move back to the flat layout
+		assert.Nil(t, os.Rename(common.BlockPath(dataPath, sha512Hex), common.FlatBlockPath(dataPath, sha512Hex)))
+	}
+
+	for sha512Hex, content := range contents {
+		assert.Equal(t, content, readBlock(t, m, sha512Hex))
+	}
+
+	m, err := NewManager(dataPath, 0, zap.NewNop())
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		for sha512Hex := range contents {
+			if _, err := os.Stat(common.BlockPath(dataPath, sha512Hex)); err != nil {
+				return false
+			}
+		}
+		return true
+	}, time.Second*5, time.Millisecond*10)
+
+	blocks := make([]string, 0)
+	assert.Nil(t, common.Traverse(dataPath, func(filePath string, info os.FileInfo) error {
+		assert.Equal(t, common.BlockPath(dataPath, info.Name()), filePath)
+		blocks = append(blocks, info.Name())
+		return nil
+	}))
+	assert.Len(t, blocks, len(contents))
+
+	for sha512Hex, content := range contents {
+		assert.Equal(t, content, readBlock(t, m, sha512Hex))
+	}
+}
+
+func writeLegacyBlock(t *testing.T, dataPath string, content []byte, usage uint16) string {
+	sum := sha512.Sum512_256(content)
+	sha512Hex := hex.EncodeToString(sum[:])
+
+	legacy := make([]byte, 2)
+	binary.LittleEndian.PutUint16(legacy, usage)
+
+	blockPath := common.BlockPath(dataPath, sha512Hex)
+	assert.Nil(t, os.MkdirAll(path.Dir(blockPath), 0777))
+	assert.Nil(t, ioutil.WriteFile(blockPath, append(legacy, content...), 0666))
+
+	return sha512Hex
+}
+
+func TestFileHeader_Legacy(t *testing.T) {
+	m, dataPath := newPackManager(t, 0)
+
+	content := randomContent(256)
+	sha512Hex := writeLegacyBlock(t, dataPath, content, 3)
+
+	assert.Nil(t, m.LockFile(sha512Hex, func(file File) error {
+		assert.False(t, file.Temporary())
+		assert.Equal(t, uint32(3), file.Usage())
+
+		size, err := file.Size()
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(256), size)
+
+		// legacy header can not keep it, block file is upgraded
+		return file.ResetUsage(70000)
+	}))
+
+	legacy, err := legacyFile(common.BlockPath(dataPath, sha512Hex))
+	assert.Nil(t, err)
+	assert.False(t, legacy)
+
+	assert.Equal(t, content, readBlock(t, m, sha512Hex))
+	assert.Nil(t, m.File(sha512Hex, func(file File) error {
+		assert.Equal(t, uint32(70000), file.Usage())
+		assert.True(t, file.VerifyForce())
+		return nil
+	}))
+}
+
+func TestManager_UpgradeHeaders(t *testing.T) {
+	dataPath, err := ioutil.TempDir("", "kertish-header")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dataPath) })
+
+	content := randomContent(256)
+	sha512Hex := writeLegacyBlock(t, dataPath, content, 2)
+
+	m, err := NewManager(dataPath, 0, zap.NewNop())
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path.Join(dataPath, headerMarkerFile))
+		return err == nil
+	}, time.Second*5, time.Millisecond*10)
+
+	legacy, err := legacyFile(common.BlockPath(dataPath, sha512Hex))
+	assert.Nil(t, err)
+	assert.False(t, legacy)
+
+	assert.Equal(t, content, readBlock(t, m, sha512Hex))
+	assert.Nil(t, m.File(sha512Hex, func(f File) error {
+		assert.Equal(t, uint32(2), f.Usage())
+
+		header := f.(*file).header
+		assert.Equal(t, crc32.Checksum(content, checksumTable), header.Checksum())
+		assert.False(t, header.Created().IsZero())
+		return nil
+	}))
+}
+
+func TestFile_Checksum(t *testing.T) {
+	m, dataPath := newPackManager(t, 0)
+
+	content := randomContent(256)
+	sha512Hex := writeBlock(t, m, content)
+	assert.Equal(t, content, readBlock(t, m, sha512Hex))
+
+	f, err := os.OpenFile(common.BlockPath(dataPath, sha512Hex), os.O_RDWR, 0666)
+	assert.Nil(t, err)
+	_, err = f.WriteAt([]byte{^content[10]}, headerSize+10)
+	assert.Nil(t, err)
+	_ = f.Close()
+
+	assert.Nil(t, m.File(sha512Hex, func(file File) error {
+		err := file.Read(func(data []byte) error { return nil }, func() error { return nil })
+		assert.Equal(t, errors.ErrVerify, err)
+
+		// ranged reads do not cover the whole block to be verified
+		assert.Nil(t, file.SeekData(100))
+		assert.Nil(t, file.Read(func(data []byte) error {
+			assert.Equal(t, content[100:], data)
+			return nil
+		}, func() error { return nil }))
+
+		assert.False(t, file.VerifyForce())
+		return nil
+	}))
+}
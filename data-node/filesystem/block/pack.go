@@ -0,0 +1,737 @@
+package block
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const packFolder = "pack"
+const packJournalFile = "journal.v2"
+const packLegacyJournalFile = "journal"
+const packTempPrefix = ".tmp-"
+const packSegmentSize uint64 = 1024 * 1024 * 256 // 256mb
+const packCompactRatio = 0.5
+const packJournalSlack = 1024
+
+// sha512 (32) + segment (8) + offset (4) + length (4) + usage (4)
+const packRecordSize = 52
+
+// sha512 (32) + segment (8) + offset (4) + length (4) + usage (2)
+const packLegacyRecordSize = 50
+
+type packEntry struct {
+	segment uint64
+	offset  uint32
+	length  uint32
+	usage   uint32
+}
+
+type packSegment struct {
+	size   uint64
+	live   uint64
+	blocks map[string]bool
+}
+
+func newPackSegment(size uint64) *packSegment {
+	return &packSegment{
+		size:   size,
+		blocks: make(map[string]bool),
+	}
+}
+
+// pack appends the small blocks into the large segment files to keep the file count low on the data path. Index is
+// kept in the memory and every change is appended to the journal file as a fixed size record, the last record of the
+// block wins on the load and the usage 0 means the block is deleted. Segment files are never changed after they are
+// sealed except compaction which writes the live blocks into a new segment, so hard linked snapshot segments stay safe
+type pack struct {
+	dataPath    string
+	segmentSize uint64
+	logger      *zap.Logger
+
+	mutex      sync.Mutex
+	index      map[string]*packEntry
+	segments   map[uint64]*packSegment
+	active     uint64
+	lastId     uint64
+	records    int
+	compacting bool
+}
+
+var packsMutex sync.Mutex
+var packs = make(map[string]*pack)
+
+// openPack returns the shared pack of the data path. Block managers of the same data path should share the index
+func openPack(dataPath string, logger *zap.Logger) (*pack, error) {
+	packsMutex.Lock()
+	defer packsMutex.Unlock()
+
+	dataPath = path.Clean(dataPath)
+
+	p, has := packs[dataPath]
+	if has {
+		return p, nil
+	}
+
+	p = &pack{
+		dataPath:    dataPath,
+		segmentSize: packSegmentSize,
+		logger:      logger,
+		mutex:       sync.Mutex{},
+		index:       make(map[string]*packEntry),
+		segments:    make(map[uint64]*packSegment),
+	}
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	packs[dataPath] = p
+
+	return p, nil
+}
+
+// ReleasePack drops the shared pack of the data path, it should be called when the data path is removed
+func ReleasePack(dataPath string) {
+	packsMutex.Lock()
+	defer packsMutex.Unlock()
+
+	delete(packs, path.Clean(dataPath))
+}
+
+// LinkPack hard links the segments of the source data path pack to the target data path and adds the source index to
+// the target index. Active segment of the source is sealed, so both packs never append to the same segment file
+func LinkPack(sourcePath string, targetPath string, logger *zap.Logger) error {
+	source, err := openPack(sourcePath, logger)
+	if err != nil {
+		return err
+	}
+
+	target, err := openPack(targetPath, logger)
+	if err != nil {
+		return err
+	}
+
+	return source.link(target)
+}
+
+// PackPath returns the folder of the pack segments and the journal in the data path
+func PackPath(dataPath string) string {
+	return path.Join(dataPath, packFolder)
+}
+
+func (p *pack) folder() string {
+	return PackPath(p.dataPath)
+}
+
+func (p *pack) segmentPath(segmentId uint64) string {
+	return path.Join(p.folder(), fmt.Sprintf("%016x", segmentId))
+}
+
+func (p *pack) load() error {
+	infos, err := ioutil.ReadDir(p.folder())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+
+		if strings.HasPrefix(info.Name(), packTempPrefix) {
+			_ = os.Remove(path.Join(p.folder(), info.Name()))
+			continue
+		}
+
+		segmentId, err := strconv.ParseUint(info.Name(), 16, 64)
+		if err != nil {
+			continue
+		}
+		p.segments[segmentId] = newPackSegment(uint64(info.Size()))
+
+		if segmentId > p.lastId {
+			p.lastId = segmentId
+		}
+	}
+
+	// legacy journal is replayed first, it is replaced with the current journal by the rewrite below
+	if err := p.replay(packLegacyJournalFile, packLegacyRecordSize); err != nil {
+		return err
+	}
+	if err := p.replay(packJournalFile, packRecordSize); err != nil {
+		return err
+	}
+
+	for sha512Hex, entry := range p.index {
+		segment, has := p.segments[entry.segment]
+		if !has || uint64(entry.offset)+uint64(entry.length) > segment.size {
+			p.logger.Warn(
+				"Packed block is missing in the segment, it is dropped from the index",
+				zap.String("sha512Hex", sha512Hex),
+				zap.String("dataPath", p.dataPath),
+			)
+			delete(p.index, sha512Hex)
+			continue
+		}
+		segment.live += uint64(entry.length)
+		segment.blocks[sha512Hex] = true
+	}
+
+	if err := p.rewriteJournalUnsafe(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(path.Join(p.folder(), packLegacyJournalFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (p *pack) replay(journalFile string, recordSize int) error {
+	journal, err := os.OpenFile(path.Join(p.folder(), journalFile), os.O_RDONLY, 0666)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer func() { _ = journal.Close() }()
+
+	record := make([]byte, recordSize)
+	for {
+		if _, err := io.ReadFull(journal, record); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				p.logger.Warn("Pack journal has an incomplete record at the end, it is ignored", zap.String("dataPath", p.dataPath))
+				return nil
+			}
+			return err
+		}
+		p.records++
+
+		sha512Hex, entry := decodePackRecord(record)
+		if entry.usage == 0 {
+			delete(p.index, sha512Hex)
+			continue
+		}
+		p.index[sha512Hex] = entry
+	}
+}
+
+func (p *pack) file(sha512Hex string, logger *zap.Logger) (File, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	entry, has := p.index[sha512Hex]
+	if !has {
+		return nil, nil
+	}
+
+	inner, err := os.OpenFile(p.segmentPath(entry.segment), os.O_RDONLY, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPackedFile(p, inner, sha512Hex, *entry, logger), nil
+}
+
+func (p *pack) has(sha512Hex string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	_, has := p.index[sha512Hex]
+	return has
+}
+
+func (p *pack) traverse(hexHandler func(sha512Hex string) error) error {
+	p.mutex.Lock()
+	sha512HexList := make([]string, 0, len(p.index))
+	for sha512Hex := range p.index {
+		sha512HexList = append(sha512HexList, sha512Hex)
+	}
+	p.mutex.Unlock()
+
+	for _, sha512Hex := range sha512HexList {
+		if err := hexHandler(sha512Hex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// append copies the content of the source file starting from the offset to the active segment
+func (p *pack) append(sha512Hex string, sourcePath string, offset int64, usage uint32) error {
+	source, err := os.OpenFile(sourcePath, os.O_RDONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = source.Close() }()
+
+	if _, err := source.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err := os.MkdirAll(p.folder(), 0777); err != nil {
+		return err
+	}
+
+	segment, has := p.segments[p.active]
+	if p.active == 0 || !has || segment.size >= p.segmentSize {
+		p.active = p.nextIdUnsafe()
+		segment = newPackSegment(0)
+		p.segments[p.active] = segment
+	}
+
+	target, err := os.OpenFile(p.segmentPath(p.active), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = target.Close() }()
+
+	entry := &packEntry{
+		segment: p.active,
+		offset:  uint32(segment.size),
+		usage:   usage,
+	}
+
+	length, err := io.Copy(target, source)
+	segment.size += uint64(length)
+	account(p.dataPath, length)
+	if err != nil {
+		return err
+	}
+	entry.length = uint32(length)
+
+	if err := p.writeRecordsUnsafe(map[string]*packEntry{sha512Hex: entry}); err != nil {
+		return err
+	}
+	p.replaceUnsafe(sha512Hex, entry)
+
+	return nil
+}
+
+func (p *pack) setUsage(sha512Hex string, usage uint32) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	entry, has := p.index[sha512Hex]
+	if !has {
+		return os.ErrNotExist
+	}
+
+	updated := *entry
+	updated.usage = usage
+
+	if err := p.writeRecordsUnsafe(map[string]*packEntry{sha512Hex: &updated}); err != nil {
+		return err
+	}
+	entry.usage = usage
+
+	return nil
+}
+
+func (p *pack) remove(sha512Hex string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	entry, has := p.index[sha512Hex]
+	if !has {
+		return os.ErrNotExist
+	}
+
+	if err := p.writeRecordsUnsafe(map[string]*packEntry{sha512Hex: {segment: entry.segment}}); err != nil {
+		return err
+	}
+	p.replaceUnsafe(sha512Hex, nil)
+
+	if !p.compacting && p.compactableUnsafe(entry.segment) {
+		p.compacting = true
+		go p.compact()
+	}
+
+	return nil
+}
+
+func (p *pack) replaceUnsafe(sha512Hex string, entry *packEntry) {
+	if current, has := p.index[sha512Hex]; has {
+		if segment, has := p.segments[current.segment]; has {
+			segment.live -= uint64(current.length)
+			delete(segment.blocks, sha512Hex)
+		}
+		delete(p.index, sha512Hex)
+	}
+
+	if entry == nil {
+		return
+	}
+
+	p.index[sha512Hex] = entry
+	if segment, has := p.segments[entry.segment]; has {
+		segment.live += uint64(entry.length)
+		segment.blocks[sha512Hex] = true
+	}
+}
+
+func (p *pack) compactableUnsafe(segmentId uint64) bool {
+	if segmentId == p.active {
+		return false
+	}
+
+	segment, has := p.segments[segmentId]
+	if !has || segment.size == 0 {
+		return false
+	}
+
+	return float64(segment.size-segment.live)/float64(segment.size) >= packCompactRatio
+}
+
+// compact rewrites the sealed segments that have more deleted than live blocks and the journal when it is grown with
+// the records of the deleted blocks
+func (p *pack) compact() {
+	p.mutex.Lock()
+	segmentIds := make([]uint64, 0)
+	for segmentId := range p.segments {
+		if p.compactableUnsafe(segmentId) {
+			segmentIds = append(segmentIds, segmentId)
+		}
+	}
+	p.mutex.Unlock()
+
+	for _, segmentId := range segmentIds {
+		if err := p.compactSegment(segmentId); err != nil {
+			p.logger.Warn(
+				"Pack segment compaction is failed",
+				zap.String("dataPath", p.dataPath),
+				zap.Uint64("segment", segmentId),
+				zap.Error(err),
+			)
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	defer func() { p.compacting = false }()
+
+	if p.records > len(p.index)*2+packJournalSlack {
+		if err := p.rewriteJournalUnsafe(); err != nil {
+			p.logger.Warn("Pack journal compaction is failed", zap.String("dataPath", p.dataPath), zap.Error(err))
+		}
+	}
+}
+
+// compactSegment copies the live blocks of the segment into a new segment without holding the lock, sealed segments
+// are not changed. The blocks that are not removed or moved meanwhile are switched to the new segment under the lock
+func (p *pack) compactSegment(segmentId uint64) error {
+	p.mutex.Lock()
+	if !p.compactableUnsafe(segmentId) {
+		p.mutex.Unlock()
+		return nil
+	}
+	segment := p.segments[segmentId]
+
+	if len(segment.blocks) == 0 {
+		defer p.mutex.Unlock()
+		return p.removeSegmentUnsafe(segmentId, segment.size)
+	}
+
+	entries := make(map[string]packEntry, len(segment.blocks))
+	for sha512Hex := range segment.blocks {
+		entries[sha512Hex] = *p.index[sha512Hex]
+	}
+	compactedId := p.nextIdUnsafe()
+	p.mutex.Unlock()
+
+	compacted, compactedEntries, err := p.copySegment(segmentId, compactedId, entries)
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.switchSegmentUnsafe(segmentId, entries, compactedId, compacted, compactedEntries)
+}
+
+// switchSegmentUnsafe moves the copied blocks that still match their entry in the segment to the compacted segment
+// and removes the segment when it is not used anymore
+func (p *pack) switchSegmentUnsafe(segmentId uint64, entries map[string]packEntry, compactedId uint64, compacted *packSegment, compactedEntries map[string]*packEntry) error {
+	segment := p.segments[segmentId]
+
+	switched := make(map[string]*packEntry)
+	for sha512Hex, entry := range entries {
+		current, has := p.index[sha512Hex]
+		if !has || current.segment != segmentId || current.offset != entry.offset {
+			continue
+		}
+		compactedEntry := compactedEntries[sha512Hex]
+		compactedEntry.usage = current.usage
+		switched[sha512Hex] = compactedEntry
+	}
+
+	p.segments[compactedId] = compacted
+	account(p.dataPath, int64(compacted.size))
+
+	if err := p.writeRecordsUnsafe(switched); err != nil {
+		return err
+	}
+	for sha512Hex, entry := range switched {
+		p.replaceUnsafe(sha512Hex, entry)
+	}
+
+	// linked blocks may still use the segment
+	if len(segment.blocks) > 0 {
+		return nil
+	}
+	return p.removeSegmentUnsafe(segmentId, segment.size-compacted.size)
+}
+
+func (p *pack) copySegment(segmentId uint64, compactedId uint64, entries map[string]packEntry) (*packSegment, map[string]*packEntry, error) {
+	source, err := os.OpenFile(p.segmentPath(segmentId), os.O_RDONLY, 0666)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = source.Close() }()
+
+	tempPath := path.Join(p.folder(), fmt.Sprintf("%s%016x", packTempPrefix, compactedId))
+
+	target, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compacted := newPackSegment(0)
+	compactedEntries := make(map[string]*packEntry)
+	for sha512Hex, entry := range entries {
+		if _, err := source.Seek(int64(entry.offset), io.SeekStart); err != nil {
+			_ = target.Close()
+			_ = os.Remove(tempPath)
+			return nil, nil, err
+		}
+		if _, err := io.CopyN(target, source, int64(entry.length)); err != nil {
+			_ = target.Close()
+			_ = os.Remove(tempPath)
+			return nil, nil, err
+		}
+
+		compactedEntries[sha512Hex] = &packEntry{
+			segment: compactedId,
+			offset:  uint32(compacted.size),
+			length:  entry.length,
+			usage:   entry.usage,
+		}
+		compacted.size += uint64(entry.length)
+	}
+
+	if err := target.Close(); err != nil {
+		_ = os.Remove(tempPath)
+		return nil, nil, err
+	}
+	if err := os.Rename(tempPath, p.segmentPath(compactedId)); err != nil {
+		_ = os.Remove(tempPath)
+		return nil, nil, err
+	}
+
+	return compacted, compactedEntries, nil
+}
+
+func (p *pack) removeSegmentUnsafe(segmentId uint64, reclaimed uint64) error {
+	release(p.segmentPath(segmentId))
+	if err := os.Remove(p.segmentPath(segmentId)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(p.segments, segmentId)
+
+	packCompactions.Inc()
+	packReclaimedBytes.Add(float64(reclaimed))
+
+	return nil
+}
+
+func (p *pack) link(target *pack) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	target.mutex.Lock()
+	defer target.mutex.Unlock()
+
+	p.active = 0
+
+	if len(p.index) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(target.folder(), 0777); err != nil {
+		return err
+	}
+
+	for segmentId, segment := range p.segments {
+		if segment.live == 0 {
+			continue
+		}
+
+		sourcePath := p.segmentPath(segmentId)
+		targetPath := target.segmentPath(segmentId)
+
+		if err := os.Link(sourcePath, targetPath); err != nil {
+			if !os.IsExist(err) {
+				return err
+			}
+			if !sameFile(sourcePath, targetPath) {
+				return fmt.Errorf("pack segment %016x exists in the target", segmentId)
+			}
+		}
+
+		if _, has := target.segments[segmentId]; !has {
+			target.segments[segmentId] = newPackSegment(segment.size)
+		}
+		if segmentId > target.lastId {
+			target.lastId = segmentId
+		}
+	}
+
+	entries := make(map[string]*packEntry)
+	for sha512Hex, entry := range p.index {
+		linked := *entry
+		entries[sha512Hex] = &linked
+	}
+
+	if err := target.writeRecordsUnsafe(entries); err != nil {
+		return err
+	}
+	for sha512Hex, entry := range entries {
+		target.replaceUnsafe(sha512Hex, entry)
+	}
+
+	return nil
+}
+
+func (p *pack) writeRecordsUnsafe(entries map[string]*packEntry) error {
+	if err := os.MkdirAll(p.folder(), 0777); err != nil {
+		return err
+	}
+
+	content := make([]byte, 0, len(entries)*packRecordSize)
+	for sha512Hex, entry := range entries {
+		record, err := encodePackRecord(sha512Hex, entry)
+		if err != nil {
+			return err
+		}
+		content = append(content, record...)
+	}
+
+	journal, err := os.OpenFile(path.Join(p.folder(), packJournalFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = journal.Close() }()
+
+	if _, err := journal.Write(content); err != nil {
+		return err
+	}
+	p.records += len(entries)
+
+	return nil
+}
+
+func (p *pack) rewriteJournalUnsafe() error {
+	if len(p.segments) == 0 && len(p.index) == 0 {
+		return nil
+	}
+
+	content := make([]byte, 0, len(p.index)*packRecordSize)
+	for sha512Hex, entry := range p.index {
+		record, err := encodePackRecord(sha512Hex, entry)
+		if err != nil {
+			return err
+		}
+		content = append(content, record...)
+	}
+
+	journalPath := path.Join(p.folder(), packJournalFile)
+	tempPath := path.Join(p.folder(), packTempPrefix+packJournalFile)
+	if err := ioutil.WriteFile(tempPath, content, 0666); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, journalPath); err != nil {
+		return err
+	}
+	p.records = len(p.index)
+
+	return nil
+}
+
+func (p *pack) nextIdUnsafe() uint64 {
+	segmentId := uint64(time.Now().UnixNano())
+	if segmentId <= p.lastId {
+		segmentId = p.lastId + 1
+	}
+	p.lastId = segmentId
+
+	return segmentId
+}
+
+func encodePackRecord(sha512Hex string, entry *packEntry) ([]byte, error) {
+	record := make([]byte, packRecordSize)
+
+	sha512HexBytes, err := hex.DecodeString(sha512Hex)
+	if err != nil {
+		return nil, err
+	}
+	if len(sha512HexBytes) != 32 {
+		return nil, fmt.Errorf("invalid block id: %s", sha512Hex)
+	}
+	copy(record, sha512HexBytes)
+
+	binary.LittleEndian.PutUint64(record[32:], entry.segment)
+	binary.LittleEndian.PutUint32(record[40:], entry.offset)
+	binary.LittleEndian.PutUint32(record[44:], entry.length)
+	binary.LittleEndian.PutUint32(record[48:], entry.usage)
+
+	return record, nil
+}
+
+func decodePackRecord(record []byte) (string, *packEntry) {
+	entry := &packEntry{
+		segment: binary.LittleEndian.Uint64(record[32:]),
+		offset:  binary.LittleEndian.Uint32(record[40:]),
+		length:  binary.LittleEndian.Uint32(record[44:]),
+	}
+
+	if len(record) == packLegacyRecordSize {
+		entry.usage = uint32(binary.LittleEndian.Uint16(record[48:]))
+	} else {
+		entry.usage = binary.LittleEndian.Uint32(record[48:])
+	}
+
+	return hex.EncodeToString(record[:32]), entry
+}
+
+func sameFile(a string, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
@@ -0,0 +1,283 @@
+package block
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/data-node/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newPackManager(t testing.TB, packLimit uint32) (Manager, string) {
+	dataPath, err := ioutil.TempDir("", "kertish-pack")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() {
+		ReleasePack(dataPath)
+		_ = os.RemoveAll(dataPath)
+	})
+
+	m, err := NewManager(dataPath, packLimit, zap.NewNop())
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return m, dataPath
+}
+
+func writeBlock(t *testing.T, m Manager, content []byte) string {
+	sum := sha512.Sum512_256(content)
+	sha512Hex := hex.EncodeToString(sum[:])
+
+	assert.Nil(t, m.LockFile(sha512Hex, func(file File) error {
+		assert.True(t, file.Temporary())
+		if err := file.Write(content); err != nil {
+			return err
+		}
+		assert.True(t, file.Verify())
+		return nil
+	}))
+	return sha512Hex
+}
+
+func readBlock(t *testing.T, m Manager, sha512Hex string) []byte {
+	content := make([]byte, 0)
+	assert.Nil(t, m.File(sha512Hex, func(file File) error {
+		if file.Temporary() {
+			return os.ErrNotExist
+		}
+		return file.Read(func(data []byte) error {
+			content = append(content, data...)
+			return nil
+		}, func() error {
+			return nil
+		})
+	}))
+	return content
+}
+
+func randomContent(size int) []byte {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return b
+}
+
+func TestManager_Pack(t *testing.T) {
+	m, dataPath := newPackManager(t, 1024)
+
+	small := randomContent(512)
+	large := randomContent(2048)
+
+	smallHex := writeBlock(t, m, small)
+	largeHex := writeBlock(t, m, large)
+
+	_, err := os.Stat(common.BlockPath(dataPath, smallHex))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(common.BlockPath(dataPath, largeHex))
+	assert.Nil(t, err)
+
+	assert.Equal(t, small, readBlock(t, m, smallHex))
+	assert.Equal(t, large, readBlock(t, m, largeHex))
+
+	assert.Nil(t, m.File(smallHex, func(file File) error {
+		assert.False(t, file.Temporary())
+		assert.True(t, file.VerifyForce())
+
+		size, err := file.Size()
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(512), size)
+
+		assert.Nil(t, file.SeekData(500))
+		return file.Read(func(data []byte) error {
+			assert.Equal(t, small[500:], data)
+			return nil
+		}, func() error {
+			return nil
+		})
+	}))
+
+	assert.Nil(t, m.LockFile(smallHex, func(file File) error {
+		return file.IncreaseUsage()
+	}))
+
+	blocks := make([]string, 0)
+	assert.Nil(t, m.Traverse(func(sha512Hex string) error {
+		blocks = append(blocks, sha512Hex)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{smallHex, largeHex}, blocks)
+
+	// reload from the journal
+	ReleasePack(dataPath)
+	m, err = NewManager(dataPath, 1024, zap.NewNop())
+	assert.Nil(t, err)
+
+	assert.Equal(t, small, readBlock(t, m, smallHex))
+	assert.Nil(t, m.LockFile(smallHex, func(file File) error {
+		assert.Equal(t, uint32(2), file.Usage())
+		assert.Nil(t, file.Delete())
+		assert.Equal(t, uint32(1), file.Usage())
+		return file.Delete()
+	}))
+
+	assert.Nil(t, m.File(smallHex, func(file File) error {
+		assert.True(t, file.Temporary())
+		return nil
+	}))
+}
+
+func TestManager_PackTruncate(t *testing.T) {
+	m, _ := newPackManager(t, 1024)
+
+	content := randomContent(256)
+	sha512Hex := writeBlock(t, m, content)
+
+	assert.Nil(t, m.LockFile(sha512Hex, func(file File) error {
+		assert.Nil(t, file.Truncate(256))
+		assert.Nil(t, file.Write(content))
+		assert.Nil(t, file.ResetUsage(3))
+		assert.True(t, file.Verify())
+		return nil
+	}))
+
+	assert.Equal(t, content, readBlock(t, m, sha512Hex))
+	assert.Nil(t, m.File(sha512Hex, func(file File) error {
+		assert.Equal(t, uint32(3), file.Usage())
+		return nil
+	}))
+}
+
+func TestPack_Compact(t *testing.T) {
+	m, dataPath := newPackManager(t, 1024)
+
+	p, err := openPack(dataPath, zap.NewNop())
+	assert.Nil(t, err)
+	p.segmentSize = 300
+
+	contents := make([][]byte, 0)
+	sha512HexList := make([]string, 0)
+	for i := 0; i < 7; i++ {
+		content := randomContent(100)
+		contents = append(contents, content)
+		sha512HexList = append(sha512HexList, writeBlock(t, m, content))
+	}
+
+	p.mutex.Lock()
+	assert.Len(t, p.segments, 3)
+	compactedSegment := p.index[sha512HexList[0]].segment
+	p.mutex.Unlock()
+
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, m.LockFile(sha512HexList[i], func(file File) error {
+			return file.Wipe()
+		}))
+	}
+
+	assert.Eventually(t, func() bool {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+
+		_, has := p.segments[compactedSegment]
+		return !has && !p.compacting
+	}, time.Second*5, time.Millisecond*10)
+
+	_, err = os.Stat(p.segmentPath(compactedSegment))
+	assert.True(t, os.IsNotExist(err))
+
+	ReleasePack(dataPath)
+	m, err = NewManager(dataPath, 1024, zap.NewNop())
+	assert.Nil(t, err)
+
+	for i := 2; i < len(contents); i++ {
+		assert.Equal(t, contents[i], readBlock(t, m, sha512HexList[i]))
+	}
+}
+
+func TestPack_CompactSwitch(t *testing.T) {
+	m, dataPath := newPackManager(t, 1024)
+
+	p, err := openPack(dataPath, zap.NewNop())
+	assert.Nil(t, err)
+	p.segmentSize = 300
+
+	contents := make([][]byte, 0)
+	sha512HexList := make([]string, 0)
+	for i := 0; i < 4; i++ {
+		content := randomContent(100)
+		contents = append(contents, content)
+		sha512HexList = append(sha512HexList, writeBlock(t, m, content))
+	}
+
+	// blocks are changed while the segment is copied without the lock
+	p.mutex.Lock()
+	p.compacting = true
+	segmentId := p.index[sha512HexList[0]].segment
+	entries := make(map[string]packEntry)
+	for sha512Hex := range p.segments[segmentId].blocks {
+		entries[sha512Hex] = *p.index[sha512Hex]
+	}
+	compactedId := p.nextIdUnsafe()
+	p.mutex.Unlock()
+	assert.Len(t, entries, 3)
+
+	compacted, compactedEntries, err := p.copySegment(segmentId, compactedId, entries)
+	assert.Nil(t, err)
+
+	assert.Nil(t, m.LockFile(sha512HexList[1], func(file File) error {
+		return file.Wipe()
+	}))
+	assert.Nil(t, p.setUsage(sha512HexList[2], 5))
+
+	p.mutex.Lock()
+	assert.Nil(t, p.switchSegmentUnsafe(segmentId, entries, compactedId, compacted, compactedEntries))
+	_, has := p.segments[segmentId]
+	assert.False(t, has)
+	_, has = p.index[sha512HexList[1]]
+	assert.False(t, has)
+	assert.Equal(t, compactedId, p.index[sha512HexList[0]].segment)
+	assert.Equal(t, compactedId, p.index[sha512HexList[2]].segment)
+	assert.Equal(t, uint32(5), p.index[sha512HexList[2]].usage)
+	assert.Equal(t, uint64(200), p.segments[compactedId].live)
+	p.compacting = false
+	p.mutex.Unlock()
+
+	ReleasePack(dataPath)
+	m, err = NewManager(dataPath, 1024, zap.NewNop())
+	assert.Nil(t, err)
+
+	assert.Equal(t, contents[0], readBlock(t, m, sha512HexList[0]))
+	assert.Equal(t, contents[2], readBlock(t, m, sha512HexList[2]))
+	assert.Equal(t, contents[3], readBlock(t, m, sha512HexList[3]))
+}
+
+func TestLinkPack(t *testing.T) {
+	m, dataPath := newPackManager(t, 1024)
+	content := randomContent(128)
+	sha512Hex := writeBlock(t, m, content)
+
+	snapshotPath := path.Join(dataPath, "snapshot.test")
+	t.Cleanup(func() { ReleasePack(snapshotPath) })
+	assert.Nil(t, LinkPack(dataPath, snapshotPath, zap.NewNop()))
+
+	assert.Nil(t, m.LockFile(sha512Hex, func(file File) error {
+		return file.Wipe()
+	}))
+
+	snapshot, err := NewManager(snapshotPath, 1024, zap.NewNop())
+	assert.Nil(t, err)
+	assert.Equal(t, content, readBlock(t, snapshot, sha512Hex))
+
+	assert.Nil(t, LinkPack(snapshotPath, dataPath, zap.NewNop()))
+	assert.Equal(t, content, readBlock(t, m, sha512Hex))
+
+	// appending after the link does not write to the shared segment
+	writeBlock(t, m, randomContent(128))
+	assert.Equal(t, content, readBlock(t, snapshot, sha512Hex))
+}
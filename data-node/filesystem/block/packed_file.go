@@ -0,0 +1,197 @@
+package block
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type packedFile struct {
+	pack     *pack
+	inner    *os.File
+	entry    packEntry
+	position int64
+
+	sha512   hash.Hash
+	verified bool
+
+	sha512Hex string
+	logger    *zap.Logger
+
+	// replacement is the file that is written after the packed block is truncated
+	replacement File
+}
+
+func newPackedFile(pack *pack, inner *os.File, sha512Hex string, entry packEntry, logger *zap.Logger) File {
+	return &packedFile{
+		pack:      pack,
+		inner:     inner,
+		entry:     entry,
+		sha512:    sha512.New512_256(),
+		verified:  true,
+		sha512Hex: sha512Hex,
+		logger:    logger,
+	}
+}
+
+func (p *packedFile) Temporary() bool {
+	return false
+}
+
+func (p *packedFile) Write(data []byte) error {
+	if p.replacement == nil {
+		return os.ErrExist
+	}
+	return p.replacement.Write(data)
+}
+
+func (p *packedFile) Verify() bool {
+	if p.replacement != nil {
+		return p.replacement.Verify()
+	}
+	return p.verified
+}
+
+func (p *packedFile) VerifyForce() bool {
+	p.sha512.Reset()
+	p.position = 0
+
+	if err := p.Read(func(data []byte) error {
+		_, err := p.sha512.Write(data)
+		return err
+	}, func() error {
+		result := hex.EncodeToString(p.sha512.Sum(nil))
+		p.verified = strings.Compare(result, p.sha512Hex) == 0
+
+		return nil
+	}); err != nil {
+		return false
+	}
+
+	return p.verified
+}
+
+func (p *packedFile) SeekData(offset int64) error {
+	if offset < 0 || offset > int64(p.entry.length) {
+		return os.ErrInvalid
+	}
+	p.position = offset
+	return nil
+}
+
+func (p *packedFile) Read(readHandler func(data []byte) error, completedHandler func() error) error {
+	buffer := make([]byte, chunkSize)
+	for p.position < int64(p.entry.length) {
+		size := int64(p.entry.length) - p.position
+		if size > int64(len(buffer)) {
+			size = int64(len(buffer))
+		}
+
+		s, err := p.inner.ReadAt(buffer[0:size], int64(p.entry.offset)+p.position)
+		if s > 0 {
+			p.position += int64(s)
+
+			if err := readHandler(buffer[0:s]); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return completedHandler()
+}
+
+func (p *packedFile) Id() string {
+	return p.sha512Hex
+}
+
+func (p *packedFile) Usage() uint32 {
+	if p.replacement != nil {
+		return p.replacement.Usage()
+	}
+	return p.entry.usage
+}
+
+func (p *packedFile) IncreaseUsage() error {
+	if p.replacement != nil {
+		return p.replacement.IncreaseUsage()
+	}
+	return p.setUsage(p.entry.usage + 1)
+}
+
+func (p *packedFile) ResetUsage(usage uint32) error {
+	if p.replacement != nil {
+		return p.replacement.ResetUsage(usage)
+	}
+	if usage < 1 {
+		usage = 1
+	}
+	return p.setUsage(usage)
+}
+
+func (p *packedFile) setUsage(usage uint32) error {
+	if err := p.pack.setUsage(p.sha512Hex, usage); err != nil {
+		return err
+	}
+	p.entry.usage = usage
+	return nil
+}
+
+func (p *packedFile) Size() (uint32, error) {
+	if p.replacement != nil {
+		return p.replacement.Size()
+	}
+	return p.entry.length, nil
+}
+
+func (p *packedFile) Delete() error {
+	if p.entry.usage <= 1 {
+		return p.Wipe()
+	}
+	return p.setUsage(p.entry.usage - 1)
+}
+
+func (p *packedFile) Wipe() error {
+	return p.pack.remove(p.sha512Hex)
+}
+
+// Truncate drops the packed block and prepares the replacement file to write the block again. Packed blocks can not
+// be changed in place
+func (p *packedFile) Truncate(_ uint32) error {
+	if err := p.pack.remove(p.sha512Hex); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	replacement, err := newFile(p.pack.dataPath, p.sha512Hex, p.pack, p.entry.length, p.logger)
+	if err != nil {
+		return err
+	}
+	p.replacement = replacement
+
+	return nil
+}
+
+func (p *packedFile) Cancel() {
+	if p.replacement != nil {
+		p.replacement.Cancel()
+	}
+}
+
+func (p *packedFile) Close() {
+	_ = p.inner.Close()
+
+	if p.replacement != nil {
+		p.replacement.Close()
+	}
+}
+
+var _ File = &packedFile{}
@@ -0,0 +1,111 @@
+package block
+
+import (
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/freakmaxi/kertish-dfs/data-node/common"
+	"go.uber.org/zap"
+)
+
+var usagesMutex sync.RWMutex
+var usages = make(map[string]*int64)
+
+// SetUsed sets the used space of the root path and starts keeping it incrementally. Block files and pack segments that
+// are created and removed under the root path, including the snapshot folders, change the used space of it
+func SetUsed(rootPath string, used uint64) {
+	rootPath = path.Clean(rootPath)
+
+	usagesMutex.Lock()
+	defer usagesMutex.Unlock()
+
+	counter, has := usages[rootPath]
+	if !has {
+		counter = new(int64)
+		usages[rootPath] = counter
+	}
+	atomic.StoreInt64(counter, int64(used))
+}
+
+// Used returns the used space of the root path that is kept incrementally
+func Used(rootPath string) uint64 {
+	usagesMutex.RLock()
+	counter, has := usages[path.Clean(rootPath)]
+	usagesMutex.RUnlock()
+
+	if !has {
+		return 0
+	}
+
+	used := atomic.LoadInt64(counter)
+	if used < 0 {
+		return 0
+	}
+	return uint64(used)
+}
+
+// account changes the used space of the root path that the data path is placed in
+func account(dataPath string, delta int64) {
+	if delta == 0 {
+		return
+	}
+
+	usagesMutex.RLock()
+	defer usagesMutex.RUnlock()
+
+	if len(usages) == 0 {
+		return
+	}
+
+	for p := path.Clean(dataPath); ; p = path.Dir(p) {
+		if counter, has := usages[p]; has {
+			atomic.AddInt64(counter, delta)
+			return
+		}
+		if p == "/" || p == "." {
+			return
+		}
+	}
+}
+
+// release accounts the space of the file that is going to be removed. The space is not freed if the file has another
+// hard link, like the snapshot copy of the block
+func release(filePath string) {
+	info, err := os.Stat(filePath)
+	if err != nil || linked(info) {
+		return
+	}
+	account(path.Dir(filePath), -info.Size())
+}
+
+// linked returns true if the file has more than one hard link
+func linked(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Nlink > 1
+}
+
+// RemovePath removes the data path with its blocks and pack, the space of the blocks that are not linked to another
+// data path is released
+func RemovePath(dataPath string, logger *zap.Logger) error {
+	if err := common.Traverse(dataPath, func(filePath string, info os.FileInfo) error {
+		release(filePath)
+		return nil
+	}); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	p, err := openPack(dataPath, logger)
+	if err == nil {
+		p.mutex.Lock()
+		for segmentId := range p.segments {
+			release(p.segmentPath(segmentId))
+		}
+		p.mutex.Unlock()
+	}
+	ReleasePack(dataPath)
+
+	return os.RemoveAll(dataPath)
+}
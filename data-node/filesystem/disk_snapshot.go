@@ -0,0 +1,205 @@
+package filesystem
+
+import (
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/data-node/filesystem/block"
+	"go.uber.org/zap"
+)
+
+type diskSnapshot struct {
+	disks     block.DiskSet
+	placement string
+	packLimit uint32
+	logger    *zap.Logger
+
+	blocksMutex sync.Mutex
+	blocks      map[time.Time]block.Manager
+}
+
+// NewDiskSnapshot creates the snapshots on every disk of the disk set at the same time, so a snapshot of the data node
+// is the combination of the same snapshot folders of the disks
+func NewDiskSnapshot(disks block.DiskSet, placement string, packLimit uint32, logger *zap.Logger) Snapshot {
+	return &diskSnapshot{
+		disks:       disks,
+		placement:   placement,
+		packLimit:   packLimit,
+		logger:      logger,
+		blocksMutex: sync.Mutex{},
+		blocks:      make(map[time.Time]block.Manager),
+	}
+}
+
+func (d *diskSnapshot) each(snapshotHandler func(disk block.Disk, snapshot Snapshot) error) error {
+	for _, disk := range d.disks.Disks() {
+		if err := snapshotHandler(disk, NewSnapshot(disk.Path, d.packLimit, d.logger)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *diskSnapshot) Create(targetSnapshot *time.Time) (*time.Time, error) {
+	nextSnapshot := time.Now().UTC()
+	if targetSnapshot != nil {
+		nextSnapshot = *targetSnapshot
+	}
+
+	created := make([]Snapshot, 0)
+	if err := d.each(func(_ block.Disk, snapshot Snapshot) error {
+		if _, err := snapshot.Create(&nextSnapshot); err != nil {
+			return err
+		}
+		created = append(created, snapshot)
+		return nil
+	}); err != nil {
+		for _, snapshot := range created {
+			if err := snapshot.Delete(nextSnapshot); err != nil {
+				d.logger.Error("Unable to delete snapshot path", zap.Error(err))
+			}
+		}
+		return nil, err
+	}
+
+	return &nextSnapshot, nil
+}
+
+func (d *diskSnapshot) Delete(targetSnapshot time.Time) error {
+	d.blocksMutex.Lock()
+	delete(d.blocks, targetSnapshot)
+	d.blocksMutex.Unlock()
+
+	return d.each(func(_ block.Disk, snapshot Snapshot) error {
+		return snapshot.Delete(targetSnapshot)
+	})
+}
+
+// Restore restores the snapshot on every disk. Disks that do not have the snapshot (added after the snapshot) are
+// wiped because their blocks are not the part of the snapshot
+func (d *diskSnapshot) Restore(sourceSnapshot time.Time) error {
+	return d.each(func(disk block.Disk, snapshot Snapshot) error {
+		_, err := os.Stat(path.Join(disk.Path, d.PathName(sourceSnapshot)))
+		if err == nil {
+			return snapshot.Restore(sourceSnapshot)
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		b, err := block.NewManager(disk.Path, d.packLimit, d.logger)
+		if err != nil {
+			return err
+		}
+		return b.Wipe()
+	})
+}
+
+func (d *diskSnapshot) Block(snapshot time.Time) (block.Manager, error) {
+	d.blocksMutex.Lock()
+	defer d.blocksMutex.Unlock()
+
+	b, has := d.blocks[snapshot]
+	if !has {
+		snapshotDisks := make([]block.Disk, 0)
+		for _, disk := range d.disks.Disks() {
+			snapshotDisks = append(snapshotDisks, block.Disk{
+				Path: path.Join(disk.Path, d.PathName(snapshot)),
+				Size: disk.Size,
+			})
+		}
+
+		var err error
+		b, err = block.NewDiskSet(snapshotDisks, d.placement, d.packLimit, d.logger)
+		if err != nil {
+			return nil, err
+		}
+
+		d.blocks[snapshot] = b
+	}
+
+	return b, nil
+}
+
+func (d *diskSnapshot) ReadHeaderBackup(snapshot time.Time) (HeaderMap, error) {
+	headerMap := make(HeaderMap)
+
+	if err := d.each(func(_ block.Disk, s Snapshot) error {
+		diskHeaderMap, err := s.ReadHeaderBackup(snapshot)
+		if err != nil {
+			return err
+		}
+
+		for sha512Hex, usage := range diskHeaderMap {
+			headerMap[sha512Hex] = usage
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return headerMap, nil
+}
+
+func (d *diskSnapshot) ReplaceHeaderBackup(snapshot time.Time, headerMap HeaderMap) error {
+	return d.each(func(_ block.Disk, s Snapshot) error {
+		return s.ReplaceHeaderBackup(snapshot, headerMap)
+	})
+}
+
+func (d *diskSnapshot) Latest() (*time.Time, error) {
+	snapshots, err := d.Dates()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+
+	return &snapshots[len(snapshots)-1], nil
+}
+
+func (d *diskSnapshot) Dates() (common.Snapshots, error) {
+	dates := make(map[time.Time]bool)
+
+	if err := d.each(func(_ block.Disk, s Snapshot) error {
+		snapshots, err := s.Dates()
+		if err != nil {
+			return err
+		}
+
+		for _, snapshot := range snapshots {
+			dates[snapshot] = true
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	snapshots := make(common.Snapshots, 0, len(dates))
+	for snapshot := range dates {
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Sort(snapshots)
+
+	return snapshots, nil
+}
+
+func (d *diskSnapshot) PathName(snapshot time.Time) string {
+	return snapshotPathName(snapshot)
+}
+
+func (d *diskSnapshot) FromUint(snapshotUint uint64) (*time.Time, error) {
+	return snapshotFromUint(snapshotUint)
+}
+
+func (d *diskSnapshot) ToUint(snapshot time.Time) uint64 {
+	return snapshotToUint(snapshot)
+}
+
+var _ Snapshot = &diskSnapshot{}
@@ -1,16 +1,26 @@
 package filesystem
 
 import (
+	"encoding/binary"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"sync"
+	"time"
 
 	dnc "github.com/freakmaxi/kertish-dfs/data-node/common"
 	"github.com/freakmaxi/kertish-dfs/data-node/filesystem/block"
 	"go.uber.org/zap"
 )
 
+const diskMarkerFile = ".disk"
+const diskCheckInterval = time.Second * 30
+
+const usedFile = ".used"
+const usedSaveInterval = time.Minute
+const usedReconcileInterval = time.Hour * 6
+
 type Manager interface {
 	Block() block.Manager
 	Snapshot(func(snapshot Snapshot) error) error
@@ -18,39 +28,310 @@ type Manager interface {
 
 	Wipe() error
 	Used() (uint64, error)
+	Shutdown()
 }
 
 type manager struct {
-	rootPath string
-	logger   *zap.Logger
+	logger *zap.Logger
 
-	block       block.Manager
+	block       block.DiskSet
 	snapshot    Snapshot
 	synchronize Synchronize
 
+	missingHandler func(sha512HexList []string)
+	repairHandler  func()
+
 	mutex sync.Mutex
+
+	usedMutex sync.Mutex
+	stopped   bool
 }
 
-func NewManager(rootPath string, logger *zap.Logger) (Manager, error) {
-	b, err := block.NewManager(rootPath, logger)
+// NewManager creates the file system manager on the disks. Disks that are not available are excluded and the disks
+// failing later are dropped, blocks of the dropped disks and the corrupted blocks found by the scrub are passed to the
+// missing handler to be recovered. Repair handler is called when the blocks of the dropped disk can not be listed, so
+// the manager node finds them. Zero scrub interval disables the scrub
+func NewManager(disks []block.Disk, placement string, packLimit uint32, scrubInterval time.Duration, missingHandler func(sha512HexList []string), repairHandler func(), logger *zap.Logger) (Manager, error) {
+	available := make([]block.Disk, 0)
+	for _, disk := range disks {
+		if err := prepareDisk(disk.Path); err != nil {
+			logger.Error("Disk is not available, it is excluded", zap.String("rootPath", disk.Path), zap.Error(err))
+			diskFailuresTotal.Inc()
+			continue
+		}
+		available = append(available, disk)
+	}
+	if len(available) == 0 {
+		return nil, fmt.Errorf("none of the disks is available")
+	}
+	disksAvailable.Set(float64(len(available)))
+
+	// used spaces are reconciled at the start if one of them is not saved on a clean shutdown. They are marked as not
+	// clean till the next shutdown, so a crash forces the reconcile
+	reconcile := false
+	for _, disk := range available {
+		used, clean, err := loadUsed(disk.Path)
+		if err != nil || !clean {
+			reconcile = true
+		}
+		block.SetUsed(disk.Path, used)
+
+		if err := saveUsed(disk.Path, used, false); err != nil {
+			logger.Warn("Used space can not be saved", zap.String("rootPath", disk.Path), zap.Error(err))
+		}
+	}
+
+	b, err := block.NewDiskSet(available, placement, packLimit, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	ss := NewSnapshot(rootPath, logger)
-	s, err := NewSynchronize(rootPath, ss, logger)
+	ss := NewDiskSnapshot(b, placement, packLimit, logger)
+	s := NewSynchronize(b, ss, logger)
+
+	m := &manager{
+		logger:         logger,
+		block:          b,
+		snapshot:       ss,
+		synchronize:    s,
+		missingHandler: missingHandler,
+		repairHandler:  repairHandler,
+		mutex:          sync.Mutex{},
+	}
+	go m.watch()
+	go m.account(reconcile)
+	if scrubInterval > 0 {
+		go m.scrub(scrubInterval)
+	}
+
+	return m, nil
+}
+
+// prepareDisk creates the marker file on the disk, missing marker means the disk is not mounted anymore
+func prepareDisk(rootPath string) error {
+	if err := os.MkdirAll(rootPath, 0777); err != nil {
+		return err
+	}
+
+	markerPath := path.Join(rootPath, diskMarkerFile)
+	if _, err := os.Stat(markerPath); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if err := ioutil.WriteFile(markerPath, make([]byte, 8), 0666); err != nil {
+			return err
+		}
+	}
+
+	return checkDisk(rootPath)
+}
+
+// checkDisk writes the current time to the marker file in place, so the disk is touched without the need of space
+func checkDisk(rootPath string) error {
+	marker, err := os.OpenFile(path.Join(rootPath, diskMarkerFile), os.O_WRONLY, 0666)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer func() { _ = marker.Close() }()
+
+	now := make([]byte, 8)
+	binary.LittleEndian.PutUint64(now, uint64(time.Now().UTC().Unix()))
+
+	if _, err := marker.WriteAt(now, 0); err != nil {
+		return err
+	}
+	return marker.Sync()
+}
+
+func (m *manager) watch() {
+	for {
+		time.Sleep(diskCheckInterval)
+
+		for _, disk := range m.block.Disks() {
+			if err := checkDisk(disk.Path); err != nil {
+				m.fail(disk, err)
+			}
+		}
+	}
+}
+
+func (m *manager) fail(disk block.Disk, err error) {
+	m.logger.Error("Disk is failed, it is excluded", zap.String("rootPath", disk.Path), zap.Error(err))
+	diskFailuresTotal.Inc()
+
+	sha512HexList, err := m.block.Fail(disk.Path)
+	disksAvailable.Set(float64(len(m.block.Disks())))
+
+	if err != nil {
+		m.logger.Warn("Blocks of the failed disk can not be listed, node repair is requested", zap.String("rootPath", disk.Path), zap.Error(err))
+		if m.repairHandler != nil {
+			m.repairHandler()
+		}
+		return
 	}
 
-	return &manager{
-		rootPath:    rootPath,
-		logger:      logger,
-		block:       b,
-		snapshot:    ss,
-		synchronize: s,
-		mutex:       sync.Mutex{},
-	}, nil
+	if len(sha512HexList) == 0 || m.missingHandler == nil {
+		return
+	}
+	m.logger.Warn(fmt.Sprintf("%d blocks are missing with the failed disk", len(sha512HexList)), zap.String("rootPath", disk.Path))
+
+	m.missingHandler(sha512HexList)
+}
+
+// scrub verifies the blocks against their hashes and checksums in the interval. Corrupted blocks are reported as
+// missing, so they are synced again from the other data nodes of the cluster
+func (m *manager) scrub(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+
+		corrupted := m.verify()
+		if len(corrupted) == 0 || m.missingHandler == nil {
+			continue
+		}
+		m.logger.Warn(fmt.Sprintf("%d corrupted blocks are found by the scrub", len(corrupted)))
+
+		m.missingHandler(corrupted)
+	}
+}
+
+func (m *manager) verify() []string {
+	corrupted := make([]string, 0)
+
+	if err := m.block.Traverse(func(sha512Hex string) error {
+		if err := m.block.LockFile(sha512Hex, func(blockFile block.File) error {
+			if !blockFile.Temporary() && !blockFile.VerifyForce() {
+				corrupted = append(corrupted, sha512Hex)
+			}
+			return nil
+		}); err != nil {
+			m.logger.Warn("Block can not be scrubbed", zap.String("sha512Hex", sha512Hex), zap.Error(err))
+		}
+		return nil
+	}); err != nil {
+		m.logger.Warn("Scrub is failed", zap.Error(err))
+	}
+	scrubCorruptedTotal.Add(float64(len(corrupted)))
+
+	return corrupted
+}
+
+// loadUsed returns the saved used space and false if it is not saved on a clean shutdown
+func loadUsed(rootPath string) (uint64, bool, error) {
+	content, err := ioutil.ReadFile(path.Join(rootPath, usedFile))
+	if err != nil {
+		return 0, false, err
+	}
+	if len(content) != 9 {
+		return 0, false, fmt.Errorf("used space file is corrupted")
+	}
+	return binary.LittleEndian.Uint64(content), content[8] == 1, nil
+}
+
+// saveUsed writes the used space to a temporary file and replaces the used space file with it, so a crash during the
+// write does not leave a corrupted file
+func saveUsed(rootPath string, used uint64, clean bool) error {
+	content := make([]byte, 9)
+	binary.LittleEndian.PutUint64(content, used)
+	if clean {
+		content[8] = 1
+	}
+
+	tempPath := path.Join(rootPath, fmt.Sprintf("%s.tmp", usedFile))
+
+	f, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, path.Join(rootPath, usedFile))
+}
+
+// account saves the used spaces that are kept incrementally and reconciles them with the disks in the background
+func (m *manager) account(reconcile bool) {
+	saved := make(map[string]uint64)
+	lastReconcile := time.Now()
+
+	for {
+		if reconcile {
+			m.reconcile()
+			lastReconcile = time.Now()
+		}
+
+		if !m.save(saved) {
+			return
+		}
+
+		time.Sleep(usedSaveInterval)
+		reconcile = time.Since(lastReconcile) >= usedReconcileInterval
+	}
+}
+
+// save writes the changed used spaces and returns false if the manager is shut down
+func (m *manager) save(saved map[string]uint64) bool {
+	m.usedMutex.Lock()
+	defer m.usedMutex.Unlock()
+
+	if m.stopped {
+		return false
+	}
+
+	total := uint64(0)
+	for _, disk := range m.block.Disks() {
+		used := block.Used(disk.Path)
+		total += used
+
+		if last, has := saved[disk.Path]; has && last == used {
+			continue
+		}
+
+		if err := saveUsed(disk.Path, used, false); err != nil {
+			m.logger.Warn("Used space can not be saved", zap.String("rootPath", disk.Path), zap.Error(err))
+			continue
+		}
+		saved[disk.Path] = used
+	}
+	usedBytes.Set(float64(total))
+
+	return true
+}
+
+// reconcile walks the disks to calculate the used spaces. Blocks are not locked during the walk, so the changes at the
+// same time can be missed till the next reconcile
+func (m *manager) reconcile() {
+	snapshotDates, err := m.snapshot.Dates()
+	if err != nil {
+		m.logger.Warn("Used space reconcile is failed", zap.Error(err))
+		return
+	}
+
+	for _, disk := range m.block.Disks() {
+		used, err := m.used(disk.Path, snapshotDates)
+		if err != nil {
+			m.logger.Warn("Used space reconcile is failed", zap.String("rootPath", disk.Path), zap.Error(err))
+			continue
+		}
+
+		if counted := block.Used(disk.Path); counted != used {
+			m.logger.Info(
+				"Used space is reconciled",
+				zap.String("rootPath", disk.Path),
+				zap.Uint64("counted", counted),
+				zap.Uint64("used", used),
+			)
+		}
+		block.SetUsed(disk.Path, used)
+	}
 }
 
 func (m *manager) Block() block.Manager {
@@ -84,18 +365,52 @@ func (m *manager) Wipe() error {
 
 	m.block.Wait()
 
-	infos, err := ioutil.ReadDir(m.rootPath)
+	for _, disk := range m.block.Disks() {
+		if err := m.wipe(disk.Path); err != nil {
+			return err
+		}
+		block.SetUsed(disk.Path, 0)
+	}
+
+	return nil
+}
+
+// Shutdown waits the running block operations and saves the used spaces as clean. Manager does not accept any
+// operation after the shutdown
+func (m *manager) Shutdown() {
+	m.mutex.Lock()
+
+	m.block.Wait()
+
+	m.usedMutex.Lock()
+	defer m.usedMutex.Unlock()
+
+	m.stopped = true
+	for _, disk := range m.block.Disks() {
+		if err := saveUsed(disk.Path, block.Used(disk.Path), true); err != nil {
+			m.logger.Warn("Used space can not be saved", zap.String("rootPath", disk.Path), zap.Error(err))
+		}
+	}
+}
+
+func (m *manager) wipe(rootPath string) error {
+	infos, err := ioutil.ReadDir(rootPath)
 	if err != nil {
 		return err
 	}
 
 	for _, info := range infos {
-		p := path.Join(m.rootPath, info.Name())
+		if info.Name() == diskMarkerFile || info.Name() == usedFile {
+			continue
+		}
+
+		p := path.Join(rootPath, info.Name())
 
 		if info.IsDir() {
 			if err := os.RemoveAll(p); err != nil {
 				return err
 			}
+			block.ReleasePack(p)
 			continue
 		}
 
@@ -103,38 +418,47 @@ func (m *manager) Wipe() error {
 			return err
 		}
 	}
+	block.ReleasePack(rootPath)
 
 	return nil
 }
 
+// Used returns the used space that is kept incrementally, it does not touch the disks
 func (m *manager) Used() (uint64, error) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
-	m.block.Wait()
+	used := uint64(0)
+	for _, disk := range m.block.Disks() {
+		used += block.Used(disk.Path)
+	}
+	return used, nil
+}
 
+func (m *manager) used(rootPath string, snapshotDates []time.Time) (uint64, error) {
 	sha512HexMap := make(map[string]uint64)
 
 	// fill for root
-	if err := dnc.Traverse(m.rootPath, func(info os.FileInfo) error {
+	if err := dnc.Traverse(rootPath, func(_ string, info os.FileInfo) error {
 		sha512HexMap[info.Name()] = uint64(info.Size())
 		return nil
 	}); err != nil {
 		return 0, err
 	}
-
-	// fill for snapshotDates
-	snapshotDates, err := m.snapshot.Dates()
-	if err != nil {
+	if err := m.usedPack(rootPath, sha512HexMap); err != nil {
 		return 0, err
 	}
 
+	// fill for snapshotDates
 	for _, snapshotDate := range snapshotDates {
-		snapshotPathName := m.snapshot.PathName(snapshotDate)
-		if err := dnc.Traverse(path.Join(m.rootPath, snapshotPathName), func(info os.FileInfo) error {
+		snapshotPath := path.Join(rootPath, m.snapshot.PathName(snapshotDate))
+		if err := dnc.Traverse(snapshotPath, func(_ string, info os.FileInfo) error {
 			sha512HexMap[info.Name()] = uint64(info.Size())
 			return nil
 		}); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		if err := m.usedPack(snapshotPath, sha512HexMap); err != nil {
 			return 0, err
 		}
 	}
@@ -147,4 +471,26 @@ func (m *manager) Used() (uint64, error) {
 	return used, nil
 }
 
+// usedPack fills the pack segment sizes, snapshot segments are hard linked so they are counted once by their names
+func (m *manager) usedPack(dataPath string, sizeMap map[string]uint64) error {
+	packPath := block.PackPath(dataPath)
+
+	infos, err := ioutil.ReadDir(packPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		sizeMap[path.Join(path.Base(packPath), info.Name())] = uint64(info.Size())
+	}
+
+	return nil
+}
+
 var _ Manager = &manager{}
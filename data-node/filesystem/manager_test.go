@@ -0,0 +1,67 @@
+package filesystem
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/data-node/filesystem/block"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newDisk(t *testing.T) block.Disk {
+	rootPath, err := ioutil.TempDir("", "kertish-fs")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(rootPath) })
+
+	return block.Disk{Path: rootPath, Size: 1024 * 1024}
+}
+
+func TestManager_UsedReload(t *testing.T) {
+	disk := newDisk(t)
+
+	m, err := NewManager([]block.Disk{disk}, block.PlacementHash, 0, 0, nil, nil, zap.NewNop())
+	assert.Nil(t, err)
+
+	_, clean, err := loadUsed(disk.Path)
+	assert.Nil(t, err)
+	assert.False(t, clean)
+
+	block.SetUsed(disk.Path, 4096)
+	m.Shutdown()
+
+	used, clean, err := loadUsed(disk.Path)
+	assert.Nil(t, err)
+	assert.True(t, clean)
+	assert.Equal(t, uint64(4096), used)
+
+	// clean saved used space is loaded without the reconcile
+	block.SetUsed(disk.Path, 0)
+	m, err = NewManager([]block.Disk{disk}, block.PlacementHash, 0, 0, nil, nil, zap.NewNop())
+	assert.Nil(t, err)
+
+	used, err = m.Used()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4096), used)
+
+	_, clean, err = loadUsed(disk.Path)
+	assert.Nil(t, err)
+	assert.False(t, clean)
+}
+
+func TestManager_UsedUncleanReconcile(t *testing.T) {
+	disk := newDisk(t)
+	assert.Nil(t, saveUsed(disk.Path, 4096, false))
+
+	m, err := NewManager([]block.Disk{disk}, block.PlacementHash, 0, 0, nil, nil, zap.NewNop())
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		used, err := m.Used()
+		return err == nil && used == 0
+	}, time.Second*5, time.Millisecond*10)
+}
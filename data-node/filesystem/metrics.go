@@ -0,0 +1,41 @@
+package filesystem
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var syncQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: "kertish",
+	Subsystem: "data",
+	Name:      "sync_queue_depth",
+	Help:      "Number of block sync requests waiting to be processed",
+})
+
+var disksAvailable = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: "kertish",
+	Subsystem: "data",
+	Name:      "disks_available",
+	Help:      "Number of disks that are in use",
+})
+
+var diskFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "kertish",
+	Subsystem: "data",
+	Name:      "disk_failures_total",
+	Help:      "Total number of disks that are excluded because of a failure",
+})
+
+var usedBytes = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: "kertish",
+	Subsystem: "data",
+	Name:      "used_bytes",
+	Help:      "Used space of the disks that is kept incrementally",
+})
+
+var scrubCorruptedTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "kertish",
+	Subsystem: "data",
+	Name:      "scrub_corrupted_blocks_total",
+	Help:      "Total number of corrupted blocks found by the scrub",
+})
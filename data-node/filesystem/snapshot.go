@@ -21,7 +21,8 @@ import (
 )
 
 const snapshotPrefix = "snapshot."
-const snapshotHeaderBackupFile = "headers.backup"
+const snapshotHeaderBackupFile = "headers.v2.backup"
+const snapshotLegacyHeaderBackupFile = "headers.backup"
 
 type Snapshot interface {
 	Create(targetSnapshot *time.Time) (*time.Time, error)
@@ -41,19 +42,21 @@ type Snapshot interface {
 	ToUint(snapshot time.Time) uint64
 }
 
-type HeaderMap map[string]uint16
+type HeaderMap map[string]uint32
 
 type snapshot struct {
-	rootPath string
-	logger   *zap.Logger
+	rootPath  string
+	packLimit uint32
+	logger    *zap.Logger
 
 	blocksMutex sync.Mutex
 	blocks      map[time.Time]block.Manager
 }
 
-func NewSnapshot(rootPath string, logger *zap.Logger) Snapshot {
+func NewSnapshot(rootPath string, packLimit uint32, logger *zap.Logger) Snapshot {
 	return &snapshot{
 		rootPath:    rootPath,
+		packLimit:   packLimit,
 		logger:      logger,
 		blocksMutex: sync.Mutex{},
 		blocks:      make(map[time.Time]block.Manager),
@@ -66,18 +69,27 @@ func (s *snapshot) ReadHeaderBackup(snapshot time.Time) (HeaderMap, error) {
 	snapshotPathName := s.PathName(snapshot)
 	snapshotPath := path.Join(s.rootPath, snapshotPathName)
 
-	headerBackupFilePath := path.Join(snapshotPath, snapshotHeaderBackupFile)
-	headerFile, err := os.OpenFile(headerBackupFilePath, os.O_RDONLY, 0666)
+	// snapshots of the older versions keep the usages in 2 bytes
+	legacy := false
+
+	headerFile, err := os.OpenFile(path.Join(snapshotPath, snapshotHeaderBackupFile), os.O_RDONLY, 0666)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return headerMap, nil
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		legacy = true
+		headerFile, err = os.OpenFile(path.Join(snapshotPath, snapshotLegacyHeaderBackupFile), os.O_RDONLY, 0666)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return headerMap, nil
+			}
+			return nil, err
 		}
-		return nil, err
 	}
 	defer func() { _ = headerFile.Close() }()
 
 	sha512HexBytes := make([]byte, 32)
-	var usage uint16
 
 	for {
 		if _, err := io.ReadAtLeast(headerFile, sha512HexBytes, len(sha512HexBytes)); err != nil {
@@ -87,8 +99,17 @@ func (s *snapshot) ReadHeaderBackup(snapshot time.Time) (HeaderMap, error) {
 			return nil, err
 		}
 
-		if err := binary.Read(headerFile, binary.LittleEndian, &usage); err != nil {
-			return nil, err
+		var usage uint32
+		if legacy {
+			var legacyUsage uint16
+			if err := binary.Read(headerFile, binary.LittleEndian, &legacyUsage); err != nil {
+				return nil, err
+			}
+			usage = uint32(legacyUsage)
+		} else {
+			if err := binary.Read(headerFile, binary.LittleEndian, &usage); err != nil {
+				return nil, err
+			}
 		}
 
 		sha512Hex := hex.EncodeToString(sha512HexBytes)
@@ -123,6 +144,9 @@ func (s *snapshot) ReplaceHeaderBackup(snapshot time.Time, headerMap HeaderMap)
 		}
 	}
 
+	if err := os.Remove(path.Join(snapshotPath, snapshotLegacyHeaderBackupFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
 
@@ -167,7 +191,7 @@ func (s *snapshot) Create(targetSnapshot *time.Time) (snapshotTime *time.Time, s
 
 	s.logger.Info("Start traversing for snapshot creation")
 
-	if err := dnc.Traverse(s.rootPath, func(info os.FileInfo) error {
+	if err := dnc.Traverse(s.rootPath, func(filePath string, info os.FileInfo) error {
 		sha512Hex := info.Name()
 
 		blockFile, err := block.NewFile(s.rootPath, sha512Hex, s.logger)
@@ -180,6 +204,13 @@ func (s *snapshot) Create(targetSnapshot *time.Time) (snapshotTime *time.Time, s
 			return nil
 		}
 
+		if err := s.link(filePath, dnc.BlockPath(nextSnapshotPath, sha512Hex)); err != nil {
+			if os.IsExist(err) {
+				return nil // visited twice because of the layout migration
+			}
+			return err
+		}
+
 		sha512HexBytes, err := hex.DecodeString(sha512Hex)
 		if err != nil {
 			return err
@@ -188,25 +219,32 @@ func (s *snapshot) Create(targetSnapshot *time.Time) (snapshotTime *time.Time, s
 			return err
 		}
 
-		if err := binary.Write(headerFile, binary.LittleEndian, blockFile.Usage()); err != nil {
-			return err
-		}
-
-		return os.Link(path.Join(s.rootPath, sha512Hex), path.Join(nextSnapshotPath, sha512Hex))
+		return binary.Write(headerFile, binary.LittleEndian, blockFile.Usage())
 	}); err != nil {
 		return nil, err
 	}
 
+	if err := block.LinkPack(s.rootPath, nextSnapshotPath, s.logger); err != nil {
+		return nil, err
+	}
+
 	s.logger.Info("Snapshot creation is completed")
 
 	return &nextSnapshot, nil
 }
 
+func (s *snapshot) link(sourcePath string, targetPath string) error {
+	if err := os.MkdirAll(path.Dir(targetPath), 0777); err != nil {
+		return err
+	}
+	return os.Link(sourcePath, targetPath)
+}
+
 func (s *snapshot) Delete(targetSnapshot time.Time) error {
 	targetSnapshotPathName := s.PathName(targetSnapshot)
 	targetSnapshotPath := path.Join(s.rootPath, targetSnapshotPathName)
 
-	return os.RemoveAll(targetSnapshotPath)
+	return block.RemovePath(targetSnapshotPath, s.logger)
 }
 
 func (s *snapshot) Restore(sourceSnapshot time.Time) error {
@@ -223,7 +261,7 @@ func (s *snapshot) Restore(sourceSnapshot time.Time) error {
 		return err
 	}
 
-	targetBlock, err := block.NewManager(s.rootPath, s.logger)
+	targetBlock, err := block.NewManager(s.rootPath, s.packLimit, s.logger)
 	if err != nil {
 		return err
 	}
@@ -233,20 +271,17 @@ func (s *snapshot) Restore(sourceSnapshot time.Time) error {
 		return err
 	}
 
-	sourceBlock, err := block.NewManager(sourceSnapshotPath, s.logger)
-	if err != nil {
-		return err
-	}
-
 	if err := targetBlock.Wipe(); err != nil {
 		return err
 	}
 
-	if err := sourceBlock.Traverse(func(sha512Hex string) error {
-		sourceFilePath := path.Join(sourceSnapshotPath, sha512Hex)
-		targetFilePath := path.Join(s.rootPath, sha512Hex)
+	err = dnc.Traverse(sourceSnapshotPath, func(filePath string, info os.FileInfo) error {
+		sha512Hex := info.Name()
 
-		if err := os.Link(sourceFilePath, targetFilePath); err != nil {
+		if err := s.link(filePath, dnc.BlockPath(s.rootPath, sha512Hex)); err != nil {
+			if os.IsExist(err) {
+				return nil // visited twice because of the layout migration
+			}
 			return err
 		}
 
@@ -261,7 +296,11 @@ func (s *snapshot) Restore(sourceSnapshot time.Time) error {
 			}
 			return targetFile.ResetUsage(usage)
 		})
-	}); err != nil {
+	})
+	if err == nil {
+		err = block.LinkPack(sourceSnapshotPath, s.rootPath, s.logger)
+	}
+	if err != nil {
 		s.logger.Error(
 			fmt.Sprintf("Restoring snapshot (%d) is failed", s.ToUint(sourceSnapshot)),
 			zap.Error(err),
@@ -287,7 +326,7 @@ func (s *snapshot) Block(snapshot time.Time) (block.Manager, error) {
 		snapshotPath := path.Join(s.rootPath, snapshotPathName)
 
 		var err error
-		b, err = block.NewManager(snapshotPath, s.logger)
+		b, err = block.NewManager(snapshotPath, s.packLimit, s.logger)
 		if err != nil {
 			return nil, err
 		}
@@ -336,10 +375,22 @@ func (s *snapshot) Dates() (common.Snapshots, error) {
 }
 
 func (s *snapshot) PathName(snapshot time.Time) string {
-	return fmt.Sprintf("%s%s", snapshotPrefix, snapshot.Format(common.MachineTimeFormatWithSeconds))
+	return snapshotPathName(snapshot)
 }
 
 func (s *snapshot) FromUint(snapshotUint uint64) (*time.Time, error) {
+	return snapshotFromUint(snapshotUint)
+}
+
+func (s *snapshot) ToUint(snapshot time.Time) uint64 {
+	return snapshotToUint(snapshot)
+}
+
+func snapshotPathName(snapshot time.Time) string {
+	return fmt.Sprintf("%s%s", snapshotPrefix, snapshot.Format(common.MachineTimeFormatWithSeconds))
+}
+
+func snapshotFromUint(snapshotUint uint64) (*time.Time, error) {
 	snapshotTime, err := time.Parse(common.MachineTimeFormatWithSeconds, strconv.FormatUint(snapshotUint, 10))
 	if err != nil {
 		return nil, err
@@ -347,7 +398,7 @@ func (s *snapshot) FromUint(snapshotUint uint64) (*time.Time, error) {
 	return &snapshotTime, nil
 }
 
-func (s *snapshot) ToUint(snapshot time.Time) uint64 {
+func snapshotToUint(snapshot time.Time) uint64 {
 	snapshotUint, _ := strconv.ParseUint(snapshot.Format(common.MachineTimeFormatWithSeconds), 10, 64)
 	return snapshotUint
 }
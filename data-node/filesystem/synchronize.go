@@ -2,15 +2,12 @@ package filesystem
 
 import (
 	"fmt"
-	"os"
-	"path"
 	"sync"
 	"time"
 
 	"github.com/freakmaxi/kertish-dfs/basics/common"
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
 	"github.com/freakmaxi/kertish-dfs/data-node/cluster"
-	dnc "github.com/freakmaxi/kertish-dfs/data-node/common"
 	"github.com/freakmaxi/kertish-dfs/data-node/filesystem/block"
 	"go.uber.org/zap"
 )
@@ -34,7 +31,7 @@ type queueItem struct {
 }
 
 type synchronize struct {
-	rootPath string
+	block    block.Manager
 	snapshot Snapshot
 	logger   *zap.Logger
 
@@ -45,9 +42,9 @@ type synchronize struct {
 	syncChan  chan queueItem
 }
 
-func NewSynchronize(rootPath string, snapshot Snapshot, logger *zap.Logger) (Synchronize, error) {
+func NewSynchronize(b block.Manager, snapshot Snapshot, logger *zap.Logger) Synchronize {
 	s := &synchronize{
-		rootPath: rootPath,
+		block:    b,
 		snapshot: snapshot,
 		logger:   logger,
 
@@ -57,25 +54,25 @@ func NewSynchronize(rootPath string, snapshot Snapshot, logger *zap.Logger) (Syn
 		syncMutex: sync.Mutex{},
 		syncChan:  make(chan queueItem, queueSize),
 	}
-	if err := s.start(); err != nil {
-		return nil, err
-	}
-	return s, nil
-}
+	s.start()
 
-func (s *synchronize) start() error {
-	b, err := block.NewManager(s.rootPath, s.logger)
-	if err != nil {
-		return err
-	}
+	return s
+}
 
+func (s *synchronize) start() {
 	go func() {
 		for nextItem := range s.syncChan {
-			s.consumeSyncQueue(b, nextItem)
+			s.consumeSyncQueue(s.block, nextItem)
 		}
 	}()
+}
 
-	return nil
+// blocks returns the block manager of the root or the snapshot
+func (s *synchronize) blocks(snapshotTime *time.Time) (block.Manager, error) {
+	if snapshotTime == nil {
+		return s.block, nil
+	}
+	return s.snapshot.Block(*snapshotTime)
 }
 
 func (s *synchronize) consumeSyncQueue(b block.Manager, firstItem queueItem) {
@@ -106,6 +103,8 @@ func (s *synchronize) getSourceDataNode(sourceAddr string) (cluster.DataNode, er
 }
 
 func (s *synchronize) processQueueItem(b block.Manager, item queueItem) {
+	defer syncQueueDepth.Dec()
+
 	if !item.create {
 		if err := s.deleteBlockFile(b, common.SyncFileItem{Sha512Hex: item.sha512Hex}, true); err != nil {
 			s.logger.Error(
@@ -148,27 +147,22 @@ func (s *synchronize) List(snapshotTime *time.Time, itemHandler func(fileItem *c
 	s.syncMutex.Lock()
 	defer s.syncMutex.Unlock()
 
-	dataPath := s.rootPath
-	headerMap := make(HeaderMap)
+	b, err := s.blocks(snapshotTime)
+	if err != nil {
+		return err
+	}
 
+	headerMap := make(HeaderMap)
 	if snapshotTime != nil {
-		snapshotPathName := s.snapshot.PathName(*snapshotTime)
-		dataPath = path.Join(dataPath, snapshotPathName)
-
 		headerMap, _ = s.snapshot.ReadHeaderBackup(*snapshotTime)
 	}
 
-	return s.iterateFileItems(dataPath, headerMap, itemHandler)
+	return s.iterateFileItems(b, headerMap, itemHandler)
 }
 
-func (s *synchronize) iterateFileItems(dataPath string, headerMap HeaderMap, itemHandler func(fileItem *common.SyncFileItem) error) error {
-	b, err := block.NewManager(dataPath, s.logger)
-	if err != nil {
-		return err
-	}
-
-	return dnc.Traverse(dataPath, func(info os.FileInfo) error {
-		return b.File(info.Name(), func(file block.File) error {
+func (s *synchronize) iterateFileItems(b block.Manager, headerMap HeaderMap, itemHandler func(fileItem *common.SyncFileItem) error) error {
+	return b.Traverse(func(sha512Hex string) error {
+		return b.File(sha512Hex, func(file block.File) error {
 			size, err := file.Size()
 			if err != nil {
 				return err
@@ -191,6 +185,7 @@ func (s *synchronize) iterateFileItems(dataPath string, headerMap HeaderMap, ite
 }
 
 func (s *synchronize) Create(sourceAddr string, sha512Hex string) {
+	syncQueueDepth.Inc()
 	s.syncChan <- queueItem{
 		sourceAddr: &sourceAddr,
 		sha512Hex:  sha512Hex,
@@ -199,6 +194,7 @@ func (s *synchronize) Create(sourceAddr string, sha512Hex string) {
 }
 
 func (s *synchronize) Delete(sha512Hex string) {
+	syncQueueDepth.Inc()
 	s.syncChan <- queueItem{
 		sourceAddr: nil,
 		sha512Hex:  sha512Hex,
@@ -241,12 +237,13 @@ func (s *synchronize) Full(sourceAddr string) error {
 func (s *synchronize) syncFileItems(sourceNode cluster.DataNode, snapshotTime *time.Time, sourceFileItems common.SyncFileItemMap) error {
 	syncLoc := "ROOT"
 
-	dataPath := s.rootPath
+	b, err := s.blocks(snapshotTime)
+	if err != nil {
+		return err
+	}
+
 	headerMap := make(HeaderMap)
 	if snapshotTime != nil {
-		snapshotPathName := s.snapshot.PathName(*snapshotTime)
-		dataPath = path.Join(dataPath, snapshotPathName)
-
 		headerMap, _ = s.snapshot.ReadHeaderBackup(*snapshotTime)
 		syncLoc = fmt.Sprintf("SNAPSHOT %s", snapshotTime.Format(common.FriendlyTimeFormatWithSeconds))
 	}
@@ -255,7 +252,7 @@ func (s *synchronize) syncFileItems(sourceNode cluster.DataNode, snapshotTime *t
 	createList := make(common.SyncFileItemList, 0)
 	sourceHeaderMap := make(HeaderMap)
 
-	if err := s.iterateFileItems(dataPath, headerMap, func(fileItem *common.SyncFileItem) error {
+	if err := s.iterateFileItems(b, headerMap, func(fileItem *common.SyncFileItem) error {
 		sourceFileItem, has := sourceFileItems[fileItem.Sha512Hex]
 		if !has {
 			wipeList = append(wipeList, *fileItem)
@@ -287,11 +284,6 @@ func (s *synchronize) syncFileItems(sourceNode cluster.DataNode, snapshotTime *t
 
 	s.logger.Info(fmt.Sprintf("Sync (%s) will, create: %d / delete: %d", syncLoc, len(createList), len(wipeList)))
 
-	b, err := block.NewManager(dataPath, s.logger)
-	if err != nil {
-		return err
-	}
-
 	wg := &sync.WaitGroup{}
 
 	wg.Add(1)
@@ -502,7 +494,7 @@ func (s *synchronize) createBlockFile(sourceNode cluster.DataNode, snapshotTime
 			func(data []byte) error {
 				return blockFile.Write(data)
 			},
-			func(usage uint16) bool {
+			func(usage uint32) bool {
 				if err := blockFile.ResetUsage(usage); err != nil {
 					return false
 				}
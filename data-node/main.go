@@ -4,14 +4,18 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
 	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/freakmaxi/kertish-dfs/basics/log"
+	"github.com/freakmaxi/kertish-dfs/basics/tracing"
 	"github.com/freakmaxi/kertish-dfs/data-node/cache"
 	"github.com/freakmaxi/kertish-dfs/data-node/filesystem"
+	"github.com/freakmaxi/kertish-dfs/data-node/filesystem/block"
 	"github.com/freakmaxi/kertish-dfs/data-node/manager"
 	"github.com/freakmaxi/kertish-dfs/data-node/service"
 	"go.uber.org/zap"
@@ -60,29 +64,119 @@ func main() {
 		logger.Error("SIZE have to be specified")
 		os.Exit(50)
 	}
-	size, err := strconv.ParseUint(sizeString, 10, 64)
-	if err != nil {
-		logger.Error("File System size is wrong", zap.Error(err))
-		os.Exit(51)
-	}
-	if size == 0 {
-		logger.Error("File System size can not be 0")
-		os.Exit(52)
+	sizes := make([]uint64, 0)
+	size := uint64(0)
+	for _, diskSizeString := range strings.Split(sizeString, ",") {
+		diskSize, err := strconv.ParseUint(strings.TrimSpace(diskSizeString), 10, 64)
+		if err != nil {
+			logger.Error("File System size is wrong", zap.Error(err))
+			os.Exit(51)
+		}
+		if diskSize == 0 {
+			logger.Error("File System size can not be 0")
+			os.Exit(52)
+		}
+		sizes = append(sizes, diskSize)
+		size += diskSize
 	}
 	logger.Info(fmt.Sprintf("SIZE: %s (%s Gb)", sizeString, strconv.FormatUint(size/(1024*1024*1024), 10)))
 
+	metricsBindAddr := os.Getenv("METRICS_BIND_ADDRESS")
+	if matched, err := regexp.MatchString(`:\d{1,5}$`, metricsBindAddr); err != nil || !matched {
+		metricsBindAddr = fmt.Sprintf("%s:9431", metricsBindAddr)
+	}
+	logger.Info(fmt.Sprintf("METRICS_BIND_ADDRESS: %s", metricsBindAddr))
+
+	tracingOutput := os.Getenv("TRACING_OUTPUT")
+	if len(tracingOutput) > 0 {
+		tracingTarget := os.Getenv("TRACING_TARGET")
+		logger.Info(fmt.Sprintf("TRACING_OUTPUT: %s", tracingOutput))
+		logger.Info(fmt.Sprintf("TRACING_TARGET: %s", tracingTarget))
+
+		if err := tracing.Setup("data-node", tracingOutput, tracingTarget, logger); err != nil {
+			logger.Error("Tracing setup is failed", zap.Error(err))
+			os.Exit(60)
+		}
+	}
+
 	rootPath := os.Getenv("ROOT_PATH")
 	if len(rootPath) == 0 {
 		rootPath = "/opt"
 	}
 	logger.Info(fmt.Sprintf("ROOT_PATH: %s", rootPath))
 
-	m, err := filesystem.NewManager(rootPath, logger)
+	rootPaths := strings.Split(rootPath, ",")
+	if len(rootPaths) != len(sizes) {
+		logger.Error("ROOT_PATH and SIZE should define the same count of disks")
+		os.Exit(53)
+	}
+	disks := make([]block.Disk, 0, len(rootPaths))
+	for i, diskPath := range rootPaths {
+		disks = append(disks, block.Disk{Path: strings.TrimSpace(diskPath), Size: sizes[i]})
+	}
+
+	placement := os.Getenv("PLACEMENT")
+	if len(placement) == 0 {
+		placement = block.PlacementHash
+	}
+	if len(disks) > 1 {
+		logger.Info(fmt.Sprintf("PLACEMENT: %s", placement))
+	}
+
+	packLimitString := os.Getenv("PACK_LIMIT")
+	if len(packLimitString) == 0 {
+		packLimitString = "0"
+	}
+	packLimit, err := strconv.ParseUint(packLimitString, 10, 32)
+	if err != nil {
+		logger.Error("Pack Limit size is wrong", zap.Error(err))
+		os.Exit(70)
+	}
+	if packLimit > 0 {
+		logger.Info(fmt.Sprintf("PACK_LIMIT: %s (%s Kb)", packLimitString, strconv.FormatUint(packLimit/1024, 10)))
+	}
+
+	scrubIntervalString := os.Getenv("SCRUB_INTERVAL")
+	if len(scrubIntervalString) == 0 {
+		scrubIntervalString = "168"
+	}
+	scrubInterval, err := strconv.ParseUint(scrubIntervalString, 10, 64)
+	if err != nil {
+		logger.Error("Scrub Interval is wrong", zap.Error(err))
+		os.Exit(71)
+	}
+	if scrubInterval > 0 {
+		logger.Info(fmt.Sprintf("SCRUB_INTERVAL: %s hour(s)", scrubIntervalString))
+	} else {
+		logger.Info("SCRUB_INTERVAL: disabled")
+	}
+
+	n := manager.NewNode(strings.Split(managerAddress, ","), size, logger)
+
+	m, err := filesystem.NewManager(disks, placement, uint32(packLimit), time.Hour*time.Duration(scrubInterval), func(sha512HexList []string) {
+		if err := n.Missing(sha512HexList); err != nil {
+			logger.Error("Reporting missing blocks is failed", zap.Int("count", len(sha512HexList)), zap.Error(err))
+		}
+	}, func() {
+		if err := n.Repair(); err != nil {
+			logger.Error("Requesting node repair is failed", zap.Error(err))
+		}
+	}, logger)
 	if err != nil {
 		logger.Error("File System Manager creation is failed", zap.Error(err))
 		os.Exit(80)
 	}
-	n := manager.NewNode(strings.Split(managerAddress, ","), size, logger)
+
+	// used spaces are saved as clean only on the shutdown by the signal, they are reconciled after a crash
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+
+		logger.Info("Data Node is shutting down...")
+		m.Shutdown()
+		os.Exit(0)
+	}()
 
 	cacheLifetime := 360
 	cacheLimitString := os.Getenv("CACHE_LIMIT")
@@ -113,9 +207,60 @@ func main() {
 			os.Exit(131)
 		}
 		logger.Info(fmt.Sprintf("CACHE_LIFETIME: %s min.", ccLifetimeString))
+		cacheLifetime = int(ccLifetime)
+	}
+
+	cachePolicyString := os.Getenv("CACHE_POLICY")
+	cachePolicy, err := cache.NewPolicy(cachePolicyString)
+	if err != nil {
+		logger.Error("Cache Policy is wrong", zap.Error(err))
+		os.Exit(132)
+	}
+	if cacheLimit > 0 {
+		logger.Info(fmt.Sprintf("CACHE_POLICY: %s", cachePolicy.Name()))
+	}
+
+	cc := cache.NewContainer(cacheLimit, time.Minute*time.Duration(cacheLifetime), cachePolicy, logger)
+
+	cacheDiskPath := os.Getenv("CACHE_DISK_PATH")
+	if len(cacheDiskPath) > 0 {
+		logger.Info(fmt.Sprintf("CACHE_DISK_PATH: %s", cacheDiskPath))
+
+		cacheDiskLimitString := os.Getenv("CACHE_DISK_LIMIT")
+		cacheDiskLimit, err := strconv.ParseUint(cacheDiskLimitString, 10, 64)
+		if err != nil || cacheDiskLimit == 0 {
+			logger.Error("Disk Cache Limit size is wrong", zap.Error(err))
+			os.Exit(140)
+		}
+		logger.Info(fmt.Sprintf("CACHE_DISK_LIMIT: %s (%s Gb)", cacheDiskLimitString, strconv.FormatUint(cacheDiskLimit/(1024*1024*1024), 10)))
+
+		dc, err := cache.NewDiskContainer(cacheDiskPath, cacheDiskLimit, logger)
+		if err != nil {
+			logger.Error("Disk Cache creation is failed", zap.Error(err))
+			os.Exit(141)
+		}
+		cc = cache.NewTieredContainer(cc, dc)
+	}
+
+	cacheBlockLimitString := os.Getenv("CACHE_BLOCK_LIMIT")
+	if len(cacheBlockLimitString) > 0 {
+		cacheBlockLimit, err := strconv.ParseUint(cacheBlockLimitString, 10, 32)
+		if err != nil || cacheBlockLimit == 0 {
+			logger.Error("Cache Block Limit size is wrong", zap.Error(err))
+			os.Exit(133)
+		}
+		logger.Info(fmt.Sprintf("CACHE_BLOCK_LIMIT: %s (%s Mb)", cacheBlockLimitString, strconv.FormatUint(cacheBlockLimit/(1024*1024), 10)))
+
+		cc = cache.NewBlockLimitedContainer(cc, cacheBlockLimit)
 	}
 
-	cc := cache.NewContainer(cacheLimit, time.Minute*time.Duration(cacheLifetime), logger)
+	cachePinnedString := os.Getenv("CACHE_PINNED")
+	if len(cachePinnedString) > 0 {
+		for _, sha512Hex := range strings.Split(cachePinnedString, ",") {
+			cc.Pin(strings.TrimSpace(sha512Hex))
+		}
+		logger.Info(fmt.Sprintf("CACHE_PINNED: %s", cachePinnedString))
+	}
 
 	c, err := service.NewCommander(m, cc, n, logger, hardwareAddr)
 	if err != nil {
@@ -129,6 +274,17 @@ func main() {
 		os.Exit(300)
 	}
 
+	ms, err := service.NewMetricsServer(metricsBindAddr, logger)
+	if err != nil {
+		logger.Error("Metrics Server creation is failed", zap.Error(err))
+		os.Exit(310)
+	}
+	go func() {
+		if err := ms.Listen(); err != nil {
+			logger.Error("Metrics Server listening is failed", zap.Error(err))
+		}
+	}()
+
 	logger.Info("Waiting for handshake...")
 	if err := n.Handshake(hardwareAddr, bindAddr, size); err != nil {
 		logger.Error("Handshake is failed", zap.Error(err))
@@ -25,7 +25,9 @@ type Node interface {
 	Leave()
 	Handshake(hardwareAddr string, bindAddr string, size uint64) error
 
-	Notify(sha512Hex string, usage uint16, size uint32, shadow bool, create bool) <-chan bool
+	Notify(sha512Hex string, usage uint32, size uint32, shadow bool, create bool) <-chan bool
+	Missing(sha512HexList []string) error
+	Repair() error
 
 	ClusterId() string
 	NodeId() string
@@ -287,7 +289,7 @@ func (n *node) Handshake(hardwareAddr string, bindAddr string, size uint64) erro
 	return nil
 }
 
-func (n *node) Notify(sha512Hex string, usage uint16, size uint32, shadow bool, create bool) <-chan bool {
+func (n *node) Notify(sha512Hex string, usage uint32, size uint32, shadow bool, create bool) <-chan bool {
 	responseChan := make(chan bool, 1)
 
 	n.notificationChan <- common.NotificationContainer{
@@ -304,6 +306,62 @@ func (n *node) Notify(sha512Hex string, usage uint16, size uint32, shadow bool,
 	return responseChan
 }
 
+// Missing reports the blocks that are lost on the data node, manager syncs them again from the other nodes
+func (n *node) Missing(sha512HexList []string) error {
+	body, err := json.Marshal(sha512HexList)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s%s", n.managerAddr[0], managerEndPoint), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Action", "missing")
+	req.Header.Set("X-Options", n.nodeId)
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != 202 {
+		if res.StatusCode == 404 {
+			return fmt.Errorf("data node is not registered")
+		}
+		return fmt.Errorf("node manager request is failed (Missing): %d - %s", res.StatusCode, common.NewErrorFromReader(res.Body).Message)
+	}
+
+	return nil
+}
+
+// Repair requests the manager to find the blocks that are lost on the data node, it is used when the lost blocks can
+// not be listed
+func (n *node) Repair() error {
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s%s", n.managerAddr[0], managerEndPoint), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Action", "repair")
+	req.Header.Set("X-Options", n.nodeId)
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != 202 {
+		if res.StatusCode == 404 {
+			return fmt.Errorf("data node is not registered")
+		}
+		return fmt.Errorf("node manager request is failed (Repair): %d - %s", res.StatusCode, common.NewErrorFromReader(res.Body).Message)
+	}
+
+	return nil
+}
+
 func (n *node) ClusterId() string {
 	return n.clusterId
 }
@@ -1,17 +1,21 @@
 package service
 
 import (
+	"context"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/freakmaxi/kertish-dfs/basics/common"
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/basics/protocol"
+	"github.com/freakmaxi/kertish-dfs/basics/tracing"
 	"github.com/freakmaxi/kertish-dfs/data-node/cache"
 	"github.com/freakmaxi/kertish-dfs/data-node/cluster"
 	"github.com/freakmaxi/kertish-dfs/data-node/filesystem"
@@ -23,6 +27,7 @@ import (
 const commandBuffer = 4             // 4b
 const defaultTransferSpeed = 625000 // bytes/s
 const notificationWaitDuration = time.Second * 30
+const keepAliveTimeout = time.Second * 60
 
 type Commander interface {
 	Handler(net.Conn)
@@ -101,30 +106,137 @@ func (c *commander) Handler(conn net.Conn) {
 		return
 	}
 
-	if err := c.process(string(buffer), conn); err != nil {
+	peer := protocol.Legacy
+	if strings.Compare(string(buffer), protocol.HandshakeCommand) == 0 {
+		var err error
+		peer, err = c.handshake(conn, buffer)
+		if err != nil {
+			c.logger.Error(
+				"Stream unable to handshake",
+				zap.String("connection", conn.RemoteAddr().String()),
+				zap.Error(err),
+			)
+			return
+		}
+	}
+
+	for {
+		if !c.handle(conn, peer, buffer) || !peer.Supports(protocol.CapKeepAlive) {
+			return
+		}
+
+		// wait for the next command of the client, closing the idle connection is not a failure
+		if err := conn.SetDeadline(time.Now().Add(keepAliveTimeout)); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buffer); err != nil {
+			return
+		}
+	}
+}
+
+// handle processes the command in the buffer, it returns true if the connection stays consistent for the next command
+func (c *commander) handle(conn net.Conn, peer protocol.Peer, buffer []byte) bool {
+	ctx := context.Background()
+	if strings.Compare(string(buffer), tracing.BinaryCommand) == 0 {
+		var err error
+		ctx, err = c.readTraceContext(ctx, conn, buffer)
+		if err != nil {
+			c.logger.Error(
+				"Stream unable to read trace context",
+				zap.String("connection", conn.RemoteAddr().String()),
+				zap.Error(err),
+			)
+			return false
+		}
+	}
+
+	command := string(buffer)
+	mConn := &meteredConn{Conn: conn}
+
+	_, span := tracing.Start(ctx, fmt.Sprintf("commander.%s", command))
+
+	begins := time.Now()
+	err := c.process(command, mConn, peer)
+	observeCommand(command, mConn, time.Since(begins), err)
+
+	span.SetAttribute("bytesIn", strconv.FormatUint(mConn.in, 10))
+	span.SetAttribute("bytesOut", strconv.FormatUint(mConn.out, 10))
+	if err != errors.ErrQuit {
+		span.SetError(err)
+	}
+	span.End()
+
+	if err != nil {
 		if err != errors.ErrQuit {
 			c.logger.Error(
 				"Unable to process command",
-				zap.String("command", string(buffer)),
+				zap.String("command", command),
 				zap.String("connection", conn.RemoteAddr().String()),
 				zap.Error(err),
 			)
 		}
-		_ = c.writeWithTimeout(conn, []byte("-"))
-		return
+		if c.setDeadline(conn, 0) != nil {
+			return false
+		}
+		return protocol.WriteFailure(conn, peer, err) == nil && err == errors.ErrQuit
+	}
+
+	return c.writeWithTimeout(conn, []byte("+")) == nil
+}
+
+// handshake negotiates the protocol with the client and reads the actual command into the buffer
+func (c *commander) handshake(conn net.Conn, buffer []byte) (protocol.Peer, error) {
+	if err := c.setDeadline(conn, 0); err != nil {
+		return protocol.Legacy, err
+	}
+
+	remote, err := protocol.ReadPeer(conn)
+	if err != nil {
+		return protocol.Legacy, err
+	}
+
+	if err := protocol.WritePeer(conn); err != nil {
+		return protocol.Legacy, err
+	}
+
+	if err := c.readWithTimeout(conn, buffer, len(buffer)); err != nil {
+		return protocol.Legacy, err
+	}
+
+	return protocol.Local.Negotiate(remote), nil
+}
+
+// readTraceContext reads the span context that prefixes the command and the actual command into the buffer
+func (c *commander) readTraceContext(ctx context.Context, conn net.Conn, buffer []byte) (context.Context, error) {
+	if err := c.setDeadline(conn, 0); err != nil {
+		return ctx, err
+	}
+
+	sc, err := tracing.ReadBinary(conn)
+	if err != nil {
+		return ctx, err
+	}
+
+	if err := c.readWithTimeout(conn, buffer, len(buffer)); err != nil {
+		return ctx, err
 	}
 
-	_ = c.writeWithTimeout(conn, []byte("+"))
+	return tracing.ContextWithSpanContext(ctx, sc), nil
 }
 
-func (c *commander) process(command string, conn net.Conn) error {
+func (c *commander) process(command string, conn net.Conn, peer protocol.Peer) error {
 	switch command {
 	case "CREA":
 		return c.crea(conn)
 	case "READ":
 		return c.read(conn)
+	case "RDRN":
+		return c.rdrn(conn)
 	case "DELE":
 		return c.dele(conn)
+	case "EXST":
+		return c.exst(conn)
 	case "HWID":
 		return c.hwid(conn)
 	case "JOIN":
@@ -136,17 +248,17 @@ func (c *commander) process(command string, conn net.Conn) error {
 	case "SYCR":
 		return c.sycr(conn)
 	case "SYRD":
-		return c.syrd(conn)
+		return c.syrd(conn, peer)
 	case "SYDE":
 		return c.syde(conn)
 	case "SYMV":
 		return c.symv(conn)
 	case "SYLS":
-		return c.syls(conn)
+		return c.syls(conn, peer)
 	case "SYFL":
 		return c.syfl(conn)
 	case "SYUS":
-		return c.syus(conn)
+		return c.syus(conn, peer)
 	case "SSCR":
 		return c.sscr()
 	case "SSDE":
@@ -162,7 +274,7 @@ func (c *commander) process(command string, conn net.Conn) error {
 	case "PING":
 		return nil
 	default:
-		return fmt.Errorf("not a meaningful command")
+		return protocol.ErrUnknownCommand
 	}
 }
 
@@ -190,7 +302,7 @@ func (c *commander) crea(conn net.Conn) error {
 		return err
 	}
 
-	var blockUsage uint16 = 1
+	var blockUsage uint32 = 1
 	var blockSize uint32
 
 	err = c.fs.Block().LockFile(sha512Hex, func(blockFile block.File) error {
@@ -226,11 +338,13 @@ func (c *commander) crea(conn net.Conn) error {
 		}
 
 		if !blockFile.Verify() {
-			return fmt.Errorf("file is not verified")
+			return errors.ErrVerify
 		}
 
 		// Add to the cache in go routine
-		go c.cache.Upsert(sha512Hex, chunkBuffer)
+		if c.cache.Accepts(uint64(blockSize)) {
+			go c.cache.Upsert(sha512Hex, chunkBuffer)
+		}
 
 		return nil
 	})
@@ -305,20 +419,113 @@ func (c *commander) read(conn net.Conn) error {
 			return err
 		}
 
+		cacheable := c.cache.Accepts(uint64(size))
+
 		cacheData := make([]byte, 0)
 		return blockFile.Read(
 			func(data []byte) error {
 				// Compile For Cache
-				cacheData = append(cacheData, data...)
+				if cacheable {
+					cacheData = append(cacheData, data...)
+				}
 
 				return c.writeWithTimeout(conn, data)
 			},
 			func() error {
 				// Add/Update Cache
-				c.cache.Upsert(sha512Hex, cacheData)
+				if cacheable {
+					c.cache.Upsert(sha512Hex, cacheData)
+				}
+
+				return nil
+			})
+	})
+}
+
+func (c *commander) rdrn(conn net.Conn) error {
+	sha512Hex, err := c.hashAsHex(conn)
+	if err != nil {
+		return err
+	}
+
+	var offset, length uint32
+	if err := c.readBinaryWithTimeout(conn, &offset); err != nil {
+		return err
+	}
+	if err := c.readBinaryWithTimeout(conn, &length); err != nil {
+		return err
+	}
+
+	// Check cache first
+	if content := c.cache.Query(sha512Hex); content != nil {
+		if uint64(offset)+uint64(length) > uint64(len(content)) {
+			return errors.ErrOutOfRange
+		}
+
+		if err := c.writeWithTimeout(conn, []byte{'+'}); err != nil {
+			return err
+		}
+
+		if err := c.writeBinaryWithTimeout(conn, length); err != nil {
+			return err
+		}
+
+		return c.writeWithTimeout(conn, content[offset:offset+length])
+	}
+
+	return c.fs.Block().File(sha512Hex, func(blockFile block.File) error {
+		if blockFile.Temporary() {
+			return os.ErrNotExist
+		}
+
+		size, err := blockFile.Size()
+		if err != nil {
+			return err
+		}
+
+		if uint64(offset)+uint64(length) > uint64(size) {
+			return errors.ErrOutOfRange
+		}
+
+		if err := blockFile.SeekData(int64(offset)); err != nil {
+			return err
+		}
+
+		if err := c.writeWithTimeout(conn, []byte{'+'}); err != nil {
+			return err
+		}
 
+		if err := c.writeBinaryWithTimeout(conn, length); err != nil {
+			return err
+		}
+
+		remains := length
+		if remains == 0 {
+			return nil
+		}
+
+		err = blockFile.Read(
+			func(data []byte) error {
+				if uint32(len(data)) > remains {
+					data = data[:remains]
+				}
+				if err := c.writeWithTimeout(conn, data); err != nil {
+					return err
+				}
+
+				remains -= uint32(len(data))
+				if remains == 0 {
+					return io.EOF
+				}
 				return nil
+			},
+			func() error {
+				return io.ErrUnexpectedEOF
 			})
+		if err == io.EOF {
+			return nil
+		}
+		return err
 	})
 }
 
@@ -328,7 +535,7 @@ func (c *commander) dele(conn net.Conn) error {
 		return err
 	}
 
-	var blockUsage uint16
+	var blockUsage uint32
 	var blockSize uint32
 
 	if err := c.fs.Block().LockFile(sha512Hex, func(blockFile block.File) error {
@@ -383,6 +590,21 @@ func (c *commander) dele(conn net.Conn) error {
 	}
 }
 
+// exst reports if the block is placed on the data node, missing block is not a failure of the command
+func (c *commander) exst(conn net.Conn) error {
+	sha512Hex, err := c.hashAsHex(conn)
+	if err != nil {
+		return err
+	}
+
+	return c.fs.Block().File(sha512Hex, func(blockFile block.File) error {
+		if blockFile.Temporary() {
+			return errors.ErrQuit
+		}
+		return nil
+	})
+}
+
 func (c *commander) hwid(conn net.Conn) error {
 	if err := c.writeWithTimeout(conn, []byte{'+'}); err != nil {
 		return err
@@ -475,7 +697,7 @@ func (c *commander) sycr(conn net.Conn) error {
 	})
 }
 
-func (c *commander) syrd(conn net.Conn) error {
+func (c *commander) syrd(conn net.Conn, peer protocol.Peer) error {
 	sha512Hex, err := c.hashAsHex(conn)
 	if err != nil {
 		return err
@@ -523,8 +745,7 @@ func (c *commander) syrd(conn net.Conn) error {
 			return err
 		}
 
-		usage := blockFile.Usage()
-		if err := c.writeBinaryWithTimeout(conn, usage); err != nil {
+		if err := c.writeWithTimeout(conn, protocol.EncodeUsage(peer, blockFile.Usage())); err != nil {
 			return err
 		}
 
@@ -589,7 +810,7 @@ func (c *commander) symv(conn net.Conn) error {
 			func(data []byte) error {
 				return blockFile.Write(data)
 			},
-			func(usage uint16) bool {
+			func(usage uint32) bool {
 				if err := blockFile.ResetUsage(usage); err != nil {
 					return false
 				}
@@ -598,7 +819,7 @@ func (c *commander) symv(conn net.Conn) error {
 	})
 }
 
-func (c *commander) syls(conn net.Conn) error {
+func (c *commander) syls(conn net.Conn, peer protocol.Peer) error {
 	var snapshotTimeUint uint64
 	if err := c.readBinaryWithTimeout(conn, &snapshotTimeUint); err != nil {
 		return err
@@ -649,7 +870,7 @@ func (c *commander) syls(conn net.Conn) error {
 				return err
 			}
 
-			if err := c.writeBinaryWithTimeout(conn, fileItem.Usage); err != nil {
+			if err := c.writeWithTimeout(conn, protocol.EncodeUsage(peer, fileItem.Usage)); err != nil {
 				return err
 			}
 
@@ -699,7 +920,7 @@ func (c *commander) syfl(conn net.Conn) error {
 	return nil
 }
 
-func (c *commander) syus(conn net.Conn) error {
+func (c *commander) syus(conn net.Conn, peer protocol.Peer) error {
 	for {
 		sha512Hex, err := c.hashAsHex(conn)
 		if err != nil {
@@ -710,10 +931,11 @@ func (c *commander) syus(conn net.Conn) error {
 			return nil
 		}
 
-		var usage uint16
-		if err := c.readBinaryWithTimeout(conn, &usage); err != nil {
+		usageBuffer := make([]byte, protocol.UsageSize(peer))
+		if err := c.readWithTimeout(conn, usageBuffer, len(usageBuffer)); err != nil {
 			return err
 		}
+		usage := protocol.DecodeUsage(peer, usageBuffer)
 
 		if err := c.fs.Block().LockFile(sha512Hex, func(blockFile block.File) error {
 			return blockFile.ResetUsage(usage)
@@ -750,7 +972,7 @@ func (c *commander) ssde(conn net.Conn) error {
 			return err
 		}
 		if uint64(len(snapshotDates)) <= snapshotIndex {
-			return fmt.Errorf("snapshot index (%d) is out of range", snapshotIndex)
+			return fmt.Errorf("snapshot index (%d) is %w", snapshotIndex, errors.ErrOutOfRange)
 		}
 		return snapshot.Delete(snapshotDates[snapshotIndex])
 	})
@@ -768,7 +990,7 @@ func (c *commander) ssrs(conn net.Conn) error {
 			return err
 		}
 		if uint64(len(snapshotDates)) <= snapshotIndex {
-			return fmt.Errorf("snapshot index (%d) is out of range", snapshotIndex)
+			return fmt.Errorf("snapshot index (%d) is %w", snapshotIndex, errors.ErrOutOfRange)
 		}
 		return snapshot.Restore(snapshotDates[snapshotIndex])
 	}); err != nil {
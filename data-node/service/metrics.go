@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/basics/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+var (
+	commandsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "commands_total",
+		Help:      "Number of processed commands by command and result",
+	}, []string{"command", "result"})
+	commandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "command_duration_seconds",
+		Help:      "Latency of processed commands by command",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"command"})
+	commandBytesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kertish",
+		Subsystem: "data",
+		Name:      "command_bytes_total",
+		Help:      "Transferred bytes by command and direction, in or out",
+	}, []string{"command", "direction"})
+)
+
+// meteredConn counts the bytes transferred on the connection while a command is being processed
+type meteredConn struct {
+	net.Conn
+
+	in  uint64
+	out uint64
+}
+
+func (m *meteredConn) Read(b []byte) (int, error) {
+	n, err := m.Conn.Read(b)
+	m.in += uint64(n)
+	return n, err
+}
+
+func (m *meteredConn) Write(b []byte) (int, error) {
+	n, err := m.Conn.Write(b)
+	m.out += uint64(n)
+	return n, err
+}
+
+func observeCommand(command string, conn *meteredConn, duration time.Duration, err error) {
+	if err == protocol.ErrUnknownCommand {
+		command = "UNKNOWN"
+	}
+
+	result := "success"
+	if err != nil && err != errors.ErrQuit {
+		result = "failure"
+	}
+
+	commandsTotal.WithLabelValues(command, result).Inc()
+	commandDuration.WithLabelValues(command).Observe(duration.Seconds())
+	commandBytesTotal.WithLabelValues(command, "in").Add(float64(conn.in))
+	commandBytesTotal.WithLabelValues(command, "out").Add(float64(conn.out))
+}
+
+type MetricsServer interface {
+	Listen() error
+}
+
+type metricsServer struct {
+	address string
+	logger  *zap.Logger
+}
+
+func NewMetricsServer(address string, logger *zap.Logger) (MetricsServer, error) {
+	if len(address) == 0 {
+		return nil, fmt.Errorf("address should be defined")
+	}
+
+	return &metricsServer{
+		address: address,
+		logger:  logger,
+	}, nil
+}
+
+func (m *metricsServer) Listen() error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	m.logger.Info(fmt.Sprintf("Metrics Service is running on %s", m.address))
+	return http.ListenAndServe(m.address, mux)
+}
+
+var _ MetricsServer = &metricsServer{}
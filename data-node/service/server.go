@@ -3,6 +3,7 @@ package service
 import (
 	"fmt"
 	"net"
+	"sync"
 
 	"go.uber.org/zap"
 )
@@ -17,8 +18,12 @@ type server struct {
 	commander Commander
 	logger    *zap.Logger
 
-	listener *net.TCPListener
-	quiting  bool
+	listenerMutex sync.Mutex
+	listener      *net.TCPListener
+	quiting       bool
+
+	connsMutex sync.Mutex
+	conns      map[net.Conn]struct{}
 }
 
 func NewServer(address string, c Commander, logger *zap.Logger) (Server, error) {
@@ -28,42 +33,93 @@ func NewServer(address string, c Commander, logger *zap.Logger) (Server, error)
 	addr, _ := net.ResolveTCPAddr("tcp4", address)
 
 	return &server{
-		address:   addr,
-		commander: c,
-		logger:    logger,
+		address:       addr,
+		commander:     c,
+		logger:        logger,
+		listenerMutex: sync.Mutex{},
+		conns:         make(map[net.Conn]struct{}),
 	}, nil
 }
 
 func (s *server) Listen() error {
-	var err error
-	s.listener, err = net.ListenTCP("tcp4", s.address)
-	if err != nil {
+	listener, err := s.listen()
+	if err != nil || listener == nil {
 		return err
 	}
 
-	for !s.quiting {
-		c, err := s.listener.Accept()
+	for {
+		c, err := listener.Accept()
 		if err != nil {
+			if s.killed() {
+				return nil
+			}
 			s.logger.Error("Unable to accept connection", zap.Error(err))
 			continue
 		}
-		go s.commander.Handler(c)
+		go s.handle(c)
 	}
+}
 
-	return nil
+// listen creates the listener unless the server is killed before it starts listening
+func (s *server) listen() (*net.TCPListener, error) {
+	s.listenerMutex.Lock()
+	defer s.listenerMutex.Unlock()
+
+	if s.quiting {
+		return nil, nil
+	}
+
+	var err error
+	s.listener, err = net.ListenTCP("tcp4", s.address)
+	if err != nil {
+		return nil, err
+	}
+	return s.listener, nil
+}
+
+func (s *server) killed() bool {
+	s.listenerMutex.Lock()
+	defer s.listenerMutex.Unlock()
+
+	return s.quiting
+}
+
+// handle keeps the connection till the commander is done with it, kept alive connections are closed on kill
+func (s *server) handle(c net.Conn) {
+	s.connsMutex.Lock()
+	s.conns[c] = struct{}{}
+	s.connsMutex.Unlock()
+
+	defer func() {
+		s.connsMutex.Lock()
+		delete(s.conns, c)
+		s.connsMutex.Unlock()
+	}()
+
+	s.commander.Handler(c)
 }
 
 func (s *server) Kill() error {
+	s.listenerMutex.Lock()
 	s.quiting = true
+	listener := s.listener
+	s.listenerMutex.Unlock()
 
-	if s.listener == nil {
+	if listener == nil {
 		return nil
 	}
 
-	if err := s.listener.Close(); err != nil {
+	if err := listener.Close(); err != nil {
 		return err
 	}
 
+	s.connsMutex.Lock()
+	defer s.connsMutex.Unlock()
+
+	for c := range s.conns {
+		_ = c.Close()
+	}
+
 	return nil
 }
 
@@ -12,12 +12,13 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/freakmaxi/kertish-dfs/basics/caller"
 	"github.com/freakmaxi/kertish-dfs/basics/common"
 )
 
 const headEndPoint = "/client/dfs"
 
-var client = http.Client{}
+var client = http.Client{Transport: caller.NewTransport()}
 
 func List(headAddresses []string, source string, usage bool) (*common.Folder, error) {
 	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s%s", headAddresses[0], headEndPoint), nil)
@@ -10,10 +10,11 @@ require (
 	github.com/gorilla/mux v1.7.4
 	github.com/mattn/go-runewidth v0.0.9
 	github.com/mediocregopher/radix/v3 v3.5.2
+	github.com/prometheus/client_golang v1.7.1
 	github.com/stretchr/testify v1.6.0
+	go.etcd.io/bbolt v1.3.5
 	go.mongodb.org/mongo-driver v1.3.5
 	go.uber.org/zap v1.15.0
 	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e // indirect
-	golang.org/x/sys v0.0.0-20191220142924-d4481acd189f // indirect
 	golang.org/x/tools v0.0.0-20200103221440-774c71fcf114 // indirect
 )
@@ -1,6 +1,7 @@
 package cluster
 
 import (
+	"context"
 	"crypto/sha512"
 	"encoding/binary"
 	"encoding/hex"
@@ -8,19 +9,26 @@ import (
 	"io"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/basics/protocol"
+	"github.com/freakmaxi/kertish-dfs/basics/tracing"
 )
 
 const commandCreate = "CREA"
 const commandRead = "READ"
+const commandReadRange = "RDRN"
 const commandDelete = "DELE"
+const commandExists = "EXST"
 
 type DataNode interface {
-	Create(data []byte) (bool, string, error)
-	CreateShadow(sha512Hex string) error
-	Read(sha512Hex string, readHandler func(data []byte) error) error
-	Delete(sha512Hex string) error
+	Create(ctx context.Context, data []byte) (bool, string, error)
+	CreateShadow(ctx context.Context, sha512Hex string) error
+	Read(ctx context.Context, sha512Hex string, startedHandler func(), readHandler func(data []byte) error) error
+	ReadRange(ctx context.Context, sha512Hex string, offset uint32, length uint32, startedHandler func(), readHandler func(data []byte) error) error
+	Delete(ctx context.Context, sha512Hex string) error
+	Exists(ctx context.Context, sha512Hex string) (bool, error)
 }
 
 type dataNode struct {
@@ -38,31 +46,59 @@ func NewDataNode(address string) (DataNode, error) {
 	}, nil
 }
 
-func (d *dataNode) connect(connectionHandler func(conn *net.TCPConn) error) error {
-	conn, err := net.DialTCP("tcp", nil, d.address)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = conn.Close() }()
+func (d *dataNode) connect(ctx context.Context, command string, connectionHandler func(conn *protocol.Conn) error) (err error) {
+	ctx, span := tracing.Start(ctx, fmt.Sprintf("dataNode.%s", command))
+	span.SetAttribute("address", d.address.String())
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	return protocol.Do(d.address, func(conn *protocol.Conn) error {
+		if done := ctx.Done(); done != nil {
+			stop := make(chan struct{})
+			exited := make(chan struct{})
+			go func() {
+				defer close(exited)
+				select {
+				case <-done:
+					// aborts the blocking read or write, pool resets the deadline if the command is already completed
+					_ = conn.SetDeadline(time.Now())
+				case <-stop:
+				}
+			}()
+			defer func() {
+				close(stop)
+				<-exited
+			}()
+		}
 
-	return connectionHandler(conn)
-}
+		// data nodes without the capability do not know the command
+		if command == commandExists && !conn.Peer().Supports(protocol.CapExists) {
+			return protocol.ErrUnknownCommand
+		}
 
-func (d *dataNode) result(conn *net.TCPConn) bool {
-	b := make([]byte, 1)
-	_, err := conn.Read(b)
-	if err != nil {
-		return false
-	}
-	return strings.Compare("+", string(b)) == 0
-}
+		// data nodes without ranged read support serve the whole block
+		if command == commandReadRange && !conn.Peer().Supports(protocol.CapRangedRead) {
+			command = commandRead
+		}
+		// trace context is written before the command
+		conn.SetCommand(command)
+
+		if err := tracing.WriteBinary(ctx, conn); err != nil {
+			return err
+		}
 
-func (d *dataNode) Create(data []byte) (exists bool, sha512Hex string, err error) {
-	err = d.connect(func(conn *net.TCPConn) error {
-		if _, err := conn.Write([]byte(commandCreate)); err != nil {
+		if _, err := conn.Write([]byte(command)); err != nil {
 			return err
 		}
 
+		return connectionHandler(conn)
+	})
+}
+
+func (d *dataNode) Create(ctx context.Context, data []byte) (exists bool, sha512Hex string, err error) {
+	err = d.connect(ctx, commandCreate, func(conn *protocol.Conn) error {
 		sha512Hash := sha512.New512_256()
 		_, _ = sha512Hash.Write(data)
 
@@ -71,7 +107,12 @@ func (d *dataNode) Create(data []byte) (exists bool, sha512Hex string, err error
 			return err
 		}
 
-		if !d.result(conn) {
+		if !conn.Result() {
+			// legacy data nodes report the existing block and the failure in the same way
+			if conn.Peer().Supports(protocol.CapErrorCodes) && conn.Code() != protocol.EC_Quit {
+				return conn.Error("create command is failed on data node")
+			}
+
 			exists = true
 			sha512Hex = hex.EncodeToString(sha512Sum)
 
@@ -87,8 +128,8 @@ func (d *dataNode) Create(data []byte) (exists bool, sha512Hex string, err error
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("create command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("create command is failed on data node")
 		}
 
 		sha512Hex = hex.EncodeToString(sha512Sum)
@@ -97,31 +138,27 @@ func (d *dataNode) Create(data []byte) (exists bool, sha512Hex string, err error
 	return
 }
 
-func (d *dataNode) CreateShadow(sha512Hex string) error {
-	return d.connect(func(conn *net.TCPConn) error {
-		if _, err := conn.Write([]byte(commandCreate)); err != nil {
-			return err
-		}
-
+func (d *dataNode) CreateShadow(ctx context.Context, sha512Hex string) error {
+	return d.connect(ctx, commandCreate, func(conn *protocol.Conn) error {
 		sha512Sum, _ := hex.DecodeString(sha512Hex)
 		if _, err := conn.Write(sha512Sum); err != nil {
 			return err
 		}
 
-		if d.result(conn) {
+		if conn.Result() {
 			return errors.ErrCreate
 		}
 
+		if conn.Peer().Supports(protocol.CapErrorCodes) && conn.Code() != protocol.EC_Quit {
+			return conn.Error("create shadow command is failed on data node")
+		}
+
 		return nil
 	})
 }
 
-func (d *dataNode) Read(sha512Hex string, readHandler func([]byte) error) error {
-	return d.connect(func(conn *net.TCPConn) error {
-		if _, err := conn.Write([]byte(commandRead)); err != nil {
-			return err
-		}
-
+func (d *dataNode) Read(ctx context.Context, sha512Hex string, startedHandler func(), readHandler func([]byte) error) error {
+	return d.connect(ctx, commandRead, func(conn *protocol.Conn) error {
 		sha512Sum, err := hex.DecodeString(sha512Hex)
 		if err != nil {
 			return err
@@ -130,8 +167,11 @@ func (d *dataNode) Read(sha512Hex string, readHandler func([]byte) error) error
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("data node refused the read request")
+		if !conn.Result() {
+			return conn.Error("data node refused the read request")
+		}
+		if startedHandler != nil {
+			startedHandler()
 		}
 
 		var blockSize uint32
@@ -152,8 +192,8 @@ func (d *dataNode) Read(sha512Hex string, readHandler func([]byte) error) error
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("read command is failed on data cluster")
+		if !conn.Result() {
+			return conn.Error("read command is failed on data cluster")
 		}
 
 		sha512HexCompare := hex.EncodeToString(sha512Hash.Sum(nil))
@@ -165,12 +205,117 @@ func (d *dataNode) Read(sha512Hex string, readHandler func([]byte) error) error
 	})
 }
 
-func (d *dataNode) Delete(sha512Hex string) error {
-	return d.connect(func(conn *net.TCPConn) error {
-		if _, err := conn.Write([]byte(commandDelete)); err != nil {
+func (d *dataNode) ReadRange(ctx context.Context, sha512Hex string, offset uint32, length uint32, startedHandler func(), readHandler func([]byte) error) error {
+	return d.connect(ctx, commandReadRange, func(conn *protocol.Conn) error {
+		if !conn.Peer().Supports(protocol.CapRangedRead) {
+			return d.readRangeLegacy(conn, sha512Hex, offset, length, startedHandler, readHandler)
+		}
+
+		sha512Sum, err := hex.DecodeString(sha512Hex)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(sha512Sum); err != nil {
+			return err
+		}
+
+		if err := binary.Write(conn, binary.LittleEndian, &offset); err != nil {
+			return err
+		}
+		if err := binary.Write(conn, binary.LittleEndian, &length); err != nil {
+			return err
+		}
+
+		if !conn.Result() {
+			return conn.Error("data node refused the ranged read request")
+		}
+		if startedHandler != nil {
+			startedHandler()
+		}
+
+		var rangeSize uint32
+		if err := binary.Read(conn, binary.LittleEndian, &rangeSize); err != nil {
+			return err
+		}
+
+		buf := make([]byte, rangeSize)
+
+		if _, err = io.ReadAtLeast(conn, buf, len(buf)); err != nil {
 			return err
 		}
 
+		if err := readHandler(buf); err != nil {
+			return err
+		}
+
+		if !conn.Result() {
+			return conn.Error("ranged read command is failed on data cluster")
+		}
+
+		return nil
+	})
+}
+
+// readRangeLegacy reads the whole block over the connection opened with READ command and slices the range
+func (d *dataNode) readRangeLegacy(conn *protocol.Conn, sha512Hex string, offset uint32, length uint32, startedHandler func(), readHandler func([]byte) error) error {
+	sha512Sum, err := hex.DecodeString(sha512Hex)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(sha512Sum); err != nil {
+		return err
+	}
+
+	if !conn.Result() {
+		return conn.Error("data node refused the read request")
+	}
+	if startedHandler != nil {
+		startedHandler()
+	}
+
+	var blockSize uint32
+	if err := binary.Read(conn, binary.LittleEndian, &blockSize); err != nil {
+		return err
+	}
+
+	buf := make([]byte, blockSize)
+
+	if _, err = io.ReadAtLeast(conn, buf, len(buf)); err != nil {
+		return err
+	}
+
+	if !conn.Result() {
+		return conn.Error("read command is failed on data cluster")
+	}
+
+	if uint64(offset)+uint64(length) > uint64(blockSize) {
+		return errors.ErrOutOfRange
+	}
+
+	return readHandler(buf[offset : offset+length])
+}
+
+func (d *dataNode) Delete(ctx context.Context, sha512Hex string) error {
+	return d.connect(ctx, commandDelete, func(conn *protocol.Conn) error {
+		sha512Sum, err := hex.DecodeString(sha512Hex)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(sha512Sum); err != nil {
+			return err
+		}
+
+		if !conn.Result() {
+			return conn.Error("delete command is failed on data cluster")
+		}
+
+		return nil
+	})
+}
+
+// Exists checks if the block is placed on the data node. Data nodes without the capability can not report it
+func (d *dataNode) Exists(ctx context.Context, sha512Hex string) (exists bool, err error) {
+	err = d.connect(ctx, commandExists, func(conn *protocol.Conn) error {
 		sha512Sum, err := hex.DecodeString(sha512Hex)
 		if err != nil {
 			return err
@@ -179,12 +324,17 @@ func (d *dataNode) Delete(sha512Hex string) error {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("delete command is failed on data cluster")
+		if !conn.Result() {
+			if conn.Code() != protocol.EC_Quit {
+				return conn.Error("exists command is failed on data node")
+			}
+			return nil
 		}
 
+		exists = true
 		return nil
 	})
+	return
 }
 
 var _ DataNode = &dataNode{}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/freakmaxi/kertish-dfs/basics/common"
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
 	"github.com/freakmaxi/locking-center-client-go/mutex"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,30 +20,41 @@ import (
 
 type LockReleaseHandler func()
 
+// EventHandler is called with the change feed events after they are saved with the metadata change
+type EventHandler func(event *common.Event)
+
 type Metadata interface {
 	Get(folderPaths []string) ([]*common.Folder, error)
 	Tree(folderPath string, includeItself bool, reverseSort bool) ([]*common.Folder, error)
 
-	SaveBlock(folderPaths []string, saveHandler func(folders map[string]*common.Folder) (bool, error)) error
-	SaveChain(folderPath string, saveHandler func(folder *common.Folder) (bool, error)) error
+	// SaveBlock and SaveChain save the change events with the folders when the save handler completes without error.
+	// Folders that are created for the chain are saved with create events
+	SaveBlock(folderPaths []string, changeEvents common.Events, saveHandler func(folders map[string]*common.Folder) (bool, error)) error
+	SaveChain(folderPath string, changeEvents common.Events, saveHandler func(folder *common.Folder) (bool, error)) error
+	// CreateChain creates the missing folders of the chain and returns true if the last folder is created
+	CreateChain(folderPath string) (bool, error)
 }
 
 const metadataCollection = "metadata"
 const metadataLockKey = "metadata"
 
 type metadata struct {
-	mutex mutex.LockingCenter
-	conn  *Connection
-	col   *mongo.Collection
+	mutex        mutex.LockingCenter
+	conn         *Connection
+	col          *mongo.Collection
+	events       events.MongoStore
+	eventHandler EventHandler
 }
 
-func NewMetadata(mutex mutex.LockingCenter, conn *Connection, database string) (Metadata, error) {
+func NewMetadata(mutex mutex.LockingCenter, conn *Connection, database string, eventStore events.MongoStore, eventHandler EventHandler) (Metadata, error) {
 	dfsCol := conn.client.Database(database).Collection(metadataCollection)
 
 	m := &metadata{
-		mutex: mutex,
-		conn:  conn,
-		col:   dfsCol,
+		mutex:        mutex,
+		conn:         conn,
+		col:          dfsCol,
+		events:       eventStore,
+		eventHandler: eventHandler,
 	}
 	if err := m.setupIndices(); err != nil {
 		return nil, err
@@ -113,7 +125,7 @@ func (m *metadata) updateOne(parentContext context.Context, folderPath string, f
 }
 
 func (m *metadata) Get(folderPaths []string) ([]*common.Folder, error) {
-	folderPaths = m.cleanDuplicates(folderPaths)
+	folderPaths = cleanDuplicates(folderPaths)
 
 	folders := make([]*common.Folder, 0)
 	for _, folderPath := range folderPaths {
@@ -170,8 +182,8 @@ func (m *metadata) Tree(folderPath string, includeItself bool, reverseSort bool)
 	return folders, nil
 }
 
-func (m *metadata) SaveBlock(folderPaths []string, saveHandler func(folders map[string]*common.Folder) (bool, error)) error {
-	folderPaths = m.cleanDuplicates(folderPaths)
+func (m *metadata) SaveBlock(folderPaths []string, changeEvents common.Events, saveHandler func(folders map[string]*common.Folder) (bool, error)) error {
+	folderPaths = cleanDuplicates(folderPaths)
 
 	m.mutex.Wait(metadataLockKey)
 
@@ -194,15 +206,39 @@ func (m *metadata) SaveBlock(folderPaths []string, saveHandler func(folders map[
 	}
 
 	save, err := saveHandler(folders)
-	if save {
-		if err := m.overwrite(folders); err != nil {
-			return err
-		}
+	if !save {
+		return err
+	}
+	if err != nil {
+		changeEvents = nil
+	}
+
+	if err := m.overwrite(folders, changeEvents); err != nil {
+		return err
 	}
+	m.publish(changeEvents)
+
 	return err
 }
 
-func (m *metadata) SaveChain(folderPath string, saveHandler func(folder *common.Folder) (bool, error)) error {
+func (m *metadata) SaveChain(folderPath string, changeEvents common.Events, saveHandler func(folder *common.Folder) (bool, error)) error {
+	return m.saveChain(folderPath, changeEvents, func(folder *common.Folder, _ bool) (bool, error) {
+		return saveHandler(folder)
+	})
+}
+
+func (m *metadata) CreateChain(folderPath string) (bool, error) {
+	created := false
+	err := m.saveChain(folderPath, nil, func(_ *common.Folder, folderCreated bool) (bool, error) {
+		created = folderCreated
+		return false, nil
+	})
+	return created, err
+}
+
+// saveChain creates the missing folders of the chain and calls the save handler with the last folder. created is true
+// if the last folder is created by this call
+func (m *metadata) saveChain(folderPath string, changeEvents common.Events, saveHandler func(folder *common.Folder, created bool) (bool, error)) error {
 	folderTree := common.PathTree(folderPath)
 
 	m.mutex.Wait(metadataLockKey)
@@ -231,29 +267,16 @@ func (m *metadata) SaveChain(folderPath string, saveHandler func(folder *common.
 		return err
 	}
 
-	session, err := m.conn.client.StartSession()
-	if err != nil {
-		return err
-	}
-
 	var folder *common.Folder
+	created := false
+	createEvents := make(common.Events, 0)
 
-	ctxS1, cancelS1Func := m.context(context.Background())
-	defer cancelS1Func()
-
-	if err = mongo.WithSession(ctxS1, session, func(sc mongo.SessionContext) error {
-		if err = sc.StartTransaction(); err != nil {
-			return err
-		}
-
-		var parentContext context.Context = sc
-		if !m.conn.transaction {
-			parentContext = context.Background()
-		}
-
+	if err := m.transaction(func(parentContext context.Context) error {
+		var err error
 		var parentFolder *common.Folder
 		for len(folderTree) > 0 {
 			folderPath := folderTree[0]
+			created = false
 
 			folder, err = m.findOne(parentContext, folderPath)
 			if err != nil {
@@ -281,6 +304,7 @@ func (m *metadata) SaveChain(folderPath string, saveHandler func(folder *common.
 					}
 					return err
 				}
+				created = true
 
 				if err := m.updateOne(parentContext, parentFolder.Full, *parentFolder); err != nil {
 					return err
@@ -288,6 +312,7 @@ func (m *metadata) SaveChain(folderPath string, saveHandler func(folder *common.
 				if err := insertOneFunc(parentContext, *folder); err != nil {
 					return err
 				}
+				createEvents = append(createEvents, common.NewEvent(common.ET_Create, true, nil, folder.Full))
 			}
 
 			if len(folderTree) == 1 {
@@ -301,33 +326,38 @@ func (m *metadata) SaveChain(folderPath string, saveHandler func(folder *common.
 			droppedMutex[parentFolder.Full] = true
 		}
 
-		return sc.CommitTransaction(parentContext)
+		return m.events.Append(parentContext, createEvents)
 	}); err != nil {
 		return err
 	}
-
-	ctxS2, cancelS2Func := m.context(context.Background())
-	defer cancelS2Func()
-
-	session.EndSession(ctxS2)
+	m.publish(createEvents)
 
 	if folder == nil {
 		return nil
 	}
 
-	save, err := saveHandler(folder)
+	save, err := saveHandler(folder, created)
 	if !save {
 		return err
 	}
+	if err != nil {
+		changeEvents = nil
+	}
 
-	if err := m.updateOne(context.Background(), folder.Full, *folder); err != nil {
+	if err := m.transaction(func(parentContext context.Context) error {
+		if err := m.updateOne(parentContext, folder.Full, *folder); err != nil {
+			return err
+		}
+		return m.events.Append(parentContext, changeEvents)
+	}); err != nil {
 		return err
 	}
+	m.publish(changeEvents)
 
 	return err
 }
 
-func (m *metadata) overwrite(folders map[string]*common.Folder) error {
+func (m *metadata) overwrite(folders map[string]*common.Folder, changeEvents common.Events) error {
 	deleteOneFunc := func(parentContext context.Context, folderPath string) error {
 		ctx, cancelFunc := m.context(parentContext)
 		defer cancelFunc()
@@ -341,6 +371,26 @@ func (m *metadata) overwrite(folders map[string]*common.Folder) error {
 		return nil
 	}
 
+	return m.transaction(func(parentContext context.Context) error {
+		for folderPath, folder := range folders {
+			if folder == nil {
+				if err := deleteOneFunc(parentContext, folderPath); err != nil && err != os.ErrNotExist {
+					return err
+				}
+				continue
+			}
+
+			if err := m.updateOne(parentContext, folderPath, *folder); err != nil {
+				return err
+			}
+		}
+
+		return m.events.Append(parentContext, changeEvents)
+	})
+}
+
+// transaction runs the handler in a transaction when the connection supports it
+func (m *metadata) transaction(handler func(parentContext context.Context) error) error {
 	session, err := m.conn.client.StartSession()
 	if err != nil {
 		return err
@@ -359,17 +409,8 @@ func (m *metadata) overwrite(folders map[string]*common.Folder) error {
 			parentContext = context.Background()
 		}
 
-		for folderPath, folder := range folders {
-			if folder == nil {
-				if err := deleteOneFunc(parentContext, folderPath); err != nil && err != os.ErrNotExist {
-					return err
-				}
-				continue
-			}
-
-			if err := m.updateOne(parentContext, folderPath, *folder); err != nil {
-				return err
-			}
+		if err := handler(parentContext); err != nil {
+			return err
 		}
 
 		return sc.CommitTransaction(parentContext)
@@ -385,7 +426,16 @@ func (m *metadata) overwrite(folders map[string]*common.Folder) error {
 	return nil
 }
 
-func (m *metadata) cleanDuplicates(folderPaths []string) []string {
+func (m *metadata) publish(changeEvents common.Events) {
+	if m.eventHandler == nil {
+		return
+	}
+	for _, event := range changeEvents {
+		m.eventHandler(event)
+	}
+}
+
+func cleanDuplicates(folderPaths []string) []string {
 	cleanedUps := make([]string, 0)
 
 	for _, folderPath := range folderPaths {
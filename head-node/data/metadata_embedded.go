@@ -0,0 +1,324 @@
+package data
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
+	"github.com/freakmaxi/locking-center-client-go/mutex"
+	bolt "go.etcd.io/bbolt"
+)
+
+type metadataEmbedded struct {
+	mutex        mutex.LockingCenter
+	store        embedded.Store
+	events       events.EmbeddedStore
+	eventHandler EventHandler
+}
+
+func NewMetadataEmbedded(store embedded.Store, mutex mutex.LockingCenter, eventStore events.EmbeddedStore, eventHandler EventHandler) (Metadata, error) {
+	if err := store.Setup(metadataCollection); err != nil {
+		return nil, err
+	}
+
+	return &metadataEmbedded{
+		mutex:        mutex,
+		store:        store,
+		events:       eventStore,
+		eventHandler: eventHandler,
+	}, nil
+}
+
+func (m *metadataEmbedded) findOne(bucket *bolt.Bucket, folderPath string) (*common.Folder, error) {
+	var folder *common.Folder
+
+	found, err := embedded.Get(bucket, folderPath, &folder)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, os.ErrNotExist
+	}
+	return folder, nil
+}
+
+func (m *metadataEmbedded) Get(folderPaths []string) ([]*common.Folder, error) {
+	folderPaths = cleanDuplicates(folderPaths)
+
+	folders := make([]*common.Folder, 0)
+	if err := m.store.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metadataCollection))
+
+		for _, folderPath := range folderPaths {
+			folder, err := m.findOne(bucket, folderPath)
+			if err != nil {
+				return err
+			}
+			folders = append(folders, folder)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (m *metadataEmbedded) Tree(folderPath string, includeItself bool, reverseSort bool) ([]*common.Folder, error) {
+	childPrefix := []byte(folderPath + "/")
+
+	folders := make([]*common.Folder, 0)
+	if err := m.store.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metadataCollection))
+
+		if includeItself {
+			folder, err := m.findOne(bucket, folderPath)
+			if err == nil {
+				folders = append(folders, folder)
+			} else if err != os.ErrNotExist {
+				return err
+			}
+		}
+
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek(childPrefix); k != nil && strings.HasPrefix(string(k), string(childPrefix)); k, v = cursor.Next() {
+			if len(k) == len(childPrefix) {
+				continue
+			}
+
+			var folder *common.Folder
+			if err := embedded.Decode(v, &folder); err != nil {
+				return err
+			}
+			folders = append(folders, folder)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(folders, func(i, j int) bool {
+		if reverseSort {
+			return strings.Compare(folders[i].Full, folders[j].Full) > 0
+		}
+		return strings.Compare(folders[i].Full, folders[j].Full) < 0
+	})
+
+	return folders, nil
+}
+
+func (m *metadataEmbedded) SaveBlock(folderPaths []string, changeEvents common.Events, saveHandler func(folders map[string]*common.Folder) (bool, error)) error {
+	folderPaths = cleanDuplicates(folderPaths)
+
+	m.mutex.Wait(metadataLockKey)
+
+	for i := range folderPaths {
+		m.mutex.Lock(folderPaths[i])
+	}
+	defer func() {
+		for _, folderPath := range folderPaths {
+			m.mutex.Unlock(folderPath)
+		}
+	}()
+
+	folders := make(map[string]*common.Folder)
+	if err := m.store.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metadataCollection))
+
+		for _, folderPath := range folderPaths {
+			folder, err := m.findOne(bucket, folderPath)
+			if err != nil {
+				return err
+			}
+			folders[folderPath] = folder
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	save, err := saveHandler(folders)
+	if !save {
+		return err
+	}
+	if err != nil {
+		changeEvents = nil
+	}
+
+	if err := m.overwrite(folders, changeEvents); err != nil {
+		return err
+	}
+	m.publish(changeEvents)
+
+	return err
+}
+
+func (m *metadataEmbedded) SaveChain(folderPath string, changeEvents common.Events, saveHandler func(folder *common.Folder) (bool, error)) error {
+	return m.saveChain(folderPath, changeEvents, func(folder *common.Folder, _ bool) (bool, error) {
+		return saveHandler(folder)
+	})
+}
+
+func (m *metadataEmbedded) CreateChain(folderPath string) (bool, error) {
+	created := false
+	err := m.saveChain(folderPath, nil, func(_ *common.Folder, folderCreated bool) (bool, error) {
+		created = folderCreated
+		return false, nil
+	})
+	return created, err
+}
+
+// saveChain creates the missing folders of the chain and calls the save handler with the last folder. created is true
+// if the last folder is created by this call
+func (m *metadataEmbedded) saveChain(folderPath string, changeEvents common.Events, saveHandler func(folder *common.Folder, created bool) (bool, error)) error {
+	folderTree := common.PathTree(folderPath)
+
+	m.mutex.Wait(metadataLockKey)
+
+	folderTreeBackup := make([]string, len(folderTree))
+	copy(folderTreeBackup, folderTree)
+
+	droppedMutex := make(map[string]bool)
+	for i := range folderTreeBackup {
+		m.mutex.Lock(folderTreeBackup[i])
+	}
+	defer func() {
+		for _, folderPath := range folderTreeBackup {
+			if _, has := droppedMutex[folderPath]; has {
+				continue
+			}
+			m.mutex.Unlock(folderPath)
+		}
+	}()
+
+	var folder *common.Folder
+	created := false
+	createEvents := make(common.Events, 0)
+
+	if err := m.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metadataCollection))
+
+		var parentFolder *common.Folder
+		for len(folderTree) > 0 {
+			folderPath := folderTree[0]
+			created = false
+
+			var err error
+			folder, err = m.findOne(bucket, folderPath)
+			if err != nil {
+				if err != os.ErrNotExist {
+					return err
+				}
+
+				if parentFolder == nil {
+					parentFolder = common.NewFolder("/")
+
+					if err := embedded.Put(bucket, parentFolder.Full, parentFolder); err != nil {
+						return err
+					}
+					folderTree = folderTree[1:]
+
+					continue
+				}
+
+				_, folderName := common.Split(folderPath)
+
+				folder, err = parentFolder.NewFolder(folderName)
+				if err != nil {
+					if err == os.ErrExist {
+						return errors.ErrRepair
+					}
+					return err
+				}
+				created = true
+
+				if err := embedded.Put(bucket, parentFolder.Full, parentFolder); err != nil {
+					return err
+				}
+				if err := embedded.Put(bucket, folder.Full, folder); err != nil {
+					return err
+				}
+				createEvents = append(createEvents, common.NewEvent(common.ET_Create, true, nil, folder.Full))
+			}
+
+			if len(folderTree) == 1 {
+				break
+			}
+
+			parentFolder = folder
+			folderTree = folderTree[1:]
+
+			m.mutex.Unlock(parentFolder.Full)
+			droppedMutex[parentFolder.Full] = true
+		}
+		return m.events.Append(tx, createEvents)
+	}); err != nil {
+		return err
+	}
+	m.publish(createEvents)
+
+	if folder == nil {
+		return nil
+	}
+
+	save, err := saveHandler(folder, created)
+	if !save {
+		return err
+	}
+	if err != nil {
+		changeEvents = nil
+	}
+
+	if err := m.store.Update(func(tx *bolt.Tx) error {
+		if err := embedded.Put(tx.Bucket([]byte(metadataCollection)), folder.Full, folder); err != nil {
+			return err
+		}
+		return m.events.Append(tx, changeEvents)
+	}); err != nil {
+		return err
+	}
+	m.publish(changeEvents)
+
+	return err
+}
+
+func (m *metadataEmbedded) publish(changeEvents common.Events) {
+	if m.eventHandler == nil {
+		return
+	}
+	for _, event := range changeEvents {
+		m.eventHandler(event)
+	}
+}
+
+func (m *metadataEmbedded) overwrite(folders map[string]*common.Folder, changeEvents common.Events) error {
+	return m.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metadataCollection))
+
+		for folderPath, folder := range folders {
+			if folder == nil {
+				if err := bucket.Delete([]byte(folderPath)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// folder can be saved to the new path, mongo filter matches the previous one in the same way
+			if strings.Compare(folderPath, folder.Full) != 0 {
+				if err := bucket.Delete([]byte(folderPath)); err != nil {
+					return err
+				}
+			}
+
+			if err := embedded.Put(bucket, folder.Full, folder); err != nil {
+				return err
+			}
+		}
+		return m.events.Append(tx, changeEvents)
+	})
+}
+
+var _ Metadata = &metadataEmbedded{}
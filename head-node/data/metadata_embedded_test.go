@@ -0,0 +1,93 @@
+package data
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetadata(t *testing.T) (Metadata, events.Store, *common.Events) {
+	folder, err := ioutil.TempDir("", "kertish-head")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(folder) })
+
+	store, err := embedded.NewStore(path.Join(folder, "kertish.db"))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	eventStore, err := events.NewStoreEmbedded(store, 0)
+	assert.Nil(t, err)
+
+	published := make(common.Events, 0)
+	metadata, err := NewMetadataEmbedded(store, embedded.NewLockingCenter(), eventStore, func(event *common.Event) {
+		published = append(published, event)
+	})
+	assert.Nil(t, err)
+
+	return metadata, eventStore, &published
+}
+
+func TestMetadataEmbedded_CreateChain(t *testing.T) {
+	metadata, eventStore, published := newMetadata(t)
+
+	created, err := metadata.CreateChain("/a/b")
+	assert.Nil(t, err)
+	assert.True(t, created)
+
+	created, err = metadata.CreateChain("/a/b")
+	assert.Nil(t, err)
+	assert.False(t, created)
+
+	created, err = metadata.CreateChain("/a")
+	assert.Nil(t, err)
+	assert.False(t, created)
+
+	folders, err := metadata.Get([]string{"/a/b"})
+	assert.Nil(t, err)
+	assert.Len(t, folders, 1)
+
+	// every created folder of the chain has its own event
+	list, err := eventStore.List(0, 10, nil)
+	assert.Nil(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "/a", list[0].Target)
+		assert.Equal(t, "/a/b", list[1].Target)
+		assert.True(t, list[1].Folder)
+	}
+	assert.Equal(t, list, *published)
+}
+
+func TestMetadataEmbedded_SaveEvents(t *testing.T) {
+	metadata, eventStore, published := newMetadata(t)
+
+	event := common.NewEvent(common.ET_Create, false, nil, "/a/file")
+	assert.Nil(t, metadata.SaveChain("/a", common.Events{event}, func(folder *common.Folder) (bool, error) {
+		_, err := folder.NewFile("file")
+		return true, err
+	}))
+
+	// failed save keeps the folder change but does not record the event
+	assert.NotNil(t, metadata.SaveBlock([]string{"/a"}, common.Events{common.NewEvent(common.ET_Delete, false, []string{"/a/file"}, "")}, func(folders map[string]*common.Folder) (bool, error) {
+		return true, errors.New("deletion is failed")
+	}))
+
+	list, err := eventStore.List(0, 10, nil)
+	assert.Nil(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "/a", list[0].Target)
+		assert.Equal(t, "/a/file", list[1].Target)
+		assert.Equal(t, event.Sequence, list[1].Sequence)
+	}
+	assert.Len(t, *published, 2)
+}
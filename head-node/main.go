@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/freakmaxi/kertish-dfs/basics/audit"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
 	"github.com/freakmaxi/kertish-dfs/basics/log"
+	"github.com/freakmaxi/kertish-dfs/basics/tracing"
 	"github.com/freakmaxi/kertish-dfs/head-node/data"
 	"github.com/freakmaxi/kertish-dfs/head-node/manager"
 	"github.com/freakmaxi/kertish-dfs/head-node/routing"
 	"github.com/freakmaxi/kertish-dfs/head-node/services"
+	managerServices "github.com/freakmaxi/kertish-dfs/manager-node/services"
 	"github.com/freakmaxi/locking-center-client-go/mutex"
 	"go.uber.org/zap"
 )
@@ -38,12 +46,58 @@ func main() {
 	}
 	logger.Info(fmt.Sprintf("BIND_ADDRESS: %s", bindAddr))
 
+	tracingOutput := os.Getenv("TRACING_OUTPUT")
+	if len(tracingOutput) > 0 {
+		tracingTarget := os.Getenv("TRACING_TARGET")
+		logger.Info(fmt.Sprintf("TRACING_OUTPUT: %s", tracingOutput))
+		logger.Info(fmt.Sprintf("TRACING_TARGET: %s", tracingTarget))
+
+		if err := tracing.Setup("head-node", tracingOutput, tracingTarget, logger); err != nil {
+			logger.Error("Tracing setup is failed", zap.Error(err))
+			os.Exit(8)
+		}
+	}
+
 	mutexSourceAddr := bindAddr
 	if strings.Index(mutexSourceAddr, ":") == 0 {
 		mutexSourceAddr = fmt.Sprintf("127.0.0.1%s", mutexSourceAddr)
 	}
 
+	embeddedStore := os.Getenv("EMBEDDED_STORE")
+	if len(embeddedStore) > 0 {
+		logger.Info(fmt.Sprintf("EMBEDDED_STORE: %s", embeddedStore))
+	}
+
+	// manager node runs in the head node process with the embedded store
+	var managerConfig managerServices.Config
+	if len(embeddedStore) > 0 {
+		managerConfig.BindAddr = managerServices.ReadBindAddress("MANAGER_BIND_ADDRESS", logger)
+
+		managerConfig.AdvertiseAddr = managerConfig.BindAddr
+		if strings.Index(managerConfig.AdvertiseAddr, ":") == 0 {
+			managerConfig.AdvertiseAddr = fmt.Sprintf("127.0.0.1%s", managerConfig.AdvertiseAddr)
+		}
+		managerConfig.AdvertiseAddr = fmt.Sprintf("http://%s", managerConfig.AdvertiseAddr)
+
+		healthCheckInterval, err := managerServices.ReadHealthCheckInterval(logger)
+		if err != nil {
+			logger.Error("Health Check Interval is wrong", zap.Error(err))
+			os.Exit(27)
+		}
+		managerConfig.HealthCheckInterval = healthCheckInterval
+
+		lifecycleInterval, err := managerServices.ReadLifecycleInterval(logger)
+		if err != nil {
+			logger.Error("Lifecycle Interval is wrong", zap.Error(err))
+			os.Exit(28)
+		}
+		managerConfig.LifecycleInterval = lifecycleInterval
+	}
+
 	managerAddress := os.Getenv("MANAGER_ADDRESS")
+	if len(managerAddress) == 0 && len(embeddedStore) > 0 {
+		managerAddress = managerConfig.AdvertiseAddr
+	}
 	if len(managerAddress) == 0 {
 		logger.Error("MANAGER_ADDRESS have to be specified")
 		os.Exit(10)
@@ -51,7 +105,7 @@ func main() {
 	logger.Info(fmt.Sprintf("MANAGER_ADDRESS: %s", managerAddress))
 
 	mongoConn := os.Getenv("MONGO_CONN")
-	if len(mongoConn) == 0 {
+	if len(mongoConn) == 0 && len(embeddedStore) == 0 {
 		logger.Error("MONGO_CONN have to be specified")
 		os.Exit(11)
 	}
@@ -66,48 +120,205 @@ func main() {
 	mongoTransaction := os.Getenv("MONGO_TRANSACTION")
 	logger.Info(fmt.Sprintf("MONGO_TRANSACTION: %t", len(mongoTransaction) > 0))
 
-	mutexConn := os.Getenv("LOCKING_CENTER")
-	if len(mutexConn) == 0 {
-		logger.Error("LOCKING_CENTER have to be specified")
-		os.Exit(13)
+	feedRetentionString := os.Getenv("CHANGE_FEED_RETENTION")
+	if len(feedRetentionString) == 0 {
+		feedRetentionString = "30"
 	}
-	logger.Info(fmt.Sprintf("LOCKING_CENTER: %s", mutexConn))
+	feedRetention, err := strconv.ParseUint(feedRetentionString, 10, 64)
+	if err != nil {
+		logger.Error("Change Feed Retention is wrong", zap.Error(err))
+		os.Exit(12)
+	}
+	logger.Info(fmt.Sprintf("CHANGE_FEED_RETENTION: %s day(s)", feedRetentionString))
 
-	m, err := mutex.NewLockingCenterWithSourceAddr(mutexConn, &mutexSourceAddr)
+	webhookTargets, err := manager.ParseWebhookTargets(os.Getenv("WEBHOOK_TARGETS"))
 	if err != nil {
-		logger.Error("Mutex Setup is failed", zap.Error(err))
-		os.Exit(14)
+		logger.Error("Webhook Targets are wrong", zap.Error(err))
+		os.Exit(16)
+	}
+	for _, webhookTarget := range webhookTargets {
+		logger.Info(fmt.Sprintf("WEBHOOK_TARGETS: %s %v", webhookTarget.Url, webhookTarget.PathPrefixes))
 	}
-	m.ResetBySource(&mutexSourceAddr)
 
-	conn, err := data.NewConnection(mongoConn, len(mongoTransaction) > 0)
+	webhookRetryString := os.Getenv("WEBHOOK_RETRY")
+	if len(webhookRetryString) == 0 {
+		webhookRetryString = "5"
+	}
+	webhookRetry, err := strconv.ParseUint(webhookRetryString, 10, 8)
 	if err != nil {
-		logger.Error("MongoDB Connection is failed", zap.Error(err))
-		os.Exit(15)
+		logger.Error("Webhook Retry is wrong", zap.Error(err))
+		os.Exit(17)
+	}
+	if len(webhookTargets) > 0 {
+		logger.Info(fmt.Sprintf("WEBHOOK_RETRY: %s", webhookRetryString))
 	}
 
-	metadata, err := data.NewMetadata(m, conn, mongoDb)
+	readAheadString := os.Getenv("READ_AHEAD")
+	if len(readAheadString) == 0 {
+		readAheadString = "4"
+	}
+	readAhead, err := strconv.ParseUint(readAheadString, 10, 8)
 	if err != nil {
-		logger.Error("Metadata Manager is failed", zap.Error(err))
-		os.Exit(18)
+		logger.Error("Read Ahead is wrong", zap.Error(err))
+		os.Exit(24)
+	}
+	logger.Info(fmt.Sprintf("READ_AHEAD: %s chunk(s)", readAheadString))
+
+	var chunker manager.Chunker
+	chunking := os.Getenv("CHUNKING")
+	switch chunking {
+	case "", "fixed":
+		logger.Info("CHUNKING: fixed")
+	case "cdc":
+		chunkSizesString := os.Getenv("CHUNK_SIZES")
+		if len(chunkSizesString) == 0 {
+			chunkSizesString = "8388608,33554432,67108864"
+		}
+		chunkSizes := make([]uint32, 0)
+		for _, chunkSizeString := range strings.Split(chunkSizesString, ",") {
+			chunkSize, err := strconv.ParseUint(chunkSizeString, 10, 32)
+			if err != nil {
+				logger.Error("Chunk Sizes are wrong", zap.Error(err))
+				os.Exit(26)
+			}
+			chunkSizes = append(chunkSizes, uint32(chunkSize))
+		}
+		if len(chunkSizes) != 3 {
+			logger.Error("Chunk Sizes should be defined as min,avg,max")
+			os.Exit(26)
+		}
+		chunker, err = manager.NewChunker(chunkSizes[0], chunkSizes[1], chunkSizes[2])
+		if err != nil {
+			logger.Error("Chunk Sizes are wrong", zap.Error(err))
+			os.Exit(26)
+		}
+		logger.Info("CHUNKING: cdc")
+		logger.Info(fmt.Sprintf("CHUNK_SIZES: %s", chunkSizesString))
+	default:
+		logger.Error(fmt.Sprintf("Chunking is wrong: %s", chunking))
+		os.Exit(25)
+	}
+
+	mutexConn := os.Getenv("LOCKING_CENTER")
+	if len(mutexConn) == 0 && len(embeddedStore) == 0 {
+		logger.Error("LOCKING_CENTER have to be specified")
+		os.Exit(13)
+	}
+	logger.Info(fmt.Sprintf("LOCKING_CENTER: %s", mutexConn))
+
+	var eventHandler data.EventHandler
+	if len(webhookTargets) > 0 {
+		eventHandler = manager.NewWebhook(webhookTargets, int(webhookRetry), logger).Queue
+	}
+
+	var metadata data.Metadata
+	var eventStore events.Store
+	var auditStore audit.Store
+
+	if len(embeddedStore) > 0 {
+		store, err := embedded.NewStore(embeddedStore)
+		if err != nil {
+			logger.Error("Embedded Store Setup is failed", zap.Error(err))
+			os.Exit(23)
+		}
+		m := embedded.NewLockingCenter()
+
+		embeddedEventStore, err := events.NewStoreEmbedded(store, time.Hour*24*time.Duration(feedRetention))
+		if err != nil {
+			logger.Error("Change Feed Manager is failed", zap.Error(err))
+			os.Exit(19)
+		}
+		eventStore = embeddedEventStore
+
+		metadata, err = data.NewMetadataEmbedded(store, m, embeddedEventStore, eventHandler)
+		if err != nil {
+			logger.Error("Metadata Manager is failed", zap.Error(err))
+			os.Exit(18)
+		}
+
+		auditStore, err = audit.NewStoreEmbedded(store)
+		if err != nil {
+			logger.Error("Audit Manager is failed", zap.Error(err))
+			os.Exit(22)
+		}
+
+		// manager node shares the store and the locking center of the head node, bolt file can be opened by one process
+		managerStores, err := managerServices.NewEmbeddedStores(store, m)
+		if err != nil {
+			logger.Error("Embedded Manager Node Stores are failed", zap.Error(err))
+			os.Exit(29)
+		}
+		managerService, err := managerServices.NewService(managerConfig, managerStores, logger.With(zap.String("node", "manager")))
+		if err != nil {
+			logger.Error("Embedded Manager Node Setup is failed", zap.Error(err))
+			os.Exit(30)
+		}
+		go managerService.Start()
+	} else {
+		m, err := mutex.NewLockingCenterWithSourceAddr(mutexConn, &mutexSourceAddr)
+		if err != nil {
+			logger.Error("Mutex Setup is failed", zap.Error(err))
+			os.Exit(14)
+		}
+		m.ResetBySource(&mutexSourceAddr)
+
+		conn, err := data.NewConnection(mongoConn, len(mongoTransaction) > 0)
+		if err != nil {
+			logger.Error("MongoDB Connection is failed", zap.Error(err))
+			os.Exit(15)
+		}
+
+		mongoEventStore, err := events.NewStore(conn.Database(mongoDb), time.Hour*24*time.Duration(feedRetention))
+		if err != nil {
+			logger.Error("Change Feed Manager is failed", zap.Error(err))
+			os.Exit(19)
+		}
+		eventStore = mongoEventStore
+
+		metadata, err = data.NewMetadata(m, conn, mongoDb, mongoEventStore, eventHandler)
+		if err != nil {
+			logger.Error("Metadata Manager is failed", zap.Error(err))
+			os.Exit(18)
+		}
+
+		auditStore, err = audit.NewStore(conn.Database(mongoDb))
+		if err != nil {
+			logger.Error("Audit Manager is failed", zap.Error(err))
+			os.Exit(22)
+		}
 	}
 
-	cluster, err := manager.NewCluster([]string{managerAddress}, logger)
+	feed := manager.NewFeed(eventStore)
+
+	cluster, err := manager.NewCluster([]string{managerAddress}, int(readAhead), chunker, logger)
 	if err != nil {
 		logger.Error("Cluster Manager is failed", zap.Error(err))
 		os.Exit(20)
 	}
 	dfs := manager.NewDfs(metadata, cluster, logger)
 	// create root if not exists
-	if err := dfs.CreateFolder("/"); err != nil && err != os.ErrExist {
+	if err := dfs.CreateFolder(context.Background(), "/"); err != nil && err != os.ErrExist {
 		logger.Error("Unable to create cluster root path", zap.Error(err))
 		os.Exit(21)
 	}
-	dfsRouter := routing.NewDfsRouter(dfs, logger)
+
+	hostname, _ := os.Hostname()
+	auditor := audit.NewAuditor(auditStore, fmt.Sprintf("head-node/%s%s", hostname, bindAddr), logger)
+
+	dfsRouter := routing.NewDfsRouter(dfs, auditor, logger)
 
 	routerManager := routing.NewManager()
 	routerManager.Add(dfsRouter)
 
+	feedRouter := routing.NewFeedRouter(feed, logger)
+	routerManager.Add(feedRouter)
+
+	auditRouter := routing.NewAuditRouter(auditor, logger)
+	routerManager.Add(auditRouter)
+
+	metricsRouter := routing.NewMetricsRouter()
+	routerManager.Add(metricsRouter)
+
 	proxy := services.NewProxy(bindAddr, routerManager, logger)
 	proxy.Start()
 
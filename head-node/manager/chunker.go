@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+)
+
+const chunkerReadBufferSize = 1024 * 1024 // 1mb
+
+// gearTable is the random value table of the rolling hash. It is generated with a fixed seed because the chunk
+// boundaries should stay the same between the versions and the head nodes for the deduplication
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+
+	seed := uint64(0x6b657274697368)
+	for i := range table {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+
+	return table
+}()
+
+type Chunker interface {
+	Split(reader io.Reader, size uint64, chunkHandler func(chunk []byte) error) error
+}
+
+type chunker struct {
+	min  uint32
+	max  uint32
+	mask uint64
+}
+
+// NewChunker creates the content defined chunker. Chunk boundaries are placed where the rolling hash of the last bytes
+// matches the mask after the min size, so the same content creates the same chunks even if the data is inserted or
+// removed before it. Chunks are cut at the max size when the boundary is not found. The block size of the cluster is
+// not applied to the chunks, so the max size is bounded by the largest block size
+func NewChunker(min uint32, avg uint32, max uint32) (Chunker, error) {
+	if min == 0 || min > avg || avg > max {
+		return nil, fmt.Errorf("chunk sizes should be 0 < min <= avg <= max")
+	}
+	if max > common.MaxBlockSize {
+		return nil, fmt.Errorf("max chunk size should not be larger than %d", common.MaxBlockSize)
+	}
+
+	maskBits := uint(0)
+	if avg > min {
+		maskBits = uint(bits.Len32(avg-min) - 1)
+	}
+
+	mask := uint64(0)
+	if maskBits > 0 {
+		// the high bits are used, the low bits of the gear hash depend on the last a few bytes only
+		mask = ((uint64(1) << maskBits) - 1) << (64 - maskBits)
+	}
+
+	return &chunker{
+		min:  min,
+		max:  max,
+		mask: mask,
+	}, nil
+}
+
+// Split reads the size of data from the reader and passes the chunks to the handler as soon as they are cut
+func (c *chunker) Split(reader io.Reader, size uint64, chunkHandler func(chunk []byte) error) error {
+	bufferedReader := bufio.NewReaderSize(io.LimitReader(reader, int64(size)), chunkerReadBufferSize)
+
+	remains := size
+	for remains > 0 {
+		chunkCapacity := uint64(c.max)
+		if remains < chunkCapacity {
+			chunkCapacity = remains
+		}
+		chunk := make([]byte, 0, chunkCapacity)
+
+		// boundary is not searched before the min size
+		skip := uint64(c.min)
+		if skip > chunkCapacity {
+			skip = chunkCapacity
+		}
+		chunk = chunk[:skip]
+		if _, err := io.ReadFull(bufferedReader, chunk); err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		hash := uint64(0)
+		for c.mask != 0 && uint64(len(chunk)) < chunkCapacity {
+			b, err := bufferedReader.ReadByte()
+			if err != nil {
+				if err == io.EOF {
+					return io.ErrUnexpectedEOF
+				}
+				return err
+			}
+			chunk = append(chunk, b)
+
+			hash = (hash << 1) + gearTable[b]
+			if hash&c.mask == 0 {
+				break
+			}
+		}
+
+		if err := chunkHandler(chunk); err != nil {
+			return err
+		}
+		remains -= uint64(len(chunk))
+	}
+
+	return nil
+}
+
+var _ Chunker = &chunker{}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func split(c Chunker, content []byte, size uint64) ([][]byte, error) {
+	chunks := make([][]byte, 0)
+	err := c.Split(bytes.NewReader(content), size, func(chunk []byte) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	return chunks, err
+}
+
+func TestChunker_Split(t *testing.T) {
+	c, err := NewChunker(1024, 4096, 8192)
+	assert.Nil(t, err)
+
+	content := make([]byte, 1024*512)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	chunks, err := split(c, content, uint64(len(content)))
+	assert.Nil(t, err)
+	assert.Equal(t, content, bytes.Join(chunks, nil))
+
+	for i, chunk := range chunks {
+		assert.True(t, len(chunk) <= 8192)
+		if i < len(chunks)-1 {
+			assert.True(t, len(chunk) >= 1024)
+		}
+	}
+	// average is respected roughly
+	assert.True(t, len(chunks) > len(content)/8192 && len(chunks) < len(content)/2048)
+
+	shifted := append([]byte("inserted"), content...)
+	shiftedChunks, err := split(c, shifted, uint64(len(shifted)))
+	assert.Nil(t, err)
+
+	hashes := make(map[string]bool)
+	for _, chunk := range chunks {
+		hashes[string(chunk)] = true
+	}
+	shared := 0
+	for _, chunk := range shiftedChunks {
+		if hashes[string(chunk)] {
+			shared++
+		}
+	}
+	assert.True(t, shared >= len(chunks)-2)
+
+	_, err = split(c, content, uint64(len(content)+1))
+	assert.NotNil(t, err)
+
+	_, err = NewChunker(4096, 1024, 8192)
+	assert.NotNil(t, err)
+
+	_, err = NewChunker(4096, 8192, common.MaxBlockSize+1)
+	assert.NotNil(t, err)
+}
@@ -1,43 +1,56 @@
 package manager
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	errors2 "errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"syscall"
 
 	"github.com/freakmaxi/kertish-dfs/basics/common"
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/basics/tracing"
 	cluster2 "github.com/freakmaxi/kertish-dfs/head-node/cluster"
 	"go.uber.org/zap"
 )
 
 const managerEndPoint = "/client/manager"
 
+// chunkBatchCount is the count of the chunks that are reserved and uploaded together when the chunker is set. The file
+// is kept in the memory up to the batch
+const chunkBatchCount = 4
+
+var errUnsupportedAction = errors2.New("manager node does not support the action")
+
 type Cluster interface {
-	Create(size uint64, reader io.Reader) (common.DataChunks, error)
-	CreateShadow(chunks common.DataChunks) error
-	Read(chunks common.DataChunks) (func(w io.Writer, begins int64, ends int64) error, error)
-	Delete(chunks common.DataChunks) (*common.DeletionResult, error)
+	Create(ctx context.Context, size uint64, blockSize uint32, writeQuorum common.WriteQuorum, reader io.Reader) (common.DataChunks, error)
+	CreateShadow(ctx context.Context, chunks common.DataChunks) error
+	Read(ctx context.Context, chunks common.DataChunks, readPreference common.ReadPreference) (func(w io.Writer, begins int64, ends int64) error, error)
+	Delete(ctx context.Context, chunks common.DataChunks) (*common.DeletionResult, error)
 }
 
 type cluster struct {
 	client      http.Client
 	managerAddr []string
+	readAhead   int
+	chunker     Chunker
 	logger      *zap.Logger
 
 	nodeCacheMutex sync.Mutex
 	nodeCache      map[string]cluster2.DataNode
 }
 
-func NewCluster(managerAddresses []string, logger *zap.Logger) (Cluster, error) {
+// NewCluster creates the cluster operations. Files are split into the fixed size chunks by the manager node when the
+// chunker is nil, otherwise they are split with the chunker on the head node and reserved in batches
+func NewCluster(managerAddresses []string, readAhead int, chunker Chunker, logger *zap.Logger) (Cluster, error) {
 	if len(managerAddresses) == 0 {
 		return nil, os.ErrInvalid
 	}
@@ -45,6 +58,8 @@ func NewCluster(managerAddresses []string, logger *zap.Logger) (Cluster, error)
 	return &cluster{
 		client:         http.Client{},
 		managerAddr:    managerAddresses,
+		readAhead:      readAhead,
+		chunker:        chunker,
 		logger:         logger,
 		nodeCacheMutex: sync.Mutex{},
 		nodeCache:      make(map[string]cluster2.DataNode),
@@ -68,38 +83,150 @@ func (c *cluster) getDataNode(address string) (cluster2.DataNode, error) {
 	return dn, nil
 }
 
-func (c *cluster) Create(size uint64, reader io.Reader) (common.DataChunks, error) {
-	reservation, err := c.makeReservation(size)
+// Create stores the data in the chunks of the cluster block size or the requested block size when it is smaller.
+// Requested block size is ignored when the content defined chunking is active. Requested write quorum overrides the
+// write quorum of the cluster, empty value uses the cluster write quorum
+func (c *cluster) Create(ctx context.Context, size uint64, blockSize uint32, writeQuorum common.WriteQuorum, reader io.Reader) (common.DataChunks, error) {
+	if c.chunker != nil {
+		return c.createChunked(ctx, size, writeQuorum, reader)
+	}
+
+	reservation, err := c.makeReservation(ctx, size, blockSize, writeQuorum, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	create := NewCreate(reservation, c.getDataNode, c.findCluster, c.logger)
-	chunks, clusterUsageMap, err := create.process(reader)
+	chunks, clusterUsageMap, err := c.upload(ctx, reservation, readerSource(reader))
+	if err != nil {
+		return nil, err
+	}
+	c.commit(ctx, reservation.Id, clusterUsageMap)
+
+	return chunks, nil
+}
+
+// createChunked reserves and uploads the chunks of the chunker in batches while the file is read, so the file is not
+// kept in the memory. Reservations are committed when all the batches are uploaded
+func (c *cluster) createChunked(ctx context.Context, size uint64, writeQuorum common.WriteQuorum, reader io.Reader) (common.DataChunks, error) {
+	chunks := make(common.DataChunks, 0)
+	reservations := make(map[string]map[string]uint64)
+
+	buffers := make([][]byte, 0)
+	bufferedSize := uint64(0)
+
+	flush := func() error {
+		if len(buffers) == 0 {
+			return nil
+		}
+		if len(chunks)+len(buffers) > math.MaxUint16+1 {
+			return fmt.Errorf("file has more than %d chunks", math.MaxUint16+1)
+		}
+
+		chunkSizes := make([]uint32, 0, len(buffers))
+		for _, buffer := range buffers {
+			chunkSizes = append(chunkSizes, uint32(len(buffer)))
+		}
+
+		reservation, err := c.makeReservation(ctx, bufferedSize, 0, writeQuorum, chunkSizes)
+		if err != nil {
+			return err
+		}
+
+		batchChunks, clusterUsageMap, err := c.upload(ctx, reservation, bufferSource(buffers, reservation))
+		if err != nil {
+			return err
+		}
+		reservations[reservation.Id] = clusterUsageMap
+
+		sequence := uint16(len(chunks))
+		for _, chunk := range batchChunks {
+			chunk.Sequence += sequence
+			chunks = append(chunks, chunk)
+		}
+
+		buffers = make([][]byte, 0)
+		bufferedSize = 0
+
+		return nil
+	}
+
+	err := c.chunker.Split(reader, size, func(chunk []byte) error {
+		buffers = append(buffers, chunk)
+		bufferedSize += uint64(len(chunk))
+
+		if len(buffers) < chunkBatchCount {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
 	if err != nil {
-		if err := c.discardReservation(reservation.Id); err != nil {
-			c.logger.Error(
-				"Discarding reservationMap is failed",
-				zap.String("reservationId", reservation.Id),
-				zap.Error(err),
-			)
+		c.revert(ctx, chunks)
+		for reservationId := range reservations {
+			c.discard(ctx, reservationId)
 		}
 		return nil, err
 	}
 
-	if err := c.commitReservation(reservation.Id, clusterUsageMap); err != nil {
+	for reservationId, clusterUsageMap := range reservations {
+		c.commit(ctx, reservationId, clusterUsageMap)
+	}
+
+	return chunks, nil
+}
+
+// upload creates the chunks of the reservation and discards the reservation if it fails
+func (c *cluster) upload(ctx context.Context, reservation *common.ReservationMap, source func(chunk common.Chunk) ([]byte, error)) (common.DataChunks, map[string]uint64, error) {
+	create := NewCreate(reservation, c.getDataNode, c.findCluster, c.logger)
+	chunks, clusterUsageMap, err := create.process(ctx, source)
+	if err != nil {
+		c.discard(ctx, reservation.Id)
+		return nil, nil, err
+	}
+	return chunks, clusterUsageMap, nil
+}
+
+// revert deletes the chunks of the batches that are uploaded before the failure
+func (c *cluster) revert(ctx context.Context, chunks common.DataChunks) {
+	successChan := make(chan *common.DataChunk, len(chunks))
+	for _, chunk := range chunks {
+		successChan <- chunk
+	}
+	close(successChan)
+
+	errorChan := make(chan error, len(chunks))
+	NewCreate(nil, c.getDataNode, c.findCluster, c.logger).revert(ctx, successChan, errorChan)
+	close(errorChan)
+
+	for err := range errorChan {
+		c.logger.Error("Reverting uploaded chunk is failed", zap.Error(err))
+	}
+}
+
+func (c *cluster) discard(ctx context.Context, reservationId string) {
+	if err := c.discardReservation(ctx, reservationId); err != nil {
 		c.logger.Error(
-			"Committing reservationMap is failed",
-			zap.String("reservationId", reservation.Id),
+			"Discarding reservationMap is failed",
+			zap.String("reservationId", reservationId),
 			zap.Error(err),
 		)
 	}
+}
 
-	return chunks, nil
+func (c *cluster) commit(ctx context.Context, reservationId string, clusterUsageMap map[string]uint64) {
+	if err := c.commitReservation(ctx, reservationId, clusterUsageMap); err != nil {
+		c.logger.Error(
+			"Committing reservationMap is failed",
+			zap.String("reservationId", reservationId),
+			zap.Error(err),
+		)
+	}
 }
 
-func (c *cluster) CreateShadow(chunks common.DataChunks) error {
-	m, err := c.createClusterMap(chunks, common.MT_Create)
+func (c *cluster) CreateShadow(ctx context.Context, chunks common.DataChunks) error {
+	m, err := c.createClusterMap(ctx, chunks, common.MT_Create)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return errors.ErrZombie
@@ -114,7 +241,7 @@ func (c *cluster) CreateShadow(chunks common.DataChunks) error {
 				return err
 			}
 
-			if err := dn.CreateShadow(chunk.Hash); err != nil {
+			if err := dn.CreateShadow(ctx, chunk.Hash); err != nil {
 				return err
 			}
 		}
@@ -123,10 +250,11 @@ func (c *cluster) CreateShadow(chunks common.DataChunks) error {
 	return nil
 }
 
-func (c *cluster) Read(chunks common.DataChunks) (func(w io.Writer, begins int64, ends int64) error, error) {
+// Read reads the chunks from the nodes selected by the read preference, empty value uses the cluster read preference
+func (c *cluster) Read(ctx context.Context, chunks common.DataChunks, readPreference common.ReadPreference) (func(w io.Writer, begins int64, ends int64) error, error) {
 	sort.Sort(chunks)
 
-	m, err := c.createClusterMap(chunks, common.MT_Read)
+	m, err := c.createReplicaMap(ctx, chunks, readPreference)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, errors.ErrZombie
@@ -135,63 +263,16 @@ func (c *cluster) Read(chunks common.DataChunks) (func(w io.Writer, begins int64
 	}
 
 	return func(w io.Writer, begins int64, ends int64) error {
-		total := int64(0)
-		for _, chunk := range chunks {
-			chunkSize := int64(chunk.Size)
-
-			total += chunkSize
-			if total < begins {
-				continue
-			}
-			if ends > -1 && ends < total-chunkSize {
-				break
-			}
-
-			startPoint := total - chunkSize
-			startPoint = begins - startPoint
-			if startPoint < 0 {
-				startPoint = 0
-			}
-
-			endPoint := chunkSize
-			if ends > -1 {
-				endsCal := (total - 1) - ends
-				if endsCal > 0 && endsCal < chunkSize {
-					endPoint -= endsCal
-				}
-			}
-
-			address, has := m[chunk.Hash]
-			if !has {
-				continue
-			}
-
-			dn, err := c.getDataNode(address)
-			if err != nil {
-				return err
-			}
-
-			if err := dn.Read(chunk.Hash, func(buffer []byte) error {
-				_, err := w.Write(buffer[startPoint:endPoint])
-				if errors2.Is(err, syscall.EPIPE) {
-					return nil
-				}
-				return err
-			}); err != nil {
-				return err
-			}
-		}
-
-		return nil
+		return NewRead(m, c.getDataNode, c.readAhead).process(ctx, w, chunks, begins, ends)
 	}, nil
 }
 
-func (c *cluster) Delete(chunks common.DataChunks) (*common.DeletionResult, error) {
+func (c *cluster) Delete(ctx context.Context, chunks common.DataChunks) (*common.DeletionResult, error) {
 	if len(chunks) == 0 {
 		return nil, errors.ErrZombie
 	}
 
-	m, err := c.createClusterMap(chunks, common.MT_Delete)
+	m, err := c.createClusterMap(ctx, chunks, common.MT_Delete)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, errors.ErrZombie
@@ -214,7 +295,7 @@ func (c *cluster) Delete(chunks common.DataChunks) (*common.DeletionResult, erro
 			continue
 		}
 
-		if err := dn.Delete(chunk.Hash); err != nil {
+		if err := dn.Delete(ctx, chunk.Hash); err != nil {
 			deletionResult.Untouched = append(deletionResult.Untouched, chunk.Hash)
 			continue
 		}
@@ -225,13 +306,39 @@ func (c *cluster) Delete(chunks common.DataChunks) (*common.DeletionResult, erro
 	return &deletionResult, nil
 }
 
-func (c *cluster) makeReservation(size uint64) (*common.ReservationMap, error) {
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s%s", c.managerAddr[0], managerEndPoint), nil)
+func (c *cluster) makeReservation(ctx context.Context, size uint64, blockSize uint32, writeQuorum common.WriteQuorum, chunkSizes []uint32) (_ *common.ReservationMap, err error) {
+	ctx, span := tracing.Start(ctx, "cluster.makeReservation")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	// chunk sizes are sent in the body, the list of a large file does not fit in the request headers
+	var body io.Reader
+	if len(chunkSizes) > 0 {
+		chunkSizesJson, err := json.Marshal(chunkSizes)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(chunkSizesJson)
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s%s", c.managerAddr[0], managerEndPoint), body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("X-Action", "reserve")
 	req.Header.Set("X-Size", strconv.FormatUint(size, 10))
+	if blockSize > 0 {
+		req.Header.Set("X-Block-Size", strconv.FormatUint(uint64(blockSize), 10))
+	}
+	if len(writeQuorum) > 0 {
+		req.Header.Set("X-Write-Quorum", string(writeQuorum))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	tracing.Inject(ctx, req.Header)
 
 	res, err := c.client.Do(req)
 	if err != nil {
@@ -257,13 +364,20 @@ func (c *cluster) makeReservation(size uint64) (*common.ReservationMap, error) {
 	return &reservationMap, nil
 }
 
-func (c *cluster) discardReservation(reservationId string) error {
+func (c *cluster) discardReservation(ctx context.Context, reservationId string) (err error) {
+	ctx, span := tracing.Start(ctx, "cluster.discardReservation")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s%s", c.managerAddr[0], managerEndPoint), nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("X-Action", "discard")
 	req.Header.Set("X-Reservation-Id", reservationId)
+	tracing.Inject(ctx, req.Header)
 
 	res, err := c.client.Do(req)
 	if err != nil {
@@ -278,7 +392,13 @@ func (c *cluster) discardReservation(reservationId string) error {
 	return nil
 }
 
-func (c *cluster) commitReservation(reservationId string, clusterUsageMap map[string]uint64) error {
+func (c *cluster) commitReservation(ctx context.Context, reservationId string, clusterUsageMap map[string]uint64) (err error) {
+	ctx, span := tracing.Start(ctx, "cluster.commitReservation")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	req, err := http.NewRequest("DELETE", fmt.Sprintf("%s%s", c.managerAddr[0], managerEndPoint), nil)
 	if err != nil {
 		return err
@@ -291,6 +411,7 @@ func (c *cluster) commitReservation(reservationId string, clusterUsageMap map[st
 		clusterUsageList = append(clusterUsageList, fmt.Sprintf("%s=%d", k, v))
 	}
 	req.Header.Set("X-Options", strings.Join(clusterUsageList, ","))
+	tracing.Inject(ctx, req.Header)
 
 	res, err := c.client.Do(req)
 	if err != nil {
@@ -305,13 +426,20 @@ func (c *cluster) commitReservation(reservationId string, clusterUsageMap map[st
 	return nil
 }
 
-func (c *cluster) findCluster(sha512Hex string) (string, string, error) {
+func (c *cluster) findCluster(ctx context.Context, sha512Hex string) (_ string, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "cluster.findCluster")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	req, err := http.NewRequest("GET", fmt.Sprintf("%s%s", c.managerAddr[0], managerEndPoint), nil)
 	if err != nil {
 		return "", "", err
 	}
 	req.Header.Set("X-Action", "find")
 	req.Header.Set("X-Options", sha512Hex)
+	tracing.Inject(ctx, req.Header)
 
 	res, err := c.client.Do(req)
 	if err != nil {
@@ -344,13 +472,13 @@ func (c *cluster) findCluster(sha512Hex string) (string, string, error) {
 	return "", "", errors.ErrRemote
 }
 
-func (c *cluster) createClusterMap(chunks common.DataChunks, mapType common.MapType) (map[string]string, error) {
+func (c *cluster) createClusterMap(ctx context.Context, chunks common.DataChunks, mapType common.MapType) (map[string]string, error) {
 	sha512HexList := make([]string, 0)
 	for _, chunk := range chunks {
 		sha512HexList = append(sha512HexList, chunk.Hash)
 	}
 
-	m, err := c.requestClusterMap(sha512HexList, mapType)
+	m, err := c.requestClusterMap(ctx, sha512HexList, mapType, "")
 	if err != nil {
 		return nil, err
 	}
@@ -358,7 +486,83 @@ func (c *cluster) createClusterMap(chunks common.DataChunks, mapType common.MapT
 	return m, nil
 }
 
-func (c *cluster) requestClusterMap(sha512HexList []string, mapType common.MapType) (map[string]string, error) {
+// createReplicaMap creates the read map with all the replicas of the chunks. Manager nodes without the replica map
+// support are asked for the read map
+func (c *cluster) createReplicaMap(ctx context.Context, chunks common.DataChunks, readPreference common.ReadPreference) (map[string]common.NodeList, error) {
+	sha512HexList := make([]string, 0)
+	for _, chunk := range chunks {
+		sha512HexList = append(sha512HexList, chunk.Hash)
+	}
+
+	m, err := c.requestReplicaMap(ctx, sha512HexList, readPreference)
+	if err != errUnsupportedAction {
+		return m, err
+	}
+
+	clusterMap, err := c.requestClusterMap(ctx, sha512HexList, common.MT_Read, readPreference)
+	if err != nil {
+		return nil, err
+	}
+
+	m = make(map[string]common.NodeList)
+	for sha512Hex, address := range clusterMap {
+		m[sha512Hex] = common.NodeList{{Address: address}}
+	}
+	return m, nil
+}
+
+func (c *cluster) requestReplicaMap(ctx context.Context, sha512HexList []string, readPreference common.ReadPreference) (_ map[string]common.NodeList, err error) {
+	ctx, span := tracing.Start(ctx, "cluster.requestReplicaMap")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s%s", c.managerAddr[0], managerEndPoint), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Action", "replicaMap")
+	req.Header.Set("X-Options", strings.Join(sha512HexList, ","))
+	if len(readPreference) > 0 {
+		req.Header.Set("X-Read-Preference", string(readPreference))
+	}
+	tracing.Inject(ctx, req.Header)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != 200 {
+		switch res.StatusCode {
+		case 404:
+			return nil, errors.ErrNotFound
+		case 422:
+			return nil, errUnsupportedAction
+		case 503:
+			return nil, errors.ErrNoAvailableActionNode
+		}
+
+		return nil, fmt.Errorf("cluster manager request is failed (requestReplicaMap): %d - %s", res.StatusCode, common.NewErrorFromReader(res.Body).Message)
+	}
+
+	var replicaMapping map[string]common.NodeList
+	if err := json.NewDecoder(res.Body).Decode(&replicaMapping); err != nil {
+		return nil, err
+	}
+
+	return replicaMapping, nil
+}
+
+func (c *cluster) requestClusterMap(ctx context.Context, sha512HexList []string, mapType common.MapType, readPreference common.ReadPreference) (_ map[string]string, err error) {
+	ctx, span := tracing.Start(ctx, "cluster.requestClusterMap")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	req, err := http.NewRequest("POST", fmt.Sprintf("%s%s", c.managerAddr[0], managerEndPoint), nil)
 	if err != nil {
 		return nil, err
@@ -373,6 +577,10 @@ func (c *cluster) requestClusterMap(sha512HexList []string, mapType common.MapTy
 	}
 	req.Header.Set("X-Action", fmt.Sprintf("%sMap", mode))
 	req.Header.Set("X-Options", strings.Join(sha512HexList, ","))
+	if mapType == common.MT_Read && len(readPreference) > 0 {
+		req.Header.Set("X-Read-Preference", string(readPreference))
+	}
+	tracing.Inject(ctx, req.Header)
 
 	res, err := c.client.Do(req)
 	if err != nil {
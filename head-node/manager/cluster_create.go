@@ -1,22 +1,30 @@
 package manager
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/freakmaxi/kertish-dfs/basics/common"
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/basics/protocol"
 	cluster2 "github.com/freakmaxi/kertish-dfs/head-node/cluster"
 	"go.uber.org/zap"
 )
 
+// quorumTimeout limits the wait of the replicas to hold the uploaded chunk
+const quorumTimeout = time.Second * 30
+const quorumTick = time.Millisecond * 200
+
 type create struct {
 	reservationMap          *common.ReservationMap
 	dataNodeProviderHandler func(address string) (cluster2.DataNode, error)
-	findClusterHandler      func(sha512Hex string) (string, string, error)
+	findClusterHandler      func(ctx context.Context, sha512Hex string) (string, string, error)
 	logger                  *zap.Logger
 
 	clusterUsageMutex sync.Mutex
@@ -26,7 +34,7 @@ type create struct {
 func NewCreate(
 	reservationMap *common.ReservationMap,
 	dataNodeProviderHandler func(address string) (cluster2.DataNode, error),
-	findClusterHandler func(sha512Hex string) (string, string, error),
+	findClusterHandler func(ctx context.Context, sha512Hex string) (string, string, error),
 	logger *zap.Logger,
 ) *create {
 	return &create{
@@ -45,7 +53,39 @@ func (c *create) calculateHash(data []byte) string {
 	return hex.EncodeToString(hash.Sum(nil))
 }
 
-func (c *create) process(reader io.Reader) (common.DataChunks, map[string]uint64, error) {
+// readerSource reads the chunks from the stream in the reservation chunk sizes
+func readerSource(reader io.Reader) func(chunk common.Chunk) ([]byte, error) {
+	return func(chunk common.Chunk) ([]byte, error) {
+		buffer := make([]byte, chunk.Size)
+		if _, err := io.ReadAtLeast(reader, buffer, len(buffer)); err != nil {
+			return nil, err
+		}
+		return buffer, nil
+	}
+}
+
+// bufferSource serves the chunks that are already split by the chunker. If the manager node does not place the
+// reservation in the same chunk sizes (older version), the buffers are read as a stream in the reservation sizes
+func bufferSource(buffers [][]byte, reservationMap *common.ReservationMap) func(chunk common.Chunk) ([]byte, error) {
+	matches := len(buffers) == len(reservationMap.Clusters)
+	for i := 0; matches && i < len(buffers); i++ {
+		matches = uint32(len(buffers[i])) == reservationMap.Clusters[i].Chunk.Size
+	}
+
+	if !matches {
+		readers := make([]io.Reader, 0, len(buffers))
+		for _, buffer := range buffers {
+			readers = append(readers, bytes.NewReader(buffer))
+		}
+		return readerSource(io.MultiReader(readers...))
+	}
+
+	return func(chunk common.Chunk) ([]byte, error) {
+		return buffers[chunk.Sequence], nil
+	}
+}
+
+func (c *create) process(ctx context.Context, source func(chunk common.Chunk) ([]byte, error)) (common.DataChunks, map[string]uint64, error) {
 	successChan := make(chan *common.DataChunk, len(c.reservationMap.Clusters))
 	errorChan := make(chan error, len(c.reservationMap.Clusters))
 
@@ -55,22 +95,21 @@ func (c *create) process(reader io.Reader) (common.DataChunks, map[string]uint64
 			break
 		}
 
-		buffer := make([]byte, clusterMap.Chunk.Size)
-		_, err := io.ReadAtLeast(reader, buffer, len(buffer))
+		buffer, err := source(clusterMap.Chunk)
 		if err != nil {
 			errorChan <- err
 			break
 		}
 
 		wg.Add(1)
-		go c.upload(wg, clusterMap, buffer, successChan, errorChan)
+		go c.upload(ctx, wg, clusterMap, buffer, successChan, errorChan)
 	}
 	wg.Wait()
 
 	close(successChan)
 
 	if len(errorChan) > 0 {
-		c.revert(successChan, errorChan)
+		c.revert(ctx, successChan, errorChan)
 		close(errorChan)
 
 		return nil, nil, c.createBulkError(errorChan, len(c.reservationMap.Clusters))
@@ -80,11 +119,11 @@ func (c *create) process(reader io.Reader) (common.DataChunks, map[string]uint64
 	return c.complete(successChan), c.clusterUsage, nil
 }
 
-func (c *create) upload(wg *sync.WaitGroup, clusterMap common.ClusterMap, data []byte, successChan chan *common.DataChunk, errorChan chan error) {
+func (c *create) upload(ctx context.Context, wg *sync.WaitGroup, clusterMap common.ClusterMap, data []byte, successChan chan *common.DataChunk, errorChan chan error) {
 	defer wg.Done()
 
 	sha512Hex := c.calculateHash(data)
-	clusterId, address, err := c.findClusterHandler(sha512Hex)
+	clusterId, address, err := c.findClusterHandler(ctx, sha512Hex)
 	if err != nil {
 		if err == errors.ErrRemote {
 			errorChan <- errors.NewUploadError(
@@ -127,7 +166,7 @@ func (c *create) upload(wg *sync.WaitGroup, clusterMap common.ClusterMap, data [
 		return
 	}
 
-	exists, sha512Hex, err := dn.Create(data)
+	exists, sha512Hex, err := dn.Create(ctx, data)
 	if err != nil {
 		errorChan <- errors.NewUploadError(
 			fmt.Sprintf(
@@ -141,6 +180,32 @@ func (c *create) upload(wg *sync.WaitGroup, clusterMap common.ClusterMap, data [
 		return
 	}
 
+	// chunk is already placed in another cluster, its replicas are not related with the reservation
+	if clusterMap.Quorum > 1 && clusterId == clusterMap.Id {
+		if err := c.waitQuorum(ctx, clusterMap, address, sha512Hex); err != nil {
+			if err := dn.Delete(ctx, sha512Hex); err != nil {
+				c.logger.Error(
+					"Deleting chunk of failed write quorum is failed",
+					zap.String("clusterId", clusterId),
+					zap.String("address", address),
+					zap.String("sha512Hex", sha512Hex),
+					zap.Error(err),
+				)
+			}
+
+			errorChan <- errors.NewUploadError(
+				fmt.Sprintf(
+					"unable to create chunk, clusterId: %s, sha512Hex: %s, quorum: %d, error: %s",
+					clusterMap.Id,
+					sha512Hex,
+					clusterMap.Quorum,
+					err,
+				),
+			)
+			return
+		}
+	}
+
 	clusterUsage := uint32(len(data))
 	if exists {
 		clusterUsage = 0
@@ -150,6 +215,54 @@ func (c *create) upload(wg *sync.WaitGroup, clusterMap common.ClusterMap, data [
 	successChan <- common.NewDataChunk(clusterMap.Chunk.Sequence, uint32(len(data)), sha512Hex)
 }
 
+// waitQuorum waits the replicas of the cluster till the quorum of the nodes, master included, hold the chunk.
+// Replicas are filled by the sync of the manager node, data nodes without the exists capability are not counted
+func (c *create) waitQuorum(ctx context.Context, clusterMap common.ClusterMap, address string, sha512Hex string) error {
+	replicas := make([]string, 0, len(clusterMap.Replicas))
+	for _, replica := range clusterMap.Replicas {
+		if replica != address {
+			replicas = append(replicas, replica)
+		}
+	}
+
+	required := clusterMap.Quorum - 1
+	deadline := time.Now().Add(quorumTimeout)
+
+	for {
+		for i := 0; i < len(replicas); {
+			dn, err := c.dataNodeProviderHandler(replicas[i])
+			if err != nil {
+				i++
+				continue
+			}
+
+			exists, err := dn.Exists(ctx, sha512Hex)
+			switch {
+			case err == protocol.ErrUnknownCommand:
+			case err == nil && exists:
+				required--
+			default:
+				i++
+				continue
+			}
+			replicas = append(replicas[:i], replicas[i+1:]...)
+		}
+
+		if required <= 0 {
+			return nil
+		}
+		if len(replicas) < required || time.Now().After(deadline) {
+			return errors.ErrQuorum
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(quorumTick):
+		}
+	}
+}
+
 func (c *create) updateClusterUsage(clusterId string, size uint64) {
 	c.clusterUsageMutex.Lock()
 	defer c.clusterUsageMutex.Unlock()
@@ -168,9 +281,9 @@ func (c *create) complete(successChan chan *common.DataChunk) common.DataChunks
 	return chunks
 }
 
-func (c *create) revert(successChan chan *common.DataChunk, errorChan chan error) {
+func (c *create) revert(ctx context.Context, successChan chan *common.DataChunk, errorChan chan error) {
 	for dataChunk := range successChan {
-		clusterId, address, err := c.findClusterHandler(dataChunk.Hash)
+		clusterId, address, err := c.findClusterHandler(ctx, dataChunk.Hash)
 		if err != nil {
 			errorChan <- fmt.Errorf(
 				"unable to revert chunk creation, sha512Hex: %s, error: %s",
@@ -192,7 +305,7 @@ func (c *create) revert(successChan chan *common.DataChunk, errorChan chan error
 			continue
 		}
 
-		if err := dn.Delete(dataChunk.Hash); err != nil {
+		if err := dn.Delete(ctx, dataChunk.Hash); err != nil {
 			errorChan <- fmt.Errorf(
 				"unable to delete chunk, failure on data node, clusterId: %s, address: %s, sha512Hex: %s, error: %s",
 				clusterId,
@@ -0,0 +1,276 @@
+package manager
+
+import (
+	"context"
+	errors2 "errors"
+	"io"
+	"syscall"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	cluster2 "github.com/freakmaxi/kertish-dfs/head-node/cluster"
+)
+
+const hedgeMinimumDelay = time.Millisecond * 20
+const hedgeMaximumDelay = time.Second * 2
+const hedgeQualityFactor = 4
+
+type readPart struct {
+	index      int
+	chunk      *common.DataChunk
+	startPoint int64
+	endPoint   int64
+	replicas   common.NodeList
+}
+
+type readResult struct {
+	data []byte
+	err  error
+}
+
+type read struct {
+	replicaMap              map[string]common.NodeList
+	dataNodeProviderHandler func(address string) (cluster2.DataNode, error)
+	readAhead               int
+}
+
+func NewRead(
+	replicaMap map[string]common.NodeList,
+	dataNodeProviderHandler func(address string) (cluster2.DataNode, error),
+	readAhead int,
+) *read {
+	return &read{
+		replicaMap:              replicaMap,
+		dataNodeProviderHandler: dataNodeProviderHandler,
+		readAhead:               readAhead,
+	}
+}
+
+// parts calculates the part of the chunks that are in the requested range
+func (r *read) parts(chunks common.DataChunks, begins int64, ends int64) []*readPart {
+	parts := make([]*readPart, 0)
+
+	total := int64(0)
+	for _, chunk := range chunks {
+		chunkSize := int64(chunk.Size)
+
+		total += chunkSize
+		if total < begins {
+			continue
+		}
+		if ends > -1 && ends < total-chunkSize {
+			break
+		}
+
+		startPoint := total - chunkSize
+		startPoint = begins - startPoint
+		if startPoint < 0 {
+			startPoint = 0
+		}
+
+		endPoint := chunkSize
+		if ends > -1 {
+			endsCal := (total - 1) - ends
+			if endsCal > 0 && endsCal < chunkSize {
+				endPoint -= endsCal
+			}
+		}
+
+		replicas, has := r.replicaMap[chunk.Hash]
+		if !has || len(replicas) == 0 {
+			continue
+		}
+
+		if startPoint >= endPoint {
+			continue
+		}
+
+		parts = append(parts, &readPart{
+			index:      len(parts),
+			chunk:      chunk,
+			startPoint: startPoint,
+			endPoint:   endPoint,
+			replicas:   replicas,
+		})
+	}
+
+	return parts
+}
+
+func (r *read) process(ctx context.Context, w io.Writer, chunks common.DataChunks, begins int64, ends int64) error {
+	parts := r.parts(chunks, begins, ends)
+
+	if r.readAhead < 2 {
+		for _, part := range parts {
+			if err := r.fetch(ctx, part, func(buffer []byte) error {
+				return r.write(w, buffer)
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	results := make([]chan readResult, len(parts))
+	for i := range results {
+		results[i] = make(chan readResult, 1)
+	}
+
+	// slots keeps the fetched but not written parts limited to bound the memory usage
+	slots := make(chan struct{}, r.readAhead)
+	quit := make(chan struct{})
+	defer close(quit)
+
+	// fetches in flight are aborted when the read fails or the writer stops
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		for _, part := range parts {
+			select {
+			case slots <- struct{}{}:
+			case <-quit:
+				return
+			}
+
+			go func(part *readPart) {
+				var data []byte
+				err := r.fetch(ctx, part, func(buffer []byte) error {
+					data = buffer
+					return nil
+				})
+				results[part.index] <- readResult{data: data, err: err}
+			}(part)
+		}
+	}()
+
+	for i := range parts {
+		var result readResult
+		select {
+		case result = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if result.err != nil {
+			return result.err
+		}
+
+		if err := r.write(w, result.data); err != nil {
+			return err
+		}
+		<-slots
+	}
+
+	return nil
+}
+
+// fetch reads the part from the first replica that the manager node ordered for the read preference. If the replica
+// does not start responding in its hedge delay, the same read is issued to the next replica and the first answer is
+// used. Failed reads are continued on the remaining replicas
+func (r *read) fetch(ctx context.Context, part *readPart, readHandler func([]byte) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	started := make(chan struct{}, len(part.replicas))
+	results := make(chan readResult, len(part.replicas))
+
+	next, pending := 0, 0
+	launch := func() *time.Timer {
+		node := part.replicas[next]
+		next++
+		pending++
+
+		go func() {
+			var data []byte
+			err := r.fetchFrom(ctx, node.Address, part, func() {
+				started <- struct{}{}
+			}, func(buffer []byte) error {
+				data = buffer
+				return nil
+			})
+			results <- readResult{data: data, err: err}
+		}()
+
+		if next == len(part.replicas) {
+			return nil
+		}
+		return time.NewTimer(hedgeDelay(node))
+	}
+
+	hedge := launch()
+	stopHedge := func() {
+		if hedge != nil {
+			hedge.Stop()
+			hedge = nil
+		}
+	}
+	defer stopHedge()
+
+	var err error
+	for pending > 0 {
+		var hedgeChan <-chan time.Time
+		if hedge != nil {
+			hedgeChan = hedge.C
+		}
+
+		select {
+		case <-started:
+			// one of the replicas is responding, no need to hedge anymore
+			stopHedge()
+		case <-hedgeChan:
+			hedge = launch()
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				cancel()
+				return readHandler(result.data)
+			}
+			err = result.err
+
+			if pending == 0 && next < len(part.replicas) {
+				stopHedge()
+				hedge = launch()
+			}
+		}
+	}
+	return err
+}
+
+// hedgeDelay is the time to wait for the node to start responding before hedging the read on another replica
+func hedgeDelay(node *common.Node) time.Duration {
+	// negative quality is the unreachable node, there is no reason to wait for it
+	if node.Quality < 0 {
+		return hedgeMinimumDelay
+	}
+	if node.Quality > int64(hedgeMaximumDelay/time.Millisecond) {
+		return hedgeMaximumDelay
+	}
+
+	delay := hedgeMinimumDelay + time.Duration(node.Quality)*time.Millisecond*hedgeQualityFactor
+	if delay > hedgeMaximumDelay {
+		return hedgeMaximumDelay
+	}
+	return delay
+}
+
+func (r *read) fetchFrom(ctx context.Context, address string, part *readPart, startedHandler func(), readHandler func([]byte) error) error {
+	dn, err := r.dataNodeProviderHandler(address)
+	if err != nil {
+		return err
+	}
+
+	if part.startPoint == 0 && part.endPoint == int64(part.chunk.Size) {
+		return dn.Read(ctx, part.chunk.Hash, startedHandler, readHandler)
+	}
+
+	// only the requested part of the chunk is transferred from the data node
+	return dn.ReadRange(ctx, part.chunk.Hash, uint32(part.startPoint), uint32(part.endPoint-part.startPoint), startedHandler, readHandler)
+}
+
+func (r *read) write(w io.Writer, buffer []byte) error {
+	_, err := w.Write(buffer)
+	if errors2.Is(err, syscall.EPIPE) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	cluster2 "github.com/freakmaxi/kertish-dfs/head-node/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+type testDataNode struct {
+	blocks map[string][]byte
+	delay  time.Duration
+	fail   bool
+}
+
+func (t *testDataNode) Create(_ context.Context, _ []byte) (bool, string, error) {
+	return false, "", os.ErrInvalid
+}
+
+func (t *testDataNode) CreateShadow(_ context.Context, _ string) error {
+	return os.ErrInvalid
+}
+
+func (t *testDataNode) Read(ctx context.Context, sha512Hex string, startedHandler func(), readHandler func([]byte) error) error {
+	return t.ReadRange(ctx, sha512Hex, 0, uint32(len(t.blocks[sha512Hex])), startedHandler, readHandler)
+}
+
+func (t *testDataNode) ReadRange(ctx context.Context, sha512Hex string, offset uint32, length uint32, startedHandler func(), readHandler func([]byte) error) error {
+	select {
+	case <-time.After(t.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if t.fail {
+		return os.ErrNotExist
+	}
+	startedHandler()
+
+	return readHandler(t.blocks[sha512Hex][offset : offset+length])
+}
+
+func (t *testDataNode) Delete(_ context.Context, _ string) error {
+	return os.ErrInvalid
+}
+
+func (t *testDataNode) Exists(_ context.Context, sha512Hex string) (bool, error) {
+	_, has := t.blocks[sha512Hex]
+	return has, nil
+}
+
+func newTestRead(nodes map[string]*testDataNode, replicaMap map[string]common.NodeList, readAhead int) *read {
+	return NewRead(replicaMap, func(address string) (cluster2.DataNode, error) {
+		return nodes[address], nil
+	}, readAhead)
+}
+
+func TestRead_Process(t *testing.T) {
+	blocks := make(map[string][]byte)
+	chunks := make(common.DataChunks, 0)
+	content := make([]byte, 0)
+	for i := 0; i < 5; i++ {
+		hash := fmt.Sprintf("chunk%d", i)
+		blocks[hash] = bytes.Repeat([]byte{byte('a' + i)}, 10)
+		chunks = append(chunks, common.NewDataChunk(uint16(i), 10, hash))
+		content = append(content, blocks[hash]...)
+	}
+
+	nodes := map[string]*testDataNode{
+		"master": {blocks: blocks},
+		"slave":  {blocks: blocks, delay: time.Millisecond * 5},
+	}
+	replicaMap := make(map[string]common.NodeList)
+	for hash := range blocks {
+		replicaMap[hash] = common.NodeList{{Address: "master"}, {Address: "slave"}}
+	}
+
+	for _, readAhead := range []int{1, 4} {
+		r := newTestRead(nodes, replicaMap, readAhead)
+
+		buffer := bytes.NewBuffer(nil)
+		assert.Nil(t, r.process(context.Background(), buffer, chunks, 0, -1))
+		assert.Equal(t, content, buffer.Bytes())
+
+		buffer.Reset()
+		assert.Nil(t, r.process(context.Background(), buffer, chunks, 15, 34))
+		assert.Equal(t, content[15:35], buffer.Bytes())
+	}
+}
+
+func TestRead_Hedged(t *testing.T) {
+	blocks := map[string][]byte{"chunk": []byte("content")}
+
+	nodes := map[string]*testDataNode{
+		"slow": {blocks: blocks, delay: time.Second * 5},
+		"fast": {blocks: blocks},
+	}
+	replicaMap := map[string]common.NodeList{
+		"chunk": {{Address: "slow"}, {Address: "fast"}},
+	}
+	chunks := common.DataChunks{common.NewDataChunk(0, 7, "chunk")}
+
+	begins := time.Now()
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(t, newTestRead(nodes, replicaMap, 1).process(context.Background(), buffer, chunks, 0, -1))
+	assert.Equal(t, blocks["chunk"], buffer.Bytes())
+	assert.True(t, time.Since(begins) < time.Second)
+}
+
+func TestRead_Failover(t *testing.T) {
+	blocks := map[string][]byte{"chunk": []byte("content")}
+
+	nodes := map[string]*testDataNode{
+		"broken": {blocks: blocks, fail: true},
+		"stable": {blocks: blocks, delay: time.Millisecond * 50},
+	}
+	replicaMap := map[string]common.NodeList{
+		"chunk": {{Address: "broken", Quality: 1000}, {Address: "stable"}},
+	}
+	chunks := common.DataChunks{common.NewDataChunk(0, 7, "chunk")}
+
+	buffer := bytes.NewBuffer(nil)
+	assert.Nil(t, newTestRead(nodes, replicaMap, 1).process(context.Background(), buffer, chunks, 0, -1))
+	assert.Equal(t, blocks["chunk"], buffer.Bytes())
+
+	nodes["stable"].fail = true
+	assert.NotNil(t, newTestRead(nodes, replicaMap, 1).process(context.Background(), buffer, chunks, 0, -1))
+}
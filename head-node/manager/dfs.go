@@ -1,22 +1,26 @@
 package manager
 
 import (
+	"context"
+	"fmt"
 	"io"
 
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/tracing"
 	"github.com/freakmaxi/kertish-dfs/head-node/data"
 	"go.uber.org/zap"
 )
 
 type Dfs interface {
-	CreateFolder(folderPath string) error
-	CreateFile(path string, mime string, size uint64, overwrite bool, contentReader io.Reader) error
+	CreateFolder(ctx context.Context, folderPath string) error
+	CreateFile(ctx context.Context, path string, mime string, size uint64, blockSize uint32, writeQuorum common.WriteQuorum, overwrite bool, contentReader io.Reader) error
 
-	Read(paths []string, join bool) (ReadContainer, error)
-	Size(folderPath string) (uint64, error)
+	Read(ctx context.Context, paths []string, join bool, readPreference common.ReadPreference) (ReadContainer, error)
+	Size(ctx context.Context, folderPath string) (uint64, error)
 
-	Change(sources []string, target string, join bool, overwrite bool, move bool) error
+	Change(ctx context.Context, sources []string, target string, join bool, overwrite bool, move bool) error
 
-	Delete(path string, killZombies bool) error
+	Delete(ctx context.Context, path string, killZombies bool) error
 }
 
 type dfs struct {
@@ -33,4 +37,14 @@ func NewDfs(metadata data.Metadata, cluster Cluster, logger *zap.Logger) Dfs {
 	}
 }
 
+// traceMetadata wraps the metadata operation with a span to expose the time spent on the database
+func (d *dfs) traceMetadata(ctx context.Context, operation string, handler func(ctx context.Context) error) error {
+	ctx, span := tracing.Start(ctx, fmt.Sprintf("metadata.%s", operation))
+	err := handler(ctx)
+	span.SetError(err)
+	span.End()
+
+	return err
+}
+
 var _ Dfs = &dfs{}
@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"context"
 	"os"
 	"strings"
 
@@ -8,20 +9,29 @@ import (
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
 )
 
-func (d *dfs) Change(sources []string, target string, join bool, overwrite bool, move bool) error {
+func (d *dfs) Change(ctx context.Context, sources []string, target string, join bool, overwrite bool, move bool) error {
 	if len(sources) > 1 && !join {
 		return os.ErrInvalid
 	}
-	if err := d.changeFolder(sources, target, move); err != nil {
+
+	eventType := common.ET_Copy
+	if move {
+		eventType = common.ET_Move
+	}
+
+	eventSources := common.CorrectPaths(sources)
+	eventTarget := common.CorrectPath(target)
+
+	if err := d.changeFolder(ctx, sources, target, move, common.Events{common.NewEvent(eventType, true, eventSources, eventTarget)}); err != nil {
 		if err != os.ErrNotExist {
 			return err
 		}
-		return d.changeFile(sources, target, overwrite, move)
+		return d.changeFile(ctx, sources, target, overwrite, move, common.Events{common.NewEvent(eventType, false, eventSources, eventTarget)})
 	}
 	return nil
 }
 
-func (d *dfs) changeFolder(sources []string, target string, move bool) error {
+func (d *dfs) changeFolder(ctx context.Context, sources []string, target string, move bool, changeEvents common.Events) error {
 	sources = common.CorrectPaths(sources)
 	target = common.CorrectPath(target)
 
@@ -89,39 +99,41 @@ func (d *dfs) changeFolder(sources []string, target string, move bool) error {
 		}
 	}
 
-	if err := d.metadata.SaveChain(target, func(targetFolder *common.Folder) (bool, error) {
-		if len(targetFolder.Files) > 0 || len(targetFolder.Folders) > 0 {
-			return false, errors.ErrNotEmpty
-		}
+	if err := d.traceMetadata(ctx, "SaveChain", func(ctx context.Context) error {
+		return d.metadata.SaveChain(target, nil, func(targetFolder *common.Folder) (bool, error) {
+			if len(targetFolder.Files) > 0 || len(targetFolder.Folders) > 0 {
+				return false, errors.ErrNotEmpty
+			}
 
-		joinedFolder.CloneInto(targetFolder)
+			joinedFolder.CloneInto(targetFolder)
 
-		for i := 0; i < len(targetFolder.Files); i++ {
-			file := targetFolder.Files[i]
+			for i := 0; i < len(targetFolder.Files); i++ {
+				file := targetFolder.Files[i]
 
-			if file.Locked() || file.ZombieCheck() {
-				if move {
-					if file.Locked() {
-						return false, errors.ErrLock
+				if file.Locked() || file.ZombieCheck() {
+					if move {
+						if file.Locked() {
+							return false, errors.ErrLock
+						}
+						return false, errors.ErrZombie
 					}
-					return false, errors.ErrZombie
+
+					_ = targetFolder.DeleteFile(file.Name, func(file *common.File) error {
+						return nil
+					})
+					i--
+					continue
 				}
 
-				_ = targetFolder.DeleteFile(file.Name, func(file *common.File) error {
-					return nil
-				})
-				i--
-				continue
-			}
+				if move {
+					continue
+				}
 
-			if move {
-				continue
+				createShadowChunks = append(createShadowChunks, file.Chunks...)
 			}
 
-			createShadowChunks = append(createShadowChunks, file.Chunks...)
-		}
-
-		return true, nil
+			return true, nil
+		})
 	}); err != nil {
 		return err
 	}
@@ -136,38 +148,40 @@ func (d *dfs) changeFolder(sources []string, target string, move bool) error {
 		}
 	}
 
-	return d.metadata.SaveBlock(clonedFolderPaths, func(folders map[string]*common.Folder) (bool, error) {
-		if move {
-			for _, source := range sources {
-				sourceParent, sourceName := common.Split(source)
-
-				folder, has := folders[sourceParent]
-				if has {
-					_ = folder.DeleteFolder(sourceName, func(_ string) error {
-						return nil
-					})
+	return d.traceMetadata(ctx, "SaveBlock", func(ctx context.Context) error {
+		return d.metadata.SaveBlock(clonedFolderPaths, changeEvents, func(folders map[string]*common.Folder) (bool, error) {
+			if move {
+				for _, source := range sources {
+					sourceParent, sourceName := common.Split(source)
+
+					folder, has := folders[sourceParent]
+					if has {
+						_ = folder.DeleteFolder(sourceName, func(_ string) error {
+							return nil
+						})
+					}
+					folders[source] = nil
 				}
-				folders[source] = nil
 			}
-		}
 
-		for k, v := range clonedFoldersMap {
-			folders[k] = v
-		}
+			for k, v := range clonedFoldersMap {
+				folders[k] = v
+			}
 
-		if move {
-			return true, nil
-		}
+			if move {
+				return true, nil
+			}
 
-		if err := d.cluster.CreateShadow(createShadowChunks); err != nil {
-			return false, err
-		}
+			if err := d.cluster.CreateShadow(ctx, createShadowChunks); err != nil {
+				return false, err
+			}
 
-		return true, nil
+			return true, nil
+		})
 	})
 }
 
-func (d *dfs) changeFile(sources []string, target string, overwrite bool, move bool) error {
+func (d *dfs) changeFile(ctx context.Context, sources []string, target string, overwrite bool, move bool, changeEvents common.Events) error {
 	targetParent, targetFilename := common.Split(target)
 
 	targetFolders, err := d.metadata.Get([]string{targetParent})
@@ -182,7 +196,7 @@ func (d *dfs) changeFile(sources []string, target string, overwrite bool, move b
 				return os.ErrExist
 			}
 
-			if err := d.deleteFile(target, false); err != nil {
+			if err := d.deleteFile(ctx, target, false, nil); err != nil {
 				return err
 			}
 		}
@@ -227,22 +241,30 @@ func (d *dfs) changeFile(sources []string, target string, overwrite bool, move b
 		return err
 	}
 
-	if err := d.metadata.SaveChain(targetParent, func(targetFolder *common.Folder) (bool, error) {
-		targetFile, err := targetFolder.NewFile(targetFilename)
-		if err != nil {
-			return false, err
-		}
-		targetFile.Reset(joinedFile.Mime, joinedFile.Size)
-		joinedFile.CloneInto(targetFile)
+	// moved file is recorded with the removal of the sources
+	copyEvents := changeEvents
+	if move {
+		copyEvents = nil
+	}
 
-		if !move {
-			if err := d.cluster.CreateShadow(targetFile.Chunks); err != nil {
+	if err := d.traceMetadata(ctx, "SaveChain", func(ctx context.Context) error {
+		return d.metadata.SaveChain(targetParent, copyEvents, func(targetFolder *common.Folder) (bool, error) {
+			targetFile, err := targetFolder.NewFile(targetFilename)
+			if err != nil {
 				return false, err
 			}
-		}
-		targetFile.Lock.Cancel()
+			targetFile.Reset(joinedFile.Mime, joinedFile.Size)
+			joinedFile.CloneInto(targetFile)
 
-		return true, nil
+			if !move {
+				if err := d.cluster.CreateShadow(ctx, targetFile.Chunks); err != nil {
+					return false, err
+				}
+			}
+			targetFile.Lock.Cancel()
+
+			return true, nil
+		})
 	}); err != nil {
 		return err
 	}
@@ -251,15 +273,17 @@ func (d *dfs) changeFile(sources []string, target string, overwrite bool, move b
 		return nil
 	}
 
-	return d.metadata.SaveBlock(sourceParents, func(folders map[string]*common.Folder) (bool, error) {
-		for _, source := range sources {
-			sourceParent, sourceFilename := common.Split(source)
-			sourceFolder := folders[sourceParent]
+	return d.traceMetadata(ctx, "SaveBlock", func(ctx context.Context) error {
+		return d.metadata.SaveBlock(sourceParents, changeEvents, func(folders map[string]*common.Folder) (bool, error) {
+			for _, source := range sources {
+				sourceParent, sourceFilename := common.Split(source)
+				sourceFolder := folders[sourceParent]
 
-			_ = sourceFolder.DeleteFile(sourceFilename, func(file *common.File) error {
-				return nil
-			})
-		}
-		return true, nil
+				_ = sourceFolder.DeleteFile(sourceFilename, func(file *common.File) error {
+					return nil
+				})
+			}
+			return true, nil
+		})
 	})
 }
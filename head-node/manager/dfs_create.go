@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"context"
 	"io"
 	"os"
 
@@ -9,15 +10,16 @@ import (
 	"go.uber.org/zap"
 )
 
-func (d *dfs) CreateFolder(folderPath string) error {
+func (d *dfs) CreateFolder(ctx context.Context, folderPath string) error {
 	folderPath = common.CorrectPath(folderPath)
 
-	return d.metadata.SaveChain(folderPath, func(folder *common.Folder) (bool, error) {
-		return true, nil
+	return d.traceMetadata(ctx, "CreateChain", func(ctx context.Context) error {
+		_, err := d.metadata.CreateChain(folderPath)
+		return err
 	})
 }
 
-func (d *dfs) CreateFile(path string, mime string, size uint64, overwrite bool, contentReader io.Reader) error {
+func (d *dfs) CreateFile(ctx context.Context, path string, mime string, size uint64, blockSize uint32, writeQuorum common.WriteQuorum, overwrite bool, contentReader io.Reader) error {
 	path = common.CorrectPath(path) // It is required in here to eliminate wrong path format
 
 	folderPath, filename := common.Split(path)
@@ -26,47 +28,51 @@ func (d *dfs) CreateFile(path string, mime string, size uint64, overwrite bool,
 	}
 
 	var file *common.File
+	overwritten := false
 
-	if err := d.metadata.SaveChain(folderPath, func(folder *common.Folder) (bool, error) {
-		var err error
+	if err := d.traceMetadata(ctx, "SaveChain", func(ctx context.Context) error {
+		return d.metadata.SaveChain(folderPath, nil, func(folder *common.Folder) (bool, error) {
+			var err error
 
-		file = folder.File(filename)
-		if file == nil {
-			file, err = folder.NewFile(filename)
-			return true, err
-		}
+			file = folder.File(filename)
+			if file == nil {
+				file, err = folder.NewFile(filename)
+				return true, err
+			}
 
-		if !overwrite {
-			return false, os.ErrExist
-		}
+			if !overwrite {
+				return false, os.ErrExist
+			}
 
-		if file.Locked() {
-			return false, errors.ErrLock
-		}
+			if file.Locked() {
+				return false, errors.ErrLock
+			}
+			overwritten = true
 
-		file.Lock = common.NewFileLockForSize(size)
+			file.Lock = common.NewFileLockForSize(size)
 
-		deletionResult, err := d.cluster.Delete(file.Chunks)
-		if deletionResult != nil {
-			file.IngestDeletion(*deletionResult)
-		}
+			deletionResult, err := d.cluster.Delete(ctx, file.Chunks)
+			if deletionResult != nil {
+				file.IngestDeletion(*deletionResult)
+			}
 
-		if err != nil {
-			if err != errors.ErrZombie {
-				file.Lock.Cancel()
-				return true, err
+			if err != nil {
+				if err != errors.ErrZombie {
+					file.Lock.Cancel()
+					return true, err
+				}
+				file.Zombie = true
 			}
-			file.Zombie = true
-		}
 
-		return true, nil
+			return true, nil
+		})
 	}); err != nil {
 		return err
 	}
 
-	chunks, err := d.cluster.Create(size, contentReader)
+	chunks, err := d.cluster.Create(ctx, size, blockSize, writeQuorum, contentReader)
 	if err != nil {
-		if errUpdate := d.update(path, nil); errUpdate != nil {
+		if errUpdate := d.update(ctx, path, nil, nil); errUpdate != nil {
 			d.logger.Error(
 				"Dropping file entry due to file creation failure is failed, file is now zombie",
 				zap.String("path", path),
@@ -80,26 +86,35 @@ func (d *dfs) CreateFile(path string, mime string, size uint64, overwrite bool,
 	file.Chunks = append(file.Chunks, chunks...)
 	file.Lock.Cancel()
 
-	err = d.update(path, file)
+	event := common.NewEvent(common.ET_Create, false, nil, path)
+	if overwritten {
+		event.Type = common.ET_Overwrite
+	}
+
+	err = d.update(ctx, path, file, common.Events{event})
 	if err != nil {
 		d.logger.Error(
 			"Saving file creation is failed. File is now zombie with orphan chunks in data node! Run repair to eliminate",
 			zap.String("path", path),
 			zap.Error(err),
 		)
+		return err
 	}
-	return err
+
+	return nil
 }
 
-func (d *dfs) update(folderPath string, file *common.File) error {
+func (d *dfs) update(ctx context.Context, folderPath string, file *common.File, changeEvents common.Events) error {
 	parent, filename := common.Split(folderPath)
 
-	return d.metadata.SaveBlock([]string{parent}, func(folders map[string]*common.Folder) (bool, error) {
-		folder := folders[parent]
-		if folder == nil {
-			return false, os.ErrNotExist
-		}
-		folder.ReplaceFile(filename, file)
-		return true, nil
+	return d.traceMetadata(ctx, "SaveBlock", func(ctx context.Context) error {
+		return d.metadata.SaveBlock([]string{parent}, changeEvents, func(folders map[string]*common.Folder) (bool, error) {
+			folder := folders[parent]
+			if folder == nil {
+				return false, os.ErrNotExist
+			}
+			folder.ReplaceFile(filename, file)
+			return true, nil
+		})
 	})
 }
@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"context"
 	"os"
 	"strings"
 
@@ -8,32 +9,36 @@ import (
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
 )
 
-func (d *dfs) Delete(target string, killZombies bool) error {
-	if err := d.deleteFolder(target, killZombies); err != nil {
+func (d *dfs) Delete(ctx context.Context, target string, killZombies bool) error {
+	sources := []string{common.CorrectPath(target)}
+
+	if err := d.deleteFolder(ctx, target, killZombies, common.Events{common.NewEvent(common.ET_Delete, true, sources, "")}); err != nil {
 		if err != os.ErrNotExist {
 			return err
 		}
-		return d.deleteFile(target, killZombies)
+		return d.deleteFile(ctx, target, killZombies, common.Events{common.NewEvent(common.ET_Delete, false, sources, "")})
 	}
 	return nil
 }
 
-func (d *dfs) deleteFolder(folderPath string, killZombies bool) error {
+func (d *dfs) deleteFolder(ctx context.Context, folderPath string, killZombies bool, changeEvents common.Events) error {
 	parentPath, pathName := common.Split(folderPath)
 
-	return d.metadata.SaveBlock([]string{parentPath}, func(folders map[string]*common.Folder) (bool, error) {
-		folder := folders[parentPath]
-		if folder == nil {
-			return false, os.ErrNotExist
-		}
+	return d.traceMetadata(ctx, "SaveBlock", func(ctx context.Context) error {
+		return d.metadata.SaveBlock([]string{parentPath}, changeEvents, func(folders map[string]*common.Folder) (bool, error) {
+			folder := folders[parentPath]
+			if folder == nil {
+				return false, os.ErrNotExist
+			}
 
-		return true, folder.DeleteFolder(pathName, func(fullPath string) error {
-			return d.deleteFolderContent(fullPath, killZombies, folders)
+			return true, folder.DeleteFolder(pathName, func(fullPath string) error {
+				return d.deleteFolderContent(ctx, fullPath, killZombies, folders)
+			})
 		})
 	})
 }
 
-func (d *dfs) deleteFolderContent(fullPath string, killZombies bool, foldersCache map[string]*common.Folder) error {
+func (d *dfs) deleteFolderContent(ctx context.Context, fullPath string, killZombies bool, foldersCache map[string]*common.Folder) error {
 	deletingFolders, err := d.metadata.Tree(fullPath, true, true)
 	if err != nil {
 		if err == os.ErrNotExist {
@@ -60,7 +65,7 @@ func (d *dfs) deleteFolderContent(fullPath string, killZombies bool, foldersCach
 			file := folder.Files[0]
 
 			if err := folder.DeleteFile(file.Name, func(file *common.File) error {
-				return d.deleteFileChunks(file, killZombies)
+				return d.deleteFileChunks(ctx, file, killZombies)
 			}); err != nil {
 				return err
 			}
@@ -81,26 +86,28 @@ func (d *dfs) deleteFolderContent(fullPath string, killZombies bool, foldersCach
 	return nil
 }
 
-func (d *dfs) deleteFile(path string, killZombies bool) error {
+func (d *dfs) deleteFile(ctx context.Context, path string, killZombies bool, changeEvents common.Events) error {
 	folderPath, filename := common.Split(path)
 
-	return d.metadata.SaveBlock([]string{folderPath}, func(folders map[string]*common.Folder) (bool, error) {
-		folder := folders[folderPath]
-		if folder == nil {
-			return false, os.ErrNotExist
-		}
-
-		return true, folder.DeleteFile(filename, func(file *common.File) error {
-			if file.Locked() {
-				return errors.ErrLock
+	return d.traceMetadata(ctx, "SaveBlock", func(ctx context.Context) error {
+		return d.metadata.SaveBlock([]string{folderPath}, changeEvents, func(folders map[string]*common.Folder) (bool, error) {
+			folder := folders[folderPath]
+			if folder == nil {
+				return false, os.ErrNotExist
 			}
-			return d.deleteFileChunks(file, killZombies)
+
+			return true, folder.DeleteFile(filename, func(file *common.File) error {
+				if file.Locked() {
+					return errors.ErrLock
+				}
+				return d.deleteFileChunks(ctx, file, killZombies)
+			})
 		})
 	})
 }
 
-func (d *dfs) deleteFileChunks(file *common.File, killZombies bool) error {
-	deletionResult, err := d.cluster.Delete(file.Chunks)
+func (d *dfs) deleteFileChunks(ctx context.Context, file *common.File, killZombies bool) error {
+	deletionResult, err := d.cluster.Delete(ctx, file.Chunks)
 	if deletionResult != nil {
 		file.IngestDeletion(*deletionResult)
 	}
@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"context"
 	"io"
 	"os"
 
@@ -8,7 +9,7 @@ import (
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
 )
 
-func (d *dfs) Read(paths []string, join bool) (ReadContainer, error) {
+func (d *dfs) Read(ctx context.Context, paths []string, join bool, readPreference common.ReadPreference) (ReadContainer, error) {
 	if len(paths) == 1 && join || len(paths) > 1 && !join {
 		return nil, os.ErrInvalid
 	}
@@ -24,7 +25,7 @@ func (d *dfs) Read(paths []string, join bool) (ReadContainer, error) {
 		}
 	}
 
-	file, streamHandler, err := d.file(paths)
+	file, streamHandler, err := d.file(ctx, paths, readPreference)
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +43,7 @@ func (d *dfs) folder(folderPath string) (*common.Folder, error) {
 	return folders[0], nil
 }
 
-func (d *dfs) file(paths []string) (*common.File, func(w io.Writer, begins int64, ends int64) error, error) {
+func (d *dfs) file(ctx context.Context, paths []string, readPreference common.ReadPreference) (*common.File, func(w io.Writer, begins int64, ends int64) error, error) {
 	files := make(common.Files, 0)
 	for _, path := range paths {
 		folderPath, filename := common.Split(path)
@@ -81,14 +82,14 @@ func (d *dfs) file(paths []string) (*common.File, func(w io.Writer, begins int64
 		}
 	}
 
-	streamHandler, err := d.cluster.Read(requestedFile.Chunks)
+	streamHandler, err := d.cluster.Read(ctx, requestedFile.Chunks, readPreference)
 	if err != nil {
 		return nil, nil, err
 	}
 	return requestedFile, streamHandler, nil
 }
 
-func (d *dfs) Size(folderPath string) (uint64, error) {
+func (d *dfs) Size(ctx context.Context, folderPath string) (uint64, error) {
 	folderPath = common.CorrectPath(folderPath)
 
 	folders, err := d.metadata.Tree(folderPath, true, false)
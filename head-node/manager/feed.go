@@ -0,0 +1,32 @@
+package manager
+
+import (
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
+)
+
+const defaultFeedLimit = 1000
+
+// Feed lists the change feed events. Events are saved with the metadata changes, see data.Metadata
+type Feed interface {
+	List(cursor uint64, limit int64, pathPrefixes []string) (common.Events, error)
+}
+
+type feed struct {
+	events events.Store
+}
+
+func NewFeed(eventStore events.Store) Feed {
+	return &feed{
+		events: eventStore,
+	}
+}
+
+func (f *feed) List(cursor uint64, limit int64, pathPrefixes []string) (common.Events, error) {
+	if limit <= 0 || limit > defaultFeedLimit {
+		limit = defaultFeedLimit
+	}
+	return f.events.List(cursor, limit, pathPrefixes)
+}
+
+var _ Feed = &feed{}
@@ -0,0 +1,169 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"go.uber.org/zap"
+)
+
+const webhookQueueLimit = 1000
+const webhookRetryInterval = time.Second * 2
+
+type Webhook interface {
+	Queue(event *common.Event)
+}
+
+type WebhookTarget struct {
+	Url          string
+	PathPrefixes []string
+}
+
+type webhook struct {
+	targets    []*webhookTarget
+	retryCount int
+	client     http.Client
+	logger     *zap.Logger
+}
+
+type webhookTarget struct {
+	target WebhookTarget
+	queue  chan *common.Event
+}
+
+// ParseWebhookTargets parses the targets in "url|/prefix,/prefix;url" format
+func ParseWebhookTargets(definition string) ([]WebhookTarget, error) {
+	targets := make([]WebhookTarget, 0)
+
+	for _, targetDefinition := range strings.Split(definition, ";") {
+		targetDefinition = strings.TrimSpace(targetDefinition)
+		if len(targetDefinition) == 0 {
+			continue
+		}
+
+		target := WebhookTarget{
+			Url:          targetDefinition,
+			PathPrefixes: make([]string, 0),
+		}
+
+		pipeIdx := strings.Index(targetDefinition, "|")
+		if pipeIdx > -1 {
+			target.Url = targetDefinition[:pipeIdx]
+
+			for _, pathPrefix := range strings.Split(targetDefinition[pipeIdx+1:], ",") {
+				if len(pathPrefix) == 0 {
+					continue
+				}
+				target.PathPrefixes = append(target.PathPrefixes, common.CorrectPath(pathPrefix))
+			}
+		}
+
+		u, err := url.Parse(target.Url)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Compare(u.Scheme, "http") != 0 && strings.Compare(u.Scheme, "https") != 0 {
+			return nil, fmt.Errorf("webhook url scheme should be http or https: %s", target.Url)
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+func NewWebhook(targets []WebhookTarget, retryCount int, logger *zap.Logger) Webhook {
+	w := &webhook{
+		targets:    make([]*webhookTarget, 0),
+		retryCount: retryCount,
+		client:     http.Client{Timeout: time.Second * 30},
+		logger:     logger,
+	}
+
+	for _, target := range targets {
+		wt := &webhookTarget{
+			target: target,
+			queue:  make(chan *common.Event, webhookQueueLimit),
+		}
+		w.targets = append(w.targets, wt)
+
+		go w.deliver(wt)
+	}
+
+	return w
+}
+
+func (w *webhook) Queue(event *common.Event) {
+	for _, wt := range w.targets {
+		if !event.Match(wt.target.PathPrefixes) {
+			continue
+		}
+
+		select {
+		case wt.queue <- event:
+		default:
+			w.logger.Warn(
+				"Webhook queue is full, event is dropped",
+				zap.String("url", wt.target.Url),
+				zap.Uint64("sequence", event.Sequence),
+			)
+		}
+	}
+}
+
+func (w *webhook) deliver(wt *webhookTarget) {
+	for event := range wt.queue {
+		var err error
+		for attempt := 0; attempt <= w.retryCount; attempt++ {
+			if attempt > 0 {
+				time.Sleep(webhookRetryInterval * time.Duration(attempt))
+			}
+
+			if err = w.post(wt.target.Url, event); err == nil {
+				break
+			}
+		}
+
+		if err != nil {
+			w.logger.Error(
+				"Webhook delivery is failed",
+				zap.String("url", wt.target.Url),
+				zap.Uint64("sequence", event.Sequence),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+func (w *webhook) post(targetUrl string, event *common.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", targetUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+var _ Webhook = &webhook{}
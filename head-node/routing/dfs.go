@@ -1,26 +1,33 @@
 package routing
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/freakmaxi/kertish-dfs/basics/audit"
 	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/tracing"
 	"github.com/freakmaxi/kertish-dfs/head-node/manager"
 	"go.uber.org/zap"
 )
 
 type dfsRouter struct {
-	dfs    manager.Dfs
-	logger *zap.Logger
+	dfs     manager.Dfs
+	auditor audit.Auditor
+	logger  *zap.Logger
 
 	definitions []*Definition
 }
 
-func NewDfsRouter(dfs manager.Dfs, logger *zap.Logger) Router {
+func NewDfsRouter(dfs manager.Dfs, auditor audit.Auditor, logger *zap.Logger) Router {
 	pR := &dfsRouter{
 		dfs:         dfs,
+		auditor:     auditor,
 		logger:      logger,
 		definitions: make([]*Definition, 0),
 	}
@@ -46,6 +53,34 @@ func (d *dfsRouter) Get() []*Definition {
 func (d *dfsRouter) manipulate(w http.ResponseWriter, r *http.Request) {
 	defer func() { _ = r.Body.Close() }()
 
+	operation := d.describeOperation(r)
+
+	statusWriter := audit.NewResponseWriter(w)
+	w = statusWriter
+
+	ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), fmt.Sprintf("dfs.%s", operation))
+	r = r.WithContext(ctx)
+
+	begins := time.Now()
+	defer func() {
+		observeRequest(operation, statusWriter.StatusCode(), time.Since(begins))
+
+		span.SetAttribute("path", r.Header.Get("X-Path"))
+		span.SetAttribute("statusCode", strconv.Itoa(statusWriter.StatusCode()))
+		if statusWriter.StatusCode() >= 500 {
+			span.SetError(fmt.Errorf("request is failed with status code %d", statusWriter.StatusCode()))
+		}
+		span.End()
+	}()
+
+	if strings.Compare(r.Method, "GET") != 0 {
+		auditEntry := d.describeAuditEntry(r, operation)
+		defer func() {
+			auditEntry.Complete(statusWriter.StatusCode())
+			d.auditor.Record(auditEntry)
+		}()
+	}
+
 	switch r.Method {
 	case "GET":
 		d.handleGet(w, r)
@@ -101,4 +136,41 @@ func (d *dfsRouter) describeXPath(xPath string) ([]string, string, error) {
 	return paths, action, nil
 }
 
+func (d *dfsRouter) describeOperation(r *http.Request) string {
+	switch r.Method {
+	case "GET":
+		return "read"
+	case "POST":
+		if strings.Compare(r.Header.Get("X-Apply-To"), "folder") == 0 {
+			return "createFolder"
+		}
+		return "createFile"
+	case "PUT":
+		_, targetAction, err := d.describeTarget(r.Header.Get("X-Target"))
+		if err == nil && strings.Compare(targetAction, "m") == 0 {
+			return "move"
+		}
+		return "copy"
+	case "DELETE":
+		return "delete"
+	default:
+		return strings.ToLower(r.Method)
+	}
+}
+
+func (d *dfsRouter) describeAuditEntry(r *http.Request, operation string) *common.AuditEntry {
+	paths, _, err := d.describeXPath(r.Header.Get("X-Path"))
+	if err != nil {
+		paths = []string{r.Header.Get("X-Path")}
+	}
+
+	if strings.Compare(r.Method, "PUT") == 0 {
+		if targetPath, _, err := d.describeTarget(r.Header.Get("X-Target")); err == nil {
+			paths = append(paths, targetPath)
+		}
+	}
+
+	return audit.NewEntry(r, operation, paths, "")
+}
+
 var _ Router = &dfsRouter{}
@@ -19,7 +19,7 @@ func (d *dfsRouter) handleDelete(w http.ResponseWriter, r *http.Request) {
 	killZombiesHeader := strings.ToLower(r.Header.Get("X-Kill-Zombies"))
 	killZombies := len(killZombiesHeader) > 0 && (strings.Compare(killZombiesHeader, "1") == 0 || strings.Compare(killZombiesHeader, "true") == 0)
 
-	if err := d.dfs.Delete(requestedPaths[0], killZombies); err != nil {
+	if err := d.dfs.Delete(r.Context(), requestedPaths[0], killZombies); err != nil {
 		if err == os.ErrNotExist {
 			w.WriteHeader(404)
 			return
@@ -21,7 +21,13 @@ func (d *dfsRouter) handleGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	read, err := d.dfs.Read(requestedPaths, strings.Compare(sourceAction, "j") == 0)
+	readPreference := common.ReadPreference(r.Header.Get("X-Read-Preference"))
+	if !readPreference.Valid() {
+		w.WriteHeader(422)
+		return
+	}
+
+	read, err := d.dfs.Read(r.Context(), requestedPaths, strings.Compare(sourceAction, "j") == 0, readPreference)
 	if err != nil {
 		if err == os.ErrNotExist {
 			w.WriteHeader(404)
@@ -54,7 +60,7 @@ func (d *dfsRouter) handleGet(w http.ResponseWriter, r *http.Request) {
 		if calculateUsage {
 			folder.CalculateUsage(func(shadows common.FolderShadows) {
 				for _, shadow := range shadows {
-					shadow.Size, _ = d.dfs.Size(shadow.Full)
+					shadow.Size, _ = d.dfs.Size(r.Context(), shadow.Full)
 				}
 			})
 		}
@@ -3,8 +3,10 @@ package routing
 import (
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/freakmaxi/kertish-dfs/basics/common"
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
 	"go.uber.org/zap"
 )
@@ -19,7 +21,7 @@ func (d *dfsRouter) handlePost(w http.ResponseWriter, r *http.Request) {
 
 	switch applyTo {
 	case "folder":
-		if err := d.dfs.CreateFolder(requestedPaths[0]); err != nil {
+		if err := d.dfs.CreateFolder(r.Context(), requestedPaths[0]); err != nil {
 			if err == os.ErrExist {
 				w.WriteHeader(409)
 				return
@@ -58,7 +60,23 @@ func (d *dfsRouter) handlePost(w http.ResponseWriter, r *http.Request) {
 		overwriteHeader := strings.ToLower(r.Header.Get("X-Overwrite"))
 		overwrite := len(overwriteHeader) > 0 && (strings.Compare(overwriteHeader, "1") == 0 || strings.Compare(overwriteHeader, "true") == 0)
 
-		if err := d.dfs.CreateFile(requestedPaths[0], contentType, uint64(contentLength), overwrite, r.Body); err != nil {
+		blockSize := uint64(0)
+		if blockSizeHeader := r.Header.Get("X-Block-Size"); len(blockSizeHeader) > 0 {
+			var err error
+			blockSize, err = strconv.ParseUint(blockSizeHeader, 10, 32)
+			if err != nil || blockSize < uint64(common.MinBlockSize) || blockSize > uint64(common.MaxBlockSize) {
+				w.WriteHeader(422)
+				return
+			}
+		}
+
+		writeQuorum := common.WriteQuorum(r.Header.Get("X-Write-Quorum"))
+		if !writeQuorum.Valid() {
+			w.WriteHeader(422)
+			return
+		}
+
+		if err := d.dfs.CreateFile(r.Context(), requestedPaths[0], contentType, uint64(contentLength), uint32(blockSize), writeQuorum, overwrite, r.Body); err != nil {
 			if err == os.ErrExist {
 				w.WriteHeader(409)
 				return
@@ -34,7 +34,7 @@ func (d *dfsRouter) handlePut(w http.ResponseWriter, r *http.Request) {
 		operation = "Move"
 	}
 
-	if err := d.dfs.Change(requestedPaths, targetPath, join, overwrite, strings.Compare(targetAction, "m") == 0); err != nil {
+	if err := d.dfs.Change(r.Context(), requestedPaths, targetPath, join, overwrite, strings.Compare(targetAction, "m") == 0); err != nil {
 		if err == os.ErrNotExist {
 			w.WriteHeader(404)
 			return
@@ -0,0 +1,123 @@
+package routing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/head-node/manager"
+	"go.uber.org/zap"
+)
+
+type feedRouter struct {
+	feed   manager.Feed
+	logger *zap.Logger
+
+	definitions []*Definition
+}
+
+func NewFeedRouter(feed manager.Feed, logger *zap.Logger) Router {
+	pR := &feedRouter{
+		feed:        feed,
+		logger:      logger,
+		definitions: make([]*Definition, 0),
+	}
+	pR.setup()
+
+	return pR
+}
+
+func (f *feedRouter) setup() {
+	f.definitions =
+		append(f.definitions,
+			&Definition{
+				Path:    "/client/feed",
+				Handler: f.manipulate,
+			},
+		)
+}
+
+func (f *feedRouter) Get() []*Definition {
+	return f.definitions
+}
+
+func (f *feedRouter) manipulate(w http.ResponseWriter, r *http.Request) {
+	defer func() { _ = r.Body.Close() }()
+
+	switch r.Method {
+	case "GET":
+		f.handleGet(w, r)
+	default:
+		w.WriteHeader(406)
+	}
+}
+
+func (f *feedRouter) handleGet(w http.ResponseWriter, r *http.Request) {
+	cursor := uint64(0)
+	if cursorHeader := r.Header.Get("X-Cursor"); len(cursorHeader) > 0 {
+		var err error
+		cursor, err = strconv.ParseUint(cursorHeader, 10, 64)
+		if err != nil {
+			w.WriteHeader(422)
+			return
+		}
+	}
+
+	limit := int64(0)
+	if limitHeader := r.Header.Get("X-Limit"); len(limitHeader) > 0 {
+		var err error
+		limit, err = strconv.ParseInt(limitHeader, 10, 64)
+		if err != nil {
+			w.WriteHeader(422)
+			return
+		}
+	}
+
+	pathPrefixes, err := f.describeXPath(r.Header.Get("X-Path"))
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	events, err := f.feed.List(cursor, limit, pathPrefixes)
+	if err != nil {
+		w.WriteHeader(500)
+		f.logger.Error("Feed request is failed", zap.Uint64("cursor", cursor), zap.Error(err))
+		return
+	}
+
+	if len(events) > 0 {
+		cursor = events[len(events)-1].Sequence
+	}
+	w.Header().Set("X-Cursor", strconv.FormatUint(cursor, 10))
+
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		f.logger.Error("Response of feed request is failed", zap.Error(err))
+	}
+}
+
+func (f *feedRouter) describeXPath(xPath string) ([]string, error) {
+	pathPrefixes := make([]string, 0)
+	if len(xPath) == 0 {
+		return pathPrefixes, nil
+	}
+
+	for _, p := range strings.Split(xPath, ",") {
+		p, err := url.QueryUnescape(p)
+		if err != nil {
+			return nil, err
+		}
+		if !common.ValidatePath(p) {
+			return nil, os.ErrInvalid
+		}
+		pathPrefixes = append(pathPrefixes, common.CorrectPath(p))
+	}
+
+	return pathPrefixes, nil
+}
+
+var _ Router = &feedRouter{}
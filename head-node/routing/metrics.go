@@ -0,0 +1,73 @@
+package routing
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kertish",
+		Subsystem: "head",
+		Name:      "requests_total",
+		Help:      "Number of dfs requests by operation and response code",
+	}, []string{"operation", "code"})
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "kertish",
+		Subsystem: "head",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of dfs requests by operation",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"operation"})
+)
+
+func observeRequest(operation string, statusCode int, duration time.Duration) {
+	requestsTotal.WithLabelValues(operation, strconv.Itoa(statusCode)).Inc()
+	requestDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+type metricsRouter struct {
+	handler http.Handler
+
+	definitions []*Definition
+}
+
+func NewMetricsRouter() Router {
+	pR := &metricsRouter{
+		handler:     promhttp.Handler(),
+		definitions: make([]*Definition, 0),
+	}
+	pR.setup()
+
+	return pR
+}
+
+func (m *metricsRouter) setup() {
+	m.definitions =
+		append(m.definitions,
+			&Definition{
+				Path:    "/metrics",
+				Handler: m.manipulate,
+			},
+		)
+}
+
+func (m *metricsRouter) Get() []*Definition {
+	return m.definitions
+}
+
+func (m *metricsRouter) manipulate(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		m.handler.ServeHTTP(w, r)
+	default:
+		w.WriteHeader(406)
+	}
+}
+
+var _ Router = &metricsRouter{}
@@ -0,0 +1,273 @@
+package integration
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/data-node/cache"
+	"github.com/freakmaxi/kertish-dfs/data-node/filesystem"
+	"github.com/freakmaxi/kertish-dfs/data-node/filesystem/block"
+	"github.com/freakmaxi/kertish-dfs/data-node/manager"
+	"github.com/freakmaxi/kertish-dfs/data-node/service"
+	"go.uber.org/zap"
+)
+
+const nodeStartTimeout = time.Second * 5
+
+// DataNode is a data node running in the test process. Kill and Start behave like a process restart,
+// the root path, hardware id and the link address are kept so the node joins back to the same cluster
+type DataNode interface {
+	Address() string
+	RootPath() string
+
+	Start() error
+	Kill() error
+	Alive() bool
+
+	Partition()
+	Heal()
+
+	Blocks() ([]string, error)
+	VerifyBlock(sha512Hex string) (bool, error)
+	CorruptBlock(sha512Hex string) error
+}
+
+type dataNode struct {
+	managerAddr  string
+	hardwareAddr string
+	bindAddr     string
+	rootPath     string
+	size         uint64
+	packLimit    uint32
+	logger       *zap.Logger
+
+	link Link
+
+	mutex  sync.Mutex
+	server service.Server
+}
+
+func NewDataNode(managerAddr string, hardwareAddr string, rootPath string, size uint64, packLimit uint32, logger *zap.Logger) (DataNode, error) {
+	bindAddr, err := freeAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := NewLink(bindAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dataNode{
+		managerAddr:  managerAddr,
+		hardwareAddr: hardwareAddr,
+		bindAddr:     bindAddr,
+		rootPath:     rootPath,
+		size:         size,
+		packLimit:    packLimit,
+		logger:       logger,
+		link:         l,
+		mutex:        sync.Mutex{},
+	}, nil
+}
+
+func (d *dataNode) Address() string {
+	return d.link.Address()
+}
+
+func (d *dataNode) RootPath() string {
+	return d.rootPath
+}
+
+func (d *dataNode) Start() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.server != nil {
+		return nil
+	}
+
+	n := manager.NewNode([]string{d.managerAddr}, d.size, d.logger)
+	disks := []block.Disk{{Path: d.rootPath, Size: d.size}}
+	fs, err := filesystem.NewManager(disks, block.PlacementHash, d.packLimit, 0, func(sha512HexList []string) {
+		if err := n.Missing(sha512HexList); err != nil {
+			d.logger.Error("Reporting missing blocks is failed", zap.Error(err))
+		}
+	}, func() {
+		if err := n.Repair(); err != nil {
+			d.logger.Error("Requesting node repair is failed", zap.Error(err))
+		}
+	}, d.logger)
+	if err != nil {
+		return err
+	}
+	cc := cache.NewContainer(0, time.Minute, cache.NewLRUPolicy(), d.logger)
+
+	c, err := service.NewCommander(fs, cc, n, d.logger, d.hardwareAddr)
+	if err != nil {
+		return err
+	}
+
+	s, err := service.NewServer(d.bindAddr, c, d.logger)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.Listen(); err != nil {
+			d.logger.Error("Data node listening is failed", zap.String("address", d.Address()), zap.Error(err))
+		}
+	}()
+
+	if err := waitListening(d.bindAddr); err != nil {
+		_ = s.Kill()
+		return err
+	}
+	d.server = s
+
+	// the farm knows the node with the link address, node id is calculated with it
+	if err := n.Handshake(d.hardwareAddr, d.Address(), d.size); err != nil {
+		d.logger.Warn("Handshake is failed, data node is running as stand-alone", zap.String("address", d.Address()), zap.Error(err))
+		return nil
+	}
+
+	if len(n.MasterAddress()) > 0 {
+		go func() {
+			if err := fs.Sync(func(sync filesystem.Synchronize) error {
+				return sync.Full(n.MasterAddress())
+			}); err != nil {
+				d.logger.Warn("Sync is failed", zap.String("masterNodeAddress", n.MasterAddress()), zap.Error(err))
+			}
+		}()
+	}
+
+	return nil
+}
+
+func (d *dataNode) Kill() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.server == nil {
+		return nil
+	}
+
+	err := d.server.Kill()
+	d.server = nil
+
+	return err
+}
+
+func (d *dataNode) Alive() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.server != nil
+}
+
+func (d *dataNode) Partition() {
+	d.link.Partition()
+}
+
+func (d *dataNode) Heal() {
+	d.link.Heal()
+}
+
+func (d *dataNode) blockManager() (block.Manager, error) {
+	return block.NewManager(d.rootPath, d.packLimit, d.logger)
+}
+
+func (d *dataNode) Blocks() ([]string, error) {
+	b, err := d.blockManager()
+	if err != nil {
+		return nil, err
+	}
+
+	sha512HexList := make([]string, 0)
+	if err := b.Traverse(func(sha512Hex string) error {
+		sha512HexList = append(sha512HexList, sha512Hex)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return sha512HexList, nil
+}
+
+func (d *dataNode) VerifyBlock(sha512Hex string) (bool, error) {
+	b, err := d.blockManager()
+	if err != nil {
+		return false, err
+	}
+
+	verified := false
+	if err := b.File(sha512Hex, func(file block.File) error {
+		if file.Temporary() {
+			return os.ErrNotExist
+		}
+		verified = file.VerifyForce()
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	return verified, nil
+}
+
+// CorruptBlock flips the first byte of the block content, header stays untouched
+func (d *dataNode) CorruptBlock(sha512Hex string) error {
+	b, err := d.blockManager()
+	if err != nil {
+		return err
+	}
+
+	return b.File(sha512Hex, func(file block.File) error {
+		if file.Temporary() {
+			return os.ErrNotExist
+		}
+
+		content := make([]byte, 0)
+		if err := file.Read(func(data []byte) error {
+			content = append(content, data...)
+			return nil
+		}, func() error {
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if len(content) == 0 {
+			return fmt.Errorf("block is empty")
+		}
+
+		if err := file.SeekData(0); err != nil {
+			return err
+		}
+		return file.Write([]byte{^content[0]})
+	})
+}
+
+func freeAddress() (string, error) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = listener.Close() }()
+
+	return listener.Addr().String(), nil
+}
+
+func waitListening(address string) error {
+	timeout := time.Now().Add(nodeStartTimeout)
+	for time.Now().Before(timeout) {
+		conn, err := net.DialTimeout("tcp4", address, time.Second)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+	return fmt.Errorf("%s is not listening", address)
+}
+
+var _ DataNode = &dataNode{}
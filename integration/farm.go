@@ -0,0 +1,358 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/audit"
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
+	headData "github.com/freakmaxi/kertish-dfs/head-node/data"
+	headManager "github.com/freakmaxi/kertish-dfs/head-node/manager"
+	headRouting "github.com/freakmaxi/kertish-dfs/head-node/routing"
+	managerServices "github.com/freakmaxi/kertish-dfs/manager-node/services"
+	"github.com/freakmaxi/locking-center-client-go/mutex"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const defaultNodeSize uint64 = 1024 * 1024 * 64 // 64mb
+const defaultHealthCheckInterval = time.Second
+const defaultReadAhead = 4
+
+type Config struct {
+	Clusters        int
+	NodesPerCluster int
+	NodeSize        uint64
+	ReadAhead       int
+	PackLimit       uint32
+	Chunker         headManager.Chunker
+
+	HealthCheckInterval time.Duration
+	Logger              *zap.Logger
+}
+
+// Farm is a complete kertish farm (manager, head and data nodes) running in the test process on loopback
+// ports. Mongo, Redis and the locking center are replaced with the embedded store and the in-process locking
+type Farm interface {
+	HeadAddress() string
+	ManagerAddress() string
+
+	Clusters() (common.Clusters, error)
+	Cluster(clusterId string) (*common.Cluster, error)
+
+	Node(address string) DataNode
+	Nodes() []DataNode
+	Master(clusterId string) (DataNode, error)
+	Slaves(clusterId string) ([]DataNode, error)
+
+	Shutdown()
+}
+
+type farm struct {
+	config   Config
+	rootPath string
+
+	store embedded.Store
+	mutex mutex.LockingCenter
+
+	managerStores   *managerServices.Stores
+	managerService  *managerServices.Service
+	managerListener net.Listener
+	headListener    net.Listener
+	headServer      *http.Server
+
+	nodes []DataNode
+}
+
+func NewFarm(config Config) (Farm, error) {
+	if config.Clusters == 0 {
+		config.Clusters = 1
+	}
+	if config.NodesPerCluster == 0 {
+		config.NodesPerCluster = 2
+	}
+	if config.NodeSize == 0 {
+		config.NodeSize = defaultNodeSize
+	}
+	if config.ReadAhead == 0 {
+		config.ReadAhead = defaultReadAhead
+	}
+	if config.HealthCheckInterval == 0 {
+		config.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if config.Logger == nil {
+		config.Logger = zap.NewNop()
+	}
+
+	rootPath, err := ioutil.TempDir("", "kertish-farm")
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := embedded.NewStore(path.Join(rootPath, "store", "kertish.db"))
+	if err != nil {
+		_ = os.RemoveAll(rootPath)
+		return nil, err
+	}
+
+	f := &farm{
+		config:   config,
+		rootPath: rootPath,
+		store:    store,
+		mutex:    embedded.NewLockingCenter(),
+		nodes:    make([]DataNode, 0),
+	}
+
+	if err := f.start(); err != nil {
+		f.Shutdown()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *farm) start() error {
+	if err := f.startManager(); err != nil {
+		return err
+	}
+
+	if err := f.startHead(); err != nil {
+		return err
+	}
+
+	for i := 0; i < f.config.Clusters; i++ {
+		addresses := make([]string, 0)
+
+		for j := 0; j < f.config.NodesPerCluster; j++ {
+			index := len(f.nodes)
+
+			dn, err := NewDataNode(
+				f.ManagerAddress(),
+				fmt.Sprintf("02:00:00:00:00:%02x", index),
+				path.Join(f.rootPath, fmt.Sprintf("data-node-%d", index)),
+				f.config.NodeSize,
+				f.config.PackLimit,
+				f.config.Logger.With(zap.Int("dataNode", index)),
+			)
+			if err != nil {
+				return err
+			}
+			f.nodes = append(f.nodes, dn)
+
+			if err := dn.Start(); err != nil {
+				return err
+			}
+			addresses = append(addresses, dn.Address())
+		}
+
+		cluster, err := f.managerService.Cluster().Register(addresses)
+		if err != nil {
+			return err
+		}
+
+		// registered clusters are frozen and paralyzed until the first sync and health check
+		if err := f.managerService.Synchronize().Cluster(cluster.Id, true, false, true); err != nil {
+			return err
+		}
+	}
+
+	return f.waitReady()
+}
+
+func (f *farm) waitReady() error {
+	timeout := time.Now().Add(f.config.HealthCheckInterval * 10)
+	for time.Now().Before(timeout) {
+		clusters, err := f.Clusters()
+		if err != nil {
+			return err
+		}
+
+		ready := true
+		for _, cluster := range clusters {
+			ready = ready && !cluster.Paralyzed && !cluster.Frozen
+		}
+		if ready {
+			return nil
+		}
+
+		time.Sleep(f.config.HealthCheckInterval / 10)
+	}
+	return fmt.Errorf("clusters are not ready")
+}
+
+func (f *farm) startManager() error {
+	logger := f.config.Logger.With(zap.String("node", "manager"))
+
+	var err error
+	f.managerStores, err = managerServices.NewEmbeddedStores(f.store, f.mutex)
+	if err != nil {
+		return err
+	}
+
+	f.managerListener, err = net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	address := f.managerListener.Addr().String()
+
+	f.managerService, err = managerServices.NewService(
+		managerServices.Config{
+			BindAddr:            address,
+			AdvertiseAddr:       address,
+			HealthCheckInterval: f.config.HealthCheckInterval,
+			// farms share the test process, cluster metrics of each farm are kept out of the default registry
+			Registerer: prometheus.NewRegistry(),
+		},
+		f.managerStores,
+		logger,
+	)
+	if err != nil {
+		_ = f.managerListener.Close()
+		return err
+	}
+
+	go func(service *managerServices.Service, listener net.Listener) {
+		if err := service.Serve(listener); err != nil {
+			logger.Error("Service is failed", zap.Error(err))
+		}
+	}(f.managerService, f.managerListener)
+
+	return nil
+}
+
+func (f *farm) startHead() error {
+	logger := f.config.Logger.With(zap.String("node", "head"))
+
+	eventStore, err := events.NewStoreEmbedded(f.store, 0)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := headData.NewMetadataEmbedded(f.store, f.mutex, eventStore, nil)
+	if err != nil {
+		return err
+	}
+
+	auditStore, err := audit.NewStoreEmbedded(f.store)
+	if err != nil {
+		return err
+	}
+
+	feed := headManager.NewFeed(eventStore)
+
+	cluster, err := headManager.NewCluster([]string{f.ManagerAddress()}, f.config.ReadAhead, f.config.Chunker, logger)
+	if err != nil {
+		return err
+	}
+	dfs := headManager.NewDfs(metadata, cluster, logger)
+	if err := dfs.CreateFolder(context.Background(), "/"); err != nil && err != os.ErrExist {
+		return err
+	}
+	auditor := audit.NewAuditor(auditStore, "head-node/integration", logger)
+
+	routerManager := headRouting.NewManager()
+	routerManager.Add(headRouting.NewDfsRouter(dfs, auditor, logger))
+	routerManager.Add(headRouting.NewFeedRouter(feed, logger))
+	routerManager.Add(headRouting.NewAuditRouter(auditor, logger))
+
+	f.headListener, f.headServer, err = serve(routerManager.Get(), logger)
+	return err
+}
+
+func serve(handler http.Handler, logger *zap.Logger) (net.Listener, *http.Server, error) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	server := &http.Server{Handler: handler}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error("Service is failed", zap.Error(err))
+		}
+	}()
+
+	return listener, server, nil
+}
+
+func (f *farm) HeadAddress() string {
+	return fmt.Sprintf("http://%s", f.headListener.Addr().String())
+}
+
+func (f *farm) ManagerAddress() string {
+	return fmt.Sprintf("http://%s", f.managerListener.Addr().String())
+}
+
+func (f *farm) Clusters() (common.Clusters, error) {
+	return f.managerStores.Clusters.GetAll()
+}
+
+func (f *farm) Cluster(clusterId string) (*common.Cluster, error) {
+	return f.managerStores.Clusters.Get(clusterId)
+}
+
+func (f *farm) Node(address string) DataNode {
+	for _, dn := range f.nodes {
+		if strings.Compare(dn.Address(), address) == 0 {
+			return dn
+		}
+	}
+	return nil
+}
+
+func (f *farm) Nodes() []DataNode {
+	return f.nodes
+}
+
+func (f *farm) Master(clusterId string) (DataNode, error) {
+	cluster, err := f.Cluster(clusterId)
+	if err != nil {
+		return nil, err
+	}
+	return f.Node(cluster.Master().Address), nil
+}
+
+func (f *farm) Slaves(clusterId string) ([]DataNode, error) {
+	cluster, err := f.Cluster(clusterId)
+	if err != nil {
+		return nil, err
+	}
+
+	slaves := make([]DataNode, 0)
+	for _, node := range cluster.Nodes {
+		if node.Master {
+			continue
+		}
+		slaves = append(slaves, f.Node(node.Address))
+	}
+	return slaves, nil
+}
+
+// Shutdown stops the services with the background jobs of the manager node and removes the farm files
+func (f *farm) Shutdown() {
+	for _, dn := range f.nodes {
+		_ = dn.Kill()
+		dn.Partition()
+	}
+
+	if f.headServer != nil {
+		_ = f.headServer.Close()
+	}
+	if f.managerService != nil {
+		f.managerService.Stop()
+	}
+	_ = f.store.Close()
+
+	_ = os.RemoveAll(f.rootPath)
+}
+
+var _ Farm = &farm{}
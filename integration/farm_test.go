@@ -0,0 +1,464 @@
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	headManager "github.com/freakmaxi/kertish-dfs/head-node/manager"
+	"github.com/stretchr/testify/assert"
+)
+
+const waitTimeout = time.Second * 30
+const waitTick = time.Millisecond * 100
+
+func upload(f Farm, filePath string, content []byte) (int, error) {
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/client/dfs", f.HeadAddress()), bytes.NewReader(content))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Path", url.QueryEscape(filePath))
+	req.Header.Set("X-Apply-To", "file")
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	return res.StatusCode, nil
+}
+
+func download(f Farm, filePath string) (int, []byte, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/client/dfs", f.HeadAddress()), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("X-Path", url.QueryEscape(filePath))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	content, err := ioutil.ReadAll(res.Body)
+	return res.StatusCode, content, err
+}
+
+func downloadRange(f Farm, filePath string, begins int, ends int) (int, []byte, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/client/dfs", f.HeadAddress()), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("X-Path", url.QueryEscape(filePath))
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", begins, ends))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	content, err := ioutil.ReadAll(res.Body)
+	return res.StatusCode, content, err
+}
+
+func content(size int) []byte {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return b
+}
+
+func sameBlocks(master DataNode, slave DataNode) bool {
+	masterBlocks, err := master.Blocks()
+	if err != nil || len(masterBlocks) == 0 {
+		return false
+	}
+	slaveBlocks, err := slave.Blocks()
+	if err != nil {
+		return false
+	}
+	sort.Strings(masterBlocks)
+	sort.Strings(slaveBlocks)
+
+	return strings.Join(masterBlocks, ",") == strings.Join(slaveBlocks, ",")
+}
+
+func newSyncedFarm(t *testing.T, filePath string, fileContent []byte) (Farm, string) {
+	f, err := NewFarm(Config{Clusters: 1, NodesPerCluster: 2})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	status, err := upload(f, filePath, fileContent)
+	assert.Nil(t, err)
+	assert.Equal(t, 202, status)
+
+	clusters, err := f.Clusters()
+	assert.Nil(t, err)
+	assert.Len(t, clusters, 1)
+	clusterId := clusters[0].Id
+
+	master, err := f.Master(clusterId)
+	assert.Nil(t, err)
+	slaves, err := f.Slaves(clusterId)
+	assert.Nil(t, err)
+	assert.Len(t, slaves, 1)
+
+	assert.Eventually(t, func() bool {
+		return sameBlocks(master, slaves[0])
+	}, waitTimeout, waitTick)
+
+	return f, clusterId
+}
+
+func TestFarm_UploadDownload(t *testing.T) {
+	f, err := NewFarm(Config{Clusters: 2, NodesPerCluster: 2})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer f.Shutdown()
+
+	assert.Len(t, f.Nodes(), 4)
+
+	fileContent := content(1024 * 256)
+
+	status, err := upload(f, "/integration/file.bin", fileContent)
+	assert.Nil(t, err)
+	assert.Equal(t, 202, status)
+
+	status, err = upload(f, "/integration/file.bin", fileContent)
+	assert.Nil(t, err)
+	assert.Equal(t, 409, status)
+
+	status, downloaded, err := download(f, "/integration/file.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, fileContent, downloaded)
+
+	status, downloaded, err = downloadRange(f, "/integration/file.bin", 1000, 1999)
+	assert.Nil(t, err)
+	assert.Equal(t, 206, status)
+	assert.Equal(t, fileContent[1000:2000], downloaded)
+
+	status, _, err = download(f, "/integration/missing.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, 404, status)
+}
+
+func TestFarm_LargeFileDownload(t *testing.T) {
+	f, err := NewFarm(Config{Clusters: 2, NodesPerCluster: 2, NodeSize: 1024 * 1024 * 128})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer f.Shutdown()
+
+	// spans three chunks of 32mb
+	fileContent := content(1024 * 1024 * 72)
+
+	status, err := upload(f, "/integration/large.bin", fileContent)
+	assert.Nil(t, err)
+	assert.Equal(t, 202, status)
+
+	status, downloaded, err := download(f, "/integration/large.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, status)
+	assert.True(t, bytes.Equal(fileContent, downloaded))
+
+	begins, ends := 1024*1024*30, 1024*1024*66
+	status, downloaded, err = downloadRange(f, "/integration/large.bin", begins, ends)
+	assert.Nil(t, err)
+	assert.Equal(t, 206, status)
+	assert.True(t, bytes.Equal(fileContent[begins:ends+1], downloaded))
+}
+
+func TestFarm_SlaveSync(t *testing.T) {
+	f, clusterId := newSyncedFarm(t, "/integration/file.bin", content(1024*256))
+	defer f.Shutdown()
+
+	slaves, err := f.Slaves(clusterId)
+	assert.Nil(t, err)
+
+	blocks, err := slaves[0].Blocks()
+	assert.Nil(t, err)
+	for _, sha512Hex := range blocks {
+		verified, err := slaves[0].VerifyBlock(sha512Hex)
+		assert.Nil(t, err)
+		assert.True(t, verified)
+	}
+}
+
+func TestFarm_MasterKilled(t *testing.T) {
+	fileContent := content(1024 * 256)
+
+	f, clusterId := newSyncedFarm(t, "/integration/file.bin", fileContent)
+	defer f.Shutdown()
+
+	master, err := f.Master(clusterId)
+	assert.Nil(t, err)
+	assert.Nil(t, master.Kill())
+	assert.False(t, master.Alive())
+
+	assert.Eventually(t, func() bool {
+		newMaster, err := f.Master(clusterId)
+		return err == nil && newMaster != master
+	}, waitTimeout, waitTick)
+
+	status, downloaded, err := download(f, "/integration/file.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, fileContent, downloaded)
+
+	assert.Nil(t, master.Start())
+	assert.True(t, master.Alive())
+}
+
+func TestFarm_MasterPartitioned(t *testing.T) {
+	fileContent := content(1024 * 256)
+
+	f, clusterId := newSyncedFarm(t, "/integration/file.bin", fileContent)
+	defer f.Shutdown()
+
+	master, err := f.Master(clusterId)
+	assert.Nil(t, err)
+	master.Partition()
+
+	assert.Eventually(t, func() bool {
+		newMaster, err := f.Master(clusterId)
+		return err == nil && newMaster != master
+	}, waitTimeout, waitTick)
+
+	status, downloaded, err := download(f, "/integration/file.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, fileContent, downloaded)
+
+	master.Heal()
+}
+
+func TestFarm_CorruptedBlockReused(t *testing.T) {
+	fileContent := content(1024 * 256)
+
+	f, clusterId := newSyncedFarm(t, "/integration/file.bin", fileContent)
+	defer f.Shutdown()
+
+	slaves, err := f.Slaves(clusterId)
+	assert.Nil(t, err)
+
+	blocks, err := slaves[0].Blocks()
+	assert.Nil(t, err)
+	if !assert.NotEmpty(t, blocks) {
+		return
+	}
+
+	assert.Nil(t, slaves[0].CorruptBlock(blocks[0]))
+	verified, err := slaves[0].VerifyBlock(blocks[0])
+	assert.Nil(t, err)
+	assert.False(t, verified)
+
+	// same content shares the block, slave verifies it while syncing the usage increase
+	status, err := upload(f, "/integration/copy.bin", fileContent)
+	assert.Nil(t, err)
+	assert.Equal(t, 202, status)
+
+	assert.Eventually(t, func() bool {
+		verified, err := slaves[0].VerifyBlock(blocks[0])
+		return err == nil && verified
+	}, waitTimeout, waitTick)
+}
+
+func TestFarm_PackedBlocks(t *testing.T) {
+	f, err := NewFarm(Config{Clusters: 1, NodesPerCluster: 2, PackLimit: 1024 * 1024})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer f.Shutdown()
+
+	fileContent := content(1024 * 256)
+
+	status, err := upload(f, "/integration/packed.bin", fileContent)
+	assert.Nil(t, err)
+	assert.Equal(t, 202, status)
+
+	clusters, err := f.Clusters()
+	assert.Nil(t, err)
+	master, err := f.Master(clusters[0].Id)
+	assert.Nil(t, err)
+	slaves, err := f.Slaves(clusters[0].Id)
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		return sameBlocks(master, slaves[0])
+	}, waitTimeout, waitTick)
+
+	for _, node := range f.Nodes() {
+		blocks, err := node.Blocks()
+		assert.Nil(t, err)
+		for _, sha512Hex := range blocks {
+			_, err := os.Stat(path.Join(node.RootPath(), sha512Hex))
+			assert.True(t, os.IsNotExist(err))
+
+			verified, err := node.VerifyBlock(sha512Hex)
+			assert.Nil(t, err)
+			assert.True(t, verified)
+		}
+	}
+
+	status, downloaded, err := download(f, "/integration/packed.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, fileContent, downloaded)
+}
+
+func TestFarm_ContentDefinedChunking(t *testing.T) {
+	chunker, err := headManager.NewChunker(1024*64, 1024*128, 1024*256)
+	assert.Nil(t, err)
+
+	f, err := NewFarm(Config{Clusters: 1, NodesPerCluster: 1, Chunker: chunker})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer f.Shutdown()
+
+	fileContent := content(1024 * 1024 * 2)
+	shiftedContent := append([]byte{0x1}, fileContent...)
+
+	status, err := upload(f, "/integration/original.bin", fileContent)
+	assert.Nil(t, err)
+	assert.Equal(t, 202, status)
+
+	blocks, err := f.Nodes()[0].Blocks()
+	assert.Nil(t, err)
+	originalCount := len(blocks)
+	assert.True(t, originalCount > 1)
+
+	status, err = upload(f, "/integration/shifted.bin", shiftedContent)
+	assert.Nil(t, err)
+	assert.Equal(t, 202, status)
+
+	// only the first chunk is changed with the inserted byte
+	blocks, err = f.Nodes()[0].Blocks()
+	assert.Nil(t, err)
+	assert.True(t, len(blocks) <= originalCount+2)
+
+	status, downloaded, err := download(f, "/integration/shifted.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, shiftedContent, downloaded)
+}
+
+func TestFarm_BlockSize(t *testing.T) {
+	f, err := NewFarm(Config{Clusters: 1, NodesPerCluster: 1})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer f.Shutdown()
+
+	clusters, err := f.Clusters()
+	assert.Nil(t, err)
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/client/manager", f.ManagerAddress()), nil)
+	assert.Nil(t, err)
+	req.Header.Set("X-Action", "blockSize")
+	req.Header.Set("X-Options", fmt.Sprintf("%s=%d", clusters[0].Id, 1024*256))
+
+	res, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_ = res.Body.Close()
+	assert.Equal(t, 200, res.StatusCode)
+
+	fileContent := content(1024 * 1024)
+	status, err := upload(f, "/integration/cluster.bin", fileContent)
+	assert.Nil(t, err)
+	assert.Equal(t, 202, status)
+
+	blocks, err := f.Nodes()[0].Blocks()
+	assert.Nil(t, err)
+	assert.Len(t, blocks, 4)
+
+	req, err = http.NewRequest("POST", fmt.Sprintf("%s/client/dfs", f.HeadAddress()), bytes.NewReader(content(1024*1024)))
+	assert.Nil(t, err)
+	req.Header.Set("X-Path", url.QueryEscape("/integration/upload.bin"))
+	req.Header.Set("X-Apply-To", "file")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Block-Size", strconv.Itoa(1024*128))
+
+	res, err = http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_ = res.Body.Close()
+	assert.Equal(t, 202, res.StatusCode)
+
+	blocks, err = f.Nodes()[0].Blocks()
+	assert.Nil(t, err)
+	assert.Len(t, blocks, 4+8)
+
+	status, downloaded, err := download(f, "/integration/cluster.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, fileContent, downloaded)
+}
+
+func TestFarm_WriteQuorum(t *testing.T) {
+	f, err := NewFarm(Config{Clusters: 1, NodesPerCluster: 2})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer f.Shutdown()
+
+	clusters, err := f.Clusters()
+	assert.Nil(t, err)
+	clusterId := clusters[0].Id
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/client/manager", f.ManagerAddress()), nil)
+	assert.Nil(t, err)
+	req.Header.Set("X-Action", "writeQuorum")
+	req.Header.Set("X-Options", fmt.Sprintf("%s=%s", clusterId, "all"))
+
+	res, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_ = res.Body.Close()
+	assert.Equal(t, 200, res.StatusCode)
+
+	status, err := upload(f, "/integration/quorum.bin", content(1024*256))
+	assert.Nil(t, err)
+	assert.Equal(t, 202, status)
+
+	// upload is acknowledged after the slave holds the blocks
+	master, err := f.Master(clusterId)
+	assert.Nil(t, err)
+	slaves, err := f.Slaves(clusterId)
+	assert.Nil(t, err)
+	assert.True(t, sameBlocks(master, slaves[0]))
+
+	req, err = http.NewRequest("POST", fmt.Sprintf("%s/client/dfs", f.HeadAddress()), bytes.NewReader(content(1024)))
+	assert.Nil(t, err)
+	req.Header.Set("X-Path", url.QueryEscape("/integration/invalid.bin"))
+	req.Header.Set("X-Apply-To", "file")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Write-Quorum", "some")
+
+	res, err = http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		return
+	}
+	_ = res.Body.Close()
+	assert.Equal(t, 422, res.StatusCode)
+}
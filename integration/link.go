@@ -0,0 +1,154 @@
+package integration
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const linkDialTimeout = time.Second * 5
+
+// Link is a loopback tcp proxy placed in front of a data node. The farm only knows the link address
+// so partitioning the link isolates the node from manager, head and the other data nodes
+type Link interface {
+	Address() string
+
+	Partition()
+	Heal()
+	Partitioned() bool
+
+	Close() error
+}
+
+type link struct {
+	target   string
+	listener net.Listener
+
+	mutex       sync.Mutex
+	partitioned bool
+	conns       map[net.Conn]bool
+}
+
+func NewLink(target string) (Link, error) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	l := &link{
+		target:   target,
+		listener: listener,
+		mutex:    sync.Mutex{},
+		conns:    make(map[net.Conn]bool),
+	}
+	go l.accept()
+
+	return l, nil
+}
+
+func (l *link) accept() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		go l.forward(conn)
+	}
+}
+
+func (l *link) forward(conn net.Conn) {
+	if l.Partitioned() {
+		_ = conn.Close()
+		return
+	}
+
+	targetConn, err := net.DialTimeout("tcp4", l.target, linkDialTimeout)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	if !l.track(conn, targetConn) {
+		_ = conn.Close()
+		_ = targetConn.Close()
+		return
+	}
+	defer l.release(conn, targetConn)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go l.pipe(wg, targetConn, conn)
+	go l.pipe(wg, conn, targetConn)
+	wg.Wait()
+}
+
+func (l *link) pipe(wg *sync.WaitGroup, dst net.Conn, src net.Conn) {
+	defer wg.Done()
+
+	_, _ = io.Copy(dst, src)
+
+	// half close is not enough for the data node protocol, drop both sides
+	_ = dst.Close()
+	_ = src.Close()
+}
+
+func (l *link) track(conns ...net.Conn) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.partitioned {
+		return false
+	}
+
+	for _, conn := range conns {
+		l.conns[conn] = true
+	}
+	return true
+}
+
+func (l *link) release(conns ...net.Conn) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, conn := range conns {
+		delete(l.conns, conn)
+	}
+}
+
+func (l *link) Address() string {
+	return l.listener.Addr().String()
+}
+
+func (l *link) Partition() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.partitioned = true
+
+	for conn := range l.conns {
+		_ = conn.Close()
+	}
+	l.conns = make(map[net.Conn]bool)
+}
+
+func (l *link) Heal() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.partitioned = false
+}
+
+func (l *link) Partitioned() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.partitioned
+}
+
+func (l *link) Close() error {
+	l.Partition()
+	return l.listener.Close()
+}
+
+var _ Link = &link{}
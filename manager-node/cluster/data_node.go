@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/protocol"
 )
 
 const (
@@ -55,7 +56,7 @@ type DataNode interface {
 	SyncMove(sha512Hex string, sourceNodeAddr string) error
 	SyncList(snapshotTime *time.Time) (*common.SyncContainer, error)
 	SyncFull(sourceNodeAddr string) bool
-	SyncUsage(usageMap map[string]uint16) error
+	SyncUsage(usageMap map[string]uint32) error
 
 	SnapshotCreate() bool
 	SnapshotDelete(snapshotIndex uint64) bool
@@ -81,26 +82,11 @@ func NewDataNode(nodeAddress string) (DataNode, error) {
 	}, nil
 }
 
-func (d *dataNode) connect(connectionHandler func(conn *net.TCPConn) error) error {
-	conn, err := net.DialTCP("tcp", nil, d.address)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = conn.Close() }()
-
-	return connectionHandler(conn)
+func (d *dataNode) connect(connectionHandler func(conn *protocol.Conn) error) error {
+	return protocol.Do(d.address, connectionHandler)
 }
 
-func (d *dataNode) result(conn *net.TCPConn) bool {
-	b := make([]byte, 1)
-	_, err := conn.Read(b)
-	if err != nil {
-		return false
-	}
-	return strings.Compare("+", string(b)) == 0
-}
-
-func (d *dataNode) resultWithTimeout(conn *net.TCPConn, timeout time.Duration) bool {
+func (d *dataNode) resultWithTimeout(conn *protocol.Conn, timeout time.Duration) bool {
 	if timeout == 0 {
 		timeout = time.Second * 30
 	}
@@ -108,10 +94,10 @@ func (d *dataNode) resultWithTimeout(conn *net.TCPConn, timeout time.Duration) b
 	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
 		return false
 	}
-	return d.result(conn)
+	return conn.Result()
 }
 
-func (d *dataNode) hashAsHex(conn *net.TCPConn) (string, error) {
+func (d *dataNode) hashAsHex(conn *protocol.Conn) (string, error) {
 	h := make([]byte, 32)
 	total, err := io.ReadAtLeast(conn, h, len(h))
 	if err != nil {
@@ -124,7 +110,7 @@ func (d *dataNode) hashAsHex(conn *net.TCPConn) (string, error) {
 }
 
 func (d *dataNode) Create(data []byte) (sha512Hex string, err error) {
-	err = d.connect(func(conn *net.TCPConn) error {
+	err = d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandCreate)); err != nil {
 			return err
 		}
@@ -137,7 +123,12 @@ func (d *dataNode) Create(data []byte) (sha512Hex string, err error) {
 			return err
 		}
 
-		if !d.result(conn) {
+		if !conn.Result() {
+			// legacy data nodes report the existing block and the failure in the same way
+			if conn.Peer().Supports(protocol.CapErrorCodes) && conn.Code() != protocol.EC_Quit {
+				return conn.Error("create command is failed on data node")
+			}
+
 			sha512Hex = hex.EncodeToString(sha512Sum)
 			return nil
 		}
@@ -151,8 +142,8 @@ func (d *dataNode) Create(data []byte) (sha512Hex string, err error) {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("create command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("create command is failed on data node")
 		}
 
 		sha512Hex = hex.EncodeToString(sha512Sum)
@@ -162,7 +153,7 @@ func (d *dataNode) Create(data []byte) (sha512Hex string, err error) {
 }
 
 func (d *dataNode) Read(sha512Hex string, readHandler func([]byte) error) error {
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandRead)); err != nil {
 			return err
 		}
@@ -175,8 +166,8 @@ func (d *dataNode) Read(sha512Hex string, readHandler func([]byte) error) error
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("data node refused the read request")
+		if !conn.Result() {
+			return conn.Error("data node refused the read request")
 		}
 
 		var blockSize uint32
@@ -199,8 +190,8 @@ func (d *dataNode) Read(sha512Hex string, readHandler func([]byte) error) error
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("read command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("read command is failed on data node")
 		}
 
 		sha512HexCompare := hex.EncodeToString(sha512Hash.Sum(nil))
@@ -213,7 +204,7 @@ func (d *dataNode) Read(sha512Hex string, readHandler func([]byte) error) error
 }
 
 func (d *dataNode) Delete(sha512Hex string) error {
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandDelete)); err != nil {
 			return err
 		}
@@ -226,8 +217,8 @@ func (d *dataNode) Delete(sha512Hex string) error {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("delete command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("delete command is failed on data node")
 		}
 
 		return nil
@@ -235,13 +226,13 @@ func (d *dataNode) Delete(sha512Hex string) error {
 }
 
 func (d *dataNode) HardwareId() (hardwareId string, err error) {
-	err = d.connect(func(conn *net.TCPConn) error {
+	err = d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandHardwareId)); err != nil {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("data node refused the hardware id request")
+		if !conn.Result() {
+			return conn.Error("data node refused the hardware id request")
 		}
 
 		var hardwareIdLength byte
@@ -254,8 +245,8 @@ func (d *dataNode) HardwareId() (hardwareId string, err error) {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("hardware id command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("hardware id command is failed on data node")
 		}
 
 		hardwareId = string(readBuffer)
@@ -265,7 +256,7 @@ func (d *dataNode) HardwareId() (hardwareId string, err error) {
 }
 
 func (d *dataNode) Join(clusterId string, nodeId string, masterAddress string) bool {
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandJoin)); err != nil {
 			return err
 		}
@@ -297,8 +288,8 @@ func (d *dataNode) Join(clusterId string, nodeId string, masterAddress string) b
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("join command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("join command is failed on data node")
 		}
 
 		return nil
@@ -306,7 +297,7 @@ func (d *dataNode) Join(clusterId string, nodeId string, masterAddress string) b
 }
 
 func (d *dataNode) Mode(master bool) bool {
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandMode)); err != nil {
 			return err
 		}
@@ -315,8 +306,8 @@ func (d *dataNode) Mode(master bool) bool {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("mode command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("mode command is failed on data node")
 		}
 
 		return nil
@@ -324,13 +315,13 @@ func (d *dataNode) Mode(master bool) bool {
 }
 
 func (d *dataNode) Leave() bool {
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandLeave)); err != nil {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("leave command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("leave command is failed on data node")
 		}
 
 		return nil
@@ -339,7 +330,7 @@ func (d *dataNode) Leave() bool {
 
 //TODO: wipe security mechanism should be implemented between manager and data node
 func (d *dataNode) Wipe() bool {
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandWipe)); err != nil {
 			return err
 		}
@@ -349,8 +340,8 @@ func (d *dataNode) Wipe() bool {
 		//	return false
 		//}
 
-		if !d.result(conn) {
-			return fmt.Errorf("wipe command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("wipe command is failed on data node")
 		}
 
 		return nil
@@ -363,7 +354,7 @@ func (d *dataNode) SyncCreate(sha512Hex string, sourceNodeAddr string) error {
 		return err
 	}
 
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSyncCreate)); err != nil {
 			return err
 		}
@@ -381,8 +372,8 @@ func (d *dataNode) SyncCreate(sha512Hex string, sourceNodeAddr string) error {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("sync create command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("sync create command is failed on data node")
 		}
 
 		return nil
@@ -395,7 +386,7 @@ func (d *dataNode) SyncDelete(sha512Hex string) error {
 		return err
 	}
 
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSyncDelete)); err != nil {
 			return err
 		}
@@ -404,8 +395,8 @@ func (d *dataNode) SyncDelete(sha512Hex string) error {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("sync delete command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("sync delete command is failed on data node")
 		}
 
 		return nil
@@ -418,7 +409,7 @@ func (d *dataNode) SyncMove(sha512Hex string, sourceNodeAddr string) error {
 		return err
 	}
 
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSyncMove)); err != nil {
 			return err
 		}
@@ -436,8 +427,8 @@ func (d *dataNode) SyncMove(sha512Hex string, sourceNodeAddr string) error {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("sync move command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("sync move command is failed on data node")
 		}
 
 		return nil
@@ -447,7 +438,7 @@ func (d *dataNode) SyncMove(sha512Hex string, sourceNodeAddr string) error {
 func (d *dataNode) SyncList(snapshotTime *time.Time) (*common.SyncContainer, error) {
 	container := common.NewSyncContainer()
 
-	if err := d.connect(func(conn *net.TCPConn) error {
+	if err := d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSyncList)); err != nil {
 			return err
 		}
@@ -461,8 +452,8 @@ func (d *dataNode) SyncList(snapshotTime *time.Time) (*common.SyncContainer, err
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("data node refused the sync list request")
+		if !conn.Result() {
+			return conn.Error("data node refused the sync list request")
 		}
 
 		var snapshotsLength uint64
@@ -494,10 +485,11 @@ func (d *dataNode) SyncList(snapshotTime *time.Time) (*common.SyncContainer, err
 				break
 			}
 
-			var usage uint16
-			if err := binary.Read(conn, binary.LittleEndian, &usage); err != nil {
+			usageBuffer := make([]byte, protocol.UsageSize(conn.Peer()))
+			if _, err := io.ReadFull(conn, usageBuffer); err != nil {
 				return err
 			}
+			usage := protocol.DecodeUsage(conn.Peer(), usageBuffer)
 
 			var size int32
 			if err := binary.Read(conn, binary.LittleEndian, &size); err != nil {
@@ -512,8 +504,8 @@ func (d *dataNode) SyncList(snapshotTime *time.Time) (*common.SyncContainer, err
 				}
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("sync list command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("sync list command is failed on data node")
 		}
 
 		return nil
@@ -525,7 +517,7 @@ func (d *dataNode) SyncList(snapshotTime *time.Time) (*common.SyncContainer, err
 }
 
 func (d *dataNode) SyncFull(sourceNodeAddr string) bool {
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSyncFull)); err != nil {
 			return err
 		}
@@ -539,16 +531,16 @@ func (d *dataNode) SyncFull(sourceNodeAddr string) bool {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("sync full command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("sync full command is failed on data node")
 		}
 
 		return nil
 	}) == nil
 }
 
-func (d *dataNode) SyncUsage(usageMap map[string]uint16) error {
-	return d.connect(func(conn *net.TCPConn) error {
+func (d *dataNode) SyncUsage(usageMap map[string]uint32) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSyncUsage)); err != nil {
 			return err
 		}
@@ -562,12 +554,12 @@ func (d *dataNode) SyncUsage(usageMap map[string]uint16) error {
 				return err
 			}
 
-			if err := binary.Write(conn, binary.LittleEndian, usage); err != nil {
+			if _, err := conn.Write(protocol.EncodeUsage(conn.Peer(), usage)); err != nil {
 				return err
 			}
 
-			if !d.result(conn) {
-				return fmt.Errorf("sync usage command is failed on data node")
+			if !conn.Result() {
+				return conn.Error("sync usage command is failed on data node")
 			}
 		}
 
@@ -579,8 +571,8 @@ func (d *dataNode) SyncUsage(usageMap map[string]uint16) error {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("sync usage command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("sync usage command is failed on data node")
 		}
 
 		return nil
@@ -588,13 +580,13 @@ func (d *dataNode) SyncUsage(usageMap map[string]uint16) error {
 }
 
 func (d *dataNode) SnapshotCreate() bool {
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSnapshotCreate)); err != nil {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("snapshot create command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("snapshot create command is failed on data node")
 		}
 
 		return nil
@@ -602,7 +594,7 @@ func (d *dataNode) SnapshotCreate() bool {
 }
 
 func (d *dataNode) SnapshotDelete(snapshotIndex uint64) bool {
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSnapshotDelete)); err != nil {
 			return err
 		}
@@ -611,8 +603,8 @@ func (d *dataNode) SnapshotDelete(snapshotIndex uint64) bool {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("snapshot delete command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("snapshot delete command is failed on data node")
 		}
 
 		return nil
@@ -620,7 +612,7 @@ func (d *dataNode) SnapshotDelete(snapshotIndex uint64) bool {
 }
 
 func (d *dataNode) SnapshotRestore(snapshotIndex uint64) bool {
-	return d.connect(func(conn *net.TCPConn) error {
+	return d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSnapshotRestore)); err != nil {
 			return err
 		}
@@ -629,8 +621,8 @@ func (d *dataNode) SnapshotRestore(snapshotIndex uint64) bool {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("snapshot restore command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("snapshot restore command is failed on data node")
 		}
 
 		return nil
@@ -640,7 +632,7 @@ func (d *dataNode) SnapshotRestore(snapshotIndex uint64) bool {
 func (d *dataNode) Ping() (latency int64) {
 	starts := time.Now().UTC()
 
-	if err := d.connect(func(conn *net.TCPConn) error {
+	if err := d.connect(func(conn *protocol.Conn) error {
 		if err := conn.SetDeadline(time.Now().Add(pingWaitDuration)); err != nil {
 			return err
 		}
@@ -662,21 +654,21 @@ func (d *dataNode) Ping() (latency int64) {
 }
 
 func (d *dataNode) Size() (size uint64, err error) {
-	err = d.connect(func(conn *net.TCPConn) error {
+	err = d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandSize)); err != nil {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("data node refused the size request")
+		if !conn.Result() {
+			return conn.Error("data node refused the size request")
 		}
 
 		if err := binary.Read(conn, binary.LittleEndian, &size); err != nil {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("size command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("size command is failed on data node")
 		}
 
 		return nil
@@ -685,21 +677,21 @@ func (d *dataNode) Size() (size uint64, err error) {
 }
 
 func (d *dataNode) Used() (used uint64, usedErr error) {
-	usedErr = d.connect(func(conn *net.TCPConn) error {
+	usedErr = d.connect(func(conn *protocol.Conn) error {
 		if _, err := conn.Write([]byte(commandUsed)); err != nil {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("data node refused the used request")
+		if !conn.Result() {
+			return conn.Error("data node refused the used request")
 		}
 
 		if err := binary.Read(conn, binary.LittleEndian, &used); err != nil {
 			return err
 		}
 
-		if !d.result(conn) {
-			return fmt.Errorf("used command is failed on data node")
+		if !conn.Result() {
+			return conn.Error("used command is failed on data node")
 		}
 
 		return nil
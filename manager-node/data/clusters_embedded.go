@@ -0,0 +1,291 @@
+package data
+
+import (
+	"sort"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/locking-center-client-go/mutex"
+	bolt "go.etcd.io/bbolt"
+)
+
+type clustersEmbedded struct {
+	mutex mutex.LockingCenter
+	store embedded.Store
+}
+
+func NewClustersEmbedded(store embedded.Store, mutex mutex.LockingCenter) (Clusters, error) {
+	if err := store.Setup(clusterCollection); err != nil {
+		return nil, err
+	}
+
+	return &clustersEmbedded{
+		mutex: mutex,
+		store: store,
+	}, nil
+}
+
+func (c *clustersEmbedded) all(tx *bolt.Tx) (common.Clusters, error) {
+	clusters := make(common.Clusters, 0)
+	if err := tx.Bucket([]byte(clusterCollection)).ForEach(func(_, v []byte) error {
+		var cluster *common.Cluster
+		if err := embedded.Decode(v, &cluster); err != nil {
+			return err
+		}
+		clusters = append(clusters, cluster)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+func (c *clustersEmbedded) RegisterCluster(cluster *common.Cluster) error {
+	c.mutex.Lock(clusterLockKey)
+	defer c.mutex.Unlock(clusterLockKey)
+
+	if _, err := c.Get(cluster.Id); err == nil {
+		return errors.ErrExists
+	} else {
+		if err != errors.ErrNotFound {
+			return err
+		}
+	}
+	return c.overwrite(common.Clusters{cluster})
+}
+
+func (c *clustersEmbedded) UnRegisterCluster(clusterId string, clusterHandler func(cluster *common.Cluster) error) error {
+	cluster, err := c.Get(clusterId)
+	if err != nil {
+		return err
+	}
+	if err := clusterHandler(cluster); err != nil {
+		return err
+	}
+
+	c.mutex.Lock(clusterLockKey)
+	defer c.mutex.Unlock(clusterLockKey)
+
+	return c.store.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(clusterCollection)).Delete([]byte(clusterId))
+	})
+}
+
+func (c *clustersEmbedded) RegisterNodeTo(clusterId string, node *common.Node) error {
+	_, err := c.getClusterByNodeId(node.Id)
+	if err == nil {
+		return errors.ErrRegistered
+	} else {
+		if err != errors.ErrNotFound {
+			return err
+		}
+	}
+
+	return c.Save(clusterId, func(cluster *common.Cluster) error {
+		examNode := cluster.Node(node.Id)
+		if examNode != nil {
+			return errors.ErrRegistered
+		}
+		cluster.Nodes = append(cluster.Nodes, node)
+		return nil
+	})
+}
+
+func (c *clustersEmbedded) UnRegisterNode(nodeId string, syncHandler func(cluster *common.Cluster) error, unregisteredNodeHandler func(deletingNode *common.Node) error, masterChangedHandler func(newMaster *common.Node) error) error {
+	nodeCluster, err := c.getClusterByNodeId(nodeId)
+	if err != nil {
+		return err
+	}
+	deletingNode := nodeCluster.Node(nodeId)
+
+	if deletingNode.Master {
+		if err := syncHandler(nodeCluster); err != nil {
+			return err
+		}
+	}
+
+	return c.Save(nodeCluster.Id, func(cluster *common.Cluster) error {
+		others := cluster.Others(nodeId)
+		if len(others) == 0 {
+			return errors.ErrLastNode
+		}
+
+		if err := unregisteredNodeHandler(deletingNode); err != nil {
+			return err
+		}
+
+		if err := cluster.Delete(nodeId, func(newMaster *common.Node) error {
+			return masterChangedHandler(newMaster)
+		}); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (c *clustersEmbedded) Get(clusterId string) (*common.Cluster, error) {
+	var cluster *common.Cluster
+
+	if err := c.store.View(func(tx *bolt.Tx) error {
+		found, err := embedded.Get(tx.Bucket([]byte(clusterCollection)), clusterId, &cluster)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.ErrNotFound
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return cluster, nil
+}
+
+func (c *clustersEmbedded) GetAll() (common.Clusters, error) {
+	var clusters common.Clusters
+
+	if err := c.store.View(func(tx *bolt.Tx) error {
+		var err error
+		clusters, err = c.all(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	sort.Sort(clusters)
+
+	return clusters, nil
+}
+
+func (c *clustersEmbedded) Save(clusterId string, saveHandler func(cluster *common.Cluster) error) error {
+	c.mutex.Lock(clusterLockKey)
+	defer c.mutex.Unlock(clusterLockKey)
+
+	cluster, err := c.Get(clusterId)
+	if err != nil {
+		return err
+	}
+
+	if err := saveHandler(cluster); err != nil {
+		return err
+	}
+	return c.overwrite(common.Clusters{cluster})
+}
+
+func (c *clustersEmbedded) SaveAll(saveAllHandler func(clusters common.Clusters) error) error {
+	c.mutex.Lock(clusterLockKey)
+	defer c.mutex.Unlock(clusterLockKey)
+
+	var clusters common.Clusters
+	if err := c.store.View(func(tx *bolt.Tx) error {
+		var err error
+		clusters, err = c.all(tx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if err := saveAllHandler(clusters); err != nil {
+		return err
+	}
+	return c.overwrite(clusters)
+}
+
+func (c *clustersEmbedded) SetNewMaster(clusterId string, masterNodeId string) error {
+	return c.Save(clusterId, func(cluster *common.Cluster) error {
+		return cluster.SetMaster(masterNodeId)
+	})
+}
+
+func (c *clustersEmbedded) UpdateNodes(cluster *common.Cluster) error {
+	return c.update(cluster.Id, func(current *common.Cluster) bool {
+		if current.Frozen {
+			return false
+		}
+		current.Nodes = cluster.Nodes
+		current.Paralyzed = cluster.Paralyzed
+		return true
+	})
+}
+
+func (c *clustersEmbedded) ResetStats(cluster *common.Cluster) error {
+	return c.update(cluster.Id, func(current *common.Cluster) bool {
+		current.Reservations = cluster.Reservations
+		current.Used = cluster.Used
+		current.Snapshots = cluster.Snapshots
+		return true
+	})
+}
+
+func (c *clustersEmbedded) SetFreeze(clusterId string, frozen bool) error {
+	return c.update(clusterId, func(current *common.Cluster) bool {
+		if current.Frozen == frozen {
+			return false
+		}
+		current.Paralyzed = true
+		current.Frozen = frozen
+		return true
+	})
+}
+
+func (c *clustersEmbedded) ClusterIdOf(nodeId string) (string, error) {
+	cluster, err := c.getClusterByNodeId(nodeId)
+	if err != nil {
+		return "", err
+	}
+	return cluster.Id, nil
+}
+
+// update applies the changes of updateHandler, missing clusters are ignored like the mongo filter does
+func (c *clustersEmbedded) update(clusterId string, updateHandler func(current *common.Cluster) bool) error {
+	c.mutex.Lock(clusterLockKey)
+	defer c.mutex.Unlock(clusterLockKey)
+
+	return c.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(clusterCollection))
+
+		var current *common.Cluster
+		found, err := embedded.Get(bucket, clusterId, &current)
+		if err != nil || !found {
+			return err
+		}
+
+		if !updateHandler(current) {
+			return nil
+		}
+		return embedded.Put(bucket, clusterId, current)
+	})
+}
+
+func (c *clustersEmbedded) getClusterByNodeId(nodeId string) (*common.Cluster, error) {
+	clusters, err := c.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cluster := range clusters {
+		if cluster.Node(nodeId) != nil {
+			return cluster, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (c *clustersEmbedded) overwrite(clusters common.Clusters) error {
+	return c.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(clusterCollection))
+
+		for _, cluster := range clusters {
+			sort.Sort(cluster.Nodes)
+			sort.Sort(cluster.Snapshots)
+
+			if err := embedded.Put(bucket, cluster.Id, cluster); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+var _ Clusters = &clustersEmbedded{}
@@ -38,3 +38,7 @@ func NewConnection(connectionString string, transaction bool) (*Connection, erro
 		transaction: transaction,
 	}, nil
 }
+
+func (c *Connection) Database(database string) *mongo.Database {
+	return c.client.Database(database)
+}
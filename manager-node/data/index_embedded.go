@@ -0,0 +1,309 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/freakmaxi/locking-center-client-go/mutex"
+	bolt "go.etcd.io/bbolt"
+)
+
+const indexChunkBucket = "index_chunk"
+const indexChunkNodesBucket = "index_chunk_nodes"
+const indexClusterBucket = "index_cluster"
+const indexBulkLockKey = "index_bulk"
+
+type indexEmbedded struct {
+	mutex mutex.LockingCenter
+	store embedded.Store
+}
+
+func NewIndexEmbedded(store embedded.Store, mutex mutex.LockingCenter) (Index, error) {
+	if err := store.Setup(indexChunkBucket, indexChunkNodesBucket, indexClusterBucket); err != nil {
+		return nil, err
+	}
+
+	return &indexEmbedded{
+		mutex: mutex,
+		store: store,
+	}, nil
+}
+
+func (i *indexEmbedded) clusterBucket(tx *bolt.Tx, clusterId string) (*bolt.Bucket, error) {
+	return tx.Bucket([]byte(indexClusterBucket)).CreateBucketIfNotExists([]byte(clusterId))
+}
+
+func (i *indexEmbedded) replace(tx *bolt.Tx, item *common.CacheFileItem) error {
+	if err := embedded.Put(tx.Bucket([]byte(indexChunkBucket)), item.FileItem.Sha512Hex, item.Export()); err != nil {
+		return err
+	}
+
+	nodesBucket := tx.Bucket([]byte(indexChunkNodesBucket))
+
+	existsIn := make(common.CacheFileItemLocationMap)
+	if _, err := embedded.Get(nodesBucket, item.FileItem.Sha512Hex, &existsIn); err != nil {
+		return err
+	}
+	for nodeId, exists := range item.ExistsIn {
+		existsIn[nodeId] = exists
+	}
+	if err := embedded.Put(nodesBucket, item.FileItem.Sha512Hex, existsIn); err != nil {
+		return err
+	}
+
+	clusterBucket, err := i.clusterBucket(tx, item.ClusterId)
+	if err != nil {
+		return err
+	}
+	return clusterBucket.Put([]byte(item.FileItem.Sha512Hex), []byte(fmt.Sprintf("%s|%d", item.ClusterId, item.FileItem.Usage)))
+}
+
+func (i *indexEmbedded) drop(tx *bolt.Tx, clusterId string, sha512Hex string) error {
+	if err := tx.Bucket([]byte(indexChunkBucket)).Delete([]byte(sha512Hex)); err != nil {
+		return err
+	}
+	if err := tx.Bucket([]byte(indexChunkNodesBucket)).Delete([]byte(sha512Hex)); err != nil {
+		return err
+	}
+
+	clusterBucket := tx.Bucket([]byte(indexClusterBucket)).Bucket([]byte(clusterId))
+	if clusterBucket == nil {
+		return nil
+	}
+	return clusterBucket.Delete([]byte(sha512Hex))
+}
+
+func (i *indexEmbedded) updateChunkNode(tx *bolt.Tx, sha512Hex string, nodeId string, exists bool) error {
+	nodesBucket := tx.Bucket([]byte(indexChunkNodesBucket))
+
+	existsIn := make(common.CacheFileItemLocationMap)
+	if _, err := embedded.Get(nodesBucket, sha512Hex, &existsIn); err != nil {
+		return err
+	}
+	existsIn[nodeId] = exists
+
+	return embedded.Put(nodesBucket, sha512Hex, existsIn)
+}
+
+func (i *indexEmbedded) DropBulk(clusterId string, sha512HexList []string) error {
+	if len(sha512HexList) == 0 {
+		return nil
+	}
+
+	i.mutex.Lock(indexBulkLockKey)
+	defer i.mutex.Unlock(indexBulkLockKey)
+
+	return i.store.Update(func(tx *bolt.Tx) error {
+		for _, sha512Hex := range sha512HexList {
+			if err := i.drop(tx, clusterId, sha512Hex); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (i *indexEmbedded) Replace(item common.CacheFileItem) error {
+	i.mutex.Wait(indexBulkLockKey)
+
+	i.mutex.Lock(item.FileItem.Sha512Hex)
+	defer i.mutex.Unlock(item.FileItem.Sha512Hex)
+
+	return i.store.Update(func(tx *bolt.Tx) error {
+		return i.replace(tx, &item)
+	})
+}
+
+func (i *indexEmbedded) ReplaceBulk(items common.CacheFileItemMap) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	i.mutex.Lock(indexBulkLockKey)
+	defer i.mutex.Unlock(indexBulkLockKey)
+
+	return i.store.Update(func(tx *bolt.Tx) error {
+		for _, item := range items {
+			if err := i.replace(tx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (i *indexEmbedded) UpdateChunkNode(sha512Hex string, nodeId string, exists bool) error {
+	i.mutex.Wait(indexBulkLockKey)
+
+	i.mutex.Lock(sha512Hex)
+	defer i.mutex.Unlock(sha512Hex)
+
+	return i.store.Update(func(tx *bolt.Tx) error {
+		return i.updateChunkNode(tx, sha512Hex, nodeId, exists)
+	})
+}
+
+func (i *indexEmbedded) UpdateChunkNodeBulk(sha512HexList []string, nodeId string, exists bool) error {
+	if len(sha512HexList) == 0 {
+		return nil
+	}
+
+	i.mutex.Lock(indexBulkLockKey)
+	defer i.mutex.Unlock(indexBulkLockKey)
+
+	return i.store.Update(func(tx *bolt.Tx) error {
+		for _, sha512Hex := range sha512HexList {
+			if err := i.updateChunkNode(tx, sha512Hex, nodeId, exists); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (i *indexEmbedded) Get(sha512Hex string) (*common.CacheFileItem, error) {
+	var item *common.CacheFileItem
+
+	if err := i.store.View(func(tx *bolt.Tx) error {
+		c := make(map[string]string)
+		found, err := embedded.Get(tx.Bucket([]byte(indexChunkBucket)), sha512Hex, &c)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return os.ErrNotExist
+		}
+
+		item = common.NewCacheFileItemFromMap(c)
+		if item.Expired() {
+			return os.ErrNotExist
+		}
+
+		_, err = embedded.Get(tx.Bucket([]byte(indexChunkNodesBucket)), sha512Hex, &item.ExistsIn)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (i *indexEmbedded) Drop(clusterId string, sha512Hex string) error {
+	i.mutex.Wait(indexBulkLockKey)
+
+	i.mutex.Lock(sha512Hex)
+	defer i.mutex.Unlock(sha512Hex)
+
+	return i.store.Update(func(tx *bolt.Tx) error {
+		return i.drop(tx, clusterId, sha512Hex)
+	})
+}
+
+func (i *indexEmbedded) PutMap(clusterId string, items common.SyncFileItemMap) error {
+	return i.store.Update(func(tx *bolt.Tx) error {
+		clusterBucket, err := i.clusterBucket(tx, clusterId)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			if err := clusterBucket.Put([]byte(item.Sha512Hex), []byte(fmt.Sprintf("%s|%d", clusterId, item.Usage))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (i *indexEmbedded) UpdateUsageInMap(clusterId string, fileItemList common.SyncFileItemList) error {
+	if len(fileItemList) == 0 {
+		return nil
+	}
+
+	i.mutex.Lock(indexBulkLockKey)
+	defer i.mutex.Unlock(indexBulkLockKey)
+
+	return i.store.Update(func(tx *bolt.Tx) error {
+		chunkBucket := tx.Bucket([]byte(indexChunkBucket))
+
+		clusterBucket, err := i.clusterBucket(tx, clusterId)
+		if err != nil {
+			return err
+		}
+
+		for _, fileItem := range fileItemList {
+			c := make(map[string]string)
+			found, err := embedded.Get(chunkBucket, fileItem.Sha512Hex, &c)
+			if err != nil {
+				return err
+			}
+			if found {
+				c["usage"] = strconv.FormatUint(uint64(fileItem.Usage), 10)
+				if err := embedded.Put(chunkBucket, fileItem.Sha512Hex, c); err != nil {
+					return err
+				}
+			}
+
+			if err := clusterBucket.Put([]byte(fileItem.Sha512Hex), []byte(fmt.Sprintf("%s|%d", clusterId, fileItem.Usage))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (i *indexEmbedded) PullMap(clusterId string) (map[string]string, error) {
+	sha512HexMap := make(map[string]string)
+
+	if err := i.store.View(func(tx *bolt.Tx) error {
+		clusterBucket := tx.Bucket([]byte(indexClusterBucket)).Bucket([]byte(clusterId))
+		if clusterBucket == nil {
+			return nil
+		}
+
+		return clusterBucket.ForEach(func(k, v []byte) error {
+			sha512HexMap[string(k)] = string(v)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return sha512HexMap, nil
+}
+
+func (i *indexEmbedded) CompareMap(clusterId string, items common.SyncFileItemMap) bool {
+	cachedMap, err := i.PullMap(clusterId)
+	if err != nil {
+		return false
+	}
+
+	for sha512Hex, itemValue := range cachedMap {
+		fileItem, has := items[sha512Hex]
+		if !has {
+			return false
+		}
+		compareValue := fmt.Sprintf("%s|%d", clusterId, fileItem.Usage)
+		if strings.Compare(itemValue, compareValue) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (i *indexEmbedded) DropMap(clusterId string) error {
+	return i.store.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket([]byte(indexClusterBucket)).DeleteBucket([]byte(clusterId))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+var _ Index = &indexEmbedded{}
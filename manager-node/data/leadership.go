@@ -0,0 +1,76 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Leadership interface {
+	// Acquire takes or renews the lease for the holder and returns the current leader. The request is bounded by the
+	// context, so the caller can limit it to the remaining lease time
+	Acquire(ctx context.Context, holder string, lease time.Duration) (string, error)
+}
+
+const leadershipCollection = "leadership"
+const leadershipKey = "manager"
+const duplicateKeyErrorCode = 11000
+
+type leadership struct {
+	col *mongo.Collection
+}
+
+func NewLeadership(conn *Connection, database string) Leadership {
+	return &leadership{
+		col: conn.client.Database(database).Collection(leadershipCollection),
+	}
+}
+
+func (l *leadership) Acquire(ctx context.Context, holder string, lease time.Duration) (string, error) {
+	now := time.Now().UTC()
+
+	filter := bson.M{
+		"_id": leadershipKey,
+		"$or": bson.A{
+			bson.M{"holder": holder},
+			bson.M{"expiresAt": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"holder": holder, "expiresAt": now.Add(lease)}}
+
+	_, err := l.col.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err == nil {
+		return holder, nil
+	}
+	if !l.duplicateKey(err) {
+		return "", err
+	}
+
+	var current struct {
+		Holder string `bson:"holder"`
+	}
+	if err := l.col.FindOne(ctx, bson.M{"_id": leadershipKey}).Decode(&current); err != nil {
+		return "", err
+	}
+	return current.Holder, nil
+}
+
+// duplicateKey reports the upsert conflict raised when the lease is held by another holder
+func (l *leadership) duplicateKey(err error) bool {
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, writeError := range e.WriteErrors {
+			if writeError.Code == duplicateKeyErrorCode {
+				return true
+			}
+		}
+	case mongo.CommandError:
+		return e.Code == duplicateKeyErrorCode
+	}
+	return false
+}
+
+var _ Leadership = &leadership{}
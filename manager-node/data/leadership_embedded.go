@@ -0,0 +1,63 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	bolt "go.etcd.io/bbolt"
+)
+
+type leadershipEmbedded struct {
+	store embedded.Store
+}
+
+type leaderLease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func NewLeadershipEmbedded(store embedded.Store) (Leadership, error) {
+	if err := store.Setup(leadershipCollection); err != nil {
+		return nil, err
+	}
+
+	return &leadershipEmbedded{
+		store: store,
+	}, nil
+}
+
+func (l *leadershipEmbedded) Acquire(ctx context.Context, holder string, lease time.Duration) (string, error) {
+	now := time.Now().UTC()
+	leader := ""
+
+	if err := l.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(leadershipCollection))
+
+		current := leaderLease{}
+		found, err := embedded.Get(bucket, leadershipKey, &current)
+		if err != nil {
+			return err
+		}
+
+		if found && strings.Compare(current.Holder, holder) != 0 && current.ExpiresAt.After(now) {
+			leader = current.Holder
+			return nil
+		}
+
+		// the lease is not taken when the caller does not wait for the result anymore
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		leader = holder
+		return embedded.Put(bucket, leadershipKey, leaderLease{Holder: holder, ExpiresAt: now.Add(lease)})
+	}); err != nil {
+		return "", err
+	}
+
+	return leader, nil
+}
+
+var _ Leadership = &leadershipEmbedded{}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/freakmaxi/kertish-dfs/basics/common"
 	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
 	"github.com/freakmaxi/locking-center-client-go/mutex"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -18,8 +19,10 @@ import (
 )
 
 type Metadata interface {
-	Cursor(folderHandler func(folder *common.Folder) (bool, error), parallelSize uint8) error
+	// Cursor saves the changed folder with the change feed events that the folder handler returns
+	Cursor(folderHandler func(folder *common.Folder) (bool, common.Events, error), parallelSize uint8) error
 	LockTree(folderHandler func(folders []*common.Folder) ([]*common.Folder, error)) error
+	LockFolder(folderPath string, folderHandler func(folder *common.Folder) (bool, error)) error
 
 	Lock()
 	Unlock()
@@ -29,18 +32,20 @@ const metadataCollection = "metadata"
 const metadataLockKey = "metadata"
 
 type metadata struct {
-	mutex mutex.LockingCenter
-	conn  *Connection
-	col   *mongo.Collection
+	mutex  mutex.LockingCenter
+	conn   *Connection
+	col    *mongo.Collection
+	events events.MongoStore
 }
 
-func NewMetadata(mutex mutex.LockingCenter, conn *Connection, database string) (Metadata, error) {
+func NewMetadata(mutex mutex.LockingCenter, conn *Connection, database string, eventStore events.MongoStore) (Metadata, error) {
 	dfsCol := conn.client.Database(database).Collection(metadataCollection)
 
 	return &metadata{
-		mutex: mutex,
-		conn:  conn,
-		col:   dfsCol,
+		mutex:  mutex,
+		conn:   conn,
+		col:    dfsCol,
+		events: eventStore,
 	}, nil
 }
 
@@ -119,7 +124,7 @@ func (m *metadata) Unlock() {
 	m.mutex.Unlock(metadataLockKey)
 }
 
-func (m *metadata) Cursor(folderHandler func(folder *common.Folder) (bool, error), parallelSize uint8) error {
+func (m *metadata) Cursor(folderHandler func(folder *common.Folder) (bool, common.Events, error), parallelSize uint8) error {
 	semaphoreChan := make(chan bool, parallelSize)
 	for i := 0; i < cap(semaphoreChan); i++ {
 		semaphoreChan <- true
@@ -166,7 +171,7 @@ func (m *metadata) Cursor(folderHandler func(folder *common.Folder) (bool, error
 			return
 		}
 
-		changed, err := folderHandler(folder)
+		changed, changeEvents, err := folderHandler(folder)
 		if err != nil {
 			errorChan <- err
 			return
@@ -175,7 +180,7 @@ func (m *metadata) Cursor(folderHandler func(folder *common.Folder) (bool, error
 			return
 		}
 
-		if err := m.save([]*common.Folder{folder}, false); err != nil {
+		if err := m.save([]*common.Folder{folder}, false, changeEvents); err != nil {
 			errorChan <- err
 		}
 	}
@@ -277,10 +282,32 @@ func (m *metadata) LockTree(folderHandler func(folders []*common.Folder) ([]*com
 		return nil
 	}
 
-	return m.save(result, true)
+	return m.save(result, true, nil)
 }
 
-func (m *metadata) save(folders []*common.Folder, upsert bool) error {
+func (m *metadata) LockFolder(folderPath string, folderHandler func(folder *common.Folder) (bool, error)) error {
+	m.mutex.Wait(metadataLockKey)
+
+	m.mutex.Lock(folderPath)
+	defer m.mutex.Unlock(folderPath)
+
+	folder, err := m.findOne(bson.M{"full": folderPath})
+	if err != nil {
+		return err
+	}
+
+	changed, err := folderHandler(folder)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+
+	return m.save([]*common.Folder{folder}, false, nil)
+}
+
+func (m *metadata) save(folders []*common.Folder, upsert bool, changeEvents common.Events) error {
 	session, err := m.conn.client.StartSession()
 	if err != nil {
 		return err
@@ -305,6 +332,10 @@ func (m *metadata) save(folders []*common.Folder, upsert bool) error {
 			}
 		}
 
+		if err := m.events.Append(parentContext, changeEvents); err != nil {
+			return err
+		}
+
 		return sc.CommitTransaction(parentContext)
 	}); err != nil {
 		return err
@@ -0,0 +1,212 @@
+package data
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
+	"github.com/freakmaxi/locking-center-client-go/mutex"
+	bolt "go.etcd.io/bbolt"
+)
+
+type metadataEmbedded struct {
+	mutex  mutex.LockingCenter
+	store  embedded.Store
+	events events.EmbeddedStore
+}
+
+func NewMetadataEmbedded(store embedded.Store, mutex mutex.LockingCenter, eventStore events.EmbeddedStore) (Metadata, error) {
+	if err := store.Setup(metadataCollection); err != nil {
+		return nil, err
+	}
+
+	return &metadataEmbedded{
+		mutex:  mutex,
+		store:  store,
+		events: eventStore,
+	}, nil
+}
+
+func (m *metadataEmbedded) findOne(folderPath string) (*common.Folder, error) {
+	var folder *common.Folder
+
+	if err := m.store.View(func(tx *bolt.Tx) error {
+		found, err := embedded.Get(tx.Bucket([]byte(metadataCollection)), folderPath, &folder)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return os.ErrNotExist
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+func (m *metadataEmbedded) Lock() {
+	m.mutex.Lock(metadataLockKey)
+}
+
+func (m *metadataEmbedded) Unlock() {
+	m.mutex.Unlock(metadataLockKey)
+}
+
+func (m *metadataEmbedded) Cursor(folderHandler func(folder *common.Folder) (bool, common.Events, error), parallelSize uint8) error {
+	semaphoreChan := make(chan bool, parallelSize)
+	for i := 0; i < cap(semaphoreChan); i++ {
+		semaphoreChan <- true
+	}
+	defer close(semaphoreChan)
+
+	folderPaths := make([]string, 0)
+	if err := m.store.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(metadataCollection)).ForEach(func(k, _ []byte) error {
+			folderPaths = append(folderPaths, string(k))
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(folderPaths)))
+
+	handlerFunc := func(wg *sync.WaitGroup, folderPath string, errorChan chan error) {
+		defer wg.Done()
+		defer func() { semaphoreChan <- true }()
+
+		m.mutex.Lock(folderPath)
+		defer m.mutex.Unlock(folderPath)
+
+		folder, err := m.findOne(folderPath)
+		if err != nil {
+			if err == os.ErrNotExist {
+				return
+			}
+			errorChan <- err
+			return
+		}
+
+		changed, changeEvents, err := folderHandler(folder)
+		if err != nil {
+			errorChan <- err
+			return
+		}
+		if !changed {
+			return
+		}
+
+		if err := m.save([]*common.Folder{folder}, false, changeEvents); err != nil {
+			errorChan <- err
+		}
+	}
+
+	wg := &sync.WaitGroup{}
+	errorChan := make(chan error, parallelSize)
+
+	for _, folderPath := range folderPaths {
+		wg.Add(1)
+		go handlerFunc(wg, folderPath, errorChan)
+
+		<-semaphoreChan
+
+		if len(errorChan) > 0 {
+			break
+		}
+	}
+	wg.Wait()
+
+	close(errorChan)
+
+	bulkError := errors.NewBulkError()
+	for err := range errorChan {
+		bulkError.Add(err)
+	}
+
+	if bulkError.HasError() {
+		return bulkError
+	}
+
+	return nil
+}
+
+func (m *metadataEmbedded) LockTree(folderHandler func(folders []*common.Folder) ([]*common.Folder, error)) error {
+	m.mutex.Lock(metadataLockKey)
+	defer m.mutex.Unlock(metadataLockKey)
+
+	folders := make([]*common.Folder, 0)
+	if err := m.store.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(metadataCollection)).ForEach(func(k, v []byte) error {
+			if !strings.HasPrefix(string(k), "/") {
+				return nil
+			}
+
+			var folder *common.Folder
+			if err := embedded.Decode(v, &folder); err != nil {
+				return err
+			}
+			folders = append(folders, folder)
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	result, err := folderHandler(folders)
+	if err != nil {
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return m.save(result, true, nil)
+}
+
+func (m *metadataEmbedded) LockFolder(folderPath string, folderHandler func(folder *common.Folder) (bool, error)) error {
+	m.mutex.Wait(metadataLockKey)
+
+	m.mutex.Lock(folderPath)
+	defer m.mutex.Unlock(folderPath)
+
+	folder, err := m.findOne(folderPath)
+	if err != nil {
+		return err
+	}
+
+	changed, err := folderHandler(folder)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+
+	return m.save([]*common.Folder{folder}, false, nil)
+}
+
+func (m *metadataEmbedded) save(folders []*common.Folder, upsert bool, changeEvents common.Events) error {
+	return m.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metadataCollection))
+
+		for _, folder := range folders {
+			if !upsert && bucket.Get([]byte(folder.Full)) == nil {
+				continue
+			}
+
+			if err := embedded.Put(bucket, folder.Full, folder); err != nil {
+				return err
+			}
+		}
+		return m.events.Append(tx, changeEvents)
+	})
+}
+
+var _ Metadata = &metadataEmbedded{}
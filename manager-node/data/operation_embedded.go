@@ -0,0 +1,56 @@
+package data
+
+import (
+	"sync"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	bolt "go.etcd.io/bbolt"
+)
+
+const operationBucket = "operation"
+
+type operationEmbedded struct {
+	mutex *sync.Mutex
+	store embedded.Store
+}
+
+func NewOperationEmbedded(store embedded.Store) (Operation, error) {
+	if err := store.Setup(operationBucket); err != nil {
+		return nil, err
+	}
+
+	return &operationEmbedded{
+		mutex: &sync.Mutex{},
+		store: store,
+	}, nil
+}
+
+func (o *operationEmbedded) RepairDetail() (RepairDetail, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	repairDetail := RepairDetail{Processing: false}
+	err := o.store.View(func(tx *bolt.Tx) error {
+		_, err := embedded.Get(tx.Bucket([]byte(operationBucket)), "repairing", &repairDetail)
+		return err
+	})
+	return repairDetail, err
+}
+
+func (o *operationEmbedded) SetRepairing(repairing bool, completed bool) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	repairDetail := RepairDetail{Processing: repairing}
+	if completed {
+		timestamp := time.Now().UTC()
+		repairDetail.Timestamp = &timestamp
+	}
+
+	return o.store.Update(func(tx *bolt.Tx) error {
+		return embedded.Put(tx.Bucket([]byte(operationBucket)), "repairing", repairDetail)
+	})
+}
+
+var _ Operation = &operationEmbedded{}
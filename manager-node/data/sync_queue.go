@@ -0,0 +1,129 @@
+package data
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SyncQueue interface {
+	Add(items common.SyncQueueItems) error
+	Remove(id string) error
+	List() (common.SyncQueueItems, error)
+}
+
+const syncQueueCollection = "sync_queue"
+
+type syncQueue struct {
+	col *mongo.Collection
+}
+
+func NewSyncQueue(conn *Connection, database string) (SyncQueue, error) {
+	syncQueueCol := conn.client.Database(database).Collection(syncQueueCollection)
+
+	s := &syncQueue{
+		col: syncQueueCol,
+	}
+	if err := s.setupIndices(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *syncQueue) context(parentContext context.Context) (context.Context, context.CancelFunc) {
+	timeoutDuration := time.Second * 30
+	return context.WithTimeout(parentContext, timeoutDuration)
+}
+
+func (s *syncQueue) setupIndices() error {
+	models := []mongo.IndexModel{
+		{Keys: bson.M{"id": 1}},
+		{Keys: bson.M{"date": 1}},
+	}
+
+	ctx, cancelFunc := s.context(context.Background())
+	defer cancelFunc()
+
+	_, err := s.col.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+func (s *syncQueue) next(cursor *mongo.Cursor) (*common.SyncQueueItem, error) {
+	ctx, cancelFunc := s.context(context.Background())
+	defer cancelFunc()
+
+	if !cursor.Next(ctx) {
+		return nil, io.EOF
+	}
+
+	var item *common.SyncQueueItem
+	if err := cursor.Decode(&item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *syncQueue) Add(items common.SyncQueueItems) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0)
+	for _, item := range items {
+		documents = append(documents, item)
+	}
+
+	ctx, cancelFunc := s.context(context.Background())
+	defer cancelFunc()
+
+	_, err := s.col.InsertMany(ctx, documents)
+	return err
+}
+
+func (s *syncQueue) Remove(id string) error {
+	ctx, cancelFunc := s.context(context.Background())
+	defer cancelFunc()
+
+	_, err := s.col.DeleteOne(ctx, bson.M{"id": id})
+	return err
+}
+
+func (s *syncQueue) List() (common.SyncQueueItems, error) {
+	opts := options.Find()
+	opts.SetSort(bson.M{"date": 1})
+
+	ctx, cancelFunc := s.context(context.Background())
+	defer cancelFunc()
+
+	cursor, err := s.col.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		ctx, cancelFunc := s.context(context.Background())
+		defer cancelFunc()
+
+		_ = cursor.Close(ctx)
+	}()
+
+	items := make(common.SyncQueueItems, 0)
+	for {
+		item, err := s.next(cursor)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+var _ SyncQueue = &syncQueue{}
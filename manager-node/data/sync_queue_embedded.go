@@ -0,0 +1,69 @@
+package data
+
+import (
+	"sort"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	bolt "go.etcd.io/bbolt"
+)
+
+type syncQueueEmbedded struct {
+	store embedded.Store
+}
+
+func NewSyncQueueEmbedded(store embedded.Store) (SyncQueue, error) {
+	if err := store.Setup(syncQueueCollection); err != nil {
+		return nil, err
+	}
+
+	return &syncQueueEmbedded{
+		store: store,
+	}, nil
+}
+
+func (s *syncQueueEmbedded) Add(items common.SyncQueueItems) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	return s.store.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(syncQueueCollection))
+
+		for _, item := range items {
+			if err := embedded.Put(bucket, item.Id, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *syncQueueEmbedded) Remove(id string) error {
+	return s.store.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(syncQueueCollection)).Delete([]byte(id))
+	})
+}
+
+func (s *syncQueueEmbedded) List() (common.SyncQueueItems, error) {
+	items := make(common.SyncQueueItems, 0)
+
+	if err := s.store.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(syncQueueCollection)).ForEach(func(_, v []byte) error {
+			var item *common.SyncQueueItem
+			if err := embedded.Decode(v, &item); err != nil {
+				return err
+			}
+			items = append(items, item)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Date.Before(items[j].Date) })
+
+	return items, nil
+}
+
+var _ SyncQueue = &syncQueueEmbedded{}
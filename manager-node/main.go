@@ -7,10 +7,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/freakmaxi/kertish-dfs/basics/audit"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
 	"github.com/freakmaxi/kertish-dfs/basics/log"
+	"github.com/freakmaxi/kertish-dfs/basics/tracing"
 	"github.com/freakmaxi/kertish-dfs/manager-node/data"
-	"github.com/freakmaxi/kertish-dfs/manager-node/manager"
-	"github.com/freakmaxi/kertish-dfs/manager-node/routing"
 	"github.com/freakmaxi/kertish-dfs/manager-node/services"
 	"github.com/freakmaxi/locking-center-client-go/mutex"
 	"go.uber.org/zap"
@@ -34,29 +35,60 @@ func main() {
 
 	logger.Info("---------- Starting Manager Node -----------")
 
-	bindAddr := os.Getenv("BIND_ADDRESS")
-	if len(bindAddr) == 0 {
-		bindAddr = ":9400"
+	bindAddr := services.ReadBindAddress("BIND_ADDRESS", logger)
+
+	tracingOutput := os.Getenv("TRACING_OUTPUT")
+	if len(tracingOutput) > 0 {
+		tracingTarget := os.Getenv("TRACING_TARGET")
+		logger.Info(fmt.Sprintf("TRACING_OUTPUT: %s", tracingOutput))
+		logger.Info(fmt.Sprintf("TRACING_TARGET: %s", tracingTarget))
+
+		if err := tracing.Setup("manager-node", tracingOutput, tracingTarget, logger); err != nil {
+			logger.Error("Tracing setup is failed", zap.Error(err))
+			os.Exit(7)
+		}
 	}
-	logger.Info(fmt.Sprintf("BIND_ADDRESS: %s", bindAddr))
+
+	advertiseAddr := os.Getenv("ADVERTISE_ADDRESS")
+	if len(advertiseAddr) == 0 {
+		hostname, _ := os.Hostname()
+		advertiseAddr = bindAddr
+		if strings.Index(advertiseAddr, ":") == 0 {
+			advertiseAddr = fmt.Sprintf("%s%s", hostname, advertiseAddr)
+		}
+	}
+	if strings.Index(advertiseAddr, "://") == -1 {
+		advertiseAddr = fmt.Sprintf("http://%s", advertiseAddr)
+	}
+	logger.Info(fmt.Sprintf("ADVERTISE_ADDRESS: %s", advertiseAddr))
 
 	mutexSourceAddr := bindAddr
 	if strings.Index(mutexSourceAddr, ":") == 0 {
 		mutexSourceAddr = fmt.Sprintf("127.0.0.1%s", mutexSourceAddr)
 	}
 
-	healthCheckIntervalString := os.Getenv("HEALTH_CHECK_INTERVAL")
-	if len(healthCheckIntervalString) == 0 {
-		healthCheckIntervalString = "10"
-	}
-	healthCheckInterval, err := strconv.ParseUint(healthCheckIntervalString, 10, 64)
+	healthCheckInterval, err := services.ReadHealthCheckInterval(logger)
 	if err != nil {
 		logger.Error("Health Check Interval is wrong", zap.Error(err))
 		os.Exit(5)
 	}
-	if healthCheckInterval > 0 {
-		logger.Info(fmt.Sprintf("HEALTH_CHECK_INTERVAL: %s second(s)", healthCheckIntervalString))
+
+	lifecycleInterval, err := services.ReadLifecycleInterval(logger)
+	if err != nil {
+		logger.Error("Lifecycle Interval is wrong", zap.Error(err))
+		os.Exit(6)
+	}
+
+	leaderLeaseString := os.Getenv("LEADER_LEASE")
+	if len(leaderLeaseString) == 0 {
+		leaderLeaseString = "15"
 	}
+	leaderLease, err := strconv.ParseUint(leaderLeaseString, 10, 64)
+	if err != nil || leaderLease == 0 {
+		logger.Error("Leader Lease is wrong", zap.Error(err))
+		os.Exit(8)
+	}
+	logger.Info(fmt.Sprintf("LEADER_LEASE: %s second(s)", leaderLeaseString))
 
 	mongoConn := os.Getenv("MONGO_CONN")
 	if len(mongoConn) == 0 {
@@ -107,7 +139,9 @@ func main() {
 		os.Exit(21)
 	}
 
-	dataClusters, err := data.NewClusters(conn, mongoDb, m)
+	stores := &services.Stores{}
+
+	stores.Clusters, err = data.NewClusters(conn, mongoDb, m)
 	if err != nil {
 		logger.Error("Cluster Data Manager is failed", zap.Error(err))
 		os.Exit(22)
@@ -123,43 +157,52 @@ func main() {
 		logger.Error("Cache Client Setup is failed", zap.Error(err))
 		os.Exit(23)
 	}
-	index := data.NewIndex(m, cacheClient, strings.ReplaceAll(mongoDb, " ", "_"))
-	operation := data.NewOperation(cacheClient, strings.ReplaceAll(mongoDb, " ", "_"))
+	stores.Index = data.NewIndex(m, cacheClient, strings.ReplaceAll(mongoDb, " ", "_"))
+	stores.Operation = data.NewOperation(cacheClient, strings.ReplaceAll(mongoDb, " ", "_"))
 
-	metadata, err := data.NewMetadata(m, conn, mongoDb)
+	// change feed events of the lifecycle deletions, the head node keeps the retention of the feed
+	eventStore, err := events.NewStore(conn.Database(mongoDb), 0)
+	if err != nil {
+		logger.Error("Change Feed Manager is failed", zap.Error(err))
+		os.Exit(28)
+	}
+
+	stores.Metadata, err = data.NewMetadata(m, conn, mongoDb, eventStore)
 	if err != nil {
 		logger.Error("Metadata Manager is failed", zap.Error(err))
 		os.Exit(24)
 	}
 
-	synchronize := manager.NewSynchronize(dataClusters, index, logger)
-	repair := manager.NewRepair(dataClusters, metadata, index, operation, synchronize, logger)
-
-	health := manager.NewHealthTracker(dataClusters, index, synchronize, repair, logger, time.Second*time.Duration(healthCheckInterval))
-	health.Start()
+	stores.Audit, err = audit.NewStore(conn.Database(mongoDb))
+	if err != nil {
+		logger.Error("Audit Manager is failed", zap.Error(err))
+		os.Exit(26)
+	}
 
-	managerCluster, err := manager.NewCluster(dataClusters, index, synchronize, logger)
+	stores.SyncQueue, err = data.NewSyncQueue(conn, mongoDb)
+	if err != nil {
+		logger.Error("Sync Queue Manager is failed", zap.Error(err))
+		os.Exit(27)
+	}
+
+	stores.Leadership = data.NewLeadership(conn, mongoDb)
+
+	service, err := services.NewService(
+		services.Config{
+			BindAddr:            bindAddr,
+			AdvertiseAddr:       advertiseAddr,
+			HealthCheckInterval: healthCheckInterval,
+			LifecycleInterval:   lifecycleInterval,
+			LeaderLease:         time.Second * time.Duration(leaderLease),
+		},
+		stores,
+		logger,
+	)
 	if err != nil {
 		logger.Error("Cluster Manager is failed", zap.Error(err))
 		os.Exit(25)
 	}
-	managerRouter := routing.NewManagerRouter(managerCluster, synchronize, repair, health, logger)
-
-	if err := managerCluster.Handshake(); err != nil {
-		logger.Error("Handshake is failed with cluster nodes", zap.Error(err))
-	} else {
-		logger.Info("Handshake is completed with cluster nodes...")
-	}
-
-	routerManager := routing.NewManager()
-	routerManager.Add(managerRouter)
-
-	managerNode := manager.NewNode(dataClusters, index, logger)
-	nodeRouter := routing.NewNodeRouter(managerNode, logger)
-	routerManager.Add(nodeRouter)
-
-	proxy := services.NewProxy(bindAddr, routerManager, logger)
-	proxy.Start()
+	service.Start()
 
 	os.Exit(0)
 }
@@ -102,6 +102,9 @@ func (b *balance) complete(balancingClusters common.Clusters) {
 }
 
 func (b *balance) Balance(clusterIds []string) error {
+	balanceRunning.Inc()
+	defer balanceRunning.Dec()
+
 	clusters, err := b.clusters.GetAll()
 	if err != nil {
 		return err
@@ -175,7 +178,10 @@ func (b *balance) Balance(clusterIds []string) error {
 		emptiestCluster := balancingClusters[0]
 		fullestCluster := balancingClusters[len(balancingClusters)-1]
 
-		if fullestCluster.Weight()-emptiestCluster.Weight() < balanceThreshold {
+		weightGap := fullestCluster.Weight() - emptiestCluster.Weight()
+		balanceWeightGap.Set(weightGap)
+
+		if weightGap < balanceThreshold {
 			break
 		}
 
@@ -208,6 +214,9 @@ func (b *balance) Balance(clusterIds []string) error {
 
 				atomicSizeFunc(emptiestCluster.Id, uint64(cacheFileItem.FileItem.Size), false)
 				atomicSizeFunc(fullestCluster.Id, uint64(cacheFileItem.FileItem.Size), true)
+			} else {
+				balanceMovedChunks.Inc()
+				balanceMovedBytes.Add(float64(cacheFileItem.FileItem.Size))
 			}
 			b.moved(emptiestCluster.Id, emptiestCluster.Master().Id, cacheFileItem.FileItem)
 		}(wg, *emptiestCluster, *fullestCluster, *sourceCacheFileItem)
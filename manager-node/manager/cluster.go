@@ -24,19 +24,23 @@ type Cluster interface {
 	GetClusters() (common.Clusters, error)
 	GetCluster(clusterId string) (*common.Cluster, error)
 
-	Reserve(size uint64) (*common.ReservationMap, error)
+	Reserve(size uint64, blockSize uint32, writeQuorum common.WriteQuorum, chunkSizes []uint32) (*common.ReservationMap, error)
 	Commit(reservationId string, clusterMap map[string]uint64) error
 	Discard(reservationId string) error
 
 	MoveCluster(sourceClusterId string, targetClusterId string) error
 	BalanceClusters(clusterIds []string) error
 	UnFreezeClusters(clusterIds []string) error
+	SetBlockSize(clusterId string, blockSize uint32) error
+	SetWriteQuorum(clusterId string, writeQuorum common.WriteQuorum) error
+	SetReadPreference(clusterId string, readPreference common.ReadPreference) error
 
 	CreateSnapshot(clusterId string) error
 	DeleteSnapshot(clusterId string, snapshotIndex uint64) error
 	RestoreSnapshot(clusterId string, snapshotIndex uint64) error
 
-	Map(sha512HexList []string, mapType common.MapType) (map[string]string, error)
+	Map(sha512HexList []string, mapType common.MapType, readPreference common.ReadPreference) (map[string]string, error)
+	ReplicaMap(sha512HexList []string, readPreference common.ReadPreference) (map[string]common.NodeList, error)
 	Find(sha512Hex string, mapType common.MapType) (string, string, error)
 }
 
@@ -44,6 +48,7 @@ type cluster struct {
 	clusters    data.Clusters
 	index       data.Index
 	synchronize Synchronize
+	reads       *readBalancer
 	logger      *zap.Logger
 }
 
@@ -52,6 +57,7 @@ func NewCluster(clusters data.Clusters, index data.Index, synchronize Synchroniz
 		clusters:    clusters,
 		index:       index,
 		synchronize: synchronize,
+		reads:       newReadBalancer(),
 		logger:      logger,
 	}, nil
 }
@@ -272,23 +278,25 @@ func (c *cluster) GetCluster(clusterId string) (*common.Cluster, error) {
 	return c.clusters.Get(clusterId)
 }
 
-func (c *cluster) Reserve(size uint64) (*common.ReservationMap, error) {
+func (c *cluster) Reserve(size uint64, blockSize uint32, writeQuorum common.WriteQuorum, chunkSizes []uint32) (*common.ReservationMap, error) {
 	var reservationMap *common.ReservationMap
 
 	if err := c.clusters.SaveAll(func(clusters common.Clusters) error {
 		var err error
-		reservationMap, err = c.createReservationMap(size, clusters)
+		reservationMap, err = c.createReservationMap(size, blockSize, writeQuorum, chunkSizes, clusters)
 
 		return err
 	}); err != nil {
+		reservationsTotal.WithLabelValues("reserve", resultLabel(err)).Inc()
 		return nil, err
 	}
+	reservationsTotal.WithLabelValues("reserve", resultLabel(nil)).Inc()
 
 	return reservationMap, nil
 }
 
 func (c *cluster) Commit(reservationId string, clusterMap map[string]uint64) error {
-	return c.clusters.SaveAll(func(clusters common.Clusters) error {
+	err := c.clusters.SaveAll(func(clusters common.Clusters) error {
 		for _, cluster := range clusters {
 			v, has := clusterMap[cluster.Id]
 			if !has {
@@ -298,15 +306,21 @@ func (c *cluster) Commit(reservationId string, clusterMap map[string]uint64) err
 		}
 		return nil
 	})
+	reservationsTotal.WithLabelValues("commit", resultLabel(err)).Inc()
+
+	return err
 }
 
 func (c *cluster) Discard(reservationId string) error {
-	return c.clusters.SaveAll(func(clusters common.Clusters) error {
+	err := c.clusters.SaveAll(func(clusters common.Clusters) error {
 		for _, cluster := range clusters {
 			cluster.Discard(reservationId)
 		}
 		return nil
 	})
+	reservationsTotal.WithLabelValues("discard", resultLabel(err)).Inc()
+
+	return err
 }
 
 func (c *cluster) MoveCluster(sourceClusterId string, targetClusterId string) (e error) {
@@ -427,6 +441,27 @@ func (c *cluster) UnFreezeClusters(clusterIds []string) error {
 	return nil
 }
 
+func (c *cluster) SetBlockSize(clusterId string, blockSize uint32) error {
+	return c.clusters.Save(clusterId, func(cluster *common.Cluster) error {
+		cluster.BlockSize = blockSize
+		return nil
+	})
+}
+
+func (c *cluster) SetWriteQuorum(clusterId string, writeQuorum common.WriteQuorum) error {
+	return c.clusters.Save(clusterId, func(cluster *common.Cluster) error {
+		cluster.WriteQuorum = writeQuorum
+		return nil
+	})
+}
+
+func (c *cluster) SetReadPreference(clusterId string, readPreference common.ReadPreference) error {
+	return c.clusters.Save(clusterId, func(cluster *common.Cluster) error {
+		cluster.ReadPreference = readPreference
+		return nil
+	})
+}
+
 func (c *cluster) CreateSnapshot(clusterId string) error {
 	cluster, err := c.clusters.Get(clusterId)
 	if err != nil {
@@ -484,10 +519,11 @@ func (c *cluster) RestoreSnapshot(clusterId string, snapshotIndex uint64) error
 	return c.synchronize.Cluster(cluster.Id, true, false, false)
 }
 
-func (c *cluster) Map(sha512HexList []string, mapType common.MapType) (map[string]string, error) {
+// Map maps the files to the node addresses. Read preference is used only for the read map
+func (c *cluster) Map(sha512HexList []string, mapType common.MapType, readPreference common.ReadPreference) (map[string]string, error) {
 	clusterMapping := make(map[string]string)
 	for _, sha512Hex := range sha512HexList {
-		_, address, err := c.Find(sha512Hex, mapType)
+		_, address, err := c.find(sha512Hex, mapType, readPreference)
 		if err != nil {
 			if err == os.ErrNotExist && mapType == common.MT_Delete {
 				continue
@@ -499,7 +535,40 @@ func (c *cluster) Map(sha512HexList []string, mapType common.MapType) (map[strin
 	return clusterMapping, nil
 }
 
+// ReplicaMap maps the files to the nodes having them in the order of the read preference
+func (c *cluster) ReplicaMap(sha512HexList []string, readPreference common.ReadPreference) (map[string]common.NodeList, error) {
+	replicaMapping := make(map[string]common.NodeList)
+	for _, sha512Hex := range sha512HexList {
+		cacheFileItem, err := c.index.Get(sha512Hex)
+		if err != nil {
+			return nil, err
+		}
+
+		cluster, err := c.clusters.Get(cacheFileItem.ClusterId)
+		if err != nil {
+			return nil, err
+		}
+
+		if cluster.Paralyzed && !cluster.Frozen {
+			return nil, errors.ErrNoAvailableClusterNode
+		}
+
+		preference := cluster.Preference(readPreference)
+
+		replicas := cluster.Readers(cacheFileItem.ExistsIn, preference)
+		if len(replicas) == 0 {
+			return nil, errors.ErrNoAvailableActionNode
+		}
+		replicaMapping[sha512Hex] = c.reads.Order(replicas, preference)
+	}
+	return replicaMapping, nil
+}
+
 func (c *cluster) Find(sha512Hex string, mapType common.MapType) (string, string, error) {
+	return c.find(sha512Hex, mapType, "")
+}
+
+func (c *cluster) find(sha512Hex string, mapType common.MapType, readPreference common.ReadPreference) (string, string, error) {
 	cacheFileItem, err := c.index.Get(sha512Hex)
 	if err != nil {
 		return "", "", err
@@ -524,7 +593,11 @@ func (c *cluster) Find(sha512Hex string, mapType common.MapType) (string, string
 
 	switch mapType {
 	case common.MT_Read:
-		node = cluster.HighQualityNode(cacheFileItem.ExistsIn)
+		preference := cluster.Preference(readPreference)
+
+		if readers := c.reads.Order(cluster.Readers(cacheFileItem.ExistsIn, preference), preference); len(readers) > 0 {
+			node = readers[0]
+		}
 	default:
 		node = cluster.Master()
 	}
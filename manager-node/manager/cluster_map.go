@@ -8,39 +8,61 @@ import (
 	"github.com/google/uuid"
 )
 
-const blockSize uint32 = 1024 * 1024 * 32 // 32Mb
-
-func (c *cluster) createReservationMap(size uint64, clusters common.Clusters) (*common.ReservationMap, error) {
-	chunks := c.calculateChunks(size)
+func (c *cluster) createReservationMap(size uint64, blockSize uint32, writeQuorum common.WriteQuorum, chunkSizes []uint32, clusters common.Clusters) (*common.ReservationMap, error) {
 	reservationId := uuid.New().String()
 
 	r := make([]common.ClusterMap, 0)
-	for len(chunks) > 0 {
-		chunk := chunks[0]
+	idx := uint64(0)
+	for seq := 0; seq == 0 || idx < size; seq++ {
+		chunkSize := uint32(0)
+		if len(chunkSizes) > 0 {
+			if seq == len(chunkSizes) {
+				break
+			}
+			chunkSize = chunkSizes[seq]
+		}
+
+		sort.Sort(clusters)
 
-		if len(clusters) == 0 {
+		var cluster *common.Cluster
+		for _, candidate := range clusters {
+			if candidate.Paralyzed {
+				continue
+			}
+			cluster = candidate
+			break
+		}
+		if cluster == nil {
 			return nil, errors.ErrNoAvailableClusterNode
 		}
-		sort.Sort(clusters)
-		cluster := clusters[0]
 
-		if cluster.Paralyzed {
-			clusters = clusters[1:]
-			continue
+		// content defined chunk sizes are calculated on the head node, block size of the cluster is for fixed chunks
+		if len(chunkSizes) == 0 {
+			chunkSize = cluster.ChunkSize(blockSize)
+			if size-idx < uint64(chunkSize) {
+				chunkSize = uint32(size - idx)
+			}
 		}
 
-		if cluster.Available() < uint64(chunk.Size) {
+		if cluster.Available() < uint64(chunkSize) {
 			return nil, errors.ErrNoDiskSpace
 		}
 
-		r = append(r, common.ClusterMap{
+		clusterMap := common.ClusterMap{
 			Id:      cluster.Id,
 			Address: cluster.Master().Address,
-			Chunk:   chunk,
-		})
+			Chunk:   common.Chunk{Sequence: uint16(seq), Index: idx, Size: chunkSize},
+		}
+		if quorum := cluster.Quorum(writeQuorum); quorum > 1 {
+			clusterMap.Quorum = quorum
+			for _, slave := range cluster.Slaves() {
+				clusterMap.Replicas = append(clusterMap.Replicas, slave.Address)
+			}
+		}
+		r = append(r, clusterMap)
 
-		cluster.Reserve(reservationId, uint64(chunk.Size))
-		chunks = chunks[1:]
+		cluster.Reserve(reservationId, uint64(chunkSize))
+		idx += uint64(chunkSize)
 	}
 
 	return &common.ReservationMap{
@@ -48,22 +70,3 @@ func (c *cluster) createReservationMap(size uint64, clusters common.Clusters) (*
 		Clusters: r,
 	}, nil
 }
-
-func (c *cluster) calculateChunks(size uint64) []common.Chunk {
-	if size < uint64(blockSize) {
-		return []common.Chunk{{Index: 0, Size: uint32(size)}}
-	}
-
-	chunks := make([]common.Chunk, 0)
-	idx := uint64(0)
-	for seq := uint16(0); idx < size; seq++ {
-		chunkSize := blockSize
-		if (size - idx) < uint64(chunkSize) {
-			chunkSize = uint32(size - idx)
-		}
-		chunks = append(chunks, common.Chunk{Sequence: seq, Index: idx, Size: chunkSize})
-		idx += uint64(chunkSize)
-	}
-
-	return chunks
-}
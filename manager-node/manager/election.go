@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/manager-node/data"
+	"go.uber.org/zap"
+)
+
+const defaultLeaseDuration = time.Second * 15
+
+type Election interface {
+	Start(elected func(), demoted func())
+	Stop()
+	Leader() bool
+	LeaderAddress() string
+}
+
+type election struct {
+	leadership    data.Leadership
+	address       string
+	lease         time.Duration
+	renewInterval time.Duration
+	logger        *zap.Logger
+
+	mutex         sync.RWMutex
+	leader        bool
+	leaderAddress string
+	validUntil    time.Time
+	stopped       bool
+	demoted       func()
+	stop          chan bool
+}
+
+func NewElection(leadership data.Leadership, address string, lease time.Duration, logger *zap.Logger) Election {
+	if lease == 0 {
+		lease = defaultLeaseDuration
+	}
+
+	return &election{
+		leadership:    leadership,
+		address:       address,
+		lease:         lease,
+		renewInterval: lease / 3,
+		logger:        logger,
+		mutex:         sync.RWMutex{},
+		stop:          make(chan bool),
+	}
+}
+
+func (e *election) Start(elected func(), demoted func()) {
+	e.mutex.Lock()
+	e.demoted = demoted
+	e.mutex.Unlock()
+
+	_ = e.campaign(elected, demoted)
+
+	go func() {
+		retry := false
+		for {
+			// failed renew is retried sooner to confirm the lease before the leader has to stop
+			wait := e.renewInterval
+			if retry {
+				wait = e.renewInterval / 4
+			}
+
+			select {
+			case <-e.stop:
+				return
+			case <-time.After(wait):
+			}
+
+			retry = e.campaign(elected, demoted)
+		}
+	}()
+}
+
+// Stop leaves the election, the node is demoted if it is the leader. The lease is not released, it expires
+func (e *election) Stop() {
+	e.mutex.Lock()
+	if e.stopped {
+		e.mutex.Unlock()
+		return
+	}
+	e.stopped = true
+	close(e.stop)
+
+	leader := e.leader
+	e.leader = false
+	demoted := e.demoted
+	e.mutex.Unlock()
+
+	if leader && demoted != nil {
+		demoted()
+	}
+}
+
+// campaign takes or renews the lease. Leader acts as leader until one renew interval before the lease expires, so it
+// stops before another node can take the expired lease. Lease requests are bounded by that time, a renew that is
+// confirmed later is not trusted. It returns true if the leader could not renew the lease
+func (e *election) campaign(elected func(), demoted func()) bool {
+	attempt := time.Now()
+	deadline := attempt.Add(e.lease - e.renewInterval)
+
+	e.mutex.RLock()
+	if e.leader && e.validUntil.Before(deadline) {
+		deadline = e.validUntil
+	}
+	e.mutex.RUnlock()
+
+	ctx, cancelFunc := context.WithDeadline(context.Background(), deadline)
+	leaderAddress, err := e.leadership.Acquire(ctx, e.address, e.lease)
+	cancelFunc()
+
+	if err == nil && !time.Now().Before(deadline) {
+		err = context.DeadlineExceeded
+	}
+	if err != nil {
+		e.logger.Warn("Leadership lease request is failed", zap.Error(err))
+	}
+
+	e.mutex.Lock()
+
+	if e.stopped {
+		e.mutex.Unlock()
+		return false
+	}
+
+	if err == nil {
+		e.leaderAddress = leaderAddress
+	}
+
+	if err == nil && strings.Compare(leaderAddress, e.address) == 0 {
+		e.validUntil = attempt.Add(e.lease - e.renewInterval)
+
+		if !e.leader {
+			e.leader = true
+			e.mutex.Unlock()
+
+			e.logger.Info("Manager node is elected as leader", zap.String("address", e.address))
+			elected()
+			return false
+		}
+		e.mutex.Unlock()
+		return false
+	}
+
+	// the leadership is kept until the lease is close to expire if the store is not accessible
+	if e.leader && (err == nil || !time.Now().Before(e.validUntil)) {
+		e.leader = false
+		e.mutex.Unlock()
+
+		e.logger.Error("Manager node lost the leadership", zap.String("address", e.address))
+		demoted()
+		return false
+	}
+	leader := e.leader
+	e.mutex.Unlock()
+
+	return leader
+}
+
+// Leader returns false as soon as the lease is not renewed in time, even before the campaign demotes the node
+func (e *election) Leader() bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	return e.leader && time.Now().Before(e.validUntil)
+}
+
+func (e *election) LeaderAddress() string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	return e.leaderAddress
+}
+
+var _ Election = &election{}
@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type testLeadership struct {
+	mutex  sync.Mutex
+	holder string
+	delay  time.Duration
+	err    error
+}
+
+func (t *testLeadership) set(holder string, delay time.Duration, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.holder = holder
+	t.delay = delay
+	t.err = err
+}
+
+func (t *testLeadership) Acquire(ctx context.Context, holder string, _ time.Duration) (string, error) {
+	t.mutex.Lock()
+	delay, err := t.delay, t.err
+	t.mutex.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.holder) == 0 {
+		t.holder = holder
+	}
+	return t.holder, nil
+}
+
+func startElection(t *testing.T, leadership *testLeadership, lease time.Duration) (Election, *int32, *int32) {
+	elected := int32(0)
+	demoted := int32(0)
+
+	e := NewElection(leadership, "manager1", lease, zap.NewNop())
+	e.Start(
+		func() { atomic.AddInt32(&elected, 1) },
+		func() { atomic.AddInt32(&demoted, 1) },
+	)
+
+	if !assert.True(t, e.Leader()) {
+		t.FailNow()
+	}
+	return e, &elected, &demoted
+}
+
+func TestElection_LeaseTakenByOther(t *testing.T) {
+	leadership := &testLeadership{}
+	e, elected, demoted := startElection(t, leadership, time.Millisecond*300)
+	assert.Equal(t, int32(1), atomic.LoadInt32(elected))
+
+	leadership.set("manager2", 0, nil)
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(demoted) == 1 }, time.Second, time.Millisecond*10)
+	assert.False(t, e.Leader())
+	assert.Equal(t, "manager2", e.LeaderAddress())
+}
+
+func TestElection_SlowRenew(t *testing.T) {
+	lease := time.Millisecond * 300
+
+	leadership := &testLeadership{}
+	e, _, demoted := startElection(t, leadership, lease)
+
+	// the last confirmed renew is started before now, the lease expires at the latest in one lease duration
+	leadership.set("manager1", time.Second*10, nil)
+	expires := time.Now().Add(lease)
+
+	assert.Eventually(t, func() bool { return !e.Leader() }, lease, time.Millisecond)
+	assert.True(t, time.Now().Before(expires))
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(demoted) == 1 }, time.Second, time.Millisecond*10)
+}
+
+func TestElection_RenewRetry(t *testing.T) {
+	leadership := &testLeadership{}
+	e, elected, demoted := startElection(t, leadership, time.Millisecond*1500)
+
+	leadership.set("manager1", 0, errors.New("store is not accessible"))
+	time.Sleep(time.Millisecond * 300)
+	leadership.set("manager1", 0, nil)
+
+	time.Sleep(time.Millisecond * 1500)
+	assert.True(t, e.Leader())
+	assert.Equal(t, int32(1), atomic.LoadInt32(elected))
+	assert.Equal(t, int32(0), atomic.LoadInt32(demoted))
+}
+
+func TestElection_Stop(t *testing.T) {
+	leadership := &testLeadership{}
+	e, elected, demoted := startElection(t, leadership, time.Millisecond*300)
+
+	e.Stop()
+	assert.False(t, e.Leader())
+	assert.Equal(t, int32(1), atomic.LoadInt32(demoted))
+
+	time.Sleep(time.Millisecond * 300)
+	assert.Equal(t, int32(1), atomic.LoadInt32(elected))
+	assert.Equal(t, int32(1), atomic.LoadInt32(demoted))
+}
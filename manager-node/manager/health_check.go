@@ -19,6 +19,7 @@ type HealthReport map[string]common.NodeList
 
 type HealthCheck interface {
 	Start()
+	Stop()
 	Report() (HealthReport, error)
 }
 
@@ -32,6 +33,9 @@ type healthCheck struct {
 
 	nodeCacheMutex sync.Mutex
 	nodeCache      map[string]cluster2.DataNode
+
+	stopMutex sync.Mutex
+	stop      chan bool
 }
 
 func NewHealthTracker(
@@ -56,6 +60,7 @@ func NewHealthTracker(
 		interval:       interval,
 		nodeCacheMutex: sync.Mutex{},
 		nodeCache:      make(map[string]cluster2.DataNode),
+		stopMutex:      sync.Mutex{},
 	}
 }
 
@@ -77,8 +82,28 @@ func (h *healthCheck) getDataNode(node *common.Node) (cluster2.DataNode, error)
 }
 
 func (h *healthCheck) Start() {
-	go h.maintain()
-	go h.health()
+	h.stopMutex.Lock()
+	defer h.stopMutex.Unlock()
+
+	if h.stop != nil {
+		return
+	}
+	h.stop = make(chan bool)
+
+	go h.maintain(h.stop)
+	go h.health(h.stop)
+}
+
+// Stop stops the background checks, the running check is completed
+func (h *healthCheck) Stop() {
+	h.stopMutex.Lock()
+	defer h.stopMutex.Unlock()
+
+	if h.stop == nil {
+		return
+	}
+	close(h.stop)
+	h.stop = nil
 }
 
 func (h *healthCheck) Report() (HealthReport, error) {
@@ -109,9 +134,13 @@ func (h *healthCheck) Report() (HealthReport, error) {
 	return report, nil
 }
 
-func (h *healthCheck) maintain() {
+func (h *healthCheck) maintain(stop chan bool) {
 	for {
-		time.Sleep(maintainInterval)
+		select {
+		case <-stop:
+			return
+		case <-time.After(maintainInterval):
+		}
 
 		h.logger.Info("Maintaining Clusters...")
 		// Fire Forget
@@ -140,9 +169,13 @@ func (h *healthCheck) maintain() {
 	}
 }
 
-func (h *healthCheck) health() {
+func (h *healthCheck) health(stop chan bool) {
 	for {
-		time.Sleep(h.interval)
+		select {
+		case <-stop:
+			return
+		case <-time.After(h.interval):
+		}
 
 		clusters, err := h.clusters.GetAll()
 		if err != nil {
@@ -0,0 +1,257 @@
+package manager
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/audit"
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	cluster2 "github.com/freakmaxi/kertish-dfs/manager-node/cluster"
+	"github.com/freakmaxi/kertish-dfs/manager-node/data"
+	"go.uber.org/zap"
+)
+
+const parallelLifecycle = 5
+
+type Lifecycle interface {
+	Start()
+	Stop()
+
+	Get(folderPath string) (*common.Lifecycle, error)
+	Set(folderPath string, lifecycle *common.Lifecycle) error
+	Apply() error
+}
+
+type lifecycle struct {
+	metadata data.Metadata
+	cluster  Cluster
+	auditor  audit.Auditor
+	logger   *zap.Logger
+	interval time.Duration
+
+	applyMutex sync.Mutex
+
+	nodeCacheMutex sync.Mutex
+	nodeCache      map[string]cluster2.DataNode
+
+	stopMutex sync.Mutex
+	stop      chan bool
+}
+
+// NewLifecycle creates the lifecycle job, zero interval disables the periodic apply. Expired files are deleted with
+// the change feed events and the audit entries in the same way as the deletions of the head node
+func NewLifecycle(metadata data.Metadata, cluster Cluster, auditor audit.Auditor, logger *zap.Logger, interval time.Duration) Lifecycle {
+	return &lifecycle{
+		metadata:       metadata,
+		cluster:        cluster,
+		auditor:        auditor,
+		logger:         logger,
+		interval:       interval,
+		applyMutex:     sync.Mutex{},
+		nodeCacheMutex: sync.Mutex{},
+		nodeCache:      make(map[string]cluster2.DataNode),
+		stopMutex:      sync.Mutex{},
+	}
+}
+
+func (l *lifecycle) getDataNode(address string) (cluster2.DataNode, error) {
+	l.nodeCacheMutex.Lock()
+	defer l.nodeCacheMutex.Unlock()
+
+	dn, has := l.nodeCache[address]
+	if !has {
+		var err error
+		dn, err = cluster2.NewDataNode(address)
+		if err != nil {
+			return nil, err
+		}
+		l.nodeCache[address] = dn
+	}
+
+	return dn, nil
+}
+
+func (l *lifecycle) Start() {
+	if l.interval == 0 {
+		return
+	}
+
+	l.stopMutex.Lock()
+	defer l.stopMutex.Unlock()
+
+	if l.stop != nil {
+		return
+	}
+	l.stop = make(chan bool)
+
+	go func(stop chan bool) {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(l.interval):
+			}
+
+			if err := l.Apply(); err != nil {
+				if err == errors.ErrProcessing {
+					continue
+				}
+				l.logger.Error("Applying lifecycle rules is failed", zap.Error(err))
+			}
+		}
+	}(l.stop)
+}
+
+// Stop stops the periodic apply, the running apply is completed
+func (l *lifecycle) Stop() {
+	l.stopMutex.Lock()
+	defer l.stopMutex.Unlock()
+
+	if l.stop == nil {
+		return
+	}
+	close(l.stop)
+	l.stop = nil
+}
+
+func (l *lifecycle) Get(folderPath string) (*common.Lifecycle, error) {
+	var folderLifecycle *common.Lifecycle
+
+	if err := l.metadata.LockFolder(common.CorrectPath(folderPath), func(folder *common.Folder) (bool, error) {
+		folderLifecycle = folder.Lifecycle
+		return false, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if folderLifecycle == nil {
+		return nil, os.ErrNotExist
+	}
+	return folderLifecycle, nil
+}
+
+func (l *lifecycle) Set(folderPath string, lifecycle *common.Lifecycle) error {
+	if lifecycle != nil && lifecycle.Empty() {
+		lifecycle = nil
+	}
+
+	return l.metadata.LockFolder(common.CorrectPath(folderPath), func(folder *common.Folder) (bool, error) {
+		folder.Lifecycle = lifecycle
+		return true, nil
+	})
+}
+
+func (l *lifecycle) Apply() error {
+	l.applyMutex.Lock()
+	defer l.applyMutex.Unlock()
+
+	l.logger.Info("Applying lifecycle rules...")
+
+	now := time.Now().UTC()
+	if err := l.metadata.Cursor(func(folder *common.Folder) (bool, common.Events, error) {
+		if folder.Lifecycle == nil || folder.Lifecycle.Empty() {
+			return false, nil, nil
+		}
+
+		expiredFiles := folder.Lifecycle.Expired(folder.Files, now)
+		if len(expiredFiles) == 0 {
+			return false, nil, nil
+		}
+
+		changed := false
+		changeEvents := make(common.Events, 0)
+		for _, file := range expiredFiles {
+			filePath := common.Join(folder.Full, file.Name)
+			auditEntry := common.NewAuditEntry("lifecycleDelete", []string{filePath}, folder.Lifecycle.String(), "lifecycle", "")
+
+			if err := folder.DeleteFile(file.Name, l.deleteFileChunks); err != nil {
+				auditEntry.Complete(500)
+				l.auditor.Record(auditEntry)
+
+				if err == errors.ErrZombie {
+					// file content is partially deleted, it is kept as zombie
+					changed = true
+					continue
+				}
+				l.logger.Warn(
+					"Lifecycle file deletion is failed",
+					zap.String("path", filePath),
+					zap.Error(err),
+				)
+				continue
+			}
+			changed = true
+
+			auditEntry.Complete(200)
+			l.auditor.Record(auditEntry)
+			changeEvents = append(changeEvents, common.NewEvent(common.ET_Delete, false, []string{filePath}, ""))
+
+			l.logger.Info(
+				"File is expired by lifecycle rule",
+				zap.String("path", filePath),
+				zap.String("lifecycle", folder.Lifecycle.String()),
+			)
+		}
+
+		return changed, changeEvents, nil
+	}, parallelLifecycle); err != nil {
+		return err
+	}
+
+	l.logger.Info("Lifecycle rules are applied")
+
+	return nil
+}
+
+func (l *lifecycle) deleteFileChunks(file *common.File) error {
+	if len(file.Chunks) == 0 {
+		file.Zombie = true
+		if file.CanDie() {
+			return nil
+		}
+		return errors.ErrZombie
+	}
+
+	sha512HexList := make([]string, 0)
+	for _, chunk := range file.Chunks {
+		sha512HexList = append(sha512HexList, chunk.Hash)
+	}
+
+	clusterMapping, err := l.cluster.Map(sha512HexList, common.MT_Delete, "")
+	if err != nil {
+		return err
+	}
+
+	deletionResult := common.NewDeletionResult()
+	for _, sha512Hex := range sha512HexList {
+		address, has := clusterMapping[sha512Hex]
+		if !has {
+			deletionResult.Missing = append(deletionResult.Missing, sha512Hex)
+			continue
+		}
+
+		dn, err := l.getDataNode(address)
+		if err != nil {
+			deletionResult.Untouched = append(deletionResult.Untouched, sha512Hex)
+			continue
+		}
+
+		if err := dn.Delete(sha512Hex); err != nil {
+			deletionResult.Untouched = append(deletionResult.Untouched, sha512Hex)
+			continue
+		}
+
+		deletionResult.Deleted = append(deletionResult.Deleted, sha512Hex)
+	}
+
+	file.IngestDeletion(deletionResult)
+
+	if file.Zombie && !file.CanDie() {
+		return errors.ErrZombie
+	}
+	return nil
+}
+
+var _ Lifecycle = &lifecycle{}
@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
+	"github.com/freakmaxi/kertish-dfs/manager-node/data"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type testAuditor struct {
+	mutex   sync.Mutex
+	entries common.AuditEntries
+}
+
+func (t *testAuditor) Record(entry *common.AuditEntry) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.entries = append(t.entries, entry)
+}
+
+func (t *testAuditor) List(_ string, _ time.Time, _ time.Time, _ int64) (common.AuditEntries, error) {
+	return t.entries, nil
+}
+
+func TestLifecycle_ApplyRecords(t *testing.T) {
+	folder, err := ioutil.TempDir("", "kertish-manager")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(folder) })
+
+	store, err := embedded.NewStore(path.Join(folder, "kertish.db"))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	eventStore, err := events.NewStoreEmbedded(store, 0)
+	assert.Nil(t, err)
+	metadata, err := data.NewMetadataEmbedded(store, embedded.NewLockingCenter(), eventStore)
+	assert.Nil(t, err)
+
+	root := common.NewFolder("/")
+	root.Lifecycle = common.NewLifecycle(0, 0, true)
+
+	zombie, err := root.NewFile("zombie")
+	assert.Nil(t, err)
+	zombie.Lock.Cancel()
+
+	kept, err := root.NewFile("kept")
+	assert.Nil(t, err)
+	kept.Lock.Cancel()
+	kept.Zombie = false
+
+	assert.Nil(t, metadata.LockTree(func(_ []*common.Folder) ([]*common.Folder, error) {
+		return []*common.Folder{root}, nil
+	}))
+
+	auditor := &testAuditor{}
+	assert.Nil(t, NewLifecycle(metadata, nil, auditor, zap.NewNop(), 0).Apply())
+
+	list, err := eventStore.List(0, 10, nil)
+	assert.Nil(t, err)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, common.ET_Delete, list[0].Type)
+		assert.Equal(t, []string{"/zombie"}, list[0].Sources)
+	}
+
+	if assert.Len(t, auditor.entries, 1) {
+		assert.Equal(t, "lifecycleDelete", auditor.entries[0].Operation)
+		assert.Equal(t, []string{"/zombie"}, auditor.entries[0].Paths)
+		assert.Equal(t, 200, auditor.entries[0].Result)
+	}
+}
@@ -0,0 +1,163 @@
+package manager
+
+import (
+	"strconv"
+
+	"github.com/freakmaxi/kertish-dfs/manager-node/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const metricsNamespace = "kertish"
+const metricsSubsystem = "manager"
+
+var (
+	syncQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "sync_queue_depth",
+		Help:      "Number of node sync requests waiting to be processed",
+	})
+	reservationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "reservations_total",
+		Help:      "Number of reservation operations by action and result",
+	}, []string{"action", "result"})
+	repairRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "repair_running",
+		Help:      "Indicates if a consistency repair is running on this manager",
+	})
+	repairsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "repairs_total",
+		Help:      "Number of completed consistency repairs by type and result",
+	}, []string{"type", "result"})
+	balanceRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "balance_running",
+		Help:      "Number of cluster balance operations running on this manager",
+	})
+	balanceWeightGap = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "balance_weight_gap",
+		Help:      "Weight difference between the fullest and the emptiest cluster of the running balance operation",
+	})
+	balanceMovedChunks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "balance_moved_chunks_total",
+		Help:      "Number of chunks moved between clusters by balance operations",
+	})
+	balanceMovedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "balance_moved_bytes_total",
+		Help:      "Size of chunks moved between clusters by balance operations",
+	})
+)
+
+func resultLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+type clusterCollector struct {
+	clusters data.Clusters
+	logger   *zap.Logger
+
+	size         *prometheus.Desc
+	used         *prometheus.Desc
+	reservations *prometheus.Desc
+	reserved     *prometheus.Desc
+	frozen       *prometheus.Desc
+	paralyzed    *prometheus.Desc
+	nodeQuality  *prometheus.Desc
+}
+
+// NewClusterCollector creates the collector that reports the cluster state on every scrape
+func NewClusterCollector(clusters data.Clusters, logger *zap.Logger) prometheus.Collector {
+	clusterLabels := []string{"clusterId"}
+
+	return &clusterCollector{
+		clusters: clusters,
+		logger:   logger,
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "cluster", "size_bytes"),
+			"Total size of the cluster", clusterLabels, nil),
+		used: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "cluster", "used_bytes"),
+			"Used size of the cluster", clusterLabels, nil),
+		reservations: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "cluster", "reservations"),
+			"Number of active reservations on the cluster", clusterLabels, nil),
+		reserved: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "cluster", "reserved_bytes"),
+			"Size reserved on the cluster by active reservations", clusterLabels, nil),
+		frozen: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "cluster", "frozen"),
+			"Indicates if the cluster is frozen", clusterLabels, nil),
+		paralyzed: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "cluster", "paralyzed"),
+			"Indicates if the cluster is paralyzed", clusterLabels, nil),
+		nodeQuality: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "node", "quality"),
+			"Response quality of the data node, lower is better", []string{"clusterId", "nodeId", "address", "master"}, nil),
+	}
+}
+
+func (c *clusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.used
+	ch <- c.reservations
+	ch <- c.reserved
+	ch <- c.frozen
+	ch <- c.paralyzed
+	ch <- c.nodeQuality
+}
+
+func (c *clusterCollector) Collect(ch chan<- prometheus.Metric) {
+	clusters, err := c.clusters.GetAll()
+	if err != nil {
+		c.logger.Error("Unable to collect cluster metrics", zap.Error(err))
+		return
+	}
+
+	for _, cluster := range clusters {
+		reserved := uint64(0)
+		for _, size := range cluster.Reservations {
+			reserved += size
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(cluster.Size), cluster.Id)
+		ch <- prometheus.MustNewConstMetric(c.used, prometheus.GaugeValue, float64(cluster.Used), cluster.Id)
+		ch <- prometheus.MustNewConstMetric(c.reservations, prometheus.GaugeValue, float64(len(cluster.Reservations)), cluster.Id)
+		ch <- prometheus.MustNewConstMetric(c.reserved, prometheus.GaugeValue, float64(reserved), cluster.Id)
+		ch <- prometheus.MustNewConstMetric(c.frozen, prometheus.GaugeValue, boolValue(cluster.Frozen), cluster.Id)
+		ch <- prometheus.MustNewConstMetric(c.paralyzed, prometheus.GaugeValue, boolValue(cluster.Paralyzed), cluster.Id)
+
+		for _, node := range cluster.Nodes {
+			ch <- prometheus.MustNewConstMetric(
+				c.nodeQuality, prometheus.GaugeValue, float64(node.Quality),
+				cluster.Id, node.Id, node.Address, strconv.FormatBool(node.Master),
+			)
+		}
+	}
+}
+
+func boolValue(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+var _ prometheus.Collector = &clusterCollector{}
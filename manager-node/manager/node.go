@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
+	cluster2 "github.com/freakmaxi/kertish-dfs/manager-node/cluster"
 	"github.com/freakmaxi/kertish-dfs/manager-node/data"
 	"go.uber.org/zap"
 )
@@ -14,6 +16,10 @@ const retryLimit = 10
 type Node interface {
 	Handshake(nodeHardwareAddr string, nodeAddress string, size uint64) (string, string, string, error)
 	Notify(nodeId string, notificationContainerList common.NotificationContainerList) error
+	Missing(nodeId string, sha512HexList []string) error
+	Repair(nodeId string) error
+	Resume() error
+	Stop()
 }
 
 type node struct {
@@ -30,6 +36,7 @@ type targetContainer struct {
 }
 
 type nodeSync struct {
+	id     string
 	create bool
 	date   time.Time
 
@@ -39,11 +46,11 @@ type nodeSync struct {
 	targets    []*targetContainer
 }
 
-func NewNode(clusters data.Clusters, index data.Index, logger *zap.Logger) Node {
+func NewNode(clusters data.Clusters, index data.Index, syncQueue data.SyncQueue, logger *zap.Logger) Node {
 	return &node{
 		index:           index,
 		clusters:        clusters,
-		nodeSyncManager: newNodeSyncManager(clusters, index, logger),
+		nodeSyncManager: newNodeSyncManager(clusters, index, syncQueue, logger),
 	}
 }
 
@@ -170,7 +177,9 @@ func (n *node) create(nodeId string, fileItemList common.SyncFileItemList) error
 		return fmt.Errorf("adding to index failed. clusterId: %s, error: %s", clusterId, err)
 	}
 
-	n.nodeSyncManager.QueueMany(nodeSyncItems)
+	if err := n.nodeSyncManager.QueueMany(nodeSyncItems); err != nil {
+		return fmt.Errorf("adding to sync queue failed. clusterId: %s, error: %s", clusterId, err)
+	}
 
 	return nil
 }
@@ -201,20 +210,131 @@ func (n *node) delete(nodeId string, fileItemList common.SyncFileItemList) error
 			return nil // nothing to sync
 		}
 
+		nodeSyncItems := make([]*nodeSync, 0)
 		for _, fileItem := range fileItemList {
-			n.nodeSyncManager.QueueOne(
-				&nodeSync{
-					create:     false,
-					date:       time.Now().UTC(),
-					clusterId:  cluster.Id,
-					sourceAddr: sourceNode.Address,
-					sha512Hex:  fileItem.Sha512Hex,
-					targets:    n.makeTargetContainerList(targetNodes),
-				})
+			nodeSyncItems = append(nodeSyncItems, &nodeSync{
+				create:     false,
+				date:       time.Now().UTC(),
+				clusterId:  cluster.Id,
+				sourceAddr: sourceNode.Address,
+				sha512Hex:  fileItem.Sha512Hex,
+				targets:    n.makeTargetContainerList(targetNodes),
+			})
+		}
+
+		if err := n.nodeSyncManager.QueueMany(nodeSyncItems); err != nil {
+			return fmt.Errorf("adding to sync queue failed. clusterId: %s, error: %s", cluster.Id, err)
 		}
 
 		return nil
 	})
 }
 
+// Missing marks the blocks as not existing on the node and syncs them again from the other node of the cluster
+func (n *node) Missing(nodeId string, sha512HexList []string) error {
+	clusterId, err := n.clusters.ClusterIdOf(nodeId)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := n.clusters.Get(clusterId)
+	if err != nil {
+		return fmt.Errorf("getting cluster is failed. clusterId: %s, error: %s", clusterId, err)
+	}
+
+	targetNode := cluster.Node(nodeId)
+	sourceNodes := cluster.Others(nodeId)
+	if sourceNodes == nil {
+		return fmt.Errorf("node id didn't match to get others: %s", nodeId)
+	}
+
+	if err := n.index.UpdateChunkNodeBulk(sha512HexList, nodeId, false); err != nil {
+		return fmt.Errorf("updating index failed. clusterId: %s, error: %s", clusterId, err)
+	}
+
+	if len(sourceNodes) == 0 {
+		return errors.ErrNoAvailableClusterNode
+	}
+
+	sourceNode := sourceNodes[0]
+	for _, node := range sourceNodes {
+		if node.Master {
+			sourceNode = node
+			break
+		}
+	}
+
+	nodeSyncItems := make([]*nodeSync, 0)
+	for _, sha512Hex := range sha512HexList {
+		nodeSyncItems = append(nodeSyncItems, &nodeSync{
+			create:     true,
+			date:       time.Now().UTC(),
+			clusterId:  cluster.Id,
+			sourceAddr: sourceNode.Address,
+			sha512Hex:  sha512Hex,
+			targets:    n.makeTargetContainerList(common.NodeList{targetNode}),
+		})
+	}
+
+	if err := n.nodeSyncManager.QueueMany(nodeSyncItems); err != nil {
+		return fmt.Errorf("adding to sync queue failed. clusterId: %s, error: %s", clusterId, err)
+	}
+
+	return nil
+}
+
+// Repair finds the blocks of the cluster index that the node does not have anymore and syncs them again as missing
+// blocks. It is used when the data node can not list the blocks that it lost
+func (n *node) Repair(nodeId string) error {
+	clusterId, err := n.clusters.ClusterIdOf(nodeId)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := n.clusters.Get(clusterId)
+	if err != nil {
+		return fmt.Errorf("getting cluster is failed. clusterId: %s, error: %s", clusterId, err)
+	}
+
+	targetNode := cluster.Node(nodeId)
+	if targetNode == nil {
+		return errors.ErrNotFound
+	}
+
+	dn, err := cluster2.NewDataNode(targetNode.Address)
+	if err != nil {
+		return err
+	}
+
+	container, err := dn.SyncList(nil)
+	if err != nil {
+		return fmt.Errorf("getting sync list is failed. nodeId: %s, error: %s", nodeId, err)
+	}
+
+	indexMap, err := n.index.PullMap(clusterId)
+	if err != nil {
+		return fmt.Errorf("pulling index map is failed. clusterId: %s, error: %s", clusterId, err)
+	}
+
+	sha512HexList := make([]string, 0)
+	for sha512Hex := range indexMap {
+		if _, has := container.FileItems[sha512Hex]; !has {
+			sha512HexList = append(sha512HexList, sha512Hex)
+		}
+	}
+
+	if len(sha512HexList) == 0 {
+		return nil
+	}
+	return n.Missing(nodeId, sha512HexList)
+}
+
+func (n *node) Resume() error {
+	return n.nodeSyncManager.Resume(n.makeTargetContainerList)
+}
+
+func (n *node) Stop() {
+	n.nodeSyncManager.Stop()
+}
+
 var _ Node = &node{}
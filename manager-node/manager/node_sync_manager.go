@@ -3,6 +3,8 @@ package manager
 import (
 	"sync"
 
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/errors"
 	"github.com/freakmaxi/kertish-dfs/manager-node/data"
 	"go.uber.org/zap"
 )
@@ -11,33 +13,63 @@ type nodeSyncManager struct {
 	workersMutex sync.Mutex
 	workers      map[string]*nodeSyncWorker
 
-	clusters data.Clusters
-	index    data.Index
-	logger   *zap.Logger
+	// queuedMutex and queued keep the ids of the node syncs that are in the memory, so resume does not queue them again
+	queuedMutex sync.Mutex
+	queued      map[string]bool
+	dispatch    func(nss []*nodeSync)
+
+	clusters  data.Clusters
+	index     data.Index
+	syncQueue data.SyncQueue
+	logger    *zap.Logger
 }
 
-func newNodeSyncManager(clusters data.Clusters, index data.Index, logger *zap.Logger) *nodeSyncManager {
-	return &nodeSyncManager{
+func newNodeSyncManager(clusters data.Clusters, index data.Index, syncQueue data.SyncQueue, logger *zap.Logger) *nodeSyncManager {
+	s := &nodeSyncManager{
 		workersMutex: sync.Mutex{},
 		workers:      make(map[string]*nodeSyncWorker),
+		queuedMutex:  sync.Mutex{},
+		queued:       make(map[string]bool),
 		clusters:     clusters,
 		index:        index,
+		syncQueue:    syncQueue,
 		logger:       logger,
 	}
+	s.dispatch = s.queue
+
+	return s
 }
 
-func (s *nodeSyncManager) QueueMany(nss []*nodeSync) {
-	if len(nss) == 0 {
-		return
+// reserve marks the node sync as queued in the memory, it returns false if it is already queued
+func (s *nodeSyncManager) reserve(id string) bool {
+	s.queuedMutex.Lock()
+	defer s.queuedMutex.Unlock()
+
+	if s.queued[id] {
+		return false
 	}
+	s.queued[id] = true
+	syncQueueDepth.Set(float64(len(s.queued)))
+
+	return true
+}
 
+func (s *nodeSyncManager) release(id string) {
+	s.queuedMutex.Lock()
+	defer s.queuedMutex.Unlock()
+
+	delete(s.queued, id)
+	syncQueueDepth.Set(float64(len(s.queued)))
+}
+
+func (s *nodeSyncManager) queue(nss []*nodeSync) {
 	s.workersMutex.Lock()
 	defer s.workersMutex.Unlock()
 
 	for _, ns := range nss {
 		cc, has := s.workers[ns.clusterId]
 		if !has {
-			cc = newNodeSyncWorker(s.clusters, s.index, s.logger)
+			cc = newNodeSyncWorker(s.clusters, s.index, s.syncQueue, s.release, s.logger)
 			s.workers[ns.clusterId] = cc
 			go cc.Start()
 		}
@@ -46,16 +78,114 @@ func (s *nodeSyncManager) QueueMany(nss []*nodeSync) {
 	}
 }
 
-func (s *nodeSyncManager) QueueOne(ns *nodeSync) {
-	s.workersMutex.Lock()
-	defer s.workersMutex.Unlock()
+// QueueMany persists the node syncs before queueing them, so they survive the leader change
+func (s *nodeSyncManager) QueueMany(nss []*nodeSync) error {
+	if len(nss) == 0 {
+		return nil
+	}
+
+	items := make(common.SyncQueueItems, 0)
+	for _, ns := range nss {
+		targetNodeIds := make([]string, 0)
+		for _, target := range ns.targets {
+			targetNodeIds = append(targetNodeIds, target.node.Id)
+		}
+
+		item := common.NewSyncQueueItem(ns.create, ns.clusterId, ns.sourceAddr, ns.sha512Hex, targetNodeIds)
+		ns.id = item.Id
+
+		// reserved before it is persisted, so the resume running at the same time skips it
+		s.reserve(item.Id)
+		items = append(items, item)
+	}
+
+	if err := s.syncQueue.Add(items); err != nil {
+		for _, item := range items {
+			s.release(item.Id)
+		}
+		return err
+	}
 
-	cc, has := s.workers[ns.clusterId]
-	if !has {
-		cc = newNodeSyncWorker(s.clusters, s.index, s.logger)
-		s.workers[ns.clusterId] = cc
-		go cc.Start()
+	s.dispatch(nss)
+
+	return nil
+}
+
+// Resume queues the node syncs that are left unprocessed by the previous leader. Node syncs that are already queued in
+// the memory are skipped
+func (s *nodeSyncManager) Resume(targetsHandler func(nodes common.NodeList) []*targetContainer) error {
+	items, err := s.syncQueue.List()
+	if err != nil {
+		return err
+	}
+
+	clusters := make(map[string]*common.Cluster)
+	nss := make([]*nodeSync, 0)
+
+	for _, item := range items {
+		if !s.reserve(item.Id) {
+			continue
+		}
+
+		cluster, has := clusters[item.ClusterId]
+		if !has {
+			cluster, err = s.clusters.Get(item.ClusterId)
+			if err != nil && err != errors.ErrNotFound {
+				s.release(item.Id)
+				return err
+			}
+			clusters[item.ClusterId] = cluster
+		}
+
+		targetNodes := make(common.NodeList, 0)
+		if cluster != nil {
+			for _, nodeId := range item.Targets {
+				if node := cluster.Node(nodeId); node != nil {
+					targetNodes = append(targetNodes, node)
+				}
+			}
+		}
+
+		if len(targetNodes) == 0 {
+			err := s.syncQueue.Remove(item.Id)
+			s.release(item.Id)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		nss = append(nss, &nodeSync{
+			id:         item.Id,
+			create:     item.Create,
+			date:       item.Date,
+			clusterId:  item.ClusterId,
+			sourceAddr: item.SourceAddr,
+			sha512Hex:  item.Sha512Hex,
+			targets:    targetsHandler(targetNodes),
+		})
+	}
+
+	if len(nss) > 0 {
+		s.logger.Info("Resuming node syncs left in the queue", zap.Int("count", len(nss)))
+	}
+	s.dispatch(nss)
+
+	return nil
+}
+
+// Stop stops the workers when the leadership is lost. Node syncs that are not completed stay in the persisted queue
+// and they are resumed by the next leader
+func (s *nodeSyncManager) Stop() {
+	s.workersMutex.Lock()
+	for _, cc := range s.workers {
+		cc.Stop()
 	}
+	s.workers = make(map[string]*nodeSyncWorker)
+	s.workersMutex.Unlock()
 
-	cc.Queue(ns)
+	s.queuedMutex.Lock()
+	s.queued = make(map[string]bool)
+	syncQueueDepth.Set(0)
+	s.queuedMutex.Unlock()
 }
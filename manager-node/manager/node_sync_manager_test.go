@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/freakmaxi/kertish-dfs/manager-node/data"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newTestNodeSyncManager(t *testing.T) (*nodeSyncManager, *common.Cluster, *[]string) {
+	folder, err := ioutil.TempDir("", "kertish-manager")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(folder) })
+
+	store, err := embedded.NewStore(path.Join(folder, "kertish.db"))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	clusters, err := data.NewClustersEmbedded(store, embedded.NewLockingCenter())
+	assert.Nil(t, err)
+	syncQueue, err := data.NewSyncQueueEmbedded(store)
+	assert.Nil(t, err)
+
+	cluster := common.NewCluster("cluster1")
+	cluster.Nodes = append(cluster.Nodes,
+		&common.Node{Id: "node1", Address: "127.0.0.1:1", Master: true},
+		&common.Node{Id: "node2", Address: "127.0.0.1:2"},
+	)
+	assert.Nil(t, clusters.RegisterCluster(cluster))
+
+	s := newNodeSyncManager(clusters, nil, syncQueue, zap.NewNop())
+
+	dispatchedMutex := sync.Mutex{}
+	dispatched := make([]string, 0)
+	s.dispatch = func(nss []*nodeSync) {
+		dispatchedMutex.Lock()
+		defer dispatchedMutex.Unlock()
+
+		for _, ns := range nss {
+			dispatched = append(dispatched, ns.id)
+		}
+	}
+
+	return s, cluster, &dispatched
+}
+
+func testNodeSync(cluster *common.Cluster, sha512Hex string) *nodeSync {
+	return &nodeSync{
+		create:     true,
+		clusterId:  cluster.Id,
+		sourceAddr: cluster.Nodes[0].Address,
+		sha512Hex:  sha512Hex,
+		targets:    []*targetContainer{{node: cluster.Nodes[1]}},
+	}
+}
+
+func TestNodeSyncManager_Resume(t *testing.T) {
+	s, cluster, dispatched := newTestNodeSyncManager(t)
+	targets := func(nodes common.NodeList) []*targetContainer {
+		return []*targetContainer{{node: nodes[0]}}
+	}
+
+	// node syncs of the current leader are not queued again by the resume
+	assert.Nil(t, s.QueueMany([]*nodeSync{testNodeSync(cluster, "sha1"), testNodeSync(cluster, "sha2")}))
+	assert.Len(t, *dispatched, 2)
+
+	assert.Nil(t, s.Resume(targets))
+	assert.Len(t, *dispatched, 2)
+
+	// node syncs left by the previous leader are resumed once
+	left := common.NewSyncQueueItem(true, cluster.Id, cluster.Nodes[0].Address, "sha3", []string{"node2"})
+	orphan := common.NewSyncQueueItem(true, "missing", cluster.Nodes[0].Address, "sha4", []string{"node2"})
+	assert.Nil(t, s.syncQueue.Add(common.SyncQueueItems{left, orphan}))
+
+	assert.Nil(t, s.Resume(targets))
+	assert.Nil(t, s.Resume(targets))
+	assert.Len(t, *dispatched, 3)
+	assert.Equal(t, left.Id, (*dispatched)[2])
+
+	items, err := s.syncQueue.List()
+	assert.Nil(t, err)
+	assert.Len(t, items, 3)
+
+	// processed node syncs can be resumed again only after they are released
+	s.release(left.Id)
+	assert.Nil(t, s.Resume(targets))
+	assert.Len(t, *dispatched, 4)
+
+	// stopped manager leaves the node syncs in the queue and resumes them on the next leadership
+	s.Stop()
+	assert.Nil(t, s.Resume(targets))
+	assert.Len(t, *dispatched, 7)
+}
@@ -15,8 +15,13 @@ const pauseDuration = time.Second * 30
 type nodeSyncWorker struct {
 	queueChan     chan string
 	semaphoreChan chan bool
+	stopChan      chan bool
+	stopOnce      sync.Once
+	done          func(id string)
 
 	processor *nodeSyncProcessor
+	syncQueue data.SyncQueue
+	logger    *zap.Logger
 
 	queueMapMutex sync.Mutex
 	queueMap      map[string]chan nodeSync
@@ -25,11 +30,15 @@ type nodeSyncWorker struct {
 	processingChanMap  map[string]chan bool
 }
 
-func newNodeSyncWorker(clusters data.Clusters, index data.Index, logger *zap.Logger) *nodeSyncWorker {
+func newNodeSyncWorker(clusters data.Clusters, index data.Index, syncQueue data.SyncQueue, done func(id string), logger *zap.Logger) *nodeSyncWorker {
 	return &nodeSyncWorker{
 		queueChan:     make(chan string, queueLimit),
 		semaphoreChan: make(chan bool, parallelLimit),
+		stopChan:      make(chan bool),
+		done:          done,
 		processor:     newNodeSyncProcessor(clusters, index, logger),
+		syncQueue:     syncQueue,
+		logger:        logger,
 
 		queueMapMutex: sync.Mutex{},
 		queueMap:      make(map[string]chan nodeSync),
@@ -43,14 +52,23 @@ func (c *nodeSyncWorker) placeAndQueue(ns *nodeSync) {
 	c.queueMapMutex.Lock()
 	defer c.queueMapMutex.Unlock()
 
+	// stopped worker leaves the node sync in the persisted queue for the next leader
 	ch, has := c.queueMap[ns.sha512Hex]
 	if !has {
-		c.queueChan <- ns.sha512Hex
+		select {
+		case c.queueChan <- ns.sha512Hex:
+		case <-c.stopChan:
+			return
+		}
 
 		ch = make(chan nodeSync, queueLimit)
 		c.queueMap[ns.sha512Hex] = ch
 	}
-	ch <- *ns
+
+	select {
+	case ch <- *ns:
+	case <-c.stopChan:
+	}
 }
 
 func (c *nodeSyncWorker) displaceFromMap(sha512Hex string) <-chan nodeSync {
@@ -86,9 +104,35 @@ func (c *nodeSyncWorker) process(sha512Hex string, nsCh <-chan nodeSync) {
 	}()
 
 	for ns := range nsCh {
+		if c.stopped() {
+			return
+		}
+
 		for !c.processor.Sync(&ns) {
-			time.Sleep(pauseDuration)
+			select {
+			case <-c.stopChan:
+				return
+			case <-time.After(pauseDuration):
+			}
+		}
+
+		if err := c.syncQueue.Remove(ns.id); err != nil {
+			c.logger.Warn(
+				"Removing node sync from the queue is failed",
+				zap.String("sha512Hex", ns.sha512Hex),
+				zap.Error(err),
+			)
 		}
+		c.done(ns.id)
+	}
+}
+
+func (c *nodeSyncWorker) stopped() bool {
+	select {
+	case <-c.stopChan:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -97,14 +141,29 @@ func (c *nodeSyncWorker) Start() {
 		c.semaphoreChan <- true
 	}
 
-	for sha512Hex := range c.queueChan {
+	for {
+		var sha512Hex string
+		select {
+		case <-c.stopChan:
+			return
+		case sha512Hex = <-c.queueChan:
+		}
 		nsCh := c.displaceFromMap(sha512Hex)
 
-		<-c.semaphoreChan
+		select {
+		case <-c.stopChan:
+			return
+		case <-c.semaphoreChan:
+		}
 		go c.process(sha512Hex, nsCh)
 	}
 }
 
+// Stop stops the worker, the running syncs are completed and the waiting ones are left in the persisted queue
+func (c *nodeSyncWorker) Stop() {
+	c.stopOnce.Do(func() { close(c.stopChan) })
+}
+
 func (c *nodeSyncWorker) Queue(ns *nodeSync) {
 	c.placeAndQueue(ns)
 }
@@ -0,0 +1,78 @@
+package manager
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+)
+
+// readLoadDecay halves the read loads of the nodes in the interval, so the least loaded preference follows the recent
+// reads
+const readLoadDecay = time.Second * 10
+
+// readBalancer orders the readers of the round robin and least loaded preferences. Load of a node is the number of the
+// reads that are mapped to it first by the manager node
+type readBalancer struct {
+	mutex     sync.Mutex
+	turn      uint64
+	loads     map[string]float64
+	decayedAt time.Time
+}
+
+func newReadBalancer() *readBalancer {
+	return &readBalancer{
+		mutex:     sync.Mutex{},
+		loads:     make(map[string]float64),
+		decayedAt: time.Now(),
+	}
+}
+
+// Order orders the readers for the read preference and counts the read on the first one
+func (r *readBalancer) Order(readers common.NodeList, preference common.ReadPreference) common.NodeList {
+	if len(readers) == 0 {
+		return readers
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.decay()
+
+	switch preference {
+	case common.RP_RoundRobin:
+		shift := int(r.turn % uint64(len(readers)))
+		r.turn++
+
+		rotated := make(common.NodeList, 0, len(readers))
+		rotated = append(rotated, readers[shift:]...)
+		readers = append(rotated, readers[:shift]...)
+	case common.RP_LeastLoaded:
+		sort.SliceStable(readers, func(i, j int) bool {
+			return r.loads[readers[i].Id] < r.loads[readers[j].Id]
+		})
+	}
+	r.loads[readers[0].Id]++
+
+	return readers
+}
+
+func (r *readBalancer) decay() {
+	steps := int(time.Since(r.decayedAt) / readLoadDecay)
+	if steps == 0 {
+		return
+	}
+	r.decayedAt = r.decayedAt.Add(readLoadDecay * time.Duration(steps))
+
+	for nodeId, load := range r.loads {
+		for i := 0; i < steps && load >= 1; i++ {
+			load /= 2
+		}
+		if load < 1 {
+			delete(r.loads, nodeId)
+			continue
+		}
+		r.loads[nodeId] = load
+	}
+}
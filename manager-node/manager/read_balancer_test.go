@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func testReaders() common.NodeList {
+	return common.NodeList{
+		&common.Node{Id: "node1"},
+		&common.Node{Id: "node2"},
+		&common.Node{Id: "node3"},
+	}
+}
+
+func readerIds(readers common.NodeList) []string {
+	ids := make([]string, 0, len(readers))
+	for _, reader := range readers {
+		ids = append(ids, reader.Id)
+	}
+	return ids
+}
+
+func TestReadBalancer_RoundRobin(t *testing.T) {
+	r := newReadBalancer()
+
+	assert.Equal(t, []string{"node1", "node2", "node3"}, readerIds(r.Order(testReaders(), common.RP_RoundRobin)))
+	assert.Equal(t, []string{"node2", "node3", "node1"}, readerIds(r.Order(testReaders(), common.RP_RoundRobin)))
+	assert.Equal(t, []string{"node3", "node1", "node2"}, readerIds(r.Order(testReaders(), common.RP_RoundRobin)))
+	assert.Equal(t, []string{"node1", "node2", "node3"}, readerIds(r.Order(testReaders(), common.RP_RoundRobin)))
+
+	assert.Empty(t, r.Order(common.NodeList{}, common.RP_RoundRobin))
+}
+
+func TestReadBalancer_LeastLoaded(t *testing.T) {
+	r := newReadBalancer()
+	r.loads["node1"] = 3
+	r.loads["node2"] = 1
+
+	assert.Equal(t, []string{"node3", "node2", "node1"}, readerIds(r.Order(testReaders(), common.RP_LeastLoaded)))
+	// node2 and node3 have the same load, the order of the readers is kept
+	assert.Equal(t, []string{"node2", "node3", "node1"}, readerIds(r.Order(testReaders(), common.RP_LeastLoaded)))
+	assert.Equal(t, float64(2), r.loads["node2"])
+	assert.Equal(t, float64(1), r.loads["node3"])
+}
+
+func TestReadBalancer_Decay(t *testing.T) {
+	r := newReadBalancer()
+	r.loads["node1"] = 8
+	r.loads["node2"] = 1
+
+	r.decay()
+	assert.Equal(t, float64(8), r.loads["node1"])
+
+	r.decayedAt = r.decayedAt.Add(-readLoadDecay * 2)
+	r.decay()
+	assert.Equal(t, float64(2), r.loads["node1"])
+	_, has := r.loads["node2"]
+	assert.False(t, has)
+	assert.True(t, time.Since(r.decayedAt) < readLoadDecay)
+}
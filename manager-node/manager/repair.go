@@ -67,20 +67,26 @@ func (r *repair) Start(repairType RepairType) error {
 	}
 
 	go func() {
-		zapRepairType := zap.String("repairType", "full")
+		repairTypeName := "full"
 		switch repairType {
 		case RT_Structure:
-			zapRepairType = zap.String("repairType", "structure")
+			repairTypeName = "structure"
 		case RT_Integrity:
-			zapRepairType = zap.String("repairType", "integrity")
+			repairTypeName = "integrity"
 		}
+		zapRepairType := zap.String("repairType", repairTypeName)
 		r.logger.Info("Consistency repair is started...", zapRepairType)
 
+		repairRunning.Set(1)
+		defer repairRunning.Set(0)
+
 		if err := r.start(repairType); err != nil {
+			repairsTotal.WithLabelValues(repairTypeName, resultLabel(err)).Inc()
 			_ = r.operation.SetRepairing(false, false)
 			r.logger.Error("Consistency repair is failed", zap.Error(err))
 			return
 		}
+		repairsTotal.WithLabelValues(repairTypeName, resultLabel(nil)).Inc()
 		_ = r.operation.SetRepairing(false, true)
 		r.logger.Info("Consistency repair is completed")
 	}()
@@ -225,7 +231,7 @@ func (r *repair) repairIntegrityPhase1(clusterIndexMap map[string]map[string]str
 	}
 
 	metadataUsageMapMutex := sync.Mutex{}
-	metadataUsageMap := make(map[string]uint16)
+	metadataUsageMap := make(map[string]uint32)
 	increaseUsageMapFunc := func(sha512Hex string) {
 		metadataUsageMapMutex.Lock()
 		defer metadataUsageMapMutex.Unlock()
@@ -238,9 +244,9 @@ func (r *repair) repairIntegrityPhase1(clusterIndexMap map[string]map[string]str
 
 	r.logger.Info("Start traversing metadata entries for usage alignment cache")
 
-	if err := r.metadata.Cursor(func(folder *common.Folder) (bool, error) {
+	if err := r.metadata.Cursor(func(folder *common.Folder) (bool, common.Events, error) {
 		if len(folder.Files) == 0 {
-			return false, nil
+			return false, nil, nil
 		}
 
 		for _, file := range folder.Files {
@@ -254,14 +260,14 @@ func (r *repair) repairIntegrityPhase1(clusterIndexMap map[string]map[string]str
 			}
 		}
 
-		return false, nil
+		return false, nil, nil
 	}, parallelRepair); err != nil {
 		return err
 	}
 
 	r.logger.Info("Examine usages of metadata entries with data nodes")
 
-	mismatchedUsageMap := make(map[string]map[string]uint16)
+	mismatchedUsageMap := make(map[string]map[string]uint32)
 
 	for sha512Hex, metadataUsage := range metadataUsageMap {
 		indexValue, has := indexUsageMap[sha512Hex]
@@ -278,12 +284,12 @@ func (r *repair) repairIntegrityPhase1(clusterIndexMap map[string]map[string]str
 			return fmt.Errorf("faulty index entry for %s", sha512Hex)
 		}
 
-		indexUsage, err := strconv.ParseUint(indexValue[pipeIdx+1:], 10, 16)
+		indexUsage, err := strconv.ParseUint(indexValue[pipeIdx+1:], 10, 32)
 		if err != nil {
 			return err
 		}
 
-		if metadataUsage == uint16(indexUsage) {
+		if metadataUsage == uint32(indexUsage) {
 			continue
 		}
 
@@ -299,15 +305,15 @@ func (r *repair) repairIntegrityPhase1(clusterIndexMap map[string]map[string]str
 		}
 
 		if _, has := mismatchedUsageMap[cluster.Id]; !has {
-			mismatchedUsageMap[cluster.Id] = make(map[string]uint16)
+			mismatchedUsageMap[cluster.Id] = make(map[string]uint32)
 		}
 		mismatchedUsageMap[cluster.Id][sha512Hex] = metadataUsage
 
 		r.logger.Warn(
 			fmt.Sprintf("Found mismatching usage for %s, expected: %d, found: %d", sha512Hex, metadataUsage, indexUsage),
 			zap.String("sha512Hex", sha512Hex),
-			zap.Uint16("metadataUsage", metadataUsage),
-			zap.Uint16("indexUsage", uint16(indexUsage)),
+			zap.Uint32("metadataUsage", metadataUsage),
+			zap.Uint32("indexUsage", uint32(indexUsage)),
 		)
 	}
 
@@ -342,7 +348,7 @@ func (r *repair) repairIntegrityPhase1(clusterIndexMap map[string]map[string]str
 	return nil
 }
 
-func (r *repair) fixUsage(wg *sync.WaitGroup, clusterId string, masterNode *common.Node, usageMap map[string]uint16, errCh chan error) {
+func (r *repair) fixUsage(wg *sync.WaitGroup, clusterId string, masterNode *common.Node, usageMap map[string]uint32, errCh chan error) {
 	defer wg.Done()
 
 	mdn, err := cluster2.NewDataNode(masterNode.Address)
@@ -382,9 +388,9 @@ func (r *repair) repairIntegrityPhase2(clusterIndexMap map[string]map[string]str
 
 	r.logger.Info("Start traversing metadata entries for integrity check up")
 
-	if err := r.metadata.Cursor(func(folder *common.Folder) (bool, error) {
+	if err := r.metadata.Cursor(func(folder *common.Folder) (bool, common.Events, error) {
 		if len(folder.Files) == 0 {
-			return false, nil
+			return false, nil, nil
 		}
 
 		for _, file := range folder.Files {
@@ -409,7 +415,7 @@ func (r *repair) repairIntegrityPhase2(clusterIndexMap map[string]map[string]str
 				cacheFileItem, err := r.index.Get(chunk.Hash)
 				if err != nil {
 					if err != os.ErrNotExist {
-						return false, err
+						return false, nil, err
 					}
 					deletionResult.Missing = append(deletionResult.Missing, chunk.Hash)
 					continue
@@ -440,7 +446,7 @@ func (r *repair) repairIntegrityPhase2(clusterIndexMap map[string]map[string]str
 			}
 		}
 
-		return true, nil
+		return true, nil, nil
 	}, parallelRepair); err != nil {
 		return err
 	}
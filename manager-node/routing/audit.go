@@ -0,0 +1,40 @@
+package routing
+
+import (
+	"github.com/freakmaxi/kertish-dfs/basics/audit"
+	"go.uber.org/zap"
+)
+
+type auditRouter struct {
+	auditor audit.Auditor
+	logger  *zap.Logger
+
+	definitions []*Definition
+}
+
+func NewAuditRouter(auditor audit.Auditor, logger *zap.Logger) Router {
+	pR := &auditRouter{
+		auditor:     auditor,
+		logger:      logger,
+		definitions: make([]*Definition, 0),
+	}
+	pR.setup()
+
+	return pR
+}
+
+func (a *auditRouter) setup() {
+	a.definitions =
+		append(a.definitions,
+			&Definition{
+				Path:    "/client/audit",
+				Handler: audit.NewHandler(a.auditor, a.logger),
+			},
+		)
+}
+
+func (a *auditRouter) Get() []*Definition {
+	return a.definitions
+}
+
+var _ Router = &auditRouter{}
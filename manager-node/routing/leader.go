@@ -0,0 +1,82 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/freakmaxi/kertish-dfs/manager-node/manager"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+const forwardedHeader = "X-Leader-Forwarded"
+
+type leaderForwarder struct {
+	election manager.Election
+	logger   *zap.Logger
+}
+
+// NewLeaderForwarder creates the middleware that forwards the state changing requests of followers to the leader
+func NewLeaderForwarder(election manager.Election, logger *zap.Logger) mux.MiddlewareFunc {
+	f := &leaderForwarder{
+		election: election,
+		logger:   logger,
+	}
+	return f.middleware
+}
+
+func (f *leaderForwarder) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.election.Leader() || !f.requiresLeader(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		leaderAddress := f.election.LeaderAddress()
+		if len(leaderAddress) == 0 || len(r.Header.Get(forwardedHeader)) > 0 {
+			w.WriteHeader(503)
+			return
+		}
+
+		target, err := url.Parse(leaderAddress)
+		if err != nil {
+			f.logger.Error("Leader address is not valid", zap.String("leaderAddress", leaderAddress), zap.Error(err))
+			w.WriteHeader(503)
+			return
+		}
+
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			f.logger.Error("Forwarding request to leader is failed", zap.String("leaderAddress", leaderAddress), zap.Error(err))
+			w.WriteHeader(502)
+		}
+
+		r.Header.Set(forwardedHeader, "true")
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+func (f *leaderForwarder) requiresLeader(r *http.Request) bool {
+	action := r.Header.Get("X-Action")
+
+	switch r.URL.Path {
+	case "/client/manager":
+		switch r.Method {
+		case "GET":
+			switch action {
+			case "sync", "repair", "move", "balance":
+				return true
+			}
+			return false
+		case "POST":
+			return strings.Compare(action, "readMap") != 0
+		}
+		return true
+	case "/client/node":
+		// handshake only reads the cluster of the node, notify and missing change the cluster state
+		return strings.Compare(action, "handshake") != 0
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type testElection struct {
+	leader        bool
+	leaderAddress string
+}
+
+func (t *testElection) Start(_ func(), _ func()) {}
+
+func (t *testElection) Stop() {}
+
+func (t *testElection) Leader() bool {
+	return t.leader
+}
+
+func (t *testElection) LeaderAddress() string {
+	return t.leaderAddress
+}
+
+func TestLeaderForwarder_Follower(t *testing.T) {
+	forwarded := make([]string, 0)
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = append(forwarded, r.Header.Get("X-Action"))
+		assert.Equal(t, "true", r.Header.Get(forwardedHeader))
+	}))
+	defer leader.Close()
+
+	served := make([]string, 0)
+	handler := NewLeaderForwarder(&testElection{leaderAddress: leader.URL}, zap.NewNop())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = append(served, r.Header.Get("X-Action"))
+		}),
+	)
+
+	for _, action := range []string{"handshake", "notify", "missing", "repair"} {
+		r := httptest.NewRequest("POST", "/client/node", nil)
+		r.Header.Set("X-Action", action)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, 200, w.Code)
+	}
+
+	assert.Equal(t, []string{"handshake"}, served)
+	assert.Equal(t, []string{"notify", "missing", "repair"}, forwarded)
+}
@@ -1,8 +1,16 @@
 package routing
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 
+	"github.com/freakmaxi/kertish-dfs/basics/audit"
+	"github.com/freakmaxi/kertish-dfs/basics/common"
+	"github.com/freakmaxi/kertish-dfs/basics/tracing"
 	"github.com/freakmaxi/kertish-dfs/manager-node/manager"
 	"go.uber.org/zap"
 )
@@ -12,17 +20,21 @@ type managerRouter struct {
 	synchronize manager.Synchronize
 	repair      manager.Repair
 	health      manager.HealthCheck
+	lifecycle   manager.Lifecycle
+	auditor     audit.Auditor
 	logger      *zap.Logger
 
 	definitions []*Definition
 }
 
-func NewManagerRouter(clusterManager manager.Cluster, synchronize manager.Synchronize, repair manager.Repair, health manager.HealthCheck, logger *zap.Logger) Router {
+func NewManagerRouter(clusterManager manager.Cluster, synchronize manager.Synchronize, repair manager.Repair, health manager.HealthCheck, lifecycle manager.Lifecycle, auditor audit.Auditor, logger *zap.Logger) Router {
 	pR := &managerRouter{
 		manager:     clusterManager,
 		synchronize: synchronize,
 		repair:      repair,
 		health:      health,
+		lifecycle:   lifecycle,
+		auditor:     auditor,
 		logger:      logger,
 		definitions: make([]*Definition, 0),
 	}
@@ -48,6 +60,27 @@ func (m *managerRouter) Get() []*Definition {
 func (m *managerRouter) manipulate(w http.ResponseWriter, r *http.Request) {
 	defer func() { _ = r.Body.Close() }()
 
+	statusWriter := audit.NewResponseWriter(w)
+	w = statusWriter
+
+	ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), fmt.Sprintf("manager.%s:%s", strings.ToLower(r.Method), r.Header.Get("X-Action")))
+	r = r.WithContext(ctx)
+	defer func() {
+		span.SetAttribute("statusCode", strconv.Itoa(statusWriter.StatusCode()))
+		if statusWriter.StatusCode() >= 500 {
+			span.SetError(fmt.Errorf("request is failed with status code %d", statusWriter.StatusCode()))
+		}
+		span.End()
+	}()
+
+	if m.auditable(r.Method, r.Header.Get("X-Action")) {
+		auditEntry := m.describeAuditEntry(r)
+		defer func() {
+			auditEntry.Complete(statusWriter.StatusCode())
+			m.auditor.Record(auditEntry)
+		}()
+	}
+
 	switch r.Method {
 	case "POST":
 		m.handlePost(w, r)
@@ -62,4 +95,53 @@ func (m *managerRouter) manipulate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (m *managerRouter) auditable(method string, action string) bool {
+	switch method {
+	case "GET":
+		switch action {
+		case "sync", "repair", "move", "balance":
+			return true
+		}
+	case "POST":
+		switch action {
+		case "register", "snapshot", "lifecycle", "blockSize", "writeQuorum", "readPreference":
+			return true
+		}
+	case "PUT":
+		return true
+	case "DELETE":
+		switch action {
+		case "unregister", "unfreeze", "snapshot", "lifecycle":
+			return true
+		}
+	}
+	return false
+}
+
+func (m *managerRouter) describeAuditEntry(r *http.Request) *common.AuditEntry {
+	paths := make([]string, 0)
+	if xPath := r.Header.Get("X-Path"); len(xPath) > 0 {
+		folderPath, err := m.describeXPath(xPath)
+		if err != nil {
+			folderPath = xPath
+		}
+		paths = append(paths, folderPath)
+	}
+
+	operation := fmt.Sprintf("%s:%s", strings.ToLower(r.Method), r.Header.Get("X-Action"))
+
+	return audit.NewEntry(r, operation, paths, r.Header.Get("X-Options"))
+}
+
+func (m *managerRouter) describeXPath(xPath string) (string, error) {
+	folderPath, err := url.QueryUnescape(xPath)
+	if err != nil {
+		return "", err
+	}
+	if !common.ValidatePath(folderPath) {
+		return "", os.ErrInvalid
+	}
+	return folderPath, nil
+}
+
 var _ Router = &managerRouter{}
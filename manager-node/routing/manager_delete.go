@@ -31,6 +31,8 @@ func (m *managerRouter) handleDelete(w http.ResponseWriter, r *http.Request) {
 		m.handleCommit(w, r)
 	case "discard":
 		m.handleDiscard(w, r)
+	case "lifecycle":
+		m.handleDeleteLifecycle(w, r)
 	default:
 		w.WriteHeader(406)
 	}
@@ -168,9 +170,34 @@ func (m *managerRouter) handleDiscard(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(200)
 }
 
+func (m *managerRouter) handleDeleteLifecycle(w http.ResponseWriter, r *http.Request) {
+	folderPath, err := m.describeXPath(r.Header.Get("X-Path"))
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	if err := m.lifecycle.Set(folderPath, nil); err != nil {
+		if err == os.ErrNotExist {
+			w.WriteHeader(404)
+		} else {
+			w.WriteHeader(500)
+			m.logger.Error("Delete lifecycle request is failed", zap.String("path", folderPath), zap.Error(err))
+		}
+
+		e := common.NewError(390, err.Error())
+		if err := json.NewEncoder(w).Encode(e); err != nil {
+			m.logger.Error("Response of delete lifecycle request is failed", zap.Error(err))
+		}
+		return
+	}
+
+	w.WriteHeader(200)
+}
+
 func (m *managerRouter) validateDeleteAction(action string) bool {
 	switch action {
-	case "unregister", "unfreeze", "snapshot", "commit", "discard":
+	case "unregister", "unfreeze", "snapshot", "commit", "discard", "lifecycle":
 		return true
 	}
 	return false
@@ -37,6 +37,8 @@ func (m *managerRouter) handleGet(w http.ResponseWriter, r *http.Request) {
 		m.handleClusters(w, r)
 	case "find":
 		m.handleFind(w, r)
+	case "lifecycle":
+		m.handleGetLifecycle(w, r)
 	default:
 		w.WriteHeader(406)
 	}
@@ -248,9 +250,37 @@ func (m *managerRouter) handleFind(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (m *managerRouter) handleGetLifecycle(w http.ResponseWriter, r *http.Request) {
+	folderPath, err := m.describeXPath(r.Header.Get("X-Path"))
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	lifecycle, err := m.lifecycle.Get(folderPath)
+	if err == nil {
+		if err := json.NewEncoder(w).Encode(lifecycle); err != nil {
+			m.logger.Error("Response of get lifecycle request is failed", zap.Error(err))
+		}
+		return
+	}
+
+	if err == os.ErrNotExist {
+		w.WriteHeader(404)
+	} else {
+		w.WriteHeader(500)
+		m.logger.Error("Get lifecycle request is failed", zap.String("path", folderPath), zap.Error(err))
+	}
+
+	e := common.NewError(145, err.Error())
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		m.logger.Error("Response of get lifecycle request is failed", zap.Error(err))
+	}
+}
+
 func (m *managerRouter) validateGetAction(action string) bool {
 	switch action {
-	case "sync", "repair", "health", "move", "balance", "clusters", "find":
+	case "sync", "repair", "health", "move", "balance", "clusters", "find", "lifecycle":
 		return true
 	}
 	return false
@@ -2,6 +2,8 @@ package routing
 
 import (
 	"encoding/json"
+	"io"
+	"math"
 	"net/http"
 	"os"
 	"strconv"
@@ -36,6 +38,16 @@ func (m *managerRouter) handlePost(w http.ResponseWriter, r *http.Request) {
 			mapType = common.MT_Delete
 		}
 		m.handleMap(w, r, mapType)
+	case "replicaMap":
+		m.handleReplicaMap(w, r)
+	case "lifecycle":
+		m.handleSetLifecycle(w, r)
+	case "blockSize":
+		m.handleSetBlockSize(w, r)
+	case "writeQuorum":
+		m.handleSetWriteQuorum(w, r)
+	case "readPreference":
+		m.handleSetReadPreference(w, r)
 	default:
 		w.WriteHeader(406)
 	}
@@ -108,7 +120,25 @@ func (m *managerRouter) handleReserve(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reservationMap, err := m.manager.Reserve(size)
+	chunkSizes, err := m.describeReserveOptions(r, size)
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	blockSize, err := m.describeBlockSize(r.Header.Get("X-Block-Size"))
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	writeQuorum := common.WriteQuorum(r.Header.Get("X-Write-Quorum"))
+	if !writeQuorum.Valid() {
+		w.WriteHeader(422)
+		return
+	}
+
+	reservationMap, err := m.manager.Reserve(size, blockSize, writeQuorum, chunkSizes)
 	if err == nil {
 		if err := json.NewEncoder(w).Encode(reservationMap); err != nil {
 			m.logger.Error("Response of reserve request is failed", zap.Error(err))
@@ -138,7 +168,13 @@ func (m *managerRouter) handleMap(w http.ResponseWriter, r *http.Request, mapTyp
 		return
 	}
 
-	clusterMapping, err := m.manager.Map(sha512HexList, mapType)
+	readPreference := common.ReadPreference(r.Header.Get("X-Read-Preference"))
+	if !readPreference.Valid() {
+		w.WriteHeader(422)
+		return
+	}
+
+	clusterMapping, err := m.manager.Map(sha512HexList, mapType, readPreference)
 	if err == nil {
 		if err := json.NewEncoder(w).Encode(clusterMapping); err != nil {
 			m.logger.Error("Response of map request is failed", zap.Error(err))
@@ -161,14 +197,278 @@ func (m *managerRouter) handleMap(w http.ResponseWriter, r *http.Request, mapTyp
 	}
 }
 
+func (m *managerRouter) handleReplicaMap(w http.ResponseWriter, r *http.Request) {
+	sha512HexList := strings.Split(r.Header.Get("X-Options"), ",")
+	if len(sha512HexList) == 0 {
+		w.WriteHeader(422)
+		return
+	}
+
+	readPreference := common.ReadPreference(r.Header.Get("X-Read-Preference"))
+	if !readPreference.Valid() {
+		w.WriteHeader(422)
+		return
+	}
+
+	replicaMapping, err := m.manager.ReplicaMap(sha512HexList, readPreference)
+	if err == nil {
+		if err := json.NewEncoder(w).Encode(replicaMapping); err != nil {
+			m.logger.Error("Response of replica map request is failed", zap.Error(err))
+		}
+		return
+	}
+
+	if err == os.ErrNotExist {
+		w.WriteHeader(404)
+	} else if err == errors.ErrNoAvailableClusterNode || err == errors.ErrNoAvailableActionNode {
+		w.WriteHeader(503)
+	} else {
+		w.WriteHeader(400)
+		m.logger.Error("Replica map request is failed", zap.Strings("sha512HexList", sha512HexList), zap.Error(err))
+	}
+
+	e := common.NewError(220, err.Error())
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		m.logger.Error("Response of replica map request is failed", zap.Error(err))
+	}
+}
+
+func (m *managerRouter) handleSetLifecycle(w http.ResponseWriter, r *http.Request) {
+	folderPath, err := m.describeXPath(r.Header.Get("X-Path"))
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	lifecycle, err := common.ParseLifecycle(r.Header.Get("X-Options"))
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	err = m.lifecycle.Set(folderPath, lifecycle)
+	if err == nil {
+		return
+	}
+
+	if err == os.ErrNotExist {
+		w.WriteHeader(404)
+	} else {
+		w.WriteHeader(500)
+		m.logger.Error(
+			"Set lifecycle request is failed",
+			zap.String("path", folderPath),
+			zap.String("lifecycle", lifecycle.String()),
+			zap.Error(err),
+		)
+	}
+
+	e := common.NewError(225, err.Error())
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		m.logger.Error("Response of set lifecycle request is failed", zap.Error(err))
+	}
+}
+
+func (m *managerRouter) handleSetBlockSize(w http.ResponseWriter, r *http.Request) {
+	clusterId, blockSize, err := m.describeSetBlockSizeOptions(r.Header.Get("X-Options"))
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	err = m.manager.SetBlockSize(clusterId, blockSize)
+	if err == nil {
+		return
+	}
+
+	if err == errors.ErrNotFound {
+		w.WriteHeader(404)
+	} else {
+		w.WriteHeader(500)
+		m.logger.Error(
+			"Set block size request is failed",
+			zap.String("clusterId", clusterId),
+			zap.Uint32("blockSize", blockSize),
+			zap.Error(err),
+		)
+	}
+
+	e := common.NewError(230, err.Error())
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		m.logger.Error("Response of set block size request is failed", zap.Error(err))
+	}
+}
+
+func (m *managerRouter) handleSetWriteQuorum(w http.ResponseWriter, r *http.Request) {
+	clusterId, writeQuorum, err := m.describeSetWriteQuorumOptions(r.Header.Get("X-Options"))
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	err = m.manager.SetWriteQuorum(clusterId, writeQuorum)
+	if err == nil {
+		return
+	}
+
+	if err == errors.ErrNotFound {
+		w.WriteHeader(404)
+	} else {
+		w.WriteHeader(500)
+		m.logger.Error(
+			"Set write quorum request is failed",
+			zap.String("clusterId", clusterId),
+			zap.String("writeQuorum", string(writeQuorum)),
+			zap.Error(err),
+		)
+	}
+
+	e := common.NewError(235, err.Error())
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		m.logger.Error("Response of set write quorum request is failed", zap.Error(err))
+	}
+}
+
+func (m *managerRouter) handleSetReadPreference(w http.ResponseWriter, r *http.Request) {
+	clusterId, readPreference, err := m.describeSetReadPreferenceOptions(r.Header.Get("X-Options"))
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	err = m.manager.SetReadPreference(clusterId, readPreference)
+	if err == nil {
+		return
+	}
+
+	if err == errors.ErrNotFound {
+		w.WriteHeader(404)
+	} else {
+		w.WriteHeader(500)
+		m.logger.Error(
+			"Set read preference request is failed",
+			zap.String("clusterId", clusterId),
+			zap.String("readPreference", string(readPreference)),
+			zap.Error(err),
+		)
+	}
+
+	e := common.NewError(240, err.Error())
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		m.logger.Error("Response of set read preference request is failed", zap.Error(err))
+	}
+}
+
 func (m *managerRouter) validatePostAction(action string) bool {
 	switch action {
-	case "register", "snapshot", "reserve", "readMap", "createMap", "deleteMap":
+	case "register", "snapshot", "reserve", "readMap", "createMap", "deleteMap", "replicaMap", "lifecycle", "blockSize",
+		"writeQuorum", "readPreference":
 		return true
 	}
 	return false
 }
 
+// describeReserveOptions parses the chunk sizes calculated on the head node, total of them should be the reservation
+// size. Chunk sizes are sent as json array in the body, X-Options header is accepted for the older head nodes
+func (m *managerRouter) describeReserveOptions(r *http.Request, size uint64) ([]uint32, error) {
+	chunkSizes := make([]uint32, 0)
+
+	if options := r.Header.Get("X-Options"); len(options) > 0 {
+		for _, chunkSizeString := range strings.Split(options, ",") {
+			chunkSize, err := strconv.ParseUint(chunkSizeString, 10, 32)
+			if err != nil {
+				return nil, os.ErrInvalid
+			}
+			chunkSizes = append(chunkSizes, uint32(chunkSize))
+		}
+	} else if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&chunkSizes); err != nil && err != io.EOF {
+			return nil, os.ErrInvalid
+		}
+	}
+
+	total := uint64(0)
+	for _, chunkSize := range chunkSizes {
+		if chunkSize == 0 || chunkSize > common.MaxBlockSize {
+			return nil, os.ErrInvalid
+		}
+		total += uint64(chunkSize)
+	}
+
+	if len(chunkSizes) > 0 && total != size || len(chunkSizes) > math.MaxUint16+1 {
+		return nil, os.ErrInvalid
+	}
+
+	return chunkSizes, nil
+}
+
+// describeBlockSize parses the block size requested for the upload, empty value means the cluster block size
+func (m *managerRouter) describeBlockSize(value string) (uint32, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	blockSize, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if blockSize < uint64(common.MinBlockSize) || blockSize > uint64(common.MaxBlockSize) {
+		return 0, os.ErrInvalid
+	}
+
+	return uint32(blockSize), nil
+}
+
+// describeSetBlockSizeOptions parses clusterId=blockSize, 0 block size resets the cluster to the default block size
+func (m *managerRouter) describeSetBlockSizeOptions(options string) (string, uint32, error) {
+	eqIdx := strings.Index(options, "=")
+	if eqIdx < 1 {
+		return "", 0, os.ErrInvalid
+	}
+	clusterId, value := options[:eqIdx], options[eqIdx+1:]
+
+	if strings.Compare(value, "0") == 0 {
+		return clusterId, 0, nil
+	}
+
+	blockSize, err := m.describeBlockSize(value)
+	if err != nil || blockSize == 0 {
+		return "", 0, os.ErrInvalid
+	}
+
+	return clusterId, blockSize, nil
+}
+
+// describeSetWriteQuorumOptions parses clusterId=writeQuorum, empty write quorum resets the cluster to the master only
+func (m *managerRouter) describeSetWriteQuorumOptions(options string) (string, common.WriteQuorum, error) {
+	eqIdx := strings.Index(options, "=")
+	if eqIdx < 1 {
+		return "", "", os.ErrInvalid
+	}
+
+	writeQuorum := common.WriteQuorum(options[eqIdx+1:])
+	if !writeQuorum.Valid() {
+		return "", "", os.ErrInvalid
+	}
+
+	return options[:eqIdx], writeQuorum, nil
+}
+
+// describeSetReadPreferenceOptions parses clusterId=readPreference, empty read preference resets the cluster to nearest
+func (m *managerRouter) describeSetReadPreferenceOptions(options string) (string, common.ReadPreference, error) {
+	eqIdx := strings.Index(options, "=")
+	if eqIdx < 1 {
+		return "", "", os.ErrInvalid
+	}
+
+	readPreference := common.ReadPreference(options[eqIdx+1:])
+	if !readPreference.Valid() {
+		return "", "", os.ErrInvalid
+	}
+
+	return options[:eqIdx], readPreference, nil
+}
+
 func (m *managerRouter) describeRegisterOptions(options string) (string, []string) {
 	clusterId := ""
 	eqIdx := strings.Index(options, "=")
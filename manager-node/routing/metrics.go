@@ -0,0 +1,48 @@
+package routing
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type metricsRouter struct {
+	handler http.Handler
+
+	definitions []*Definition
+}
+
+func NewMetricsRouter() Router {
+	pR := &metricsRouter{
+		handler:     promhttp.Handler(),
+		definitions: make([]*Definition, 0),
+	}
+	pR.setup()
+
+	return pR
+}
+
+func (m *metricsRouter) setup() {
+	m.definitions =
+		append(m.definitions,
+			&Definition{
+				Path:    "/metrics",
+				Handler: m.manipulate,
+			},
+		)
+}
+
+func (m *metricsRouter) Get() []*Definition {
+	return m.definitions
+}
+
+func (m *metricsRouter) manipulate(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		m.handler.ServeHTTP(w, r)
+	default:
+		w.WriteHeader(406)
+	}
+}
+
+var _ Router = &metricsRouter{}
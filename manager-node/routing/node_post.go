@@ -26,6 +26,10 @@ func (n *nodeRouter) handlePost(w http.ResponseWriter, r *http.Request) {
 		n.handleHandshake(w, r)
 	case "notify":
 		n.handleNotify(w, r)
+	case "missing":
+		n.handleMissing(w, r)
+	case "repair":
+		n.handleRepair(w, r)
 	default:
 		w.WriteHeader(406)
 	}
@@ -81,9 +85,53 @@ func (n *nodeRouter) handleNotify(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(202)
 }
 
+func (n *nodeRouter) handleMissing(w http.ResponseWriter, r *http.Request) {
+	nodeId, sha512HexList, err := n.describeMissingOptions(r)
+	if err != nil {
+		w.WriteHeader(422)
+		return
+	}
+
+	if err := n.manager.Missing(nodeId, sha512HexList); err != nil {
+		if err == errors.ErrNotFound {
+			w.WriteHeader(404)
+		} else if err == errors.ErrNoAvailableClusterNode {
+			w.WriteHeader(503)
+		} else {
+			w.WriteHeader(500)
+			n.logger.Error("Node missing request is failed", zap.Error(err))
+		}
+		return
+	}
+
+	w.WriteHeader(202)
+}
+
+func (n *nodeRouter) handleRepair(w http.ResponseWriter, r *http.Request) {
+	nodeId := r.Header.Get("X-Options")
+	if len(nodeId) == 0 {
+		w.WriteHeader(422)
+		return
+	}
+
+	if err := n.manager.Repair(nodeId); err != nil {
+		if err == errors.ErrNotFound {
+			w.WriteHeader(404)
+		} else if err == errors.ErrNoAvailableClusterNode {
+			w.WriteHeader(503)
+		} else {
+			w.WriteHeader(500)
+			n.logger.Error("Node repair request is failed", zap.Error(err))
+		}
+		return
+	}
+
+	w.WriteHeader(202)
+}
+
 func (n *nodeRouter) validatePostAction(action string) bool {
 	switch action {
-	case "handshake", "notify":
+	case "handshake", "notify", "missing", "repair":
 		return true
 	}
 	return false
@@ -127,3 +175,28 @@ func (n *nodeRouter) describeNotifyOptions(r *http.Request) (string, common.Noti
 
 	return nodeId, notificationContainerList, nil
 }
+
+func (n *nodeRouter) describeMissingOptions(r *http.Request) (string, []string, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", nil, err
+	}
+
+	nodeId := r.Header.Get("X-Options")
+	sha512HexList := make([]string, 0)
+	if err := json.Unmarshal(body, &sha512HexList); err != nil {
+		return "", nil, err
+	}
+
+	if len(nodeId) == 0 || len(sha512HexList) == 0 {
+		return "", nil, os.ErrInvalid
+	}
+
+	for _, sha512Hex := range sha512HexList {
+		if len(sha512Hex) != 64 {
+			return "", nil, os.ErrInvalid
+		}
+	}
+
+	return nodeId, sha512HexList, nil
+}
@@ -33,6 +33,10 @@ func (m *Manager) Add(router Router) {
 	}
 }
 
+func (m *Manager) Use(middleware mux.MiddlewareFunc) {
+	m.mux.Use(middleware)
+}
+
 func (m *Manager) Get() *mux.Router {
 	return m.mux
 }
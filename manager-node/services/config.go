@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ReadBindAddress reads the service binding address from the environment variable of the key. Manager node uses
+// BIND_ADDRESS and the head node uses MANAGER_BIND_ADDRESS for the manager node that it runs. Default: :9400
+func ReadBindAddress(key string, logger *zap.Logger) string {
+	bindAddr := os.Getenv(key)
+	if len(bindAddr) == 0 {
+		bindAddr = ":9400"
+	}
+	logger.Info(fmt.Sprintf("%s: %s", key, bindAddr))
+
+	return bindAddr
+}
+
+// ReadHealthCheckInterval reads HEALTH_CHECK_INTERVAL in seconds. Default: 10
+func ReadHealthCheckInterval(logger *zap.Logger) (time.Duration, error) {
+	healthCheckIntervalString := os.Getenv("HEALTH_CHECK_INTERVAL")
+	if len(healthCheckIntervalString) == 0 {
+		healthCheckIntervalString = "10"
+	}
+	healthCheckInterval, err := strconv.ParseUint(healthCheckIntervalString, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if healthCheckInterval > 0 {
+		logger.Info(fmt.Sprintf("HEALTH_CHECK_INTERVAL: %s second(s)", healthCheckIntervalString))
+	}
+
+	return time.Second * time.Duration(healthCheckInterval), nil
+}
+
+// ReadLifecycleInterval reads LIFECYCLE_INTERVAL in seconds, 0 disables the lifecycle job. Default: 3600
+func ReadLifecycleInterval(logger *zap.Logger) (time.Duration, error) {
+	lifecycleIntervalString := os.Getenv("LIFECYCLE_INTERVAL")
+	if len(lifecycleIntervalString) == 0 {
+		lifecycleIntervalString = "3600"
+	}
+	lifecycleInterval, err := strconv.ParseUint(lifecycleIntervalString, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if lifecycleInterval > 0 {
+		logger.Info(fmt.Sprintf("LIFECYCLE_INTERVAL: %s second(s)", lifecycleIntervalString))
+	} else {
+		logger.Info("LIFECYCLE_INTERVAL: disabled")
+	}
+
+	return time.Second * time.Duration(lifecycleInterval), nil
+}
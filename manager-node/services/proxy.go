@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 
 	"github.com/freakmaxi/kertish-dfs/manager-node/routing"
@@ -10,21 +11,33 @@ import (
 
 type Proxy struct {
 	bindAddr string
-	manager  *routing.Manager
+	server   *http.Server
 	logger   *zap.Logger
 }
 
 func NewProxy(bindAddr string, manager *routing.Manager, logger *zap.Logger) *Proxy {
 	return &Proxy{
 		bindAddr: bindAddr,
-		manager:  manager,
+		server:   &http.Server{Addr: bindAddr, Handler: manager.Get()},
 		logger:   logger,
 	}
 }
 
 func (p *Proxy) Start() {
 	p.logger.Info(fmt.Sprintf("Manager Service is running on %s", p.bindAddr))
-	if err := http.ListenAndServe(p.bindAddr, p.manager.Get()); err != nil {
+	if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		p.logger.Error("Manager service is failed", zap.Error(err))
 	}
 }
+
+// Serve serves on the listener instead of the bind address. It blocks until the proxy is stopped
+func (p *Proxy) Serve(listener net.Listener) error {
+	if err := p.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (p *Proxy) Stop() {
+	_ = p.server.Close()
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/freakmaxi/kertish-dfs/basics/audit"
+	"github.com/freakmaxi/kertish-dfs/basics/embedded"
+	"github.com/freakmaxi/kertish-dfs/basics/events"
+	"github.com/freakmaxi/kertish-dfs/manager-node/data"
+	"github.com/freakmaxi/kertish-dfs/manager-node/manager"
+	"github.com/freakmaxi/kertish-dfs/manager-node/routing"
+	"github.com/freakmaxi/locking-center-client-go/mutex"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	BindAddr            string
+	AdvertiseAddr       string
+	HealthCheckInterval time.Duration
+	LifecycleInterval   time.Duration
+	LeaderLease         time.Duration
+	// Registerer is used for the cluster metrics, prometheus default registerer if it is nil
+	Registerer prometheus.Registerer
+}
+
+// Stores keeps the data managers of the manager node
+type Stores struct {
+	Clusters   data.Clusters
+	Index      data.Index
+	Operation  data.Operation
+	Metadata   data.Metadata
+	Audit      audit.Store
+	SyncQueue  data.SyncQueue
+	Leadership data.Leadership
+}
+
+// NewEmbeddedStores creates the data managers on the embedded store. The locking center should be the one that the
+// head node in the same process uses
+func NewEmbeddedStores(store embedded.Store, m mutex.LockingCenter) (*Stores, error) {
+	var err error
+	stores := &Stores{}
+
+	if stores.Clusters, err = data.NewClustersEmbedded(store, m); err != nil {
+		return nil, err
+	}
+	if stores.Index, err = data.NewIndexEmbedded(store, m); err != nil {
+		return nil, err
+	}
+	if stores.Operation, err = data.NewOperationEmbedded(store); err != nil {
+		return nil, err
+	}
+	eventStore, err := events.NewStoreEmbedded(store, 0)
+	if err != nil {
+		return nil, err
+	}
+	if stores.Metadata, err = data.NewMetadataEmbedded(store, m, eventStore); err != nil {
+		return nil, err
+	}
+	if stores.Audit, err = audit.NewStoreEmbedded(store); err != nil {
+		return nil, err
+	}
+	if stores.SyncQueue, err = data.NewSyncQueueEmbedded(store); err != nil {
+		return nil, err
+	}
+	if stores.Leadership, err = data.NewLeadershipEmbedded(store); err != nil {
+		return nil, err
+	}
+	return stores, nil
+}
+
+// Service is the manager node that serves on the bind address
+type Service struct {
+	config  Config
+	logger  *zap.Logger
+	elected func()
+	demoted func()
+
+	cluster     manager.Cluster
+	synchronize manager.Synchronize
+	election    manager.Election
+	proxy       *Proxy
+}
+
+func NewService(config Config, stores *Stores, logger *zap.Logger) (*Service, error) {
+	synchronize := manager.NewSynchronize(stores.Clusters, stores.Index, logger)
+	repair := manager.NewRepair(stores.Clusters, stores.Metadata, stores.Index, stores.Operation, synchronize, logger)
+
+	health := manager.NewHealthTracker(stores.Clusters, stores.Index, synchronize, repair, logger, config.HealthCheckInterval)
+
+	managerCluster, err := manager.NewCluster(stores.Clusters, stores.Index, synchronize, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	auditor := audit.NewAuditor(stores.Audit, fmt.Sprintf("manager-node/%s%s", hostname, config.BindAddr), logger)
+
+	lifecycle := manager.NewLifecycle(stores.Metadata, managerCluster, auditor, logger, config.LifecycleInterval)
+
+	managerRouter := routing.NewManagerRouter(managerCluster, synchronize, repair, health, lifecycle, auditor, logger)
+
+	routerManager := routing.NewManager()
+	routerManager.Add(managerRouter)
+
+	auditRouter := routing.NewAuditRouter(auditor, logger)
+	routerManager.Add(auditRouter)
+
+	managerNode := manager.NewNode(stores.Clusters, stores.Index, stores.SyncQueue, logger)
+	nodeRouter := routing.NewNodeRouter(managerNode, logger)
+	routerManager.Add(nodeRouter)
+
+	registerer := config.Registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	if err := registerer.Register(manager.NewClusterCollector(stores.Clusters, logger)); err != nil {
+		return nil, err
+	}
+	metricsRouter := routing.NewMetricsRouter()
+	routerManager.Add(metricsRouter)
+
+	election := manager.NewElection(stores.Leadership, config.AdvertiseAddr, config.LeaderLease, logger)
+	routerManager.Use(routing.NewLeaderForwarder(election, logger))
+
+	return &Service{
+		config: config,
+		logger: logger,
+		elected: func() {
+			health.Start()
+			lifecycle.Start()
+
+			go func() {
+				if err := managerCluster.Handshake(); err != nil {
+					logger.Error("Handshake is failed with cluster nodes", zap.Error(err))
+				} else {
+					logger.Info("Handshake is completed with cluster nodes...")
+				}
+
+				if err := managerNode.Resume(); err != nil {
+					logger.Error("Resuming node syncs is failed", zap.Error(err))
+				}
+			}()
+		},
+		demoted: func() {
+			health.Stop()
+			lifecycle.Stop()
+			managerNode.Stop()
+
+			logger.Warn("Leadership is lost, manager node continues as follower")
+		},
+		cluster:     managerCluster,
+		synchronize: synchronize,
+		election:    election,
+		proxy:       NewProxy(config.BindAddr, routerManager, logger),
+	}, nil
+}
+
+// Start joins the leader election and serves the requests on the bind address. It blocks until the service fails
+func (s *Service) Start() {
+	s.election.Start(s.elected, s.demoted)
+
+	s.proxy.Start()
+}
+
+// Serve joins the leader election and serves the requests on the listener. It blocks until the service is stopped
+func (s *Service) Serve(listener net.Listener) error {
+	s.election.Start(s.elected, s.demoted)
+
+	return s.proxy.Serve(listener)
+}
+
+// Stop leaves the leader election, stops the background jobs and closes the server
+func (s *Service) Stop() {
+	s.election.Stop()
+	s.proxy.Stop()
+}
+
+func (s *Service) Cluster() manager.Cluster {
+	return s.cluster
+}
+
+func (s *Service) Synchronize() manager.Synchronize {
+	return s.synchronize
+}